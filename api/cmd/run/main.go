@@ -2,11 +2,14 @@ package main
 
 import (
 	"context"
+	"crawlquery/api/domain"
 	"crawlquery/api/migration"
 	"crawlquery/api/router"
+	"crawlquery/pkg/canonical"
 	"database/sql"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	eventService "crawlquery/api/event/service"
@@ -18,9 +21,21 @@ import (
 	accountMysqlRepo "crawlquery/api/account/repository/mysql"
 	accountService "crawlquery/api/account/service"
 
+	feedMysqlRepo "crawlquery/api/crawl/feed/repository/mysql"
+	feedService "crawlquery/api/crawl/feed/service"
+	urlFilterHandler "crawlquery/api/crawl/filter/handler"
+	urlFilterService "crawlquery/api/crawl/filter/service"
 	crawlJobMysqlRepo "crawlquery/api/crawl/job/repository/mysql"
 	crawlLogMysqlRepo "crawlquery/api/crawl/log/repository/mysql"
+	crawlPriorityService "crawlquery/api/crawl/priority/service"
+	recrawlService "crawlquery/api/crawl/recrawl/service"
+	crawlScopeHandler "crawlquery/api/crawl/scope/handler"
+	crawlScopeMysqlRepo "crawlquery/api/crawl/scope/repository/mysql"
+	crawlScopeService "crawlquery/api/crawl/scope/service"
 	crawlService "crawlquery/api/crawl/service"
+	sitemapHandler "crawlquery/api/crawl/sitemap/handler"
+	sitemapService "crawlquery/api/crawl/sitemap/service"
+	crawlThrottleHandler "crawlquery/api/crawl/throttle/handler"
 	crawlThrottleService "crawlquery/api/crawl/throttle/service"
 
 	nodeHandler "crawlquery/api/node/handler"
@@ -32,14 +47,20 @@ import (
 
 	searchHandler "crawlquery/api/search/handler"
 	searchService "crawlquery/api/search/service"
+	searchSignal "crawlquery/api/search/signal"
 
+	queryHandler "crawlquery/api/query/handler"
+	queryService "crawlquery/api/query/service"
+
+	hostLinkMysqlRepo "crawlquery/api/link/host/repository/mysql"
 	linkMySQLRepo "crawlquery/api/link/repository/mysql"
 	linkService "crawlquery/api/link/service"
 
-	// pageRankMysqlRepo "crawlquery/api/pagerank/repository/mysql"
-	pageRankMemRepo "crawlquery/api/pagerank/repository/mem"
+	hostRankMysqlRepo "crawlquery/api/pagerank/host/repository/mysql"
+	pageRankMysqlRepo "crawlquery/api/pagerank/repository/mysql"
 	pageRankService "crawlquery/api/pagerank/service"
 
+	pageAliasMysqlRepo "crawlquery/api/page/alias/repository/mysql"
 	pageHandler "crawlquery/api/page/handler"
 	pageMysqlRepo "crawlquery/api/page/repository/mysql"
 	pageService "crawlquery/api/page/service"
@@ -78,6 +99,10 @@ func main() {
 		}
 	}
 
+	if trackingParams := os.Getenv("TRACKING_PARAMS"); trackingParams != "" {
+		canonical.Default = canonical.New(canonical.WithTrackingParams(strings.Split(trackingParams, ",")...))
+	}
+
 	eventService := eventService.NewService()
 
 	accountRepo := accountMysqlRepo.NewRepository(db)
@@ -103,16 +128,56 @@ func main() {
 	)
 	nodeHandler := nodeHandler.NewHandler(nodeService)
 
+	var urlFilterOptions []urlFilterService.Option
+	if filterFile := os.Getenv("CRAWL_FILTER_FILE"); filterFile != "" {
+		config, err := urlFilterService.ReadConfig(filterFile)
+		if err != nil {
+			fmt.Println("Error reading crawl filter rules: ", err)
+			return
+		}
+		urlFilterOptions = append(urlFilterOptions, urlFilterService.WithConfig(config))
+	}
+	urlFilterService := urlFilterService.NewService(urlFilterOptions...)
+	urlFilterHandler := urlFilterHandler.NewHandler(urlFilterService)
+
 	pageRepo := pageMysqlRepo.NewRepository(db)
+	pageAliasRepo := pageAliasMysqlRepo.NewRepository(db)
+	scopeRepo := crawlScopeMysqlRepo.NewRepository(db)
 	pageService := pageService.NewService(
 		pageService.WithEventService(eventService),
 		pageService.WithEventListeners(),
 		pageService.WithPageRepo(pageRepo),
+		pageService.WithPageAliasRepo(pageAliasRepo),
 		pageService.WithShardService(shardService),
+		pageService.WithSkippedRels(domain.RelNofollow, domain.RelSponsored, domain.RelUGC),
+		pageService.WithURLFilterService(urlFilterService),
+		pageService.WithScopeRepo(scopeRepo),
 		pageService.WithLogger(sugar),
 	)
 	pageHandler := pageHandler.NewHandler(pageService)
 
+	crawlJobRepo := crawlJobMysqlRepo.NewRepository(db)
+
+	scopeService := crawlScopeService.NewService(
+		crawlScopeService.WithScopeRepo(scopeRepo),
+		crawlScopeService.WithPageRepo(pageRepo),
+		crawlScopeService.WithCrawlJobRepo(crawlJobRepo),
+		crawlScopeService.WithPageService(pageService),
+		crawlScopeService.WithLogger(sugar),
+	)
+	scopeHandler := crawlScopeHandler.NewHandler(scopeService)
+
+	linkRepo := linkMySQLRepo.NewRepository(db)
+	linkService := linkService.NewService(
+		linkService.WithLinkRepo(linkRepo),
+		linkService.WithHostLinkRepo(hostLinkMysqlRepo.NewRepository(db)),
+		linkService.WithPageAliasRepo(pageAliasRepo),
+		linkService.WithPageRepo(pageRepo),
+		linkService.WithLogger(sugar),
+		linkService.WithEventService(eventService),
+		linkService.WithEventListeners(),
+	)
+
 	indexJobRepo := indexJobMySQLRepo.NewRepository(db)
 	indexLogRepo := indexLogMysqlRepo.NewRepository(db)
 	indexService := indexService.NewService(
@@ -121,36 +186,93 @@ func main() {
 		indexService.WithIndexJobRepo(indexJobRepo),
 		indexService.WithIndexLogRepo(indexLogRepo),
 		indexService.WithNodeService(nodeService),
+		indexService.WithLinkService(linkService),
 		indexService.WithLogger(sugar),
 		indexService.WithWorkers(10),
 		indexService.WithMaxQueueSize(100),
 	)
 
+	pageRankOptions := []pageRankService.Option{
+		pageRankService.WithWarmStart(),
+		pageRankService.WithEventService(eventService),
+		pageRankService.WithChangeThreshold(0.01),
+		pageRankService.WithInternalLinkWeight(0.5),
+		pageRankService.WithHostRank(hostRankMysqlRepo.NewRepository(db)),
+	}
+
+	var seedDomains []domain.URL
+	if seedFile := os.Getenv("PAGERANK_SEED_FILE"); seedFile != "" {
+		seedDomains, err = pageRankService.ReadSeedDomains(seedFile)
+		if err != nil {
+			fmt.Println("Error reading PageRank seed domains: ", err)
+			return
+		}
+		pageRankOptions = append(pageRankOptions, pageRankService.WithSeedDomains(seedDomains))
+	}
+
+	pageRankRepo := pageRankMysqlRepo.NewRepository(db)
+	pageRankService := pageRankService.NewService(linkService, pageRankRepo, sugar, pageRankOptions...)
+
 	crawlThrottleService := crawlThrottleService.NewService(
-		crawlThrottleService.WithRateLimit(time.Second * 20),
+		crawlThrottleService.WithRateLimit(time.Second*20),
+		crawlThrottleService.WithMaxCrawlDelay(time.Minute),
+		crawlThrottleService.WithMaxBackoff(time.Hour),
+		crawlThrottleService.WithSlowResponse(10*time.Second),
+		crawlThrottleService.WithMaxConnectionsPerHost(1),
+		crawlThrottleService.WithMaxConnectionsPerIP(4),
+		crawlThrottleService.WithResolver(crawlThrottleService.LookupIP),
+	)
+	crawlThrottleHandler := crawlThrottleHandler.NewHandler(crawlThrottleService)
+	crawlPriorityService := crawlPriorityService.NewService(
+		crawlPriorityService.WithPageRankService(pageRankService),
+		crawlPriorityService.WithLinkRepo(linkRepo),
+		crawlPriorityService.WithCrawlJobRepo(crawlJobRepo),
+		crawlPriorityService.WithSeedDomains(seedDomains),
+		crawlPriorityService.WithLogger(sugar),
 	)
-	crawlJobRepo := crawlJobMysqlRepo.NewRepository(db)
 	crawlJobService := crawlService.NewService(
 		crawlService.WithEventService(eventService),
 		crawlService.WithEventListeners(),
 		crawlService.WithCrawlThrottleService(crawlThrottleService),
 		crawlService.WithCrawlJobRepo(crawlJobRepo),
+		crawlService.WithCrawlPriorityService(crawlPriorityService),
 		crawlService.WithCrawlLogRepo(crawlLogMysqlRepo.NewRepository(db)),
 		crawlService.WithNodeService(nodeService),
 		crawlService.WithLogger(sugar),
 		crawlService.WithWorkers(60),
 		crawlService.WithMaxQueueSize(10000),
+		crawlService.WithMaxJobsPerHost(100),
 	)
 
-	linkRepo := linkMySQLRepo.NewRepository(db)
-	linkService := linkService.NewService(
-		linkService.WithLinkRepo(linkRepo),
-		linkService.WithLogger(sugar),
-		linkService.WithEventService(eventService),
-		linkService.WithEventListeners(),
+	sitemapService := sitemapService.NewService(
+		sitemapService.WithPageService(pageService),
+		sitemapService.WithCrawlJobRepo(crawlJobRepo),
+		sitemapService.WithURLFilterService(urlFilterService),
+		sitemapService.WithLogger(sugar),
+	)
+	sitemapHandler := sitemapHandler.NewHandler(sitemapService)
+
+	feedService := feedService.NewService(
+		feedService.WithEventService(eventService),
+		feedService.WithEventListeners(),
+		feedService.WithFeedRepo(feedMysqlRepo.NewRepository(db)),
+		feedService.WithPageService(pageService),
+		feedService.WithCrawlJobRepo(crawlJobRepo),
+		feedService.WithURLFilterService(urlFilterService),
+		feedService.WithIntervalBounds(15*time.Minute, 24*time.Hour),
+		feedService.WithLogger(sugar),
 	)
 
 	pageVersionRepo := pageVersionMysqlRepo.NewRepository(db)
+	recrawlService.NewService(
+		recrawlService.WithEventService(eventService),
+		recrawlService.WithEventListeners(),
+		recrawlService.WithVersionRepo(pageVersionRepo),
+		recrawlService.WithCrawlJobRepo(crawlJobRepo),
+		recrawlService.WithIntervalBounds(time.Hour, 30*24*time.Hour),
+		recrawlService.WithInitialInterval(24*time.Hour),
+		recrawlService.WithLogger(sugar),
+	)
 	pageVersionService.NewService(
 		pageVersionService.WithEventService(eventService),
 		pageVersionService.WithEventListeners(),
@@ -158,19 +280,37 @@ func main() {
 		pageVersionService.WithLogger(sugar),
 	)
 
-	// pageRankRepo := pageRankMysqlRepo.NewRepository(db)
-	pageRankRepo := pageRankMemRepo.NewRepository()
-	pageRankService := pageRankService.NewService(linkService, pageRankRepo, sugar)
-
-	searchService := searchService.NewService(nodeService, pageRankService, sugar)
+	searchService := searchService.NewService(
+		nodeService,
+		pageRankService,
+		sugar,
+		searchService.WithSignal(searchSignal.NewHostRank(pageService, pageRankService), 0.25),
+	)
 	searchHandler := searchHandler.NewHandler(searchService)
 
+	queryService := queryService.NewService(
+		queryService.WithNodeService(nodeService),
+		queryService.WithLogger(sugar),
+	)
+	queryHandler := queryHandler.NewHandler(queryService)
+
+	// before page and host ranks are first computed
+	if err := pageService.AliasLegacyPages(); err != nil {
+		sugar.Errorw("Error aliasing legacy pages", "error", err)
+	}
+
+	if err := linkService.BackfillHostLinks(); err != nil {
+		sugar.Errorw("Error backfilling host links", "error", err)
+	}
+
 	go crawlJobService.RunCrawlProcess(context.Background())
 
 	go indexService.RunIndexProcess(context.Background())
 
 	go pageRankService.UpdatePageRanksEvery(time.Minute)
 
+	go feedService.PollDueEvery(time.Minute)
+
 	r := router.NewRouter(
 		accountService,
 		authHandler,
@@ -178,6 +318,11 @@ func main() {
 		pageHandler,
 		nodeHandler,
 		searchHandler,
+		queryHandler,
+		crawlThrottleHandler,
+		urlFilterHandler,
+		scopeHandler,
+		sitemapHandler,
 	)
 
 	r.Run(":8080")
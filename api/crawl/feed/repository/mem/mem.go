@@ -0,0 +1,64 @@
+package mem
+
+import (
+	"crawlquery/api/domain"
+	"sort"
+	"sync"
+	"time"
+)
+
+type Repository struct {
+	feeds map[domain.FeedID]*domain.Feed
+	lock  sync.RWMutex
+}
+
+func NewRepository() *Repository {
+	return &Repository{
+		feeds: make(map[domain.FeedID]*domain.Feed),
+	}
+}
+
+func (r *Repository) Get(id domain.FeedID) (*domain.Feed, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	feed, ok := r.feeds[id]
+	if !ok {
+		return nil, domain.ErrFeedNotFound
+	}
+
+	copied := *feed
+	return &copied, nil
+}
+
+func (r *Repository) Save(feed *domain.Feed) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	copied := *feed
+	r.feeds[feed.ID] = &copied
+	return nil
+}
+
+func (r *Repository) ListDue(limit int, now time.Time) ([]*domain.Feed, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var feeds []*domain.Feed
+	for _, feed := range r.feeds {
+		if !feed.NextPollAt.After(now) {
+			copied := *feed
+			feeds = append(feeds, &copied)
+		}
+	}
+
+	sort.Slice(feeds, func(i, j int) bool {
+		return feeds[i].NextPollAt.Before(feeds[j].NextPollAt)
+	})
+
+	if len(feeds) > limit {
+		feeds = feeds[:limit]
+	}
+
+	return feeds, nil
+}
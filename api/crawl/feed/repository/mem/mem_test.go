@@ -0,0 +1,60 @@
+package mem
+
+import (
+	"crawlquery/api/domain"
+	"testing"
+	"time"
+)
+
+func TestSave(t *testing.T) {
+	t.Run("saves and gets a feed", func(t *testing.T) {
+		repo := NewRepository()
+
+		repo.Save(&domain.Feed{ID: "news", URL: "http://example.com/feed", Interval: time.Hour})
+
+		feed, err := repo.Get("news")
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if feed.URL != "http://example.com/feed" || feed.Interval != time.Hour {
+			t.Errorf("unexpected feed: %+v", feed)
+		}
+	})
+
+	t.Run("returns ErrFeedNotFound for unknown feeds", func(t *testing.T) {
+		repo := NewRepository()
+
+		if _, err := repo.Get("missing"); err != domain.ErrFeedNotFound {
+			t.Errorf("expected ErrFeedNotFound, got %v", err)
+		}
+	})
+}
+
+func TestListDue(t *testing.T) {
+	t.Run("lists due feeds longest overdue first", func(t *testing.T) {
+		repo := NewRepository()
+		now := time.Now()
+
+		repo.Save(&domain.Feed{ID: "recent", NextPollAt: now.Add(-time.Minute)})
+		repo.Save(&domain.Feed{ID: "overdue", NextPollAt: now.Add(-time.Hour)})
+		repo.Save(&domain.Feed{ID: "later", NextPollAt: now.Add(time.Hour)})
+
+		feeds, err := repo.ListDue(10, now)
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(feeds) != 2 || feeds[0].ID != "overdue" || feeds[1].ID != "recent" {
+			t.Errorf("unexpected feeds: %+v", feeds)
+		}
+
+		feeds, _ = repo.ListDue(1, now)
+
+		if len(feeds) != 1 || feeds[0].ID != "overdue" {
+			t.Errorf("expected the limit to apply, got %+v", feeds)
+		}
+	})
+}
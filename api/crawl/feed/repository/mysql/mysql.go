@@ -0,0 +1,82 @@
+package mysql
+
+import (
+	"crawlquery/api/domain"
+	"database/sql"
+	"time"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+const selectColumns = "SELECT id, url, page_id, etag, last_modified, poll_interval, failures, last_polled_at, next_poll_at, created_at FROM feeds"
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanFeed(row scanner) (*domain.Feed, error) {
+	var feed domain.Feed
+	var interval int64
+	var lastPolledAt sql.NullTime
+	err := row.Scan(&feed.ID, &feed.URL, &feed.PageID, &feed.ETag, &feed.LastModified, &interval, &feed.Failures, &lastPolledAt, &feed.NextPollAt, &feed.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	feed.Interval = time.Duration(interval) * time.Second
+	if lastPolledAt.Valid {
+		feed.LastPolledAt = lastPolledAt.Time
+	}
+	return &feed, nil
+}
+
+func (r *Repository) Get(id domain.FeedID) (*domain.Feed, error) {
+	feed, err := scanFeed(r.db.QueryRow(selectColumns+" WHERE id = ?", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrFeedNotFound
+		}
+		return nil, err
+	}
+	return feed, nil
+}
+
+func (r *Repository) Save(feed *domain.Feed) error {
+	var lastPolledAt sql.NullTime
+	if !feed.LastPolledAt.IsZero() {
+		lastPolledAt = sql.NullTime{Time: feed.LastPolledAt, Valid: true}
+	}
+
+	interval := int64(feed.Interval / time.Second)
+
+	_, err := r.db.Exec("INSERT INTO feeds (id, url, page_id, etag, last_modified, poll_interval, failures, last_polled_at, next_poll_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE etag = ?, last_modified = ?, poll_interval = ?, failures = ?, last_polled_at = ?, next_poll_at = ?", feed.ID, feed.URL, feed.PageID, feed.ETag, feed.LastModified, interval, feed.Failures, lastPolledAt, feed.NextPollAt, feed.CreatedAt, feed.ETag, feed.LastModified, interval, feed.Failures, lastPolledAt, feed.NextPollAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) ListDue(limit int, now time.Time) ([]*domain.Feed, error) {
+	rows, err := r.db.Query(selectColumns+" WHERE next_poll_at <= ? ORDER BY next_poll_at LIMIT ?", now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var feeds []*domain.Feed
+	for rows.Next() {
+		feed, err := scanFeed(rows)
+		if err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds, rows.Err()
+}
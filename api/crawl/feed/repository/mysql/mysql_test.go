@@ -0,0 +1,120 @@
+package mysql_test
+
+import (
+	"crawlquery/api/domain"
+	"crawlquery/api/migration"
+	"crawlquery/pkg/testutil"
+	"testing"
+	"time"
+
+	feedRepo "crawlquery/api/crawl/feed/repository/mysql"
+)
+
+func TestSave(t *testing.T) {
+	t.Run("saves and gets a feed", func(t *testing.T) {
+		db := testutil.CreateTestMysqlDB()
+		defer db.Close()
+		migration.Up(db)
+
+		repo := feedRepo.NewRepository(db)
+
+		defer db.Exec("DELETE FROM feeds WHERE id = ?", "savefeednews")
+
+		err := repo.Save(&domain.Feed{
+			ID:         "savefeednews",
+			URL:        "http://example.com/feed",
+			PageID:     "page1",
+			Interval:   time.Hour,
+			NextPollAt: time.Now(),
+			CreatedAt:  time.Now(),
+		})
+
+		if err != nil {
+			t.Fatalf("Error saving feed: %v", err)
+		}
+
+		err = repo.Save(&domain.Feed{
+			ID:           "savefeednews",
+			URL:          "http://example.com/feed",
+			PageID:       "page1",
+			ETag:         `"abc"`,
+			Interval:     2 * time.Hour,
+			LastPolledAt: time.Now(),
+			NextPollAt:   time.Now().Add(2 * time.Hour),
+			CreatedAt:    time.Now(),
+		})
+
+		if err != nil {
+			t.Fatalf("Error updating feed: %v", err)
+		}
+
+		feed, err := repo.Get("savefeednews")
+
+		if err != nil {
+			t.Fatalf("Error getting feed: %v", err)
+		}
+
+		if feed.URL != "http://example.com/feed" || feed.ETag != `"abc"` || feed.Interval != 2*time.Hour || feed.LastPolledAt.IsZero() {
+			t.Errorf("Unexpected feed: %+v", feed)
+		}
+	})
+
+	t.Run("returns an error for unknown feeds", func(t *testing.T) {
+		db := testutil.CreateTestMysqlDB()
+		defer db.Close()
+		migration.Up(db)
+
+		repo := feedRepo.NewRepository(db)
+
+		if _, err := repo.Get("missingfeed"); err != domain.ErrFeedNotFound {
+			t.Errorf("Expected ErrFeedNotFound, got %v", err)
+		}
+	})
+}
+
+func TestListDue(t *testing.T) {
+	t.Run("lists feeds due a poll", func(t *testing.T) {
+		db := testutil.CreateTestMysqlDB()
+		defer db.Close()
+		migration.Up(db)
+
+		repo := feedRepo.NewRepository(db)
+
+		now := time.Now()
+
+		for id, nextPollAt := range map[domain.FeedID]time.Time{
+			"listduefeeddue":   now.Add(-time.Hour),
+			"listduefeedlater": now.Add(time.Hour),
+		} {
+			defer db.Exec("DELETE FROM feeds WHERE id = ?", id)
+
+			err := repo.Save(&domain.Feed{ID: id, URL: "http://example.com/" + domain.URL(id), Interval: time.Hour, NextPollAt: nextPollAt, CreatedAt: now})
+			if err != nil {
+				t.Fatalf("Error saving feed: %v", err)
+			}
+		}
+
+		feeds, err := repo.ListDue(100, now)
+
+		if err != nil {
+			t.Fatalf("Error listing feeds: %v", err)
+		}
+
+		for _, feed := range feeds {
+			if feed.ID == "listduefeedlater" {
+				t.Errorf("Expected feeds not yet due to be left out")
+			}
+		}
+
+		found := false
+		for _, feed := range feeds {
+			if feed.ID == "listduefeeddue" {
+				found = true
+			}
+		}
+
+		if !found {
+			t.Errorf("Expected the due feed to be listed")
+		}
+	})
+}
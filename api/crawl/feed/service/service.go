@@ -0,0 +1,381 @@
+package service
+
+import (
+	"crawlquery/api/domain"
+	"crawlquery/pkg/canonical"
+	"crawlquery/pkg/feed"
+	"crawlquery/pkg/safehttp"
+	"crawlquery/pkg/util"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// UserAgent is sent when polling feeds.
+const UserAgent = "crawlquery"
+
+// Service registers the feeds found on crawled pages and polls them, adding
+// their new entries to the crawl ahead of other pages so that fresh content
+// is searchable soon after it is published.
+type Service struct {
+	client       *http.Client
+	eventService domain.EventService
+	feedRepo     domain.FeedRepository
+	pageService  domain.PageService
+	crawlJobRepo domain.CrawlJobRepository
+	urlFilter    domain.URLFilterService
+	logger       *zap.SugaredLogger
+
+	minInterval   time.Duration
+	maxInterval   time.Duration
+	batchSize     int
+	entryPriority float64
+	maxEntryAge   time.Duration
+}
+
+type Option func(*Service)
+
+// WithHTTPClient replaces the client feeds are polled with, which by default
+// only connects to public addresses.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *Service) {
+		s.client = client
+	}
+}
+
+func WithEventService(eventService domain.EventService) Option {
+	return func(s *Service) {
+		s.eventService = eventService
+	}
+}
+
+func WithFeedRepo(feedRepo domain.FeedRepository) Option {
+	return func(s *Service) {
+		s.feedRepo = feedRepo
+	}
+}
+
+func WithPageService(pageService domain.PageService) Option {
+	return func(s *Service) {
+		s.pageService = pageService
+	}
+}
+
+func WithCrawlJobRepo(crawlJobRepo domain.CrawlJobRepository) Option {
+	return func(s *Service) {
+		s.crawlJobRepo = crawlJobRepo
+	}
+}
+
+// WithURLFilterService leaves out the entries the filter denies, as it does
+// for discovered links.
+func WithURLFilterService(urlFilter domain.URLFilterService) Option {
+	return func(s *Service) {
+		s.urlFilter = urlFilter
+	}
+}
+
+func WithLogger(logger *zap.SugaredLogger) Option {
+	return func(s *Service) {
+		s.logger = logger
+	}
+}
+
+// WithIntervalBounds sets the shortest and longest time between polls of a
+// feed. New feeds are polled at the shortest interval.
+func WithIntervalBounds(minInterval, maxInterval time.Duration) Option {
+	return func(s *Service) {
+		s.minInterval = minInterval
+		s.maxInterval = maxInterval
+	}
+}
+
+// WithBatchSize sets how many due feeds PollDue polls at a time.
+func WithBatchSize(batchSize int) Option {
+	return func(s *Service) {
+		s.batchSize = batchSize
+	}
+}
+
+// WithEntryPriority sets the crawl priority given to new entries. The
+// default of 1 is the highest score the crawl priority service gives.
+func WithEntryPriority(priority float64) Option {
+	return func(s *Service) {
+		s.entryPriority = priority
+	}
+}
+
+// WithMaxEntryAge sets how recently an entry must have been published for
+// its page to be crawled ahead of others. Older entries, such as those found
+// when a feed is first polled, are crawled at their usual priority.
+func WithMaxEntryAge(maxEntryAge time.Duration) Option {
+	return func(s *Service) {
+		s.maxEntryAge = maxEntryAge
+	}
+}
+
+func WithEventListeners() Option {
+	return func(s *Service) {
+		s.registerEventListeners()
+	}
+}
+
+func NewService(opts ...Option) *Service {
+	s := &Service{
+		client:        safehttp.NewClient(30 * time.Second),
+		minInterval:   15 * time.Minute,
+		maxInterval:   24 * time.Hour,
+		batchSize:     100,
+		entryPriority: 1,
+		maxEntryAge:   7 * 24 * time.Hour,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func (s *Service) registerEventListeners() {
+	if s.eventService == nil {
+		s.logger.Fatal("EventService is required")
+	}
+
+	s.eventService.Subscribe(domain.CrawlCompletedKey, s.handleCrawlCompleted)
+}
+
+func (s *Service) handleCrawlCompleted(e domain.Event) {
+	crawlCompleted := e.(*domain.CrawlCompleted)
+
+	for _, url := range crawlCompleted.Feeds {
+		if _, err := s.Register(url, crawlCompleted.PageID); err != nil && err != domain.ErrFeedDenied {
+			s.logger.Errorw("Error registering feed", "error", err, "url", url, "pageID", crawlCompleted.PageID)
+		}
+	}
+}
+
+// Register saves a feed to be polled unless it is known already. Feeds the
+// URL filter denies are not registered.
+func (s *Service) Register(url domain.URL, pageID domain.PageID) (*domain.Feed, error) {
+	feedURL, err := canonical.URL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.urlFilter != nil {
+		if decision := s.urlFilter.Evaluate(feedURL); !decision.Allowed {
+			return nil, domain.ErrFeedDenied
+		}
+	}
+
+	id := domain.FeedID(util.Sha256Hex32([]byte(feedURL)))
+
+	existing, err := s.feedRepo.Get(id)
+	if err == nil {
+		return existing, nil
+	}
+	if err != domain.ErrFeedNotFound {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	feed := &domain.Feed{
+		ID:         id,
+		URL:        feedURL,
+		PageID:     pageID,
+		Interval:   s.minInterval,
+		NextPollAt: now,
+		CreatedAt:  now,
+	}
+
+	if err := s.feedRepo.Save(feed); err != nil {
+		return nil, err
+	}
+
+	s.logger.Infow("Registered feed", "url", feedURL, "pageID", pageID)
+
+	return feed, nil
+}
+
+// PollDue polls the feeds that are due, longest overdue first.
+func (s *Service) PollDue() error {
+	feeds, err := s.feedRepo.ListDue(s.batchSize, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, feed := range feeds {
+		if _, err := s.Poll(feed); err != nil {
+			s.logger.Warnw("Error polling feed", "error", err, "url", feed.URL, "failures", feed.Failures)
+		}
+	}
+
+	return nil
+}
+
+// PollDueEvery polls the feeds that are due now and then every interval.
+func (s *Service) PollDueEvery(interval time.Duration) {
+	if err := s.PollDue(); err != nil {
+		s.logger.Errorw("Error polling feeds", "error", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := s.PollDue(); err != nil {
+			s.logger.Errorw("Error polling feeds", "error", err)
+		}
+	}
+}
+
+// Poll fetches a feed unless it is unchanged since the last poll and adds
+// the pages of its entries that are new to the crawl. Polls that find new
+// entries halve the feed's interval and polls that don't, or that fail,
+// double it.
+func (s *Service) Poll(f *domain.Feed) (int, error) {
+	created, err := s.poll(f)
+
+	if err != nil {
+		f.Failures++
+	} else {
+		f.Failures = 0
+	}
+
+	if created > 0 {
+		f.Interval /= 2
+	} else {
+		f.Interval *= 2
+	}
+
+	if f.Interval < s.minInterval {
+		f.Interval = s.minInterval
+	}
+	if f.Interval > s.maxInterval {
+		f.Interval = s.maxInterval
+	}
+
+	f.LastPolledAt = time.Now()
+	f.NextPollAt = f.LastPolledAt.Add(f.Interval)
+
+	if saveErr := s.feedRepo.Save(f); saveErr != nil {
+		return created, saveErr
+	}
+
+	return created, err
+}
+
+func (s *Service) poll(f *domain.Feed) (int, error) {
+	req, err := http.NewRequest(http.MethodGet, string(f.URL), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("User-Agent", UserAgent)
+	if f.ETag != "" {
+		req.Header.Set("If-None-Match", f.ETag)
+	}
+	if f.LastModified != "" {
+		req.Header.Set("If-Modified-Since", f.LastModified)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotModified {
+		return 0, nil
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("fetching feed %s: status %d", f.URL, res.StatusCode)
+	}
+
+	entries, err := feed.Parse(res.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	f.ETag = res.Header.Get("ETag")
+	f.LastModified = res.Header.Get("Last-Modified")
+
+	created := 0
+	for _, entry := range entries {
+		if s.add(f, entry) {
+			created++
+		}
+	}
+
+	if created > 0 {
+		s.logger.Infow("Polled feed", "url", f.URL, "entries", len(entries), "created", created)
+	}
+
+	return created, nil
+}
+
+// add creates the page of an entry, reporting whether it was new. Fresh
+// entries on the feed's own site are crawled ahead of others; entries on
+// other sites are not, so that a feed cannot move other sites up the crawl.
+func (s *Service) add(f *domain.Feed, entry feed.Entry) bool {
+	link, err := util.MakeAbsoluteIfRelative(string(f.URL), entry.Link)
+	if err != nil {
+		return false
+	}
+
+	pageURL, err := canonical.URL(domain.URL(link))
+	if err != nil {
+		return false
+	}
+
+	if s.urlFilter != nil {
+		if decision := s.urlFilter.Evaluate(pageURL); !decision.Allowed {
+			return false
+		}
+	}
+
+	page, err := s.pageService.Create(domain.URL(link))
+	if err != nil {
+		if err != domain.ErrPageAlreadyExists {
+			s.logger.Debugw("Error creating page from feed", "error", err, "url", pageURL, "feed", f.URL)
+		}
+		return false
+	}
+
+	if s.fresh(f, entry) && util.Site(pageURL) == util.Site(f.URL) {
+		s.prioritise(page.ID)
+	}
+
+	return true
+}
+
+// fresh reports whether an entry was published recently. Entries without a
+// date are taken to be fresh only once the feed has been polled before, as
+// the first poll finds the feed's whole backlog.
+func (s *Service) fresh(f *domain.Feed, entry feed.Entry) bool {
+	if entry.Published.IsZero() {
+		return !f.LastPolledAt.IsZero()
+	}
+
+	return time.Since(entry.Published) <= s.maxEntryAge
+}
+
+// prioritise raises the crawl priority of a new entry's job to the entry
+// priority.
+func (s *Service) prioritise(pageID domain.PageID) {
+	job, err := s.crawlJobRepo.Get(pageID)
+	if err != nil || job.Priority >= s.entryPriority {
+		return
+	}
+
+	job.Priority = s.entryPriority
+
+	if err := s.crawlJobRepo.Save(job); err != nil {
+		s.logger.Errorw("Error saving crawl job", "error", err, "pageID", pageID)
+	}
+}
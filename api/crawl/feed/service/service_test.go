@@ -0,0 +1,294 @@
+package service_test
+
+import (
+	feedRepo "crawlquery/api/crawl/feed/repository/mem"
+	"crawlquery/api/crawl/feed/service"
+	urlFilterService "crawlquery/api/crawl/filter/service"
+	crawlService "crawlquery/api/crawl/service"
+	"crawlquery/api/domain"
+	"crawlquery/api/testfactory"
+	"crawlquery/pkg/safehttp"
+	"crawlquery/pkg/testutil"
+	"crawlquery/pkg/util"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func setup(opts ...service.Option) (*service.Service, *feedRepo.Repository, *testfactory.ServiceFactory) {
+	sf := testfactory.NewServiceFactory(
+		testfactory.WithShard(&domain.Shard{ID: 0}),
+	)
+
+	crawlService.NewService(
+		crawlService.WithEventService(sf.EventService),
+		crawlService.WithCrawlJobRepo(sf.CrawlJobRepo),
+		crawlService.WithCrawlLogRepo(sf.CrawlLogRepo),
+		crawlService.WithLogger(testutil.NewTestLogger()),
+		crawlService.WithEventListeners(),
+	)
+
+	repo := feedRepo.NewRepository()
+
+	// the test sites listen on loopback, which the default client refuses
+	opts = append([]service.Option{
+		service.WithHTTPClient(http.DefaultClient),
+		service.WithEventService(sf.EventService),
+		service.WithFeedRepo(repo),
+		service.WithPageService(sf.PageService),
+		service.WithCrawlJobRepo(sf.CrawlJobRepo),
+		service.WithLogger(testutil.NewTestLogger()),
+		service.WithIntervalBounds(time.Minute, time.Hour),
+	}, opts...)
+
+	return service.NewService(opts...), repo, sf
+}
+
+func TestRegister(t *testing.T) {
+	t.Run("registers the feeds of crawled pages once", func(t *testing.T) {
+		s, repo, sf := setup(service.WithEventListeners())
+
+		for i := 0; i < 2; i++ {
+			sf.EventService.Publish(&domain.CrawlCompleted{
+				PageID: "page1",
+				URL:    "http://example.com/",
+				Feeds:  []domain.URL{"http://example.com/feed"},
+			})
+		}
+
+		feed, err := s.Register("HTTP://EXAMPLE.com/feed#top", "page2")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if feed.URL != "http://example.com/feed" || feed.PageID != "page1" || feed.Interval != time.Minute {
+			t.Errorf("unexpected feed: %+v", feed)
+		}
+
+		feeds, _ := repo.ListDue(10, time.Now())
+		if len(feeds) != 1 {
+			t.Errorf("expected 1 feed due a poll, got %d", len(feeds))
+		}
+	})
+
+	t.Run("rejects feeds that cannot be fetched", func(t *testing.T) {
+		s, _, _ := setup()
+
+		if _, err := s.Register("ftp://example.com/feed", "page1"); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+
+	t.Run("rejects feeds the url filter denies", func(t *testing.T) {
+		config, err := urlFilterService.ParseConfig([]byte(`{"rules": [{"name": "comments", "action": "deny", "path_glob": "/comments/*"}]}`))
+		if err != nil {
+			t.Fatalf("error parsing config: %v", err)
+		}
+
+		s, repo, _ := setup(service.WithURLFilterService(urlFilterService.NewService(urlFilterService.WithConfig(config))))
+
+		if _, err := s.Register("http://example.com/comments/feed", "page1"); err != domain.ErrFeedDenied {
+			t.Errorf("expected ErrFeedDenied, got %v", err)
+		}
+
+		if feeds, _ := repo.ListDue(10, time.Now()); len(feeds) != 0 {
+			t.Errorf("expected no feeds, got %d", len(feeds))
+		}
+	})
+}
+
+func TestPoll(t *testing.T) {
+	t.Run("adds new entries with high priority", func(t *testing.T) {
+		published := time.Now().Add(-time.Hour).UTC().Format(time.RFC1123Z)
+
+		polls := 0
+
+		var site *httptest.Server
+		site = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			polls++
+
+			later := ""
+			if polls > 1 {
+				later = "<item><link>/later</link></item>"
+			}
+
+			fmt.Fprintf(w, `<rss><channel>
+				<item><link>%[1]s/fresh</link><pubDate>%[2]s</pubDate></item>
+				<item><link>/undated</link></item>
+				<item><link>%[1]s/old</link><pubDate>Mon, 01 Jan 2001 00:00:00 +0000</pubDate></item>
+				<item><link>http://other.example/fresh</link><pubDate>%[2]s</pubDate></item>
+				%[3]s
+			</channel></rss>`, site.URL, published, later)
+		}))
+		defer site.Close()
+
+		s, _, sf := setup()
+
+		feed, err := s.Register(domain.URL(site.URL+"/feed"), "page1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		created, err := s.Poll(feed)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if created != 4 {
+			t.Errorf("expected 4 pages, got %d", created)
+		}
+
+		created, err = s.Poll(feed)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if created != 1 {
+			t.Errorf("expected 1 new page on the second poll, got %d", created)
+		}
+
+		for url, high := range map[string]bool{
+			site.URL + "/fresh":          true,
+			site.URL + "/undated":        false,
+			site.URL + "/old":            false,
+			site.URL + "/later":          true,
+			"http://other.example/fresh": false,
+		} {
+			job, err := sf.CrawlJobRepo.Get(util.PageID(domain.URL(url)))
+			if err != nil {
+				t.Fatalf("expected a crawl job for %s, got %v", url, err)
+			}
+
+			if (job.Priority == 1) != high {
+				t.Errorf("expected %s to have high priority %v, got %v", url, high, job.Priority)
+			}
+		}
+	})
+
+	t.Run("polls feeds with new entries more often", func(t *testing.T) {
+		entry := 0
+
+		site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			entry++
+			fmt.Fprintf(w, `<feed><entry><link href="http://example.com/%d"/></entry></feed>`, entry)
+		}))
+		defer site.Close()
+
+		s, repo, _ := setup()
+
+		feed := &domain.Feed{ID: "news", URL: domain.URL(site.URL), Interval: 40 * time.Minute}
+
+		if _, err := s.Poll(feed); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		saved, _ := repo.Get("news")
+		if saved.Interval != 20*time.Minute {
+			t.Errorf("expected the interval to halve, got %v", saved.Interval)
+		}
+
+		if saved.NextPollAt.Sub(saved.LastPolledAt) != 20*time.Minute {
+			t.Errorf("expected the next poll in 20 minutes, got %v", saved.NextPollAt.Sub(saved.LastPolledAt))
+		}
+	})
+
+	t.Run("sends validators and backs off unchanged feeds", func(t *testing.T) {
+		site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			fmt.Fprint(w, `<rss><channel></channel></rss>`)
+		}))
+		defer site.Close()
+
+		s, _, _ := setup()
+
+		feed := &domain.Feed{ID: "news", URL: domain.URL(site.URL), Interval: 40 * time.Minute}
+
+		for i := 0; i < 2; i++ {
+			if _, err := s.Poll(feed); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		if feed.ETag != `"v1"` {
+			t.Errorf("expected the etag to be kept, got %q", feed.ETag)
+		}
+
+		if feed.Interval != time.Hour {
+			t.Errorf("expected the interval to grow to the longest, got %v", feed.Interval)
+		}
+	})
+
+	t.Run("counts failures", func(t *testing.T) {
+		site := httptest.NewServer(http.NotFoundHandler())
+		defer site.Close()
+
+		s, repo, _ := setup()
+
+		feed := &domain.Feed{ID: "news", URL: domain.URL(site.URL), Interval: time.Minute}
+
+		if _, err := s.Poll(feed); err == nil {
+			t.Errorf("expected an error")
+		}
+
+		saved, _ := repo.Get("news")
+		if saved.Failures != 1 || saved.Interval != 2*time.Minute {
+			t.Errorf("expected a failure and a longer interval, got %+v", saved)
+		}
+	})
+
+	t.Run("refuses feeds on addresses that aren't public", func(t *testing.T) {
+		site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("expected no request to reach the feed")
+		}))
+		defer site.Close()
+
+		repo := feedRepo.NewRepository()
+		s := service.NewService(
+			service.WithFeedRepo(repo),
+			service.WithLogger(testutil.NewTestLogger()),
+		)
+
+		feed := &domain.Feed{ID: "internal", URL: domain.URL(site.URL), Interval: time.Minute}
+
+		if _, err := s.Poll(feed); !errors.Is(err, safehttp.ErrNonPublicAddress) {
+			t.Errorf("expected ErrNonPublicAddress, got %v", err)
+		}
+	})
+}
+
+func TestPollDue(t *testing.T) {
+	t.Run("polls the feeds that are due", func(t *testing.T) {
+		polled := 0
+
+		site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			polled++
+			fmt.Fprint(w, `<rss><channel></channel></rss>`)
+		}))
+		defer site.Close()
+
+		s, repo, _ := setup()
+
+		repo.Save(&domain.Feed{ID: "due", URL: domain.URL(site.URL + "/due"), Interval: time.Minute, NextPollAt: time.Now().Add(-time.Minute)})
+		repo.Save(&domain.Feed{ID: "later", URL: domain.URL(site.URL + "/later"), Interval: time.Minute, NextPollAt: time.Now().Add(time.Minute)})
+
+		if err := s.PollDue(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if polled != 1 {
+			t.Errorf("expected 1 feed to be polled, got %d", polled)
+		}
+
+		due, _ := repo.Get("due")
+		if !due.NextPollAt.After(time.Now()) {
+			t.Errorf("expected the feed to be scheduled again, got %v", due.NextPollAt)
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"crawlquery/api/domain"
+	"crawlquery/api/dto"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	urlFilterService domain.URLFilterService
+}
+
+func NewHandler(urlFilterService domain.URLFilterService) *Handler {
+	return &Handler{
+		urlFilterService: urlFilterService,
+	}
+}
+
+// Evaluate is a dry run of the crawl filter rules, explaining for each URL
+// which rule would let it be crawled or keep it out.
+func (h *Handler) Evaluate(c *gin.Context) {
+	var req dto.EvaluateURLFilterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	decisions := make([]domain.URLFilterDecision, 0, len(req.URLs))
+	for _, url := range req.URLs {
+		decisions = append(decisions, h.urlFilterService.Evaluate(domain.URL(url)))
+	}
+
+	c.JSON(http.StatusOK, dto.NewEvaluateURLFilterResponse(decisions))
+}
@@ -0,0 +1,66 @@
+package handler_test
+
+import (
+	"bytes"
+	"crawlquery/api/crawl/filter/handler"
+	"crawlquery/api/crawl/filter/service"
+	"crawlquery/api/dto"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEvaluate(t *testing.T) {
+	t.Run("explains which rule matched each url", func(t *testing.T) {
+		config, err := service.ParseConfig([]byte(`{"rules": [{"name": "binaries", "action": "deny", "extensions": ["zip"]}]}`))
+		if err != nil {
+			t.Fatalf("Error parsing config: %v", err)
+		}
+
+		handler := handler.NewHandler(service.NewService(service.WithConfig(config)))
+
+		responseWriter := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(responseWriter)
+		ctx.Request = httptest.NewRequest("POST", "/crawl/filters/evaluate", bytes.NewBufferString(`{"urls": ["http://example.com/a.zip", "http://example.com/a"]}`))
+
+		handler.Evaluate(ctx)
+
+		if ctx.Writer.Status() != http.StatusOK {
+			t.Errorf("Expected status to be 200, got %d", ctx.Writer.Status())
+		}
+
+		var res dto.EvaluateURLFilterResponse
+		if err := json.Unmarshal(responseWriter.Body.Bytes(), &res); err != nil {
+			t.Fatalf("Error decoding response: %v", err)
+		}
+
+		if len(res.Decisions) != 2 {
+			t.Fatalf("Expected 2 decisions, got %d", len(res.Decisions))
+		}
+
+		if res.Decisions[0].Allowed || res.Decisions[0].Rule != "binaries" {
+			t.Errorf("Expected the zip to be denied by binaries, got %+v", res.Decisions[0])
+		}
+
+		if !res.Decisions[1].Allowed || res.Decisions[1].Reason == "" {
+			t.Errorf("Expected the page to be allowed with a reason, got %+v", res.Decisions[1])
+		}
+	})
+
+	t.Run("requires urls", func(t *testing.T) {
+		handler := handler.NewHandler(service.NewService())
+
+		responseWriter := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(responseWriter)
+		ctx.Request = httptest.NewRequest("POST", "/crawl/filters/evaluate", bytes.NewBufferString(`{}`))
+
+		handler.Evaluate(ctx)
+
+		if ctx.Writer.Status() != http.StatusBadRequest {
+			t.Errorf("Expected status to be 400, got %d", ctx.Writer.Status())
+		}
+	})
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"crawlquery/api/domain"
+	"crawlquery/pkg/util"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidAction = errors.New("invalid filter action")
+
+const (
+	ActionAllow = "allow"
+	ActionDeny  = "deny"
+)
+
+// Rule allows or denies the URLs that match all of its conditions. A rule
+// without conditions matches every URL.
+type Rule struct {
+	Name   string `json:"name"`
+	Action string `json:"action"`
+
+	// Domains match a host or any of its subdomains.
+	Domains []string `json:"domains,omitempty"`
+
+	// PathGlob matches the path, where "*" matches within a segment and "**"
+	// across segments.
+	PathGlob string `json:"path_glob,omitempty"`
+
+	// PathRegex matches anywhere in the path and query.
+	PathRegex string `json:"path_regex,omitempty"`
+
+	// Extensions match the extension of the last path segment, with or
+	// without a leading dot.
+	Extensions []string `json:"extensions,omitempty"`
+
+	glob  *regexp.Regexp
+	regex *regexp.Regexp
+}
+
+// Config is a set of rules read from a file. URLs over any of the limits
+// are denied first, then the first matching rule decides and URLs matching
+// no rule get the default action. Limits of zero are not checked.
+type Config struct {
+	Default        string `json:"default"`
+	MaxLength      int    `json:"max_length"`
+	MaxDepth       int    `json:"max_depth"`
+	MaxQueryParams int    `json:"max_query_params"`
+	Rules          []Rule `json:"rules"`
+}
+
+// ParseConfig reads a JSON config and checks its rules.
+func ParseConfig(data []byte) (*Config, error) {
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if config.Default == "" {
+		config.Default = ActionAllow
+	}
+
+	if !validAction(config.Default) {
+		return nil, fmt.Errorf("%w: default %q", ErrInvalidAction, config.Default)
+	}
+
+	for i := range config.Rules {
+		if err := config.Rules[i].compile(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+
+	return &config, nil
+}
+
+// ReadConfig reads a JSON config from a file.
+func ReadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseConfig(data)
+}
+
+func validAction(action string) bool {
+	return action == ActionAllow || action == ActionDeny
+}
+
+func (r *Rule) compile() error {
+	if !validAction(r.Action) {
+		return fmt.Errorf("%w: %q", ErrInvalidAction, r.Action)
+	}
+
+	if r.Name == "" {
+		r.Name = r.Action
+	}
+
+	for i, d := range r.Domains {
+		r.Domains[i] = strings.TrimPrefix(strings.ToLower(d), "www.")
+	}
+
+	for i, ext := range r.Extensions {
+		r.Extensions[i] = strings.TrimPrefix(strings.ToLower(ext), ".")
+	}
+
+	var err error
+
+	if r.PathGlob != "" {
+		r.glob, err = globRegexp(r.PathGlob)
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.PathRegex != "" {
+		r.regex, err = regexp.Compile(r.PathRegex)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// globRegexp turns a path glob into an anchored regular expression.
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+
+	b.WriteString("^")
+
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	b.WriteString("$")
+
+	return regexp.Compile(b.String())
+}
+
+// target is the parts of a URL the rules look at.
+type target struct {
+	host      domain.Host
+	path      string
+	pathQuery string
+	extension string
+}
+
+func (r *Rule) matches(t target) bool {
+	if len(r.Domains) > 0 && !matchesDomain(t.host, r.Domains) {
+		return false
+	}
+
+	if r.glob != nil && !r.glob.MatchString(t.path) {
+		return false
+	}
+
+	if r.regex != nil && !r.regex.MatchString(t.pathQuery) {
+		return false
+	}
+
+	if len(r.Extensions) > 0 && !contains(r.Extensions, t.extension) {
+		return false
+	}
+
+	return true
+}
+
+func matchesDomain(host domain.Host, domains []string) bool {
+	for _, d := range domains {
+		if string(host) == d || strings.HasSuffix(string(host), "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type Service struct {
+	config *Config
+}
+
+type Option func(*Service)
+
+func WithConfig(config *Config) Option {
+	return func(s *Service) {
+		s.config = config
+	}
+}
+
+// NewService creates a filter that allows everything unless given a config.
+func NewService(opts ...Option) *Service {
+	s := &Service{
+		config: &Config{Default: ActionAllow},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Evaluate decides whether a URL may be crawled, explaining which limit or
+// rule decided it. URLs that cannot be parsed are denied.
+func (s *Service) Evaluate(rawURL domain.URL) domain.URLFilterDecision {
+	decision := domain.URLFilterDecision{URL: rawURL}
+
+	parsed, err := url.Parse(string(rawURL))
+	if err != nil {
+		decision.Reason = "invalid url: " + err.Error()
+		return decision
+	}
+
+	if limit := s.config.MaxLength; limit > 0 && len(rawURL) > limit {
+		decision.Rule = "max_length"
+		decision.Reason = fmt.Sprintf("url is %d characters, over %d", len(rawURL), limit)
+		return decision
+	}
+
+	if limit, depth := s.config.MaxDepth, util.PathDepth(parsed.Path); limit > 0 && depth > limit {
+		decision.Rule = "max_depth"
+		decision.Reason = fmt.Sprintf("path is %d segments deep, over %d", depth, limit)
+		return decision
+	}
+
+	if limit, params := s.config.MaxQueryParams, queryParams(parsed.RawQuery); limit > 0 && params > limit {
+		decision.Rule = "max_query_params"
+		decision.Reason = fmt.Sprintf("query has %d parameters, over %d", params, limit)
+		return decision
+	}
+
+	t := target{
+		host:      util.Host(rawURL),
+		path:      parsed.EscapedPath(),
+		extension: strings.TrimPrefix(strings.ToLower(path.Ext(parsed.Path)), "."),
+	}
+
+	t.pathQuery = t.path
+	if parsed.RawQuery != "" {
+		t.pathQuery += "?" + parsed.RawQuery
+	}
+
+	for _, rule := range s.config.Rules {
+		if rule.matches(t) {
+			decision.Allowed = rule.Action == ActionAllow
+			decision.Rule = rule.Name
+			decision.Reason = "matched rule " + rule.Name
+			return decision
+		}
+	}
+
+	decision.Allowed = s.config.Default == ActionAllow
+	decision.Reason = "no rule matched, " + s.config.Default + " by default"
+
+	return decision
+}
+
+func queryParams(rawQuery string) int {
+	params := 0
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair != "" {
+			params++
+		}
+	}
+	return params
+}
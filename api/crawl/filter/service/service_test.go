@@ -0,0 +1,149 @@
+package service_test
+
+import (
+	"crawlquery/api/crawl/filter/service"
+	"crawlquery/api/domain"
+	"errors"
+	"testing"
+)
+
+func mustParse(t *testing.T, config string) *service.Config {
+	t.Helper()
+	c, err := service.ParseConfig([]byte(config))
+	if err != nil {
+		t.Fatalf("Error parsing config: %v", err)
+	}
+	return c
+}
+
+func TestParseConfig(t *testing.T) {
+	t.Run("allows by default", func(t *testing.T) {
+		c := mustParse(t, `{}`)
+
+		if c.Default != service.ActionAllow {
+			t.Errorf("Expected default to be allow, got %s", c.Default)
+		}
+	})
+
+	t.Run("rejects invalid configs", func(t *testing.T) {
+		for name, config := range map[string]string{
+			"bad json":       `{`,
+			"bad default":    `{"default": "maybe"}`,
+			"bad action":     `{"rules": [{"action": "block"}]}`,
+			"missing action": `{"rules": [{"name": "x"}]}`,
+			"bad regex":      `{"rules": [{"action": "deny", "path_regex": "("}]}`,
+		} {
+			if _, err := service.ParseConfig([]byte(config)); err == nil {
+				t.Errorf("Expected an error for %s", name)
+			}
+		}
+
+		_, err := service.ParseConfig([]byte(`{"rules": [{"action": "block"}]}`))
+		if !errors.Is(err, service.ErrInvalidAction) {
+			t.Errorf("Expected ErrInvalidAction, got %v", err)
+		}
+	})
+
+	t.Run("reads the seed rules", func(t *testing.T) {
+		c, err := service.ReadConfig("../../../../seed/filters.json")
+
+		if err != nil {
+			t.Fatalf("Error reading config: %v", err)
+		}
+
+		if len(c.Rules) == 0 {
+			t.Errorf("Expected rules to be read")
+		}
+	})
+}
+
+func TestEvaluate(t *testing.T) {
+	config := `{
+		"max_length": 60,
+		"max_depth": 4,
+		"max_query_params": 2,
+		"rules": [
+			{"name": "trusted docs", "action": "allow", "domains": ["docs.example.com"], "path_glob": "/guide/**"},
+			{"name": "binaries", "action": "deny", "extensions": [".PDF", "zip"]},
+			{"name": "blocked site", "action": "deny", "domains": ["www.spam.com"]},
+			{"name": "login", "action": "deny", "path_glob": "/*/login"},
+			{"name": "calendar", "action": "deny", "path_regex": "[?&]month="},
+			{"action": "deny", "path_glob": "/tmp?/*"}
+		]
+	}`
+
+	svc := service.NewService(service.WithConfig(mustParse(t, config)))
+
+	tests := []struct {
+		url     domain.URL
+		allowed bool
+		rule    string
+	}{
+		{url: "http://example.com/about", allowed: true, rule: ""},
+		{url: "http://example.com/" + "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", allowed: false, rule: "max_length"},
+		{url: "http://example.com/a/b/c/d/e", allowed: false, rule: "max_depth"},
+		{url: "http://example.com/a/b/c/d", allowed: true, rule: ""},
+		{url: "http://example.com/?a=1&b=2&c=3", allowed: false, rule: "max_query_params"},
+		{url: "http://example.com/?a=1&&b=2", allowed: true, rule: ""},
+		{url: "http://docs.example.com/guide/a/b.pdf", allowed: true, rule: "trusted docs"},
+		{url: "http://example.com/guide/a/b.pdf", allowed: false, rule: "binaries"},
+		{url: "http://example.com/files/archive.ZIP", allowed: false, rule: "binaries"},
+		{url: "http://example.com/zip", allowed: true, rule: ""},
+		{url: "http://spam.com/", allowed: false, rule: "blocked site"},
+		{url: "http://www.spam.com/", allowed: false, rule: "blocked site"},
+		{url: "http://blog.spam.com/", allowed: false, rule: "blocked site"},
+		{url: "http://notspam.com/", allowed: true, rule: ""},
+		{url: "http://example.com/en/login", allowed: false, rule: "login"},
+		{url: "http://example.com/en/us/login", allowed: true, rule: ""},
+		{url: "http://example.com/events?month=6", allowed: false, rule: "calendar"},
+		{url: "http://example.com/events?year=2024&month=6", allowed: false, rule: "calendar"},
+		{url: "http://example.com/tmp1/x", allowed: false, rule: "deny"},
+		{url: "http://example.com/tmp12/x", allowed: true, rule: ""},
+		{url: "http://example.com/%zz", allowed: false, rule: ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(string(tc.url), func(t *testing.T) {
+			decision := svc.Evaluate(tc.url)
+
+			if decision.Allowed != tc.allowed {
+				t.Errorf("Expected allowed to be %v, got %v (%s)", tc.allowed, decision.Allowed, decision.Reason)
+			}
+
+			if decision.Rule != tc.rule {
+				t.Errorf("Expected rule %q, got %q", tc.rule, decision.Rule)
+			}
+
+			if decision.Reason == "" {
+				t.Errorf("Expected a reason")
+			}
+
+			if decision.URL != tc.url {
+				t.Errorf("Expected url %s, got %s", tc.url, decision.URL)
+			}
+		})
+	}
+
+	t.Run("denies by default when configured to", func(t *testing.T) {
+		svc := service.NewService(service.WithConfig(mustParse(t, `{
+			"default": "deny",
+			"rules": [{"name": "seed", "action": "allow", "domains": ["example.com"]}]
+		}`)))
+
+		if decision := svc.Evaluate("http://example.com/a"); !decision.Allowed {
+			t.Errorf("Expected the seed domain to be allowed, got %s", decision.Reason)
+		}
+
+		if decision := svc.Evaluate("http://example.net/a"); decision.Allowed {
+			t.Errorf("Expected other domains to be denied")
+		}
+	})
+
+	t.Run("allows everything without a config", func(t *testing.T) {
+		svc := service.NewService()
+
+		if decision := svc.Evaluate("http://example.com/a.zip"); !decision.Allowed {
+			t.Errorf("Expected the url to be allowed, got %s", decision.Reason)
+		}
+	})
+}
@@ -2,7 +2,9 @@ package mem
 
 import (
 	"crawlquery/api/domain"
+	"sort"
 	"sync"
+	"time"
 )
 
 type Repository struct {
@@ -48,3 +50,74 @@ func (r *Repository) ListByStatus(limit int, status domain.CrawlStatus) ([]*doma
 	}
 	return jobs, nil
 }
+
+func (r *Repository) ListDue(limit, perHost int, now time.Time) ([]*domain.CrawlJob, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var jobs []*domain.CrawlJob
+	for _, job := range r.jobs {
+		switch job.Status {
+		case domain.CrawlStatusPending, domain.CrawlStatusFailed:
+			if job.NextAttemptAt.After(now) {
+				continue
+			}
+		case domain.CrawlStatusCompleted:
+			if job.NextAttemptAt.IsZero() || job.NextAttemptAt.After(now) {
+				continue
+			}
+		default:
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].Priority != jobs[j].Priority {
+			return jobs[i].Priority > jobs[j].Priority
+		}
+		if !jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+		}
+		return jobs[i].PageID < jobs[j].PageID
+	})
+	hostCounts := make(map[domain.Host]int)
+	capped := jobs[:0]
+	for _, job := range jobs {
+		if hostCounts[job.Host] >= perHost {
+			continue
+		}
+		hostCounts[job.Host]++
+		capped = append(capped, job)
+	}
+	jobs = capped
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	return jobs, nil
+}
+
+func (r *Repository) ListStale(limit int, updatedBefore time.Time) ([]*domain.CrawlJob, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var jobs []*domain.CrawlJob
+	for _, job := range r.jobs {
+		if job.Status == domain.CrawlStatusInProgress && job.UpdatedAt.Before(updatedBefore) {
+			jobs = append(jobs, job)
+		}
+	}
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	return jobs, nil
+}
+
+func (r *Repository) CountByHost(host domain.Host) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	count := 0
+	for _, job := range r.jobs {
+		if job.Host == host {
+			count++
+		}
+	}
+	return count, nil
+}
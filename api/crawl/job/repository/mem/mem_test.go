@@ -4,6 +4,7 @@ import (
 	"crawlquery/api/domain"
 	"fmt"
 	"testing"
+	"time"
 )
 
 func TestGet(t *testing.T) {
@@ -99,3 +100,155 @@ func TestListByStatus(t *testing.T) {
 		}
 	})
 }
+
+func TestListDue(t *testing.T) {
+	t.Run("lists pending jobs and jobs due a retry or recrawl", func(t *testing.T) {
+		crawlJobRepo := NewRepository()
+		now := time.Now()
+
+		jobs := []*domain.CrawlJob{
+			{PageID: "pending", Status: domain.CrawlStatusPending},
+			{PageID: "retry", Status: domain.CrawlStatusFailed, NextAttemptAt: now.Add(-time.Minute)},
+			{PageID: "later", Status: domain.CrawlStatusFailed, NextAttemptAt: now.Add(time.Minute)},
+			{PageID: "dead", Status: domain.CrawlStatusDead},
+			{PageID: "completed", Status: domain.CrawlStatusCompleted},
+			{PageID: "recrawl", Status: domain.CrawlStatusCompleted, NextAttemptAt: now.Add(-time.Minute)},
+			{PageID: "recrawl-later", Status: domain.CrawlStatusCompleted, NextAttemptAt: now.Add(time.Minute)},
+		}
+
+		for _, job := range jobs {
+			crawlJobRepo.jobs[job.PageID] = job
+		}
+
+		due, err := crawlJobRepo.ListDue(10, 10, now)
+
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+
+		if len(due) != 3 {
+			t.Fatalf("expected 3 jobs, got %v", len(due))
+		}
+
+		for _, job := range due {
+			if job.PageID != "pending" && job.PageID != "retry" && job.PageID != "recrawl" {
+				t.Errorf("expected pending, retry or recrawl, got %v", job.PageID)
+			}
+		}
+	})
+}
+
+func TestListStale(t *testing.T) {
+	t.Run("lists jobs in progress since before the given time", func(t *testing.T) {
+		crawlJobRepo := NewRepository()
+		now := time.Now()
+
+		jobs := []*domain.CrawlJob{
+			{PageID: "stale", Status: domain.CrawlStatusInProgress, UpdatedAt: now.Add(-time.Hour)},
+			{PageID: "running", Status: domain.CrawlStatusInProgress, UpdatedAt: now},
+			{PageID: "pending", Status: domain.CrawlStatusPending, UpdatedAt: now.Add(-time.Hour)},
+		}
+
+		for _, job := range jobs {
+			crawlJobRepo.jobs[job.PageID] = job
+		}
+
+		stale, err := crawlJobRepo.ListStale(10, now.Add(-time.Minute))
+
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+
+		if len(stale) != 1 || stale[0].PageID != "stale" {
+			t.Errorf("expected only the stale job, got %v", stale)
+		}
+	})
+}
+
+func TestListDueOrder(t *testing.T) {
+	t.Run("lists the highest priority jobs first", func(t *testing.T) {
+		crawlJobRepo := NewRepository()
+		now := time.Now()
+
+		jobs := []*domain.CrawlJob{
+			{PageID: "low", Status: domain.CrawlStatusPending, Priority: 0.1},
+			{PageID: "high", Status: domain.CrawlStatusPending, Priority: 0.9},
+			{PageID: "newer", Status: domain.CrawlStatusPending, Priority: 0.5, CreatedAt: now},
+			{PageID: "older", Status: domain.CrawlStatusPending, Priority: 0.5, CreatedAt: now.Add(-time.Minute)},
+		}
+
+		for _, job := range jobs {
+			crawlJobRepo.jobs[job.PageID] = job
+		}
+
+		due, err := crawlJobRepo.ListDue(3, 3, now)
+
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+
+		expected := []domain.PageID{"high", "older", "newer"}
+
+		if len(due) != len(expected) {
+			t.Fatalf("expected %d jobs, got %d", len(expected), len(due))
+		}
+
+		for i, pageID := range expected {
+			if due[i].PageID != pageID {
+				t.Errorf("expected %v at %d, got %v", pageID, i, due[i].PageID)
+			}
+		}
+	})
+}
+
+func TestListDuePerHost(t *testing.T) {
+	t.Run("caps the jobs taken from one host", func(t *testing.T) {
+		crawlJobRepo := NewRepository()
+		now := time.Now()
+
+		for i := 0; i < 5; i++ {
+			pageID := domain.PageID(fmt.Sprintf("large%d", i))
+			crawlJobRepo.jobs[pageID] = &domain.CrawlJob{PageID: pageID, Host: "large.com", Status: domain.CrawlStatusPending, Priority: 0.9}
+		}
+		crawlJobRepo.jobs["small"] = &domain.CrawlJob{PageID: "small", Host: "small.com", Status: domain.CrawlStatusPending, Priority: 0.1}
+
+		due, err := crawlJobRepo.ListDue(10, 2, now)
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		hosts := make(map[domain.Host]int)
+		for _, job := range due {
+			hosts[job.Host]++
+		}
+
+		if hosts["large.com"] != 2 {
+			t.Errorf("expected 2 jobs from the large host, got %d", hosts["large.com"])
+		}
+
+		if hosts["small.com"] != 1 {
+			t.Errorf("expected 1 job from the small host, got %d", hosts["small.com"])
+		}
+	})
+}
+
+func TestCountByHost(t *testing.T) {
+	t.Run("counts jobs on a host", func(t *testing.T) {
+		crawlJobRepo := NewRepository()
+
+		crawlJobRepo.jobs["a"] = &domain.CrawlJob{PageID: "a", Host: "example.com"}
+		crawlJobRepo.jobs["b"] = &domain.CrawlJob{PageID: "b", Host: "example.com"}
+		crawlJobRepo.jobs["c"] = &domain.CrawlJob{PageID: "c", Host: "example.net"}
+
+		count, err := crawlJobRepo.CountByHost("example.com")
+
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+
+		if count != 2 {
+			t.Errorf("expected 2 jobs, got %d", count)
+		}
+	})
+}
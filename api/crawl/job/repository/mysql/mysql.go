@@ -3,6 +3,7 @@ package mysql
 import (
 	"crawlquery/api/domain"
 	"database/sql"
+	"time"
 )
 
 type Repository struct {
@@ -15,28 +16,53 @@ func NewRepository(db *sql.DB) *Repository {
 	}
 }
 
-func (r *Repository) Get(pageID domain.PageID) (*domain.CrawlJob, error) {
+const columns = "page_id, url, host, shard_id, scope_id, status, priority, attempts, next_attempt_at, created_at, updated_at"
+
+const selectColumns = "SELECT " + columns + " FROM crawl_jobs"
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJob(row scanner) (*domain.CrawlJob, error) {
 	var job domain.CrawlJob
-	err := r.db.QueryRow("SELECT page_id, url, shard_id, status, created_at, updated_at FROM crawl_jobs WHERE page_id = ?", pageID).Scan(&job.PageID, &job.URL, &job.ShardID, &job.Status, &job.CreatedAt, &job.UpdatedAt)
+	var nextAttemptAt sql.NullTime
+	err := row.Scan(&job.PageID, &job.URL, &job.Host, &job.ShardID, &job.ScopeID, &job.Status, &job.Priority, &job.Attempts, &nextAttemptAt, &job.CreatedAt, &job.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if nextAttemptAt.Valid {
+		job.NextAttemptAt = nextAttemptAt.Time
+	}
+	return &job, nil
+}
+
+func (r *Repository) Get(pageID domain.PageID) (*domain.CrawlJob, error) {
+	job, err := scanJob(r.db.QueryRow(selectColumns+" WHERE page_id = ?", pageID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, domain.ErrCrawlJobNotFound
 		}
 		return nil, err
 	}
-	return &job, nil
+	return job, nil
 }
 
 func (r *Repository) Save(job *domain.CrawlJob) error {
-	_, err := r.db.Exec("INSERT INTO crawl_jobs (page_id, url, shard_id, status, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE status = ?, updated_at = ?", job.PageID, job.URL, job.ShardID, job.Status, job.CreatedAt, job.UpdatedAt, job.Status, job.UpdatedAt)
+	var nextAttemptAt sql.NullTime
+	if !job.NextAttemptAt.IsZero() {
+		nextAttemptAt = sql.NullTime{Time: job.NextAttemptAt, Valid: true}
+	}
+
+	_, err := r.db.Exec("INSERT INTO crawl_jobs (page_id, url, host, shard_id, scope_id, status, priority, attempts, next_attempt_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE status = ?, priority = ?, attempts = ?, next_attempt_at = ?, updated_at = ?", job.PageID, job.URL, job.Host, job.ShardID, job.ScopeID, job.Status, job.Priority, job.Attempts, nextAttemptAt, job.CreatedAt, job.UpdatedAt, job.Status, job.Priority, job.Attempts, nextAttemptAt, job.UpdatedAt)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (r *Repository) ListByStatus(limit int, status domain.CrawlStatus) ([]*domain.CrawlJob, error) {
-	rows, err := r.db.Query("SELECT page_id, url, shard_id, status, created_at, updated_at FROM crawl_jobs WHERE status = ? LIMIT ?", status, limit)
+func (r *Repository) list(query string, args ...any) ([]*domain.CrawlJob, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -44,12 +70,45 @@ func (r *Repository) ListByStatus(limit int, status domain.CrawlStatus) ([]*doma
 
 	var jobs []*domain.CrawlJob
 	for rows.Next() {
-		var job domain.CrawlJob
-		err := rows.Scan(&job.PageID, &job.URL, &job.ShardID, &job.Status, &job.CreatedAt, &job.UpdatedAt)
+		job, err := scanJob(rows)
 		if err != nil {
 			return nil, err
 		}
-		jobs = append(jobs, &job)
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func (r *Repository) ListByStatus(limit int, status domain.CrawlStatus) ([]*domain.CrawlJob, error) {
+	return r.list(selectColumns+" WHERE status = ? LIMIT ?", status, limit)
+}
+
+// dueCandidates is how many of the best due jobs are considered for each job
+// ListDue returns, so that jobs are ranked within their host without sorting
+// every due job. A host with more than that many of the best jobs holds
+// back the others until its jobs are taken.
+const dueCandidates = 10
+
+func (r *Repository) ListDue(limit, perHost int, now time.Time) ([]*domain.CrawlJob, error) {
+	return r.list(
+		"SELECT "+columns+" FROM ("+
+			"SELECT "+columns+", ROW_NUMBER() OVER (PARTITION BY host ORDER BY priority DESC, created_at) AS host_rank FROM ("+
+			selectColumns+" WHERE (status IN (?, ?) AND (next_attempt_at IS NULL OR next_attempt_at <= ?)) OR (status = ? AND next_attempt_at <= ?) ORDER BY priority DESC, created_at LIMIT ?"+
+			") AS candidates"+
+			") AS due WHERE host_rank <= ? ORDER BY priority DESC, created_at LIMIT ?",
+		domain.CrawlStatusPending, domain.CrawlStatusFailed, now, domain.CrawlStatusCompleted, now, limit*dueCandidates, perHost, limit,
+	)
+}
+
+func (r *Repository) ListStale(limit int, updatedBefore time.Time) ([]*domain.CrawlJob, error) {
+	return r.list(selectColumns+" WHERE status = ? AND updated_at < ? LIMIT ?", domain.CrawlStatusInProgress, updatedBefore, limit)
+}
+
+func (r *Repository) CountByHost(host domain.Host) (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM crawl_jobs WHERE host = ?", host).Scan(&count)
+	if err != nil {
+		return 0, err
 	}
-	return jobs, nil
+	return count, nil
 }
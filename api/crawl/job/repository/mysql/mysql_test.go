@@ -4,6 +4,7 @@ import (
 	"crawlquery/api/domain"
 	"crawlquery/api/migration"
 	"crawlquery/pkg/testutil"
+	"fmt"
 	"testing"
 	"time"
 
@@ -250,3 +251,189 @@ func TestListByStatus(t *testing.T) {
 
 	})
 }
+
+func TestRetries(t *testing.T) {
+	t.Run("saves attempts and next attempt at", func(t *testing.T) {
+		db := testutil.CreateTestMysqlDB()
+		defer db.Close()
+		migration.Up(db)
+		crawlJobRepo := crawlJobRepo.NewRepository(db)
+		now := time.Now()
+		crawlJob := &domain.CrawlJob{
+			PageID:        "page1",
+			URL:           "http://example.com",
+			Status:        domain.CrawlStatusFailed,
+			Attempts:      2,
+			NextAttemptAt: now.Add(time.Hour),
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+
+		err := crawlJobRepo.Save(crawlJob)
+		defer db.Exec("DELETE FROM crawl_jobs WHERE page_id = ?", crawlJob.PageID)
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		job, err := crawlJobRepo.Get("page1")
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if job.Attempts != 2 {
+			t.Errorf("expected 2 attempts, got %d", job.Attempts)
+		}
+
+		if job.NextAttemptAt.UTC().Round(time.Second) != crawlJob.NextAttemptAt.UTC().Round(time.Second) {
+			t.Errorf("expected %v, got %v", crawlJob.NextAttemptAt, job.NextAttemptAt)
+		}
+	})
+
+	t.Run("lists due and stale jobs", func(t *testing.T) {
+		db := testutil.CreateTestMysqlDB()
+		defer db.Close()
+		migration.Up(db)
+		crawlJobRepo := crawlJobRepo.NewRepository(db)
+		now := time.Now()
+
+		jobs := []*domain.CrawlJob{
+			{PageID: "pending", Status: domain.CrawlStatusPending, CreatedAt: now, UpdatedAt: now},
+			{PageID: "retry", Status: domain.CrawlStatusFailed, NextAttemptAt: now.Add(-time.Hour), CreatedAt: now, UpdatedAt: now},
+			{PageID: "later", Status: domain.CrawlStatusFailed, NextAttemptAt: now.Add(time.Hour), CreatedAt: now, UpdatedAt: now},
+			{PageID: "stale", Status: domain.CrawlStatusInProgress, CreatedAt: now, UpdatedAt: now.Add(-time.Hour)},
+			{PageID: "running", Status: domain.CrawlStatusInProgress, CreatedAt: now, UpdatedAt: now},
+			{PageID: "completed", Status: domain.CrawlStatusCompleted, CreatedAt: now, UpdatedAt: now},
+			{PageID: "recrawl", Status: domain.CrawlStatusCompleted, NextAttemptAt: now.Add(-time.Hour), CreatedAt: now, UpdatedAt: now},
+		}
+
+		for _, job := range jobs {
+			job.URL = "http://example.com"
+			if err := crawlJobRepo.Save(job); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			defer db.Exec("DELETE FROM crawl_jobs WHERE page_id = ?", job.PageID)
+		}
+
+		due, err := crawlJobRepo.ListDue(10, 10, now)
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if len(due) != 3 {
+			t.Errorf("expected 3 due jobs, got %d", len(due))
+		}
+
+		stale, err := crawlJobRepo.ListStale(10, now.Add(-time.Minute))
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if len(stale) != 1 || stale[0].PageID != "stale" {
+			t.Errorf("expected only the stale job, got %v", stale)
+		}
+	})
+}
+
+func TestListDuePerHost(t *testing.T) {
+	t.Run("caps the jobs taken from one host", func(t *testing.T) {
+		db := testutil.CreateTestMysqlDB()
+		defer db.Close()
+		migration.Up(db)
+		crawlJobRepo := crawlJobRepo.NewRepository(db)
+		now := time.Now()
+
+		var jobs []*domain.CrawlJob
+		for i := 0; i < 5; i++ {
+			jobs = append(jobs, &domain.CrawlJob{
+				PageID:   domain.PageID(fmt.Sprintf("large%d", i)),
+				URL:      domain.URL(fmt.Sprintf("http://large.com/%d", i)),
+				Host:     "large.com",
+				Priority: 0.9,
+			})
+		}
+		jobs = append(jobs, &domain.CrawlJob{PageID: "small", URL: "http://small.com/", Host: "small.com", Priority: 0.1})
+
+		for _, job := range jobs {
+			job.Status = domain.CrawlStatusPending
+			job.CreatedAt = now
+			job.UpdatedAt = now
+			if err := crawlJobRepo.Save(job); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			defer db.Exec("DELETE FROM crawl_jobs WHERE page_id = ?", job.PageID)
+		}
+
+		due, err := crawlJobRepo.ListDue(10, 2, now)
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		hosts := make(map[domain.Host]int)
+		for _, job := range due {
+			hosts[job.Host]++
+		}
+
+		if hosts["large.com"] != 2 {
+			t.Errorf("expected 2 jobs from the large host, got %d", hosts["large.com"])
+		}
+
+		if hosts["small.com"] != 1 {
+			t.Errorf("expected 1 job from the small host, got %d", hosts["small.com"])
+		}
+	})
+}
+
+func TestPriority(t *testing.T) {
+	t.Run("lists due jobs by priority and counts them by host", func(t *testing.T) {
+		db := testutil.CreateTestMysqlDB()
+		defer db.Close()
+		migration.Up(db)
+		crawlJobRepo := crawlJobRepo.NewRepository(db)
+		now := time.Now()
+
+		jobs := []*domain.CrawlJob{
+			{PageID: "low", URL: "http://example.com/low", Host: "example.com", Priority: 0.1},
+			{PageID: "high", URL: "http://example.com/high", Host: "example.com", Priority: 0.9},
+			{PageID: "other", URL: "http://example.net/", Host: "example.net", Priority: 0.5},
+		}
+
+		for _, job := range jobs {
+			job.Status = domain.CrawlStatusPending
+			job.CreatedAt = now
+			job.UpdatedAt = now
+			if err := crawlJobRepo.Save(job); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			defer db.Exec("DELETE FROM crawl_jobs WHERE page_id = ?", job.PageID)
+		}
+
+		due, err := crawlJobRepo.ListDue(10, 10, now)
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if len(due) != 3 || due[0].PageID != "high" || due[1].PageID != "other" || due[2].PageID != "low" {
+			t.Errorf("expected jobs in priority order, got %v", due)
+		}
+
+		if due[0].Host != "example.com" || due[0].Priority != 0.9 {
+			t.Errorf("expected host and priority to be saved, got %v and %v", due[0].Host, due[0].Priority)
+		}
+
+		count, err := crawlJobRepo.CountByHost("example.com")
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if count != 2 {
+			t.Errorf("expected 2 jobs, got %d", count)
+		}
+	})
+}
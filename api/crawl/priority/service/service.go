@@ -0,0 +1,183 @@
+package service
+
+import (
+	"crawlquery/api/domain"
+	"crawlquery/pkg/util"
+	"net/url"
+
+	"go.uber.org/zap"
+)
+
+// Service scores pages for the crawl frontier between 0 and 1 from the
+// PageRank of the best page linking to them, how shallow their URL is,
+// whether they are on a seed host and how few pages of their host are
+// already known, weighted and summed.
+type Service struct {
+	pageRankService domain.PageRankService
+	linkRepo        domain.LinkRepository
+	crawlJobRepo    domain.CrawlJobRepository
+	seedHosts       map[domain.Host]bool
+	logger          *zap.SugaredLogger
+
+	rankWeight      float64
+	depthWeight     float64
+	seedWeight      float64
+	diversityWeight float64
+	rankReference   float64
+}
+
+type Option func(*Service)
+
+func WithPageRankService(pageRankService domain.PageRankService) Option {
+	return func(s *Service) {
+		s.pageRankService = pageRankService
+	}
+}
+
+func WithLinkRepo(linkRepo domain.LinkRepository) Option {
+	return func(s *Service) {
+		s.linkRepo = linkRepo
+	}
+}
+
+func WithCrawlJobRepo(crawlJobRepo domain.CrawlJobRepository) Option {
+	return func(s *Service) {
+		s.crawlJobRepo = crawlJobRepo
+	}
+}
+
+// WithSeedDomains favours pages on the hosts of the seed URLs.
+func WithSeedDomains(urls []domain.URL) Option {
+	return func(s *Service) {
+		s.seedHosts = make(map[domain.Host]bool, len(urls))
+		for _, url := range urls {
+			s.seedHosts[util.Host(url)] = true
+		}
+	}
+}
+
+func WithLogger(logger *zap.SugaredLogger) Option {
+	return func(s *Service) {
+		s.logger = logger
+	}
+}
+
+// WithWeights sets how much each part of the score counts. They should sum
+// to 1 to keep scores between 0 and 1.
+func WithWeights(rank, depth, seed, diversity float64) Option {
+	return func(s *Service) {
+		s.rankWeight = rank
+		s.depthWeight = depth
+		s.seedWeight = seed
+		s.diversityWeight = diversity
+	}
+}
+
+// WithRankReference sets the PageRank that scores half marks for rank.
+// PageRanks sum to 1 over every page, so a good reference shrinks as the
+// crawl grows.
+func WithRankReference(rank float64) Option {
+	return func(s *Service) {
+		s.rankReference = rank
+	}
+}
+
+func NewService(opts ...Option) *Service {
+	s := &Service{
+		seedHosts:       map[domain.Host]bool{},
+		rankWeight:      0.4,
+		depthWeight:     0.2,
+		seedWeight:      0.2,
+		diversityWeight: 0.2,
+		rankReference:   0.0001,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func (s *Service) Priority(page *domain.Page) float64 {
+	host := util.Host(page.URL)
+
+	priority := s.rankWeight*s.rankScore(page.ID) +
+		s.depthWeight/float64(1+Depth(page.URL)) +
+		s.diversityWeight*s.diversityScore(host)
+
+	if s.seedHosts[host] {
+		priority += s.seedWeight
+	}
+
+	return priority
+}
+
+// rankScore is the highest PageRank of the pages linking to the page,
+// scaled to between 0 and 1.
+func (s *Service) rankScore(pageID domain.PageID) float64 {
+	if s.linkRepo == nil || s.pageRankService == nil {
+		return 0
+	}
+
+	links, err := s.linkRepo.GetAllByDstID(pageID)
+	if err != nil {
+		s.logger.Errorw("Error getting links to page", "error", err, "pageID", pageID)
+		return 0
+	}
+
+	var srcIDs []domain.PageID
+	for _, link := range links {
+		// nofollow links don't vouch for their target
+		if !link.Nofollow() {
+			srcIDs = append(srcIDs, link.SrcID)
+		}
+	}
+
+	if len(srcIDs) == 0 {
+		return 0
+	}
+
+	// pages without a rank yet count as 0
+	ranks, err := s.pageRankService.GetPageRanks(srcIDs)
+	if err != nil {
+		return 0
+	}
+
+	var best float64
+	for _, rank := range ranks {
+		best = max(best, rank)
+	}
+
+	return best / (best + s.rankReference)
+}
+
+// diversityScore favours hosts with few pages known to the crawl.
+func (s *Service) diversityScore(host domain.Host) float64 {
+	if s.crawlJobRepo == nil {
+		return 1
+	}
+
+	count, err := s.crawlJobRepo.CountByHost(host)
+	if err != nil {
+		s.logger.Errorw("Error counting jobs for host", "error", err, "host", host)
+		return 0
+	}
+
+	return 1 / float64(1+count)
+}
+
+// Depth counts the segments of a URL's path, counting a query as one more.
+func Depth(rawURL domain.URL) int {
+	parsed, err := url.Parse(string(rawURL))
+	if err != nil {
+		return 0
+	}
+
+	depth := util.PathDepth(parsed.Path)
+	if parsed.RawQuery != "" {
+		depth++
+	}
+
+	return depth
+}
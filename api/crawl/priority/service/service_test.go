@@ -0,0 +1,125 @@
+package service_test
+
+import (
+	"crawlquery/api/domain"
+	"math"
+	"testing"
+	"time"
+
+	crawlJobRepo "crawlquery/api/crawl/job/repository/mem"
+	priorityService "crawlquery/api/crawl/priority/service"
+	linkRepo "crawlquery/api/link/repository/mem"
+	pageRankRepo "crawlquery/api/pagerank/repository/mem"
+	pageRankService "crawlquery/api/pagerank/service"
+	"crawlquery/pkg/testutil"
+	"crawlquery/pkg/util"
+)
+
+func page(url domain.URL) *domain.Page {
+	return &domain.Page{ID: util.PageID(url), URL: url}
+}
+
+func TestDepth(t *testing.T) {
+	cases := map[domain.URL]int{
+		"http://example.com":             0,
+		"http://example.com/":            0,
+		"http://example.com/a":           1,
+		"http://example.com/a/b/":        2,
+		"http://example.com/a/b/c.html":  3,
+		"http://example.com/search?q=go": 2,
+	}
+
+	for url, want := range cases {
+		if got := priorityService.Depth(url); got != want {
+			t.Errorf("Depth(%s) = %d, want %d", url, got, want)
+		}
+	}
+}
+
+func TestPriority(t *testing.T) {
+	t.Run("favours shallow pages", func(t *testing.T) {
+		service := priorityService.NewService(priorityService.WithWeights(0, 1, 0, 0))
+
+		if got := service.Priority(page("http://example.com/")); got != 1 {
+			t.Errorf("expected 1 for the home page, got %v", got)
+		}
+
+		if got := service.Priority(page("http://example.com/a/b/c")); got != 0.25 {
+			t.Errorf("expected 0.25 for a page 3 deep, got %v", got)
+		}
+	})
+
+	t.Run("favours seed hosts", func(t *testing.T) {
+		service := priorityService.NewService(
+			priorityService.WithWeights(0, 0, 1, 0),
+			priorityService.WithSeedDomains([]domain.URL{"https://www.example.com"}),
+		)
+
+		if got := service.Priority(page("http://example.com/a")); got != 1 {
+			t.Errorf("expected 1 for a seed host, got %v", got)
+		}
+
+		if got := service.Priority(page("http://example.net/a")); got != 0 {
+			t.Errorf("expected 0 for another host, got %v", got)
+		}
+	})
+
+	t.Run("favours hosts with few known pages", func(t *testing.T) {
+		jobRepo := crawlJobRepo.NewRepository()
+		for _, url := range []domain.URL{"http://example.com/1", "http://example.com/2", "http://example.com/3"} {
+			jobRepo.Save(&domain.CrawlJob{PageID: util.PageID(url), URL: url, Host: util.Host(url)})
+		}
+
+		service := priorityService.NewService(
+			priorityService.WithWeights(0, 0, 0, 1),
+			priorityService.WithCrawlJobRepo(jobRepo),
+			priorityService.WithLogger(testutil.NewTestLogger()),
+		)
+
+		if got := service.Priority(page("http://example.com/4")); got != 0.25 {
+			t.Errorf("expected 0.25 for a host with 3 pages, got %v", got)
+		}
+
+		if got := service.Priority(page("http://example.net/1")); got != 1 {
+			t.Errorf("expected 1 for a new host, got %v", got)
+		}
+	})
+
+	t.Run("favours pages linked from highly ranked pages", func(t *testing.T) {
+		links := linkRepo.NewRepository()
+		ranks := pageRankRepo.NewRepository()
+
+		ranked := util.PageID("http://ranked.com")
+		unranked := util.PageID("http://unranked.com")
+		nofollow := util.PageID("http://nofollow.com")
+
+		ranks.Update(ranked, 0.3, time.Now())
+		ranks.Update(unranked, 0.1, time.Now())
+		ranks.Update(nofollow, 0.9, time.Now())
+
+		links.Create(&domain.Link{SrcID: ranked, DstID: util.PageID("http://example.com/a")})
+		links.Create(&domain.Link{SrcID: unranked, DstID: util.PageID("http://example.com/a")})
+		links.Create(&domain.Link{SrcID: unranked, DstID: util.PageID("http://example.com/b")})
+		links.Create(&domain.Link{SrcID: nofollow, DstID: util.PageID("http://example.com/b"), Rel: "nofollow"})
+
+		service := priorityService.NewService(
+			priorityService.WithWeights(1, 0, 0, 0),
+			priorityService.WithRankReference(0.1),
+			priorityService.WithLinkRepo(links),
+			priorityService.WithPageRankService(pageRankService.NewService(nil, ranks, testutil.NewTestLogger())),
+			priorityService.WithLogger(testutil.NewTestLogger()),
+		)
+
+		if got := service.Priority(page("http://example.com/a")); math.Abs(got-0.75) > 1e-9 {
+			t.Errorf("expected 0.75 from the best linking page, got %v", got)
+		}
+
+		if got := service.Priority(page("http://example.com/b")); math.Abs(got-0.5) > 1e-9 {
+			t.Errorf("expected 0.5 ignoring the nofollow link, got %v", got)
+		}
+
+		if got := service.Priority(page("http://example.com/c")); got != 0 {
+			t.Errorf("expected 0 without links, got %v", got)
+		}
+	})
+}
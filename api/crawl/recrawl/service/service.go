@@ -0,0 +1,209 @@
+package service
+
+import (
+	"crawlquery/api/domain"
+	"math"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// historySize is how many of a page's latest versions are used to estimate
+// how often it changes, so that the estimate follows pages whose habits
+// change.
+const historySize = 20
+
+// Service schedules crawled pages to be crawled again, sooner for pages whose
+// content changes often and later for pages that rarely change.
+type Service struct {
+	eventService    domain.EventService
+	versionRepo     domain.PageVersionRepository
+	crawlJobRepo    domain.CrawlJobRepository
+	logger          *zap.SugaredLogger
+	minInterval     time.Duration
+	maxInterval     time.Duration
+	initialInterval time.Duration
+}
+
+type Option func(*Service)
+
+func WithEventService(eventService domain.EventService) Option {
+	return func(s *Service) {
+		s.eventService = eventService
+	}
+}
+
+func WithVersionRepo(versionRepo domain.PageVersionRepository) Option {
+	return func(s *Service) {
+		s.versionRepo = versionRepo
+	}
+}
+
+func WithCrawlJobRepo(crawlJobRepo domain.CrawlJobRepository) Option {
+	return func(s *Service) {
+		s.crawlJobRepo = crawlJobRepo
+	}
+}
+
+func WithLogger(logger *zap.SugaredLogger) Option {
+	return func(s *Service) {
+		s.logger = logger
+	}
+}
+
+// WithIntervalBounds sets the shortest and longest time between crawls of a
+// page.
+func WithIntervalBounds(minInterval, maxInterval time.Duration) Option {
+	return func(s *Service) {
+		s.minInterval = minInterval
+		s.maxInterval = maxInterval
+	}
+}
+
+// WithInitialInterval sets the time between crawls of a page with too little
+// history to tell how often it changes.
+func WithInitialInterval(initialInterval time.Duration) Option {
+	return func(s *Service) {
+		s.initialInterval = initialInterval
+	}
+}
+
+func WithEventListeners() Option {
+	return func(s *Service) {
+		s.registerEventListeners()
+	}
+}
+
+func NewService(opts ...Option) *Service {
+	s := &Service{
+		minInterval:     time.Hour,
+		maxInterval:     30 * 24 * time.Hour,
+		initialInterval: 24 * time.Hour,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func (s *Service) registerEventListeners() {
+	if s.eventService == nil {
+		s.logger.Fatal("EventService is required")
+	}
+
+	s.eventService.Subscribe(domain.PageVersionCreatedKey, s.handlePageVersionCreated)
+}
+
+func (s *Service) handlePageVersionCreated(e domain.Event) {
+	version := e.(*domain.PageVersionCreated).PageVersion
+
+	if err := s.Schedule(version.PageID); err != nil {
+		s.logger.Errorw("Error scheduling recrawl", "error", err, "pageID", version.PageID)
+	}
+}
+
+// Schedule sets when a completed crawl job is next due from the history of
+// its page's versions.
+func (s *Service) Schedule(pageID domain.PageID) error {
+	job, err := s.crawlJobRepo.Get(pageID)
+	if err != nil {
+		return err
+	}
+
+	if job.Status != domain.CrawlStatusCompleted {
+		return nil
+	}
+
+	versions, err := s.versionRepo.ListByPageID(pageID)
+	if err != nil {
+		return err
+	}
+
+	last := time.Now()
+	if len(versions) > 0 {
+		last = latest(versions).CreatedAt
+	}
+
+	interval := s.Interval(versions)
+
+	job.NextAttemptAt = last.Add(interval)
+	job.UpdatedAt = time.Now()
+
+	s.logger.Infow("Scheduled recrawl", "pageID", pageID, "interval", interval, "at", job.NextAttemptAt)
+
+	return s.crawlJobRepo.Save(job)
+}
+
+func latest(versions []*domain.PageVersion) *domain.PageVersion {
+	latest := versions[0]
+	for _, v := range versions[1:] {
+		if v.CreatedAt.After(latest.CreatedAt) {
+			latest = v
+		}
+	}
+	return latest
+}
+
+// Interval estimates how long to wait before crawling a page again from its
+// versions. A page that changed is crawled about as often as it is estimated
+// to change, and one that hasn't changed waits twice as long as it has been
+// observed not changing.
+func (s *Service) Interval(versions []*domain.PageVersion) time.Duration {
+	sorted := make([]*domain.PageVersion, len(versions))
+	copy(sorted, versions)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+
+	if len(sorted) > historySize {
+		sorted = sorted[len(sorted)-historySize:]
+	}
+
+	if len(sorted) < 2 {
+		return s.clamp(s.initialInterval)
+	}
+
+	span := sorted[len(sorted)-1].CreatedAt.Sub(sorted[0].CreatedAt)
+	if span <= 0 {
+		return s.clamp(s.initialInterval)
+	}
+
+	crawls := len(sorted) - 1
+	changes := 0
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].ContentHash != sorted[i-1].ContentHash {
+			changes++
+		}
+	}
+
+	if changes == 0 {
+		return s.clamp(2 * span)
+	}
+
+	// A crawl only shows whether a page changed since the last one, not how
+	// many times, so the change rate is estimated assuming changes arrive at
+	// random (Cho and Garcia-Molina) rather than as changes seen over time,
+	// which would underestimate pages that change on most crawls.
+	n := float64(crawls)
+	rate := -math.Log((n-float64(changes)+0.5)/(n+0.5)) / (span.Seconds() / n)
+
+	seconds := 1 / rate
+	if seconds > s.maxInterval.Seconds() {
+		return s.maxInterval
+	}
+
+	return s.clamp(time.Duration(seconds * float64(time.Second)))
+}
+
+func (s *Service) clamp(interval time.Duration) time.Duration {
+	if interval < s.minInterval {
+		return s.minInterval
+	}
+	if interval > s.maxInterval {
+		return s.maxInterval
+	}
+	return interval
+}
@@ -0,0 +1,172 @@
+package service_test
+
+import (
+	"crawlquery/api/domain"
+	"fmt"
+	"testing"
+	"time"
+
+	crawlJobRepo "crawlquery/api/crawl/job/repository/mem"
+	recrawlService "crawlquery/api/crawl/recrawl/service"
+	eventService "crawlquery/api/event/service"
+	pageVersionRepo "crawlquery/api/page/version/repository/mem"
+	pageVersionService "crawlquery/api/page/version/service"
+	"crawlquery/pkg/testutil"
+)
+
+// history returns versions of a page crawled every interval, with the
+// content hash changing on the crawls listed in changes.
+func history(crawls int, interval time.Duration, changes ...int) []*domain.PageVersion {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	changed := map[int]bool{}
+	for _, c := range changes {
+		changed[c] = true
+	}
+
+	var versions []*domain.PageVersion
+	hash := 0
+	for i := 0; i < crawls; i++ {
+		if changed[i] {
+			hash++
+		}
+		versions = append(versions, &domain.PageVersion{
+			ID:          domain.PageVersionID(fmt.Sprintf("v%d", i)),
+			PageID:      "page1",
+			ContentHash: domain.ContentHash(fmt.Sprintf("hash%d", hash)),
+			CreatedAt:   start.Add(time.Duration(i) * interval),
+		})
+	}
+	return versions
+}
+
+func TestInterval(t *testing.T) {
+	day := 24 * time.Hour
+
+	service := recrawlService.NewService(
+		recrawlService.WithIntervalBounds(time.Minute, 30*day),
+		recrawlService.WithInitialInterval(day),
+	)
+
+	t.Run("uses the initial interval without history", func(t *testing.T) {
+		if got := service.Interval(history(1, day)); got != day {
+			t.Errorf("expected %s, got %s", day, got)
+		}
+	})
+
+	t.Run("waits longer for pages that don't change", func(t *testing.T) {
+		if got := service.Interval(history(3, 5*day)); got != 20*day {
+			t.Errorf("expected %s, got %s", 20*day, got)
+		}
+	})
+
+	t.Run("caps the interval", func(t *testing.T) {
+		if got := service.Interval(history(5, 10*day)); got != 30*day {
+			t.Errorf("expected %s, got %s", 30*day, got)
+		}
+	})
+
+	t.Run("estimates how often pages change", func(t *testing.T) {
+		// 2 changes over 10 daily crawls
+		got := service.Interval(history(11, day, 3, 7))
+
+		if got < 4*day || got > 5*day {
+			t.Errorf("expected between 4 and 5 days, got %s", got)
+		}
+	})
+
+	t.Run("crawls pages that change every crawl more often than they are crawled", func(t *testing.T) {
+		got := service.Interval(history(5, time.Hour, 1, 2, 3, 4))
+
+		if got >= time.Hour || got < time.Minute {
+			t.Errorf("expected less than an hour, got %s", got)
+		}
+	})
+
+	t.Run("keeps to the minimum interval", func(t *testing.T) {
+		got := service.Interval(history(5, time.Second, 1, 2, 3, 4))
+
+		if got != time.Minute {
+			t.Errorf("expected %s, got %s", time.Minute, got)
+		}
+	})
+
+	t.Run("only considers recent history", func(t *testing.T) {
+		// changed often long ago, static for the last 20 crawls
+		versions := history(40, time.Hour, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+
+		if got := service.Interval(versions); got != 2*19*time.Hour {
+			t.Errorf("expected %s, got %s", 2*19*time.Hour, got)
+		}
+	})
+}
+
+func TestSchedule(t *testing.T) {
+	t.Run("schedules a recrawl when a page version is created", func(t *testing.T) {
+		eventSvc := eventService.NewService()
+		jobRepo := crawlJobRepo.NewRepository()
+		versionRepo := pageVersionRepo.NewRepository()
+
+		recrawlService.NewService(
+			recrawlService.WithEventService(eventSvc),
+			recrawlService.WithCrawlJobRepo(jobRepo),
+			recrawlService.WithVersionRepo(versionRepo),
+			recrawlService.WithInitialInterval(6*time.Hour),
+			recrawlService.WithLogger(testutil.NewTestLogger()),
+			recrawlService.WithEventListeners(),
+		)
+
+		versionSvc := pageVersionService.NewService(
+			pageVersionService.WithEventService(eventSvc),
+			pageVersionService.WithVersionRepo(versionRepo),
+		)
+
+		jobRepo.Save(&domain.CrawlJob{
+			PageID: "page1",
+			URL:    "http://example.com",
+			Status: domain.CrawlStatusCompleted,
+		})
+
+		version, err := versionSvc.Create("page1", "hash")
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		job, err := jobRepo.Get("page1")
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if !job.NextAttemptAt.Equal(version.CreatedAt.Add(6 * time.Hour)) {
+			t.Errorf("expected a recrawl 6h after the crawl, got %v", job.NextAttemptAt.Sub(version.CreatedAt))
+		}
+	})
+
+	t.Run("leaves jobs that haven't completed", func(t *testing.T) {
+		jobRepo := crawlJobRepo.NewRepository()
+		versionRepo := pageVersionRepo.NewRepository()
+
+		service := recrawlService.NewService(
+			recrawlService.WithCrawlJobRepo(jobRepo),
+			recrawlService.WithVersionRepo(versionRepo),
+			recrawlService.WithLogger(testutil.NewTestLogger()),
+		)
+
+		jobRepo.Save(&domain.CrawlJob{
+			PageID: "page1",
+			URL:    "http://example.com",
+			Status: domain.CrawlStatusFailed,
+		})
+
+		if err := service.Schedule("page1"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		job, _ := jobRepo.Get("page1")
+
+		if !job.NextAttemptAt.IsZero() {
+			t.Errorf("expected no recrawl to be scheduled, got %v", job.NextAttemptAt)
+		}
+	})
+}
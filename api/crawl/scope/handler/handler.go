@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"crawlquery/api/domain"
+	"crawlquery/api/dto"
+	"crawlquery/api/errorutil"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	scopeService domain.ScopeService
+}
+
+func NewHandler(scopeService domain.ScopeService) *Handler {
+	return &Handler{
+		scopeService: scopeService,
+	}
+}
+
+func (h *Handler) Create(c *gin.Context) {
+	var req dto.CreateCrawlScopeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scope := &domain.Scope{
+		Name:       req.Name,
+		MaxDepth:   req.MaxDepth,
+		PageBudget: req.PageBudget,
+	}
+
+	for _, seed := range req.Seeds {
+		scope.Seeds = append(scope.Seeds, domain.URL(seed))
+	}
+
+	for _, d := range req.Domains {
+		scope.Domains = append(scope.Domains, domain.Host(d))
+	}
+
+	created, err := h.scopeService.Create(scope)
+	if err != nil {
+		errorutil.HandleGinError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	pages, err := h.scopeService.Pages(created.ID)
+	if err != nil {
+		errorutil.HandleGinError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.NewCrawlScope(created, pages))
+}
+
+func (h *Handler) Get(c *gin.Context) {
+	scope, err := h.scopeService.Get(domain.ScopeID(c.Param("scopeID")))
+	if err != nil {
+		if err == domain.ErrScopeNotFound {
+			errorutil.HandleGinError(c, err, http.StatusNotFound)
+			return
+		}
+		errorutil.HandleGinError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	pages, err := h.scopeService.Pages(scope.ID)
+	if err != nil {
+		errorutil.HandleGinError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewCrawlScope(scope, pages))
+}
+
+func (h *Handler) List(c *gin.Context) {
+	scopes, err := h.scopeService.List()
+	if err != nil {
+		errorutil.HandleGinError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	res := dto.ListCrawlScopesResponse{
+		Scopes: []dto.CrawlScope{},
+	}
+
+	for _, scope := range scopes {
+		pages, err := h.scopeService.Pages(scope.ID)
+		if err != nil {
+			errorutil.HandleGinError(c, err, http.StatusInternalServerError)
+			return
+		}
+		res.Scopes = append(res.Scopes, dto.NewCrawlScope(scope, pages))
+	}
+
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,121 @@
+package handler_test
+
+import (
+	"bytes"
+	"crawlquery/api/crawl/scope/handler"
+	scopeRepo "crawlquery/api/crawl/scope/repository/mem"
+	"crawlquery/api/crawl/scope/service"
+	"crawlquery/api/domain"
+	"crawlquery/api/dto"
+	"crawlquery/api/testfactory"
+	"crawlquery/pkg/testutil"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupHandler() *handler.Handler {
+	sf := testfactory.NewServiceFactory(
+		testfactory.WithShard(&domain.Shard{ID: 0}),
+	)
+
+	return handler.NewHandler(service.NewService(
+		service.WithScopeRepo(scopeRepo.NewRepository()),
+		service.WithPageRepo(sf.PageRepo),
+		service.WithPageService(sf.PageService),
+		service.WithLogger(testutil.NewTestLogger()),
+	))
+}
+
+func TestCreate(t *testing.T) {
+	t.Run("creates a scope", func(t *testing.T) {
+		handler := setupHandler()
+
+		responseWriter := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(responseWriter)
+		ctx.Request = httptest.NewRequest("POST", "/crawl/scopes", bytes.NewBufferString(`{"name": "Recipes", "seeds": ["http://example.com/recipes"], "max_depth": 2}`))
+
+		handler.Create(ctx)
+
+		if ctx.Writer.Status() != http.StatusCreated {
+			t.Fatalf("Expected status to be 201, got %d", ctx.Writer.Status())
+		}
+
+		var res dto.CrawlScope
+		if err := json.Unmarshal(responseWriter.Body.Bytes(), &res); err != nil {
+			t.Fatalf("Error decoding response: %v", err)
+		}
+
+		if res.ID == "" || res.Name != "Recipes" || res.MaxDepth != 2 || res.Pages != 1 {
+			t.Errorf("Unexpected scope: %+v", res)
+		}
+	})
+
+	t.Run("requires seeds", func(t *testing.T) {
+		handler := setupHandler()
+
+		responseWriter := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(responseWriter)
+		ctx.Request = httptest.NewRequest("POST", "/crawl/scopes", bytes.NewBufferString(`{"name": "Recipes"}`))
+
+		handler.Create(ctx)
+
+		if ctx.Writer.Status() != http.StatusBadRequest {
+			t.Errorf("Expected status to be 400, got %d", ctx.Writer.Status())
+		}
+	})
+}
+
+func TestGet(t *testing.T) {
+	t.Run("returns 404 for unknown scopes", func(t *testing.T) {
+		handler := setupHandler()
+
+		responseWriter := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(responseWriter)
+		ctx.Request = httptest.NewRequest("GET", "/crawl/scopes/missing", nil)
+		ctx.Params = gin.Params{{Key: "scopeID", Value: "missing"}}
+
+		handler.Get(ctx)
+
+		if ctx.Writer.Status() != http.StatusNotFound {
+			t.Errorf("Expected status to be 404, got %d", ctx.Writer.Status())
+		}
+	})
+}
+
+func TestList(t *testing.T) {
+	t.Run("lists scopes", func(t *testing.T) {
+		handler := setupHandler()
+
+		for _, body := range []string{
+			`{"name": "Recipes", "seeds": ["http://example.com/"]}`,
+			`{"name": "Docs", "seeds": ["http://docs.example.org/"]}`,
+		} {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest("POST", "/crawl/scopes", bytes.NewBufferString(body))
+			handler.Create(ctx)
+		}
+
+		responseWriter := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(responseWriter)
+		ctx.Request = httptest.NewRequest("GET", "/crawl/scopes", nil)
+
+		handler.List(ctx)
+
+		if ctx.Writer.Status() != http.StatusOK {
+			t.Fatalf("Expected status to be 200, got %d", ctx.Writer.Status())
+		}
+
+		var res dto.ListCrawlScopesResponse
+		if err := json.Unmarshal(responseWriter.Body.Bytes(), &res); err != nil {
+			t.Fatalf("Error decoding response: %v", err)
+		}
+
+		if len(res.Scopes) != 2 || res.Scopes[0].Name != "Recipes" || res.Scopes[1].Name != "Docs" {
+			t.Errorf("Unexpected scopes: %+v", res.Scopes)
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package mem
+
+import (
+	"crawlquery/api/domain"
+	"sort"
+	"sync"
+)
+
+type Repository struct {
+	scopes map[domain.ScopeID]*domain.Scope
+	lock   sync.RWMutex
+}
+
+func NewRepository() *Repository {
+	return &Repository{
+		scopes: make(map[domain.ScopeID]*domain.Scope),
+	}
+}
+
+func (r *Repository) Get(id domain.ScopeID) (*domain.Scope, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	scope, ok := r.scopes[id]
+	if !ok {
+		return nil, domain.ErrScopeNotFound
+	}
+
+	copied := *scope
+	return &copied, nil
+}
+
+// List lists the scopes oldest first.
+func (r *Repository) List() ([]*domain.Scope, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	scopes := []*domain.Scope{}
+	for _, scope := range r.scopes {
+		copied := *scope
+		scopes = append(scopes, &copied)
+	}
+
+	sort.Slice(scopes, func(i, j int) bool {
+		if !scopes[i].CreatedAt.Equal(scopes[j].CreatedAt) {
+			return scopes[i].CreatedAt.Before(scopes[j].CreatedAt)
+		}
+		return scopes[i].ID < scopes[j].ID
+	})
+
+	return scopes, nil
+}
+
+func (r *Repository) Save(scope *domain.Scope) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	copied := *scope
+	r.scopes[scope.ID] = &copied
+	return nil
+}
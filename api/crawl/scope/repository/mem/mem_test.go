@@ -0,0 +1,62 @@
+package mem
+
+import (
+	"crawlquery/api/domain"
+	"testing"
+	"time"
+)
+
+func TestSave(t *testing.T) {
+	t.Run("saves and gets a scope", func(t *testing.T) {
+		repo := NewRepository()
+
+		scope := &domain.Scope{
+			ID:      "recipes",
+			Name:    "Recipes",
+			Seeds:   []domain.URL{"https://recipes.example.com/"},
+			Domains: []domain.Host{"recipes.example.com"},
+		}
+
+		if err := repo.Save(scope); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		saved, err := repo.Get("recipes")
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if saved.Name != "Recipes" || len(saved.Seeds) != 1 {
+			t.Errorf("expected the saved scope, got %+v", saved)
+		}
+	})
+
+	t.Run("returns an error for unknown scopes", func(t *testing.T) {
+		repo := NewRepository()
+
+		if _, err := repo.Get("missing"); err != domain.ErrScopeNotFound {
+			t.Errorf("expected ErrScopeNotFound, got %v", err)
+		}
+	})
+}
+
+func TestList(t *testing.T) {
+	t.Run("lists scopes oldest first", func(t *testing.T) {
+		repo := NewRepository()
+		now := time.Now()
+
+		repo.Save(&domain.Scope{ID: "b", CreatedAt: now})
+		repo.Save(&domain.Scope{ID: "a", CreatedAt: now.Add(time.Minute)})
+
+		scopes, err := repo.List()
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if len(scopes) != 2 || scopes[0].ID != "b" || scopes[1].ID != "a" {
+			t.Errorf("expected scopes b then a, got %v", scopes)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package mysql
+
+import (
+	"crawlquery/api/domain"
+	"database/sql"
+	"encoding/json"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+const selectColumns = "SELECT id, name, seeds, domains, max_depth, page_budget, created_at FROM crawl_scopes"
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanScope(row scanner) (*domain.Scope, error) {
+	var scope domain.Scope
+	var seeds, domains []byte
+
+	err := row.Scan(&scope.ID, &scope.Name, &seeds, &domains, &scope.MaxDepth, &scope.PageBudget, &scope.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(seeds, &scope.Seeds); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(domains, &scope.Domains); err != nil {
+		return nil, err
+	}
+
+	return &scope, nil
+}
+
+func (r *Repository) Get(id domain.ScopeID) (*domain.Scope, error) {
+	scope, err := scanScope(r.db.QueryRow(selectColumns+" WHERE id = ?", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrScopeNotFound
+		}
+		return nil, err
+	}
+	return scope, nil
+}
+
+// List lists the scopes oldest first.
+func (r *Repository) List() ([]*domain.Scope, error) {
+	rows, err := r.db.Query(selectColumns + " ORDER BY created_at, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scopes := []*domain.Scope{}
+	for rows.Next() {
+		scope, err := scanScope(rows)
+		if err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, scope)
+	}
+	return scopes, rows.Err()
+}
+
+func (r *Repository) Save(scope *domain.Scope) error {
+	seeds, err := json.Marshal(scope.Seeds)
+	if err != nil {
+		return err
+	}
+
+	domains, err := json.Marshal(scope.Domains)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec("INSERT INTO crawl_scopes (id, name, seeds, domains, max_depth, page_budget, created_at) VALUES (?, ?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE name = ?, seeds = ?, domains = ?, max_depth = ?, page_budget = ?", scope.ID, scope.Name, seeds, domains, scope.MaxDepth, scope.PageBudget, scope.CreatedAt, scope.Name, seeds, domains, scope.MaxDepth, scope.PageBudget)
+	if err != nil {
+		return err
+	}
+	return nil
+}
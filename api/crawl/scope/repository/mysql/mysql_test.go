@@ -0,0 +1,77 @@
+package mysql_test
+
+import (
+	"crawlquery/api/domain"
+	"crawlquery/api/migration"
+	"crawlquery/pkg/testutil"
+	"testing"
+	"time"
+
+	scopeRepo "crawlquery/api/crawl/scope/repository/mysql"
+)
+
+func TestSave(t *testing.T) {
+	t.Run("saves and gets a scope", func(t *testing.T) {
+		db := testutil.CreateTestMysqlDB()
+		defer db.Close()
+		migration.Up(db)
+
+		repo := scopeRepo.NewRepository(db)
+
+		defer db.Exec("DELETE FROM crawl_scopes WHERE id = ?", "savescoperecipes")
+
+		err := repo.Save(&domain.Scope{
+			ID:         "savescoperecipes",
+			Name:       "Recipes",
+			Seeds:      []domain.URL{"https://recipes.example.com/"},
+			Domains:    []domain.Host{"recipes.example.com"},
+			MaxDepth:   3,
+			PageBudget: 1000,
+			CreatedAt:  time.Now(),
+		})
+
+		if err != nil {
+			t.Fatalf("Error saving scope: %v", err)
+		}
+
+		scope, err := repo.Get("savescoperecipes")
+
+		if err != nil {
+			t.Fatalf("Error getting scope: %v", err)
+		}
+
+		if scope.Name != "Recipes" || scope.MaxDepth != 3 || scope.PageBudget != 1000 {
+			t.Errorf("Expected the saved scope, got %+v", scope)
+		}
+
+		if len(scope.Seeds) != 1 || scope.Seeds[0] != "https://recipes.example.com/" {
+			t.Errorf("Expected the saved seeds, got %v", scope.Seeds)
+		}
+
+		if len(scope.Domains) != 1 || scope.Domains[0] != "recipes.example.com" {
+			t.Errorf("Expected the saved domains, got %v", scope.Domains)
+		}
+
+		scopes, err := repo.List()
+
+		if err != nil {
+			t.Fatalf("Error listing scopes: %v", err)
+		}
+
+		if len(scopes) == 0 {
+			t.Errorf("Expected the scope to be listed")
+		}
+	})
+
+	t.Run("returns an error for unknown scopes", func(t *testing.T) {
+		db := testutil.CreateTestMysqlDB()
+		defer db.Close()
+		migration.Up(db)
+
+		repo := scopeRepo.NewRepository(db)
+
+		if _, err := repo.Get("missingscope"); err != domain.ErrScopeNotFound {
+			t.Errorf("Expected ErrScopeNotFound, got %v", err)
+		}
+	})
+}
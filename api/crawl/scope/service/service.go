@@ -0,0 +1,172 @@
+package service
+
+import (
+	"crawlquery/api/domain"
+	"crawlquery/pkg/canonical"
+	"crawlquery/pkg/util"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type Service struct {
+	scopeRepo    domain.ScopeRepository
+	pageRepo     domain.PageRepository
+	crawlJobRepo domain.CrawlJobRepository
+	pageService  domain.PageService
+	logger       *zap.SugaredLogger
+}
+
+type Option func(*Service)
+
+func WithScopeRepo(scopeRepo domain.ScopeRepository) Option {
+	return func(s *Service) {
+		s.scopeRepo = scopeRepo
+	}
+}
+
+func WithPageRepo(pageRepo domain.PageRepository) Option {
+	return func(s *Service) {
+		s.pageRepo = pageRepo
+	}
+}
+
+func WithCrawlJobRepo(crawlJobRepo domain.CrawlJobRepository) Option {
+	return func(s *Service) {
+		s.crawlJobRepo = crawlJobRepo
+	}
+}
+
+func WithPageService(pageService domain.PageService) Option {
+	return func(s *Service) {
+		s.pageService = pageService
+	}
+}
+
+func WithLogger(logger *zap.SugaredLogger) Option {
+	return func(s *Service) {
+		s.logger = logger
+	}
+}
+
+func NewService(opts ...Option) *Service {
+	s := &Service{}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Create saves a new scope and adds its seeds to the crawl. A scope without
+// domains is limited to the hosts of its seeds. Seeds that are already known
+// pages are moved into the scope at depth 0, along with their crawl jobs.
+func (s *Service) Create(scope *domain.Scope) (*domain.Scope, error) {
+	if strings.TrimSpace(scope.Name) == "" || len(scope.Seeds) == 0 || scope.MaxDepth < 0 || scope.PageBudget < 0 {
+		return nil, domain.ErrScopeInvalid
+	}
+
+	created := &domain.Scope{
+		ID:         domain.ScopeID(util.UUIDString()),
+		Name:       strings.TrimSpace(scope.Name),
+		MaxDepth:   scope.MaxDepth,
+		PageBudget: scope.PageBudget,
+		CreatedAt:  time.Now(),
+	}
+
+	for _, seed := range scope.Seeds {
+		url, err := canonical.URL(seed)
+		if err != nil {
+			return nil, err
+		}
+		created.Seeds = append(created.Seeds, url)
+	}
+
+	for _, d := range scope.Domains {
+		created.Domains = addDomain(created.Domains, normalizeDomain(string(d)))
+	}
+
+	if len(created.Domains) == 0 {
+		for _, seed := range created.Seeds {
+			created.Domains = addDomain(created.Domains, util.Host(seed))
+		}
+	}
+
+	if err := s.scopeRepo.Save(created); err != nil {
+		s.logger.Errorw("Error saving scope", "error", err, "name", created.Name)
+		return nil, err
+	}
+
+	// seed pages are fetched from the URLs as given
+	for i, seed := range created.Seeds {
+		_, err := s.pageService.CreateInScope(scope.Seeds[i], created.ID, 0)
+		if err == domain.ErrPageAlreadyExists {
+			err = s.adopt(util.PageID(seed), created.ID)
+		}
+		if err != nil {
+			s.logger.Errorw("Error creating seed page", "error", err, "url", seed, "scopeID", created.ID)
+		}
+	}
+
+	return created, nil
+}
+
+// adopt moves a known page and its crawl job into a scope as a seed. A seed
+// that was already crawled is crawled again now, so that its links are
+// found from within the scope.
+func (s *Service) adopt(pageID domain.PageID, scopeID domain.ScopeID) error {
+	if err := s.pageRepo.SetScope(pageID, scopeID, 0); err != nil {
+		return err
+	}
+
+	job, err := s.crawlJobRepo.Get(pageID)
+	if err == domain.ErrCrawlJobNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	job.ScopeID = scopeID
+	job.UpdatedAt = time.Now()
+
+	if job.Status == domain.CrawlStatusCompleted {
+		job.NextAttemptAt = job.UpdatedAt
+	}
+
+	return s.crawlJobRepo.Save(job)
+}
+
+// normalizeDomain lower cases a domain and drops any "www." so that it
+// matches util.Host.
+func normalizeDomain(d string) domain.Host {
+	d = strings.ToLower(strings.TrimSpace(d))
+	d = strings.TrimSuffix(d, ".")
+	return domain.Host(strings.TrimPrefix(d, "www."))
+}
+
+func addDomain(domains []domain.Host, d domain.Host) []domain.Host {
+	if d == "" {
+		return domains
+	}
+	for _, existing := range domains {
+		if existing == d {
+			return domains
+		}
+	}
+	return append(domains, d)
+}
+
+func (s *Service) Get(id domain.ScopeID) (*domain.Scope, error) {
+	return s.scopeRepo.Get(id)
+}
+
+func (s *Service) List() ([]*domain.Scope, error) {
+	return s.scopeRepo.List()
+}
+
+func (s *Service) Pages(id domain.ScopeID) (int, error) {
+	return s.pageRepo.CountByScope(id)
+}
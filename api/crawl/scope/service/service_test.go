@@ -0,0 +1,157 @@
+package service_test
+
+import (
+	scopeRepo "crawlquery/api/crawl/scope/repository/mem"
+	"crawlquery/api/crawl/scope/service"
+	"crawlquery/api/domain"
+	"crawlquery/api/testfactory"
+	"crawlquery/pkg/testutil"
+	"crawlquery/pkg/util"
+	"testing"
+	"time"
+)
+
+func setupService() (*service.Service, *testfactory.ServiceFactory) {
+	sf := testfactory.NewServiceFactory(
+		testfactory.WithShard(&domain.Shard{ID: 0}),
+	)
+
+	s := service.NewService(
+		service.WithScopeRepo(scopeRepo.NewRepository()),
+		service.WithPageRepo(sf.PageRepo),
+		service.WithCrawlJobRepo(sf.CrawlJobRepo),
+		service.WithPageService(sf.PageService),
+		service.WithLogger(testutil.NewTestLogger()),
+	)
+
+	return s, sf
+}
+
+func TestCreate(t *testing.T) {
+	t.Run("creates a scope and its seed pages", func(t *testing.T) {
+		s, sf := setupService()
+
+		scope, err := s.Create(&domain.Scope{
+			Name:     " Recipes ",
+			Seeds:    []domain.URL{"HTTP://WWW.Example.com/recipes/", "http://cooking.example.net"},
+			MaxDepth: 3,
+		})
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if scope.ID == "" || scope.Name != "Recipes" || scope.MaxDepth != 3 {
+			t.Errorf("unexpected scope: %+v", scope)
+		}
+
+		if len(scope.Seeds) != 2 || scope.Seeds[0] != "http://www.example.com/recipes" || scope.Seeds[1] != "http://cooking.example.net/" {
+			t.Errorf("expected canonical seeds, got %v", scope.Seeds)
+		}
+
+		if len(scope.Domains) != 2 || scope.Domains[0] != "example.com" || scope.Domains[1] != "cooking.example.net" {
+			t.Errorf("expected the seed hosts as domains, got %v", scope.Domains)
+		}
+
+		for _, seed := range scope.Seeds {
+			page, err := sf.PageRepo.Get(util.PageID(seed))
+			if err != nil {
+				t.Fatalf("expected a page for seed %s, got %v", seed, err)
+			}
+
+			if page.ScopeID != scope.ID || page.Depth != 0 {
+				t.Errorf("expected seed %s in the scope at depth 0, got %q at %d", seed, page.ScopeID, page.Depth)
+			}
+		}
+
+		pages, err := s.Pages(scope.ID)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		if pages != 2 {
+			t.Errorf("expected 2 pages, got %d", pages)
+		}
+
+		got, err := s.Get(scope.ID)
+		if err != nil || got.Name != "Recipes" {
+			t.Errorf("expected to get the scope, got %v and %v", got, err)
+		}
+	})
+
+	t.Run("normalizes the given domains", func(t *testing.T) {
+		s, _ := setupService()
+
+		scope, err := s.Create(&domain.Scope{
+			Name:    "Docs",
+			Seeds:   []domain.URL{"http://docs.example.com/"},
+			Domains: []domain.Host{"WWW.Example.com.", "example.com", " example.org "},
+		})
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(scope.Domains) != 2 || scope.Domains[0] != "example.com" || scope.Domains[1] != "example.org" {
+			t.Errorf("expected normalized domains, got %v", scope.Domains)
+		}
+	})
+
+	t.Run("adopts seeds that are already pages", func(t *testing.T) {
+		s, sf := setupService()
+
+		pageID := util.PageID("http://example.com/")
+		sf.PageRepo.Create(&domain.Page{ID: pageID, URL: "http://example.com/", Depth: 4})
+		sf.CrawlJobRepo.Save(&domain.CrawlJob{PageID: pageID, URL: "http://example.com/", Status: domain.CrawlStatusCompleted})
+
+		scope, err := s.Create(&domain.Scope{Name: "Example", Seeds: []domain.URL{"http://example.com/"}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		page, _ := sf.PageRepo.Get(pageID)
+		if page.ScopeID != scope.ID || page.Depth != 0 {
+			t.Errorf("expected the page in the scope at depth 0, got %q at %d", page.ScopeID, page.Depth)
+		}
+
+		job, err := sf.CrawlJobRepo.Get(pageID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if job.ScopeID != scope.ID {
+			t.Errorf("expected the crawl job in the scope, got %q", job.ScopeID)
+		}
+
+		if job.NextAttemptAt.IsZero() || job.NextAttemptAt.After(time.Now()) {
+			t.Errorf("expected the crawl job to be due, got %v", job.NextAttemptAt)
+		}
+
+		if pages, _ := s.Pages(scope.ID); pages != 1 {
+			t.Errorf("expected 1 page, got %d", pages)
+		}
+	})
+
+	t.Run("rejects invalid scopes", func(t *testing.T) {
+		s, _ := setupService()
+
+		for _, scope := range []*domain.Scope{
+			{Seeds: []domain.URL{"http://example.com/"}},
+			{Name: "No seeds"},
+			{Name: "Negative", Seeds: []domain.URL{"http://example.com/"}, MaxDepth: -1},
+			{Name: "Negative", Seeds: []domain.URL{"http://example.com/"}, PageBudget: -1},
+		} {
+			if _, err := s.Create(scope); err != domain.ErrScopeInvalid {
+				t.Errorf("expected ErrScopeInvalid for %+v, got %v", scope, err)
+			}
+		}
+	})
+
+	t.Run("rejects seeds that cannot be crawled", func(t *testing.T) {
+		s, _ := setupService()
+
+		if _, err := s.Create(&domain.Scope{Name: "FTP", Seeds: []domain.URL{"ftp://example.com/"}}); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
@@ -3,7 +3,11 @@ package service
 import (
 	"context"
 	"crawlquery/api/domain"
+	"crawlquery/pkg/canonical"
 	"crawlquery/pkg/util"
+	"errors"
+	"math/rand"
+	"net/http"
 	"sync"
 	"time"
 
@@ -15,13 +19,20 @@ type Service struct {
 	crawlJobRepo         domain.CrawlJobRepository
 	crawlLogRepo         domain.CrawlLogRepository
 	crawlThrottleService domain.CrawlThrottleService
+	crawlPriorityService domain.CrawlPriorityService
 
 	nodeService domain.NodeService
 
 	logger *zap.SugaredLogger
 
-	workers      int
-	maxQueueSize int
+	workers        int
+	maxQueueSize   int
+	maxJobsPerHost int
+
+	maxAttempts    int
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
+	staleTimeout   time.Duration
 }
 
 type Option func(*Service)
@@ -38,6 +49,12 @@ func WithCrawlThrottleService(crawlThrottleService domain.CrawlThrottleService)
 	}
 }
 
+func WithCrawlPriorityService(crawlPriorityService domain.CrawlPriorityService) func(*Service) {
+	return func(s *Service) {
+		s.crawlPriorityService = crawlPriorityService
+	}
+}
+
 func WithCrawlJobRepo(crawlJobRepo domain.CrawlJobRepository) func(*Service) {
 	return func(s *Service) {
 		s.crawlJobRepo = crawlJobRepo
@@ -74,6 +91,40 @@ func WithMaxQueueSize(maxQueueSize int) func(*Service) {
 	}
 }
 
+// WithMaxJobsPerHost sets how many of a host's jobs are queued at once, so
+// that one large host can't fill the queue. It defaults to no cap beyond the
+// queue size.
+func WithMaxJobsPerHost(maxJobsPerHost int) func(*Service) {
+	return func(s *Service) {
+		s.maxJobsPerHost = maxJobsPerHost
+	}
+}
+
+// WithMaxAttempts sets how many times a job is crawled before it is marked
+// dead.
+func WithMaxAttempts(maxAttempts int) func(*Service) {
+	return func(s *Service) {
+		s.maxAttempts = maxAttempts
+	}
+}
+
+// WithRetryBackoff sets the delay before the first retry of a failed job,
+// which doubles with each attempt up to maxDelay.
+func WithRetryBackoff(baseDelay, maxDelay time.Duration) func(*Service) {
+	return func(s *Service) {
+		s.retryBaseDelay = baseDelay
+		s.retryMaxDelay = maxDelay
+	}
+}
+
+// WithStaleTimeout sets how long a job may stay in progress before it is
+// treated as failed.
+func WithStaleTimeout(staleTimeout time.Duration) func(*Service) {
+	return func(s *Service) {
+		s.staleTimeout = staleTimeout
+	}
+}
+
 func WithEventListeners() func(*Service) {
 	return func(s *Service) {
 		s.registerEventListeners()
@@ -81,7 +132,12 @@ func WithEventListeners() func(*Service) {
 }
 
 func NewService(opts ...Option) *Service {
-	s := &Service{}
+	s := &Service{
+		maxAttempts:    5,
+		retryBaseDelay: time.Minute,
+		retryMaxDelay:  6 * time.Hour,
+		staleTimeout:   10 * time.Minute,
+	}
 
 	for _, opt := range opts {
 		opt(s)
@@ -117,12 +173,18 @@ func (s *Service) CreateJob(page *domain.Page) error {
 	cj := &domain.CrawlJob{
 		PageID:    page.ID,
 		URL:       page.URL,
+		Host:      util.Host(page.URL),
 		ShardID:   page.ShardID,
+		ScopeID:   page.ScopeID,
 		Status:    domain.CrawlStatusPending,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
+	if s.crawlPriorityService != nil {
+		cj.Priority = s.crawlPriorityService.Priority(page)
+	}
+
 	err := s.crawlJobRepo.Save(cj)
 	if err != nil {
 		return err
@@ -145,7 +207,12 @@ func (s *Service) CreateJob(page *domain.Page) error {
 }
 
 func (s *Service) jobsToProcess() ([]*domain.CrawlJob, error) {
-	jobs, err := s.crawlJobRepo.ListByStatus(s.maxQueueSize, domain.CrawlStatusPending)
+	perHost := s.maxJobsPerHost
+	if perHost <= 0 {
+		perHost = s.maxQueueSize
+	}
+
+	jobs, err := s.crawlJobRepo.ListDue(s.maxQueueSize, perHost, time.Now())
 	if err != nil {
 		return nil, err
 	}
@@ -159,7 +226,12 @@ func (s *Service) RunCrawlProcess(ctx context.Context) error {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
-			err := s.processJobsWithWorkers(ctx)
+			err := s.ReapStaleJobs()
+			if err != nil {
+				return err
+			}
+
+			err = s.processJobsWithWorkers(ctx)
 			if err != nil {
 				return err
 			}
@@ -187,30 +259,39 @@ func (s *Service) processJobsWithWorkers(ctx context.Context) error {
 	}
 
 	for _, job := range jobsToProcess {
-
-		canCrawl, err := s.crawlThrottleService.CheckAndThrottle(job.URL)
+		err := s.crawlThrottleService.Enqueue(job)
 
 		if err != nil {
+			// retrying won't make the url valid
 			s.logger.Errorw("Throttle returned an error", "error", err)
-			err = s.updateJob(job, domain.CrawlStatusFailed, err)
+			err = s.updateJob(job, domain.CrawlStatusDead, err)
 			if err != nil {
 				s.logger.Errorw("Error updating job", "error", err)
 				return err
 			}
-			continue
 		}
+	}
 
-		if !canCrawl {
-			err = s.updateJob(job, domain.CrawlStatusPending, err)
-			if err != nil {
-				s.logger.Errorw("Error updating job", "error", err)
-				return err
-			}
-			continue
+	for {
+		job, err := s.crawlThrottleService.Next()
+
+		if err == domain.ErrCrawlQueueEmpty {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		// a recrawl starts counting attempts afresh
+		if job.Status == domain.CrawlStatusCompleted {
+			job.Attempts = 0
 		}
 
-		err = s.updateJob(job, domain.CrawlStatusInProgress, err)
+		job.Attempts++
+		err = s.updateJob(job, domain.CrawlStatusInProgress, nil)
 		if err != nil {
+			s.crawlThrottleService.Done(job)
 			return err
 		}
 		jobs <- job
@@ -244,11 +325,19 @@ func (s *Service) processJob(ctx context.Context, jobs <-chan *domain.CrawlJob,
 				break
 			}
 
+			// the site answered, another node would only ask it again
+			var fetchErr *domain.CrawlFetchError
+			if errors.As(err, &fetchErr) || errors.Is(err, domain.ErrCrawlDisallowed) {
+				break
+			}
+
 			if attempts >= maxAttempts {
 				s.logger.Errorw("Failed to process job after max attempts", "job", job, "error", err)
 				break
 			}
 		}
+
+		s.crawlThrottleService.Done(job)
 	}
 }
 
@@ -267,6 +356,59 @@ func (s *Service) updateJob(job *domain.CrawlJob, status domain.CrawlStatus, wit
 	return s.updateLog(job, status, withErr.Error())
 }
 
+// failJob records a failed attempt at a job, scheduling a retry or marking
+// the job dead once it has used all its attempts. Jobs disallowed by the
+// site's robots.txt are marked dead straight away, retrying won't help.
+func (s *Service) failJob(job *domain.CrawlJob, withErr error) error {
+	if job.Attempts >= s.maxAttempts || errors.Is(withErr, domain.ErrCrawlDisallowed) {
+		job.NextAttemptAt = time.Time{}
+		return s.updateJob(job, domain.CrawlStatusDead, withErr)
+	}
+
+	job.NextAttemptAt = time.Now().Add(s.retryDelay(job.Attempts))
+	return s.updateJob(job, domain.CrawlStatusFailed, withErr)
+}
+
+// retryDelay doubles the base delay for each attempt made, up to the max
+// delay, and picks a random delay between half and all of that so that jobs
+// which failed together don't all retry together.
+func (s *Service) retryDelay(attempts int) time.Duration {
+	delay := s.retryBaseDelay
+	for i := 1; i < attempts && delay < s.retryMaxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > s.retryMaxDelay {
+		delay = s.retryMaxDelay
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// ReapStaleJobs fails jobs left in progress for longer than the stale
+// timeout, such as those being crawled when the API stopped, so that they
+// are retried.
+func (s *Service) ReapStaleJobs() error {
+	if s.staleTimeout <= 0 {
+		return nil
+	}
+
+	jobs, err := s.crawlJobRepo.ListStale(s.maxQueueSize, time.Now().Add(-s.staleTimeout))
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		s.logger.Infow("Requeueing stale crawl job", "pageID", job.PageID, "attempts", job.Attempts)
+		if err := s.failJob(job, domain.ErrCrawlJobStale); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (s *Service) updateLog(job *domain.CrawlJob, status domain.CrawlStatus, info string) error {
 	cl := &domain.CrawlLog{
 		ID:        domain.CrawlLogID(util.UUIDString()),
@@ -285,21 +427,44 @@ func (s *Service) ProcessQueueItem(ctx context.Context, job *domain.CrawlJob, as
 		return err
 	}
 
+	start := time.Now()
+
 	res, err := s.nodeService.SendCrawlJob(
 		ctx,
 		assignedNode,
 		job,
 	)
 
+	duration := time.Since(start)
+
 	if err != nil {
-		s.logger.Errorw("Error sending crawl job", "error", err)
-		if err := s.updateJob(job, domain.CrawlStatusFailed, err); err != nil {
+		s.logger.Errorw("Error sending crawl job", "error", err, "attempts", job.Attempts)
+
+		var fetchErr *domain.CrawlFetchError
+		if errors.As(err, &fetchErr) && s.crawlThrottleService != nil {
+			s.crawlThrottleService.Observe(job.URL, domain.CrawlOutcome{
+				StatusCode: fetchErr.StatusCode,
+				Duration:   duration,
+				RetryAfter: fetchErr.RetryAfter,
+			})
+		}
+
+		if err := s.failJob(job, err); err != nil {
 			return err
 		}
 
 		return err
 	}
 
+	if s.crawlThrottleService != nil {
+		s.crawlThrottleService.Observe(job.URL, domain.CrawlOutcome{
+			StatusCode: http.StatusOK,
+			Duration:   duration,
+			CrawlDelay: time.Duration(res.CrawlDelay * float64(time.Second)),
+		})
+	}
+
+	job.NextAttemptAt = time.Time{}
 	err = s.updateJob(job, domain.CrawlStatusCompleted, nil)
 
 	if err != nil {
@@ -312,13 +477,71 @@ func (s *Service) ProcessQueueItem(ctx context.Context, job *domain.CrawlJob, as
 		links = append(links, domain.URL(link))
 	}
 
+	var anchors []domain.Anchor
+	for _, anchor := range res.Anchors {
+		anchors = append(anchors, domain.Anchor{
+			URL:  domain.URL(anchor.URL),
+			Text: anchor.Text,
+			Rel:  anchor.Rel,
+		})
+	}
+
+	var redirects []domain.URL
+	for _, redirect := range res.Redirects {
+		if redirectURL := canonicalURL(redirect); redirectURL != "" {
+			redirects = append(redirects, redirectURL)
+		}
+	}
+
+	var feeds []domain.URL
+	for _, feed := range res.Feeds {
+		if feedURL := canonicalURL(feed); feedURL != "" {
+			feeds = append(feeds, feedURL)
+		}
+	}
+
+	finalURL := canonicalURL(res.FinalURL)
+
+	// a page can only name a page on its own site as its canonical version,
+	// so one site cannot fold its pages into another's
+	named := canonicalURL(res.Canonical)
+	fetchedFrom := job.URL
+	if finalURL != "" {
+		fetchedFrom = finalURL
+	}
+	if named != "" && util.Site(named) != util.Site(fetchedFrom) {
+		s.logger.Debugw("Ignoring canonical URL on another site", "url", fetchedFrom, "canonical", named)
+		named = ""
+	}
+
 	s.eventService.Publish(&domain.CrawlCompleted{
 		PageID:      job.PageID,
 		ShardID:     job.ShardID,
 		URL:         job.URL,
 		ContentHash: domain.ContentHash(res.ContentHash),
 		Links:       links,
+		Anchors:     anchors,
+		FinalURL:    finalURL,
+		Redirects:   redirects,
+		Canonical:   named,
+		Feeds:       feeds,
+		NoIndex:     res.NoIndex,
 	})
 
 	return nil
 }
+
+// canonicalURL returns the canonical form of a URL reported by a node, or
+// nothing if it is not one that can be crawled.
+func canonicalURL(rawURL string) domain.URL {
+	if rawURL == "" {
+		return ""
+	}
+
+	url, err := canonical.URL(domain.URL(rawURL))
+	if err != nil {
+		return ""
+	}
+
+	return url
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"crawlquery/api/testfactory"
 	"crawlquery/node/dto"
+	"errors"
 	"fmt"
+	"reflect"
 	"strings"
 	"time"
 
@@ -17,6 +19,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/h2non/gock"
 
+	crawlPriorityService "crawlquery/api/crawl/priority/service"
 	crawlService "crawlquery/api/crawl/service"
 	crawlThrottleService "crawlquery/api/crawl/throttle/service"
 )
@@ -108,6 +111,50 @@ func TestCreateJob(t *testing.T) {
 			t.Errorf("expected log status to be pending, got %v", logs[0].Status)
 		}
 	})
+
+	t.Run("should set the job's host and priority", func(t *testing.T) {
+		sf := testfactory.NewServiceFactory()
+
+		crawlService := crawlService.NewService(
+			crawlService.WithCrawlJobRepo(sf.CrawlJobRepo),
+			crawlService.WithCrawlLogRepo(sf.CrawlLogRepo),
+			crawlService.WithCrawlPriorityService(crawlPriorityService.NewService(
+				crawlPriorityService.WithSeedDomains([]domain.URL{"http://example.com"}),
+			)),
+		)
+
+		seed := &domain.Page{
+			ID:      util.PageID("http://example.com/a"),
+			URL:     "http://example.com/a",
+			ScopeID: "recipes",
+		}
+
+		other := &domain.Page{
+			ID:  util.PageID("http://example.net/a/b"),
+			URL: "http://example.net/a/b",
+		}
+
+		for _, page := range []*domain.Page{seed, other} {
+			if err := crawlService.CreateJob(page); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+
+		seedJob, _ := sf.CrawlJobRepo.Get(seed.ID)
+		otherJob, _ := sf.CrawlJobRepo.Get(other.ID)
+
+		if seedJob.Host != "example.com" {
+			t.Errorf("expected host to be example.com, got %v", seedJob.Host)
+		}
+
+		if seedJob.ScopeID != "recipes" {
+			t.Errorf("expected scope to be recipes, got %v", seedJob.ScopeID)
+		}
+
+		if seedJob.Priority <= otherJob.Priority {
+			t.Errorf("expected the seed page to have a higher priority, got %v and %v", seedJob.Priority, otherJob.Priority)
+		}
+	})
 }
 
 func TestProcessQueueItem(t *testing.T) {
@@ -314,6 +361,83 @@ func TestProcessQueueItem(t *testing.T) {
 		}
 	})
 
+	t.Run("backs off the host when the site rate limits", func(t *testing.T) {
+		sf, job, node := setupCrawlTests()
+
+		defer gock.Off()
+
+		gock.New("http://node1.cluster.com:8080").
+			Post("/crawl").
+			Reply(400).
+			JSON(dto.CrawlErrorResponse{
+				Error:      "failed to fetch html: status 429",
+				StatusCode: 429,
+				RetryAfter: 120,
+			})
+
+		if err := sf.CrawlThrottleService.Enqueue(job); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if _, err := sf.CrawlThrottleService.Next(); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		err := sf.CrawlService.ProcessQueueItem(context.Background(), job, node)
+
+		var fetchErr *domain.CrawlFetchError
+		if !errors.As(err, &fetchErr) || fetchErr.StatusCode != 429 {
+			t.Fatalf("expected a 429 fetch error, got %v", err)
+		}
+
+		state := sf.CrawlThrottleService.State()
+
+		if len(state) != 1 {
+			t.Fatalf("expected 1 host, got %d", len(state))
+		}
+
+		if state[0].Backoff != 2*time.Minute {
+			t.Errorf("expected a backoff of 2m, got %s", state[0].Backoff)
+		}
+
+		if !gock.IsDone() {
+			t.Errorf("expected all mocks to be called")
+		}
+	})
+
+	t.Run("marks the job dead when robots.txt disallows it", func(t *testing.T) {
+		sf, job, node := setupCrawlTests()
+
+		defer gock.Off()
+
+		gock.New("http://node1.cluster.com:8080").
+			Post("/crawl").
+			Reply(400).
+			JSON(dto.CrawlErrorResponse{
+				Error:      "disallowed by robots.txt",
+				Disallowed: true,
+			})
+
+		err := sf.CrawlService.ProcessQueueItem(context.Background(), job, node)
+
+		if !errors.Is(err, domain.ErrCrawlDisallowed) {
+			t.Fatalf("expected a disallowed error, got %v", err)
+		}
+
+		saved, err := sf.CrawlJobRepo.Get(job.PageID)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if saved.Status != domain.CrawlStatusDead {
+			t.Errorf("expected job to be dead, got %v", saved.Status)
+		}
+
+		if !gock.IsDone() {
+			t.Errorf("expected all mocks to be called")
+		}
+	})
+
 	t.Run("should timeout after deadline", func(t *testing.T) {
 		sf, job, node := setupCrawlTests()
 
@@ -365,6 +489,11 @@ func TestProcessQueueItem(t *testing.T) {
 					"http://example.com/1",
 					"http://example.com/2",
 				},
+				Anchors: []dto.CrawlLink{
+					{URL: "http://example.com/1", Text: "One"},
+					{URL: "http://example.com/2", Text: "Two", Rel: "nofollow"},
+				},
+				Feeds: []string{"HTTP://example.com/feed#rss", "mailto:feed@example.com"},
 			})
 
 		var eventPublished bool
@@ -388,6 +517,19 @@ func TestProcessQueueItem(t *testing.T) {
 			if crawlCompleted.ContentHash != "hash" {
 				t.Errorf("expected content hash to be 'hash', got %v", crawlCompleted.ContentHash)
 			}
+
+			expectedAnchors := []domain.Anchor{
+				{URL: "http://example.com/1", Text: "One"},
+				{URL: "http://example.com/2", Text: "Two", Rel: "nofollow"},
+			}
+
+			if !reflect.DeepEqual(crawlCompleted.Anchors, expectedAnchors) {
+				t.Errorf("expected anchors to be %v, got %v", expectedAnchors, crawlCompleted.Anchors)
+			}
+
+			if !reflect.DeepEqual(crawlCompleted.Feeds, []domain.URL{"http://example.com/feed"}) {
+				t.Errorf("expected the canonical feed url, got %v", crawlCompleted.Feeds)
+			}
 		})
 
 		ctx := context.Background()
@@ -405,6 +547,38 @@ func TestProcessQueueItem(t *testing.T) {
 			t.Errorf("expected event to be published")
 		}
 	})
+
+	t.Run("keeps only canonical urls on the same site", func(t *testing.T) {
+		for canonicalURL, expected := range map[string]domain.URL{
+			"https://www.example.com/article": "https://www.example.com/article",
+			"https://other.com/article":       "",
+		} {
+			sf, job, node := setupCrawlTests()
+
+			gock.New("http://node1.cluster.com:8080").
+				Post("/crawl").
+				Reply(200).
+				JSON(dto.CrawlResponse{
+					ContentHash: "hash",
+					Canonical:   canonicalURL,
+				})
+
+			var canonical domain.URL
+			sf.EventService.Subscribe(domain.CrawlCompletedKey, func(event domain.Event) {
+				canonical = event.(*domain.CrawlCompleted).Canonical
+			})
+
+			if err := sf.CrawlService.ProcessQueueItem(context.Background(), job, node); err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+
+			if canonical != expected {
+				t.Errorf("expected canonical %q for %s, got %q", expected, canonicalURL, canonical)
+			}
+
+			gock.Off()
+		}
+	})
 }
 
 func TestRunCrawlProcess(t *testing.T) {
@@ -770,3 +944,193 @@ func TestHandlesPageCreatedEvent(t *testing.T) {
 		}
 	})
 }
+
+func TestRetries(t *testing.T) {
+	t.Run("schedules a retry with backoff when a crawl fails", func(t *testing.T) {
+		sf, job, node := setupCrawlTests()
+
+		defer gock.Off()
+
+		gock.New("http://node1.cluster.com:8080").
+			Post("/crawl").
+			Reply(500)
+
+		job.Attempts = 2
+		before := time.Now()
+
+		err := sf.CrawlService.ProcessQueueItem(context.Background(), job, node)
+
+		if err == nil {
+			t.Fatalf("expected an error")
+		}
+
+		updatedJob, err := sf.CrawlJobRepo.Get(job.PageID)
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if updatedJob.Status != domain.CrawlStatusFailed {
+			t.Errorf("expected job status to be failed, got %v", updatedJob.Status)
+		}
+
+		// the second attempt waits between half and all of twice the base delay
+		if updatedJob.NextAttemptAt.Before(before.Add(time.Minute)) || updatedJob.NextAttemptAt.After(time.Now().Add(2*time.Minute)) {
+			t.Errorf("expected the next attempt in 1m to 2m, got %v", updatedJob.NextAttemptAt.Sub(before))
+		}
+	})
+
+	t.Run("marks the job dead after the last attempt", func(t *testing.T) {
+		sf, job, node := setupCrawlTests()
+
+		defer gock.Off()
+
+		gock.New("http://node1.cluster.com:8080").
+			Post("/crawl").
+			Reply(500)
+
+		job.Attempts = 5
+
+		err := sf.CrawlService.ProcessQueueItem(context.Background(), job, node)
+
+		if err == nil {
+			t.Fatalf("expected an error")
+		}
+
+		updatedJob, err := sf.CrawlJobRepo.Get(job.PageID)
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if updatedJob.Status != domain.CrawlStatusDead {
+			t.Errorf("expected job status to be dead, got %v", updatedJob.Status)
+		}
+	})
+
+	t.Run("fails jobs left in progress", func(t *testing.T) {
+		sf, job, _ := setupCrawlTests()
+
+		job.Status = domain.CrawlStatusInProgress
+		job.Attempts = 1
+		job.UpdatedAt = time.Now().Add(-time.Hour)
+
+		if err := sf.CrawlJobRepo.Save(job); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if err := sf.CrawlService.ReapStaleJobs(); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		updatedJob, err := sf.CrawlJobRepo.Get(job.PageID)
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if updatedJob.Status != domain.CrawlStatusFailed {
+			t.Errorf("expected job status to be failed, got %v", updatedJob.Status)
+		}
+
+		if updatedJob.NextAttemptAt.IsZero() {
+			t.Errorf("expected a retry to be scheduled")
+		}
+	})
+
+	t.Run("retries failed jobs once they are due", func(t *testing.T) {
+		sf, job, node := setupCrawlTests()
+
+		defer gock.Off()
+
+		gock.New("http://node1.cluster.com:8080").
+			Post("/crawl").
+			Reply(200).
+			JSON(dto.CrawlResponse{})
+
+		sf.NodeRepo.Create(node)
+
+		job.Status = domain.CrawlStatusFailed
+		job.Attempts = 1
+		job.NextAttemptAt = time.Now().Add(-time.Second)
+
+		if err := sf.CrawlJobRepo.Save(job); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		err := sf.CrawlService.RunCrawlProcess(ctx)
+
+		if err != context.DeadlineExceeded {
+			t.Errorf("expected context deadline exceeded, got %v", err)
+		}
+
+		updatedJob, err := sf.CrawlJobRepo.Get(job.PageID)
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if updatedJob.Status != domain.CrawlStatusCompleted {
+			t.Errorf("expected job status to be completed, got %v", updatedJob.Status)
+		}
+
+		if updatedJob.Attempts != 2 {
+			t.Errorf("expected 2 attempts, got %d", updatedJob.Attempts)
+		}
+
+		if !gock.IsDone() {
+			t.Errorf("expected all mocks to be called")
+		}
+	})
+
+	t.Run("recrawls completed jobs once due", func(t *testing.T) {
+		sf, job, node := setupCrawlTests()
+
+		defer gock.Off()
+
+		gock.New("http://node1.cluster.com:8080").
+			Post("/crawl").
+			Reply(200).
+			JSON(dto.CrawlResponse{})
+
+		sf.NodeRepo.Create(node)
+
+		job.Status = domain.CrawlStatusCompleted
+		job.Attempts = 3
+		job.NextAttemptAt = time.Now().Add(-time.Second)
+
+		if err := sf.CrawlJobRepo.Save(job); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		err := sf.CrawlService.RunCrawlProcess(ctx)
+
+		if err != context.DeadlineExceeded {
+			t.Errorf("expected context deadline exceeded, got %v", err)
+		}
+
+		updatedJob, err := sf.CrawlJobRepo.Get(job.PageID)
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if updatedJob.Status != domain.CrawlStatusCompleted {
+			t.Errorf("expected job status to be completed, got %v", updatedJob.Status)
+		}
+
+		if updatedJob.Attempts != 1 {
+			t.Errorf("expected attempts to start again, got %d", updatedJob.Attempts)
+		}
+
+		if !gock.IsDone() {
+			t.Errorf("expected all mocks to be called")
+		}
+	})
+}
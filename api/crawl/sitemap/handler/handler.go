@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"crawlquery/api/domain"
+	"crawlquery/api/dto"
+	"crawlquery/api/errorutil"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	sitemapService domain.SitemapService
+}
+
+func NewHandler(sitemapService domain.SitemapService) *Handler {
+	return &Handler{
+		sitemapService: sitemapService,
+	}
+}
+
+// Seed starts adding the pages of a site's sitemaps to the crawl, answering
+// with the seed to follow it by.
+func (h *Handler) Seed(c *gin.Context) {
+	var req dto.SeedSitemapsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	seed, err := h.sitemapService.Start(domain.URL(req.URL))
+	if err != nil {
+		errorutil.HandleGinError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	c.Header("Location", "/crawl/sitemaps/"+string(seed.ID))
+	c.JSON(http.StatusAccepted, dto.NewSitemapSeedResponse(seed))
+}
+
+func (h *Handler) GetSeed(c *gin.Context) {
+	seed, err := h.sitemapService.GetSeed(domain.SitemapSeedID(c.Param("seedID")))
+	if err != nil {
+		if err == domain.ErrSitemapSeedNotFound {
+			errorutil.HandleGinError(c, err, http.StatusNotFound)
+			return
+		}
+		errorutil.HandleGinError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewSitemapSeedResponse(seed))
+}
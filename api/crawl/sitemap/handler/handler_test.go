@@ -0,0 +1,125 @@
+package handler_test
+
+import (
+	"bytes"
+	"crawlquery/api/crawl/sitemap/handler"
+	"crawlquery/api/crawl/sitemap/service"
+	"crawlquery/api/domain"
+	"crawlquery/api/dto"
+	"crawlquery/api/testfactory"
+	"crawlquery/pkg/testutil"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupHandler() *handler.Handler {
+	sf := testfactory.NewServiceFactory(
+		testfactory.WithShard(&domain.Shard{ID: 0}),
+	)
+
+	return handler.NewHandler(service.NewService(
+		service.WithHTTPClient(http.DefaultClient),
+		service.WithPageService(sf.PageService),
+		service.WithCrawlJobRepo(sf.CrawlJobRepo),
+		service.WithLogger(testutil.NewTestLogger()),
+	))
+}
+
+func TestSeed(t *testing.T) {
+	t.Run("starts seeding a site from its sitemap", func(t *testing.T) {
+		var site *httptest.Server
+		site = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/sitemap.xml" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write([]byte(`<urlset><url><loc>` + site.URL + `/a</loc></url></urlset>`))
+		}))
+		defer site.Close()
+
+		handler := setupHandler()
+
+		responseWriter := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(responseWriter)
+		ctx.Request = httptest.NewRequest("POST", "/crawl/sitemaps", bytes.NewBufferString(`{"url": "`+site.URL+`"}`))
+
+		handler.Seed(ctx)
+
+		if ctx.Writer.Status() != http.StatusAccepted {
+			t.Fatalf("Expected status to be 202, got %d", ctx.Writer.Status())
+		}
+
+		var res dto.SitemapSeedResponse
+		if err := json.Unmarshal(responseWriter.Body.Bytes(), &res); err != nil {
+			t.Fatalf("Error decoding response: %v", err)
+		}
+
+		if res.ID == "" || res.Site != site.URL {
+			t.Errorf("Unexpected response: %+v", res)
+		}
+
+		if location := responseWriter.Header().Get("Location"); location != "/crawl/sitemaps/"+res.ID {
+			t.Errorf("Expected the seed's location, got %s", location)
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for res.Status == "running" && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+
+			responseWriter = httptest.NewRecorder()
+			ctx, _ = gin.CreateTestContext(responseWriter)
+			ctx.Request = httptest.NewRequest("GET", "/crawl/sitemaps/"+res.ID, nil)
+			ctx.Params = gin.Params{{Key: "seedID", Value: res.ID}}
+
+			handler.GetSeed(ctx)
+
+			if ctx.Writer.Status() != http.StatusOK {
+				t.Fatalf("Expected status to be 200, got %d", ctx.Writer.Status())
+			}
+
+			if err := json.Unmarshal(responseWriter.Body.Bytes(), &res); err != nil {
+				t.Fatalf("Error decoding response: %v", err)
+			}
+		}
+
+		if res.Status != "completed" || res.Result == nil || len(res.Result.Sitemaps) != 1 || res.Result.URLs != 1 || res.Result.Created != 1 {
+			t.Errorf("Unexpected response: %+v", res)
+		}
+	})
+
+	t.Run("requires a url", func(t *testing.T) {
+		handler := setupHandler()
+
+		responseWriter := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(responseWriter)
+		ctx.Request = httptest.NewRequest("POST", "/crawl/sitemaps", bytes.NewBufferString(`{"url": "not a url"}`))
+
+		handler.Seed(ctx)
+
+		if ctx.Writer.Status() != http.StatusBadRequest {
+			t.Errorf("Expected status to be 400, got %d", ctx.Writer.Status())
+		}
+	})
+}
+
+func TestGetSeed(t *testing.T) {
+	t.Run("returns 404 for unknown seeds", func(t *testing.T) {
+		handler := setupHandler()
+
+		responseWriter := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(responseWriter)
+		ctx.Request = httptest.NewRequest("GET", "/crawl/sitemaps/unknown", nil)
+		ctx.Params = gin.Params{{Key: "seedID", Value: "unknown"}}
+
+		handler.GetSeed(ctx)
+
+		if ctx.Writer.Status() != http.StatusNotFound {
+			t.Errorf("Expected status to be 404, got %d", ctx.Writer.Status())
+		}
+	})
+}
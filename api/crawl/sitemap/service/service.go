@@ -0,0 +1,410 @@
+package service
+
+import (
+	"crawlquery/api/domain"
+	"crawlquery/pkg/canonical"
+	"crawlquery/pkg/safehttp"
+	"crawlquery/pkg/sitemap"
+	"crawlquery/pkg/util"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/temoto/robotstxt"
+	"go.uber.org/zap"
+)
+
+// UserAgent is sent when fetching robots.txt files and sitemaps.
+const UserAgent = "crawlquery"
+
+// Service seeds the crawl from the sitemaps sites list in their robots.txt,
+// following sitemap indexes. New pages are crawled sooner or later by the
+// priority the sitemap gives them, and known pages whose lastmod is after
+// their last crawl are crawled again.
+type Service struct {
+	client       *http.Client
+	pageService  domain.PageService
+	crawlJobRepo domain.CrawlJobRepository
+	urlFilter    domain.URLFilterService
+	logger       *zap.SugaredLogger
+
+	maxSitemaps    int
+	maxURLs        int
+	priorityWeight float64
+	seedTTL        time.Duration
+
+	mu    sync.Mutex
+	seeds map[domain.SitemapSeedID]*domain.SitemapSeed
+}
+
+type Option func(*Service)
+
+// WithHTTPClient replaces the client robots.txt files and sitemaps are
+// fetched with, which by default only connects to public addresses.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *Service) {
+		s.client = client
+	}
+}
+
+func WithPageService(pageService domain.PageService) Option {
+	return func(s *Service) {
+		s.pageService = pageService
+	}
+}
+
+func WithCrawlJobRepo(crawlJobRepo domain.CrawlJobRepository) Option {
+	return func(s *Service) {
+		s.crawlJobRepo = crawlJobRepo
+	}
+}
+
+// WithURLFilterService leaves out the URLs the filter denies, as it does for
+// discovered links.
+func WithURLFilterService(urlFilter domain.URLFilterService) Option {
+	return func(s *Service) {
+		s.urlFilter = urlFilter
+	}
+}
+
+func WithLogger(logger *zap.SugaredLogger) Option {
+	return func(s *Service) {
+		s.logger = logger
+	}
+}
+
+// WithLimits caps how many sitemaps are fetched and how many URLs are read
+// when seeding a site.
+func WithLimits(maxSitemaps, maxURLs int) Option {
+	return func(s *Service) {
+		s.maxSitemaps = maxSitemaps
+		s.maxURLs = maxURLs
+	}
+}
+
+// WithPriorityWeight sets how far a sitemap priority moves the crawl priority
+// of a new page. Pages at the default sitemap priority of 0.5 are not moved
+// and pages at 0 or 1 are moved by half the weight.
+func WithPriorityWeight(weight float64) Option {
+	return func(s *Service) {
+		s.priorityWeight = weight
+	}
+}
+
+// WithSeedTTL sets how long finished seeds can be looked up for.
+func WithSeedTTL(ttl time.Duration) Option {
+	return func(s *Service) {
+		s.seedTTL = ttl
+	}
+}
+
+func NewService(opts ...Option) *Service {
+	s := &Service{
+		client:         safehttp.NewClient(30 * time.Second),
+		maxSitemaps:    50,
+		maxURLs:        50000,
+		priorityWeight: 0.2,
+		seedTTL:        24 * time.Hour,
+		seeds:          make(map[domain.SitemapSeedID]*domain.SitemapSeed),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// site returns the scheme and host of a site's URL.
+func site(siteURL domain.URL) (string, error) {
+	canonicalURL, err := canonical.URL(siteURL)
+	if err != nil {
+		return "", err
+	}
+
+	parsed, err := url.Parse(string(canonicalURL))
+	if err != nil {
+		return "", err
+	}
+
+	return parsed.Scheme + "://" + parsed.Host, nil
+}
+
+func (s *Service) get(rawURL string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", UserAgent)
+
+	return s.client.Do(req)
+}
+
+func (s *Service) Discover(siteURL domain.URL) ([]domain.URL, error) {
+	root, err := site(siteURL)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.get(root + "/robots.txt")
+	if err != nil {
+		s.logger.Errorw("Error fetching robots.txt", "error", err, "site", root)
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	robots, err := robotstxt.FromResponse(res)
+	if err != nil {
+		s.logger.Errorw("Error parsing robots.txt", "error", err, "site", root)
+		return nil, err
+	}
+
+	var sitemaps []domain.URL
+
+	for _, loc := range robots.Sitemaps {
+		sitemapURL, err := canonical.URL(domain.URL(loc))
+		if err != nil {
+			s.logger.Debugw("Skipping invalid sitemap", "error", err, "site", root, "sitemap", loc)
+			continue
+		}
+		sitemaps = append(sitemaps, sitemapURL)
+	}
+
+	if len(sitemaps) == 0 {
+		sitemaps = append(sitemaps, domain.URL(root+"/sitemap.xml"))
+	}
+
+	return sitemaps, nil
+}
+
+func (s *Service) fetch(sitemapURL domain.URL) (*sitemap.Sitemap, error) {
+	res, err := s.get(string(sitemapURL))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching sitemap %s: status %d", sitemapURL, res.StatusCode)
+	}
+
+	return sitemap.Parse(res.Body)
+}
+
+// Seed adds the pages of the site's sitemaps to the crawl. Only pages on the
+// site's host are added, as sitemaps may not list pages of other sites. It
+// returns ErrSitemapNotFound when none of the site's sitemaps could be read.
+func (s *Service) Seed(siteURL domain.URL) (*domain.SitemapSeedResult, error) {
+	queue, err := s.Discover(siteURL)
+	if err != nil {
+		return nil, err
+	}
+
+	host := util.Host(siteURL)
+	result := &domain.SitemapSeedResult{}
+	seen := make(map[domain.URL]bool)
+
+	for _, sitemapURL := range queue {
+		seen[sitemapURL] = true
+	}
+
+	for len(queue) > 0 && len(result.Sitemaps) < s.maxSitemaps && result.URLs < s.maxURLs {
+		sitemapURL := queue[0]
+		queue = queue[1:]
+
+		sm, err := s.fetch(sitemapURL)
+		if err != nil {
+			s.logger.Warnw("Error fetching sitemap", "error", err, "sitemap", sitemapURL)
+			continue
+		}
+
+		result.Sitemaps = append(result.Sitemaps, sitemapURL)
+
+		for _, child := range sm.Sitemaps {
+			childURL, err := canonical.URL(domain.URL(child.Loc))
+			if err != nil || seen[childURL] {
+				continue
+			}
+			seen[childURL] = true
+			queue = append(queue, childURL)
+		}
+
+		for _, entry := range sm.URLs {
+			if result.URLs >= s.maxURLs {
+				break
+			}
+			result.URLs++
+			s.add(host, entry, result)
+		}
+	}
+
+	if len(result.Sitemaps) == 0 {
+		return nil, domain.ErrSitemapNotFound
+	}
+
+	s.logger.Infow("Seeded site from sitemaps", "site", siteURL, "sitemaps", len(result.Sitemaps), "urls", result.URLs, "created", result.Created, "recrawled", result.Recrawled, "skipped", result.Skipped)
+
+	return result, nil
+}
+
+func (s *Service) Start(siteURL domain.URL) (*domain.SitemapSeed, error) {
+	root, err := site(siteURL)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evictFinished(now)
+
+	for _, seed := range s.seeds {
+		if seed.Status == domain.SitemapSeedStatusRunning && seed.Site == domain.URL(root) {
+			copied := *seed
+			return &copied, nil
+		}
+	}
+
+	seed := &domain.SitemapSeed{
+		ID:        domain.SitemapSeedID(util.UUIDString()),
+		Site:      domain.URL(root),
+		Status:    domain.SitemapSeedStatusRunning,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.seeds[seed.ID] = seed
+
+	go s.run(seed.ID, siteURL)
+
+	copied := *seed
+	return &copied, nil
+}
+
+// run seeds a site and records how it went on its seed.
+func (s *Service) run(id domain.SitemapSeedID, siteURL domain.URL) {
+	result, err := s.Seed(siteURL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seed, ok := s.seeds[id]
+	if !ok {
+		return
+	}
+
+	seed.UpdatedAt = time.Now()
+
+	if err != nil {
+		seed.Status = domain.SitemapSeedStatusFailed
+		seed.Error = err.Error()
+		return
+	}
+
+	seed.Status = domain.SitemapSeedStatusCompleted
+	seed.Result = result
+}
+
+// evictFinished drops the seeds that finished more than seedTTL ago. The
+// caller must hold mu.
+func (s *Service) evictFinished(now time.Time) {
+	for id, seed := range s.seeds {
+		if seed.Status != domain.SitemapSeedStatusRunning && now.Sub(seed.UpdatedAt) >= s.seedTTL {
+			delete(s.seeds, id)
+		}
+	}
+}
+
+func (s *Service) GetSeed(id domain.SitemapSeedID) (*domain.SitemapSeed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seed, ok := s.seeds[id]
+	if !ok {
+		return nil, domain.ErrSitemapSeedNotFound
+	}
+
+	copied := *seed
+	return &copied, nil
+}
+
+func (s *Service) add(host domain.Host, entry sitemap.URL, result *domain.SitemapSeedResult) {
+	pageURL, err := canonical.URL(domain.URL(entry.Loc))
+	if err != nil || util.Host(pageURL) != host {
+		result.Skipped++
+		return
+	}
+
+	if s.urlFilter != nil {
+		if decision := s.urlFilter.Evaluate(pageURL); !decision.Allowed {
+			result.Skipped++
+			return
+		}
+	}
+
+	page, err := s.pageService.Create(domain.URL(entry.Loc))
+	if err == domain.ErrPageAlreadyExists {
+		if s.recrawl(util.PageID(pageURL), entry.LastMod) {
+			result.Recrawled++
+		}
+		return
+	}
+	if err != nil {
+		s.logger.Debugw("Error creating page from sitemap", "error", err, "url", pageURL)
+		result.Skipped++
+		return
+	}
+
+	result.Created++
+	s.prioritise(page.ID, entry.Priority)
+}
+
+// prioritise moves the crawl priority of a new page's job by its sitemap
+// priority relative to the default.
+func (s *Service) prioritise(pageID domain.PageID, priority float64) {
+	if priority == sitemap.DefaultPriority {
+		return
+	}
+
+	job, err := s.crawlJobRepo.Get(pageID)
+	if err != nil {
+		return
+	}
+
+	job.Priority += s.priorityWeight * (priority - sitemap.DefaultPriority)
+
+	if err := s.crawlJobRepo.Save(job); err != nil {
+		s.logger.Errorw("Error saving crawl job", "error", err, "pageID", pageID)
+	}
+}
+
+// recrawl makes a crawled page due to be crawled again when the sitemap says
+// it changed after it was crawled, reporting whether it did.
+func (s *Service) recrawl(pageID domain.PageID, lastMod time.Time) bool {
+	if lastMod.IsZero() {
+		return false
+	}
+
+	job, err := s.crawlJobRepo.Get(pageID)
+	if err != nil || job.Status != domain.CrawlStatusCompleted || !lastMod.After(job.UpdatedAt) {
+		return false
+	}
+
+	now := time.Now()
+	if !job.NextAttemptAt.IsZero() && !job.NextAttemptAt.After(now) {
+		return false
+	}
+
+	job.NextAttemptAt = now
+
+	if err := s.crawlJobRepo.Save(job); err != nil {
+		s.logger.Errorw("Error saving crawl job", "error", err, "pageID", pageID)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,315 @@
+package service_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	crawlService "crawlquery/api/crawl/service"
+	"crawlquery/api/crawl/sitemap/service"
+	"crawlquery/api/domain"
+	"crawlquery/api/testfactory"
+	"crawlquery/pkg/safehttp"
+	"crawlquery/pkg/testutil"
+	"crawlquery/pkg/util"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newSite serves the given paths, replacing %[1]s in them with the site's
+// URL.
+func newSite(t *testing.T, paths map[string]string) *httptest.Server {
+	var srv *httptest.Server
+
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := paths[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		body = fmt.Sprintf(body, srv.URL)
+
+		if strings.HasSuffix(r.URL.Path, ".gz") {
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			gz.Write([]byte(body))
+			gz.Close()
+			body = buf.String()
+		}
+
+		w.Write([]byte(body))
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func setup(opts ...service.Option) (*service.Service, *testfactory.ServiceFactory) {
+	sf := testfactory.NewServiceFactory(
+		testfactory.WithShard(&domain.Shard{ID: 0}),
+	)
+
+	crawlService.NewService(
+		crawlService.WithEventService(sf.EventService),
+		crawlService.WithCrawlJobRepo(sf.CrawlJobRepo),
+		crawlService.WithCrawlLogRepo(sf.CrawlLogRepo),
+		crawlService.WithLogger(testutil.NewTestLogger()),
+		crawlService.WithEventListeners(),
+	)
+
+	// the test sites listen on loopback, which the default client refuses
+	opts = append([]service.Option{
+		service.WithHTTPClient(http.DefaultClient),
+		service.WithPageService(sf.PageService),
+		service.WithCrawlJobRepo(sf.CrawlJobRepo),
+		service.WithLogger(testutil.NewTestLogger()),
+	}, opts...)
+
+	return service.NewService(opts...), sf
+}
+
+func TestDiscover(t *testing.T) {
+	t.Run("returns the sitemaps named in robots.txt", func(t *testing.T) {
+		site := newSite(t, map[string]string{
+			"/robots.txt": "User-agent: *\nDisallow: /private\nSitemap: %[1]s/posts.xml\nSitemap: %[1]s/pages.xml.gz\n",
+		})
+
+		s, _ := setup()
+
+		sitemaps, err := s.Discover(domain.URL(site.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(sitemaps) != 2 || sitemaps[0] != domain.URL(site.URL+"/posts.xml") || sitemaps[1] != domain.URL(site.URL+"/pages.xml.gz") {
+			t.Errorf("unexpected sitemaps: %v", sitemaps)
+		}
+	})
+
+	t.Run("falls back to sitemap.xml", func(t *testing.T) {
+		site := newSite(t, map[string]string{})
+
+		s, _ := setup()
+
+		sitemaps, err := s.Discover(domain.URL(site.URL + "/some/page"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(sitemaps) != 1 || sitemaps[0] != domain.URL(site.URL+"/sitemap.xml") {
+			t.Errorf("unexpected sitemaps: %v", sitemaps)
+		}
+	})
+}
+
+func TestSeed(t *testing.T) {
+	t.Run("adds the pages of sitemap indexes and gzipped sitemaps", func(t *testing.T) {
+		site := newSite(t, map[string]string{
+			"/robots.txt": "User-agent: *\nSitemap: %[1]s/index.xml\n",
+			"/index.xml": `<sitemapindex>
+				<sitemap><loc>%[1]s/posts.xml.gz</loc></sitemap>
+				<sitemap><loc>%[1]s/pages.xml</loc></sitemap>
+				<sitemap><loc>%[1]s/index.xml</loc></sitemap>
+			</sitemapindex>`,
+			"/posts.xml.gz": `<urlset>
+				<url><loc>%[1]s/posts/a</loc><priority>1.0</priority></url>
+				<url><loc>%[1]s/posts/b</loc><priority>0.1</priority></url>
+			</urlset>`,
+			"/pages.xml": `<urlset>
+				<url><loc>%[1]s/about</loc></url>
+				<url><loc>http://elsewhere.com/</loc></url>
+				<url><loc>mailto:a@example.com</loc></url>
+			</urlset>`,
+		})
+
+		s, sf := setup()
+
+		result, err := s.Seed(domain.URL(site.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(result.Sitemaps) != 3 || result.URLs != 5 || result.Created != 3 || result.Skipped != 2 {
+			t.Errorf("unexpected result: %+v", result)
+		}
+
+		for _, path := range []string{"/posts/a", "/posts/b", "/about"} {
+			if _, err := sf.PageRepo.Get(util.PageID(domain.URL(site.URL + path))); err != nil {
+				t.Errorf("expected a page for %s, got %v", path, err)
+			}
+		}
+
+		if _, err := sf.PageRepo.Get(util.PageID("http://elsewhere.com/")); err != domain.ErrPageNotFound {
+			t.Errorf("expected no page for another site, got %v", err)
+		}
+
+		high, _ := sf.CrawlJobRepo.Get(util.PageID(domain.URL(site.URL + "/posts/a")))
+		low, _ := sf.CrawlJobRepo.Get(util.PageID(domain.URL(site.URL + "/posts/b")))
+		normal, _ := sf.CrawlJobRepo.Get(util.PageID(domain.URL(site.URL + "/about")))
+
+		if !(high.Priority > normal.Priority && normal.Priority > low.Priority) {
+			t.Errorf("expected priorities to follow the sitemap, got %v, %v and %v", high.Priority, normal.Priority, low.Priority)
+		}
+	})
+
+	t.Run("recrawls known pages that changed since their last crawl", func(t *testing.T) {
+		site := newSite(t, map[string]string{
+			"/sitemap.xml": `<urlset>
+				<url><loc>%[1]s/changed</loc><lastmod>2030-01-01</lastmod></url>
+				<url><loc>%[1]s/unchanged</loc><lastmod>2000-01-01</lastmod></url>
+			</urlset>`,
+		})
+
+		s, sf := setup()
+
+		for _, path := range []string{"/changed", "/unchanged"} {
+			url := domain.URL(site.URL + path)
+			sf.PageRepo.Create(&domain.Page{ID: util.PageID(url), URL: url})
+			sf.CrawlJobRepo.Save(&domain.CrawlJob{
+				PageID:    util.PageID(url),
+				URL:       url,
+				Status:    domain.CrawlStatusCompleted,
+				UpdatedAt: time.Now().Add(-time.Hour),
+			})
+		}
+
+		result, err := s.Seed(domain.URL(site.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if result.Created != 0 || result.Recrawled != 1 {
+			t.Errorf("unexpected result: %+v", result)
+		}
+
+		changed, _ := sf.CrawlJobRepo.Get(util.PageID(domain.URL(site.URL + "/changed")))
+		unchanged, _ := sf.CrawlJobRepo.Get(util.PageID(domain.URL(site.URL + "/unchanged")))
+
+		if changed.NextAttemptAt.IsZero() || changed.NextAttemptAt.After(time.Now()) {
+			t.Errorf("expected the changed page to be due, got %v", changed.NextAttemptAt)
+		}
+
+		if !unchanged.NextAttemptAt.IsZero() {
+			t.Errorf("expected the unchanged page not to be scheduled, got %v", unchanged.NextAttemptAt)
+		}
+	})
+
+	t.Run("stops at the url limit", func(t *testing.T) {
+		site := newSite(t, map[string]string{
+			"/sitemap.xml": `<urlset>
+				<url><loc>%[1]s/a</loc></url>
+				<url><loc>%[1]s/b</loc></url>
+				<url><loc>%[1]s/c</loc></url>
+			</urlset>`,
+		})
+
+		s, _ := setup(service.WithLimits(10, 2))
+
+		result, err := s.Seed(domain.URL(site.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if result.URLs != 2 || result.Created != 2 {
+			t.Errorf("unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("refuses sites on addresses that aren't public", func(t *testing.T) {
+		site := newSite(t, map[string]string{
+			"/sitemap.xml": `<urlset><url><loc>%[1]s/a</loc></url></urlset>`,
+		})
+
+		s := service.NewService(service.WithLogger(testutil.NewTestLogger()))
+
+		if _, err := s.Seed(domain.URL(site.URL)); !errors.Is(err, safehttp.ErrNonPublicAddress) {
+			t.Errorf("expected ErrNonPublicAddress, got %v", err)
+		}
+	})
+
+	t.Run("returns ErrSitemapNotFound for sites without sitemaps", func(t *testing.T) {
+		site := newSite(t, map[string]string{})
+
+		s, _ := setup()
+
+		if _, err := s.Seed(domain.URL(site.URL)); err != domain.ErrSitemapNotFound {
+			t.Errorf("expected ErrSitemapNotFound, got %v", err)
+		}
+	})
+}
+
+// waitForSeed polls a seed until it has finished.
+func waitForSeed(t *testing.T, s *service.Service, id domain.SitemapSeedID) *domain.SitemapSeed {
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) {
+		seed, err := s.GetSeed(id)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if seed.Status != domain.SitemapSeedStatusRunning {
+			return seed
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("seed %s did not finish", id)
+	return nil
+}
+
+func TestStart(t *testing.T) {
+	t.Run("seeds the site in the background", func(t *testing.T) {
+		site := newSite(t, map[string]string{
+			"/sitemap.xml": `<urlset><url><loc>%[1]s/a</loc></url><url><loc>%[1]s/b</loc></url></urlset>`,
+		})
+
+		s, _ := setup()
+
+		seed, err := s.Start(domain.URL(site.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if seed.ID == "" || seed.Site != domain.URL(site.URL) {
+			t.Errorf("unexpected seed: %+v", seed)
+		}
+
+		seed = waitForSeed(t, s, seed.ID)
+
+		if seed.Status != domain.SitemapSeedStatusCompleted || seed.Result == nil || seed.Result.Created != 2 {
+			t.Errorf("expected a completed seed creating 2 pages, got %+v", seed)
+		}
+	})
+
+	t.Run("records why a seed failed", func(t *testing.T) {
+		site := newSite(t, map[string]string{})
+
+		s, _ := setup()
+
+		seed, err := s.Start(domain.URL(site.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		seed = waitForSeed(t, s, seed.ID)
+
+		if seed.Status != domain.SitemapSeedStatusFailed || seed.Error != domain.ErrSitemapNotFound.Error() {
+			t.Errorf("expected a failed seed, got %+v", seed)
+		}
+	})
+
+	t.Run("returns ErrSitemapSeedNotFound for unknown seeds", func(t *testing.T) {
+		s, _ := setup()
+
+		if _, err := s.GetSeed("unknown"); err != domain.ErrSitemapSeedNotFound {
+			t.Errorf("expected ErrSitemapSeedNotFound, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"crawlquery/api/domain"
+	"crawlquery/api/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	crawlThrottleService domain.CrawlThrottleService
+}
+
+func NewHandler(crawlThrottleService domain.CrawlThrottleService) *Handler {
+	return &Handler{
+		crawlThrottleService: crawlThrottleService,
+	}
+}
+
+// Hosts lists the hosts being crawled with their queues and delays.
+func (h *Handler) Hosts(c *gin.Context) {
+	c.JSON(200, dto.NewListCrawlHostsResponse(h.crawlThrottleService.State()))
+}
@@ -0,0 +1,56 @@
+package handler_test
+
+import (
+	"crawlquery/api/crawl/throttle/handler"
+	"crawlquery/api/crawl/throttle/service"
+	"crawlquery/api/domain"
+	"crawlquery/api/dto"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHosts(t *testing.T) {
+	t.Run("lists queued hosts", func(t *testing.T) {
+		svc := service.NewService(service.WithRateLimit(5 * time.Second))
+
+		for _, url := range []domain.URL{"http://example.com/a", "http://example.com/b", "http://example.net/a"} {
+			if err := svc.Enqueue(&domain.CrawlJob{PageID: domain.PageID(url), URL: url}); err != nil {
+				t.Fatalf("Error enqueueing job: %v", err)
+			}
+		}
+
+		handler := handler.NewHandler(svc)
+
+		responseWriter := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(responseWriter)
+		ctx.Request = httptest.NewRequest("GET", "/crawl/hosts", nil)
+
+		handler.Hosts(ctx)
+
+		if ctx.Writer.Status() != http.StatusOK {
+			t.Errorf("Expected status to be 200, got %d", ctx.Writer.Status())
+		}
+
+		var res dto.ListCrawlHostsResponse
+		if err := json.Unmarshal(responseWriter.Body.Bytes(), &res); err != nil {
+			t.Fatalf("Error decoding response: %v", err)
+		}
+
+		if len(res.Hosts) != 2 {
+			t.Fatalf("Expected 2 hosts, got %d", len(res.Hosts))
+		}
+
+		if res.Hosts[0].Host != "example.com" || res.Hosts[0].Queued != 2 {
+			t.Errorf("Expected example.com with 2 queued, got %s with %d", res.Hosts[0].Host, res.Hosts[0].Queued)
+		}
+
+		if res.Hosts[1].Delay != 5 {
+			t.Errorf("Expected a delay of 5 seconds, got %v", res.Hosts[1].Delay)
+		}
+	})
+}
@@ -2,38 +2,136 @@ package service
 
 import (
 	"crawlquery/api/domain"
+	"crawlquery/pkg/util"
+	"errors"
+	"net"
+	"net/http"
 	"net/url"
+	"sort"
 	"sync"
 	"time"
 )
 
+var ErrInvalidURL = errors.New("invalid url")
+
+// host is the queue and politeness state of a single host.
+type host struct {
+	name       domain.Host
+	ip         string
+	queue      []*domain.CrawlJob
+	active     int
+	crawlDelay time.Duration
+	backoff    time.Duration
+	nextAt     time.Time
+}
+
+// Service hands out crawl jobs highest priority first, waiting between
+// requests to the same host for the longest of the minimum delay, the host's
+// robots.txt crawl delay and any backoff from it answering slowly or asking
+// crawlers to slow down. Each host's jobs are queued by priority and hosts
+// whose best jobs are equal take turns.
 type Service struct {
-	domains map[string]time.Time
-	mutex   *sync.Mutex
+	minDelay          time.Duration
+	maxCrawlDelay     time.Duration
+	maxBackoff        time.Duration
+	slowResponse      time.Duration
+	maxConnsPerHost   int
+	maxConnsPerIP     int
+	resolve           func(host string) (string, error)
+	now               func() time.Time
+	mutex             *sync.Mutex
+	hosts             map[domain.Host]*host
+	order             []domain.Host
+	cursor            int
+	queued            map[domain.PageID]bool
+	activeConnections map[string]int
 }
 
 type Option func(*Service)
 
+// WithRateLimit sets the minimum delay between requests to the same host.
 func WithRateLimit(rateLimit time.Duration) Option {
-	return func(t *Service) {
-		go func() {
-			for {
-				t.mutex.Lock()
-				for k, v := range t.domains {
-					if time.Since(v) > rateLimit {
-						delete(t.domains, k)
-					}
-				}
-				t.mutex.Unlock()
-			}
-		}()
+	return func(s *Service) {
+		s.minDelay = rateLimit
+	}
+}
+
+// WithMaxCrawlDelay caps the crawl delay taken from robots.txt.
+func WithMaxCrawlDelay(maxCrawlDelay time.Duration) Option {
+	return func(s *Service) {
+		s.maxCrawlDelay = maxCrawlDelay
+	}
+}
+
+// WithMaxBackoff caps how far a host is backed off.
+func WithMaxBackoff(maxBackoff time.Duration) Option {
+	return func(s *Service) {
+		s.maxBackoff = maxBackoff
+	}
+}
+
+// WithSlowResponse sets how long a response may take before the host is
+// backed off.
+func WithSlowResponse(slowResponse time.Duration) Option {
+	return func(s *Service) {
+		s.slowResponse = slowResponse
+	}
+}
+
+func WithMaxConnectionsPerHost(n int) Option {
+	return func(s *Service) {
+		s.maxConnsPerHost = n
+	}
+}
+
+// WithMaxConnectionsPerIP caps connections to hosts sharing an address. No
+// cap applies unless set, and host names are only resolved when it is.
+func WithMaxConnectionsPerIP(n int) Option {
+	return func(s *Service) {
+		s.maxConnsPerIP = n
+	}
+}
+
+// WithResolver sets how host names are resolved to the address connections
+// are counted against.
+func WithResolver(resolve func(host string) (string, error)) Option {
+	return func(s *Service) {
+		s.resolve = resolve
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(s *Service) {
+		s.now = now
+	}
+}
+
+// LookupIP resolves a host name to its first address.
+func LookupIP(host string) (string, error) {
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return "", err
+	}
+
+	if len(ips) == 0 {
+		return "", errors.New("no addresses found")
 	}
+
+	return ips[0].String(), nil
 }
 
 func NewService(opts ...Option) *Service {
 	s := &Service{
-		map[string]time.Time{},
-		&sync.Mutex{},
+		maxCrawlDelay:     time.Minute,
+		maxBackoff:        time.Hour,
+		slowResponse:      10 * time.Second,
+		maxConnsPerHost:   1,
+		resolve:           LookupIP,
+		now:               time.Now,
+		mutex:             &sync.Mutex{},
+		hosts:             map[domain.Host]*host{},
+		queued:            map[domain.PageID]bool{},
+		activeConnections: map[string]int{},
 	}
 
 	for _, opt := range opts {
@@ -43,20 +141,262 @@ func NewService(opts ...Option) *Service {
 	return s
 }
 
-func (t *Service) CheckAndThrottle(rawURL domain.URL) (bool, error) {
+func hostOf(rawURL domain.URL) (domain.Host, string, error) {
 	parsed, err := url.ParseRequestURI(string(rawURL))
+	if err != nil {
+		return "", "", err
+	}
+
+	name := util.Host(rawURL)
+	if name == "" {
+		return "", "", ErrInvalidURL
+	}
+
+	return name, parsed.Hostname(), nil
+}
 
+func (s *Service) Enqueue(job *domain.CrawlJob) error {
+	name, hostname, err := hostOf(job.URL)
 	if err != nil {
-		return false, err
+		return err
+	}
+
+	s.mutex.Lock()
+	_, known := s.hosts[name]
+	s.mutex.Unlock()
+
+	// resolve outside the lock, lookups can be slow
+	var ip string
+	if !known && s.maxConnsPerIP > 0 {
+		ip, err = s.resolve(hostname)
+		if err != nil {
+			ip = ""
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.queued[job.PageID] {
+		return nil
+	}
+
+	h, ok := s.hosts[name]
+	if !ok {
+		h = &host{name: name, ip: ip}
+		s.hosts[name] = h
+		s.order = append(s.order, name)
+	}
+
+	// keep the queue in priority order, first come first served for equals
+	i := sort.Search(len(h.queue), func(i int) bool {
+		return h.queue[i].Priority < job.Priority
+	})
+	h.queue = append(h.queue, nil)
+	copy(h.queue[i+1:], h.queue[i:])
+	h.queue[i] = job
+	s.queued[job.PageID] = true
+
+	return nil
+}
+
+// connectionKey is what connections to the host are counted against when
+// connections per IP are capped.
+func (h *host) connectionKey() string {
+	if h.ip != "" {
+		return h.ip
 	}
+	return string(h.name)
+}
+
+func (s *Service) delay(h *host) time.Duration {
+	delay := s.minDelay
 
-	t.mutex.Lock()
-	defer t.mutex.Unlock()
+	crawlDelay := h.crawlDelay
+	if crawlDelay > s.maxCrawlDelay {
+		crawlDelay = s.maxCrawlDelay
+	}
+
+	if crawlDelay > delay {
+		delay = crawlDelay
+	}
 
-	if _, ok := t.domains[parsed.Host]; ok {
-		return false, nil
+	if h.backoff > delay {
+		delay = h.backoff
 	}
 
-	t.domains[parsed.Host] = time.Now()
-	return true, nil
+	return delay
+}
+
+func (s *Service) ready(h *host, now time.Time) bool {
+	if len(h.queue) == 0 || now.Before(h.nextAt) {
+		return false
+	}
+
+	if s.maxConnsPerHost > 0 && h.active >= s.maxConnsPerHost {
+		return false
+	}
+
+	if s.maxConnsPerIP > 0 && s.activeConnections[h.connectionKey()] >= s.maxConnsPerIP {
+		return false
+	}
+
+	return true
+}
+
+// Next returns the best job of the ready host with the best job. Hosts are
+// considered in turn from the one after the last taken, so that hosts with
+// equal jobs share the crawl.
+func (s *Service) Next() (*domain.CrawlJob, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+
+	best := -1
+	for i := 0; i < len(s.order); i++ {
+		idx := (s.cursor + i) % len(s.order)
+		h := s.hosts[s.order[idx]]
+
+		if !s.ready(h, now) {
+			continue
+		}
+
+		if best == -1 || h.queue[0].Priority > s.hosts[s.order[best]].queue[0].Priority {
+			best = idx
+		}
+	}
+
+	if best == -1 {
+		s.prune(now)
+		return nil, domain.ErrCrawlQueueEmpty
+	}
+
+	h := s.hosts[s.order[best]]
+
+	job := h.queue[0]
+	h.queue[0] = nil
+	h.queue = h.queue[1:]
+	delete(s.queued, job.PageID)
+
+	h.active++
+	s.activeConnections[h.connectionKey()]++
+	h.nextAt = now.Add(s.delay(h))
+
+	s.cursor = best + 1
+
+	return job, nil
+}
+
+// prune forgets hosts with nothing queued or in flight once their delay has
+// passed.
+func (s *Service) prune(now time.Time) {
+	kept := s.order[:0]
+	cursor := s.cursor
+
+	for i, name := range s.order {
+		h := s.hosts[name]
+		if len(h.queue) == 0 && h.active == 0 && !now.Before(h.nextAt) {
+			delete(s.hosts, name)
+			if i < s.cursor {
+				cursor--
+			}
+			continue
+		}
+		kept = append(kept, name)
+	}
+
+	s.order = kept
+	s.cursor = cursor
+	if s.cursor >= len(s.order) {
+		s.cursor = 0
+	}
+}
+
+func (s *Service) Observe(rawURL domain.URL, outcome domain.CrawlOutcome) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	h, ok := s.hosts[util.Host(rawURL)]
+	if !ok {
+		return
+	}
+
+	switch {
+	case outcome.StatusCode == http.StatusTooManyRequests || outcome.StatusCode == http.StatusServiceUnavailable:
+		backoff := 2 * h.backoff
+		if backoff < 2*s.minDelay {
+			backoff = 2 * s.minDelay
+		}
+		if outcome.RetryAfter > backoff {
+			backoff = outcome.RetryAfter
+		}
+		h.backoff = backoff
+	case s.slowResponse > 0 && outcome.Duration > s.slowResponse:
+		if 2*outcome.Duration > h.backoff {
+			h.backoff = 2 * outcome.Duration
+		}
+	case outcome.StatusCode < http.StatusBadRequest:
+		h.backoff /= 2
+		if h.backoff < s.minDelay {
+			h.backoff = 0
+		}
+	}
+
+	if h.backoff > s.maxBackoff {
+		h.backoff = s.maxBackoff
+	}
+
+	if outcome.StatusCode < http.StatusBadRequest {
+		h.crawlDelay = outcome.CrawlDelay
+	}
+
+	nextAt := s.now().Add(s.delay(h))
+	if nextAt.After(h.nextAt) {
+		h.nextAt = nextAt
+	}
+}
+
+func (s *Service) Done(job *domain.CrawlJob) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	h, ok := s.hosts[util.Host(job.URL)]
+	if !ok || h.active == 0 {
+		return
+	}
+
+	h.active--
+
+	key := h.connectionKey()
+	s.activeConnections[key]--
+	if s.activeConnections[key] <= 0 {
+		delete(s.activeConnections, key)
+	}
+}
+
+func (s *Service) State() []domain.HostCrawlState {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	states := make([]domain.HostCrawlState, 0, len(s.hosts))
+
+	for _, h := range s.hosts {
+		states = append(states, domain.HostCrawlState{
+			Host:        h.name,
+			IP:          h.ip,
+			Queued:      len(h.queue),
+			Active:      h.active,
+			Delay:       s.delay(h),
+			CrawlDelay:  h.crawlDelay,
+			Backoff:     h.backoff,
+			NextCrawlAt: h.nextAt,
+		})
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Host < states[j].Host
+	})
+
+	return states
 }
@@ -2,112 +2,324 @@ package service
 
 import (
 	"crawlquery/api/domain"
+	"errors"
 	"testing"
 	"time"
 )
 
-func TestCheckAndThrottle(t *testing.T) {
-	service := NewService()
-
-	// Test adding a new URL
-	url := domain.URL("http://example.com")
-	throttled, err := service.CheckAndThrottle(url)
+type clock struct {
+	now time.Time
+}
 
-	if err != nil {
-		t.Errorf("expected no error, got %v", err)
-	}
+func (c *clock) Now() time.Time {
+	return c.now
+}
 
-	if !throttled {
-		t.Errorf("expected to throttle URL %s, but it was not throttled", url)
-	}
+func (c *clock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
 
-	// Test throttling the same URL again
-	throttled, err = service.CheckAndThrottle(url)
+func newClock() *clock {
+	return &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
 
-	if err != nil {
-		t.Errorf("expected no error, got %v", err)
+func job(url domain.URL) *domain.CrawlJob {
+	return &domain.CrawlJob{
+		PageID: domain.PageID(url),
+		URL:    url,
 	}
+}
 
-	if throttled {
-		t.Errorf("expected not to throttle URL %s, but it was throttled", url)
+func enqueue(t *testing.T, s *Service, urls ...domain.URL) {
+	t.Helper()
+	for _, url := range urls {
+		if err := s.Enqueue(job(url)); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
 	}
+}
 
-	// Test adding a different URL
-	anotherURL := domain.URL("http://another-example.com")
-	throttled, err = service.CheckAndThrottle(anotherURL)
-
+func next(t *testing.T, s *Service) *domain.CrawlJob {
+	t.Helper()
+	j, err := s.Next()
 	if err != nil {
-		t.Errorf("expected no error, got %v", err)
+		t.Fatalf("expected a job, got %v", err)
 	}
+	return j
+}
 
-	if !throttled {
-		t.Errorf("expected to throttle URL %s, but it was not throttled", anotherURL)
+func expectEmpty(t *testing.T, s *Service) {
+	t.Helper()
+	if j, err := s.Next(); err != domain.ErrCrawlQueueEmpty {
+		t.Fatalf("expected an empty queue, got %v and %v", j, err)
 	}
+}
 
-	// Ensure the second URL is not throttled again
-	throttled, err = service.CheckAndThrottle(anotherURL)
+func TestEnqueue(t *testing.T) {
+	t.Run("rejects invalid urls", func(t *testing.T) {
+		s := NewService()
 
-	if err != nil {
-		t.Errorf("expected no error, got %v", err)
-	}
+		if err := s.Enqueue(job("not a url")); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
 
-	if throttled {
-		t.Errorf("expected not to throttle URL %s, but it was throttled", anotherURL)
-	}
+	t.Run("ignores jobs already queued", func(t *testing.T) {
+		s := NewService()
+
+		enqueue(t, s, "http://example.com/a", "http://example.com/a")
+
+		state := s.State()
+		if len(state) != 1 || state[0].Queued != 1 {
+			t.Errorf("expected 1 queued job, got %+v", state)
+		}
+	})
 }
 
-func TestCheckAndThrottleInvalidURL(t *testing.T) {
-	service := NewService()
+func TestNext(t *testing.T) {
+	t.Run("takes hosts in turn", func(t *testing.T) {
+		c := newClock()
+		s := NewService(WithClock(c.Now))
 
-	// Test adding an invalid URL
-	invalidURL := domain.URL("http//google.com")
-	_, err := service.CheckAndThrottle(invalidURL)
+		enqueue(t, s,
+			"http://example.com/1",
+			"http://example.com/2",
+			"http://example.net/1",
+			"http://example.net/2",
+		)
 
-	if err == nil {
-		t.Errorf("expected an error, got nil")
-	}
+		first := next(t, s)
+		s.Done(first)
+		second := next(t, s)
+		s.Done(second)
+
+		if first.URL != "http://example.com/1" || second.URL != "http://example.net/1" {
+			t.Errorf("expected one job from each host, got %s and %s", first.URL, second.URL)
+		}
+
+		third := next(t, s)
+		if third.URL != "http://example.com/2" {
+			t.Errorf("expected http://example.com/2, got %s", third.URL)
+		}
+	})
+
+	t.Run("takes the best job of the ready hosts", func(t *testing.T) {
+		c := newClock()
+		s := NewService(WithClock(c.Now), WithMaxConnectionsPerHost(2))
+
+		for _, j := range []*domain.CrawlJob{
+			{PageID: "1", URL: "http://example.com/1", Priority: 0.2},
+			{PageID: "2", URL: "http://example.com/2", Priority: 0.8},
+			{PageID: "3", URL: "http://example.net/3", Priority: 0.5},
+			{PageID: "4", URL: "http://example.com/4", Priority: 0.2},
+		} {
+			if err := s.Enqueue(j); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+
+		for _, want := range []domain.PageID{"2", "3", "1"} {
+			if j := next(t, s); j.PageID != want {
+				t.Errorf("expected job %s, got %s", want, j.PageID)
+			}
+		}
+
+		// example.com has both its connections in use
+		expectEmpty(t, s)
+	})
+
+	t.Run("waits the rate limit between requests to a host", func(t *testing.T) {
+		c := newClock()
+		s := NewService(WithClock(c.Now), WithRateLimit(20*time.Second))
+
+		enqueue(t, s, "http://example.com/1", "http://www.example.com/2")
+
+		s.Done(next(t, s))
+		expectEmpty(t, s)
+
+		c.Advance(19 * time.Second)
+		expectEmpty(t, s)
+
+		c.Advance(time.Second)
+		if j := next(t, s); j.URL != "http://www.example.com/2" {
+			t.Errorf("expected http://www.example.com/2, got %s", j.URL)
+		}
+	})
+
+	t.Run("caps connections per host", func(t *testing.T) {
+		c := newClock()
+		s := NewService(WithClock(c.Now), WithMaxConnectionsPerHost(2))
+
+		enqueue(t, s, "http://example.com/1", "http://example.com/2", "http://example.com/3")
+
+		first := next(t, s)
+		next(t, s)
+		expectEmpty(t, s)
+
+		s.Done(first)
+		next(t, s)
+	})
+
+	t.Run("caps connections per ip", func(t *testing.T) {
+		c := newClock()
+		s := NewService(
+			WithClock(c.Now),
+			WithMaxConnectionsPerIP(1),
+			WithResolver(func(host string) (string, error) {
+				return "10.0.0.1", nil
+			}),
+		)
+
+		enqueue(t, s, "http://example.com/1", "http://example.net/1")
+
+		first := next(t, s)
+		expectEmpty(t, s)
+
+		s.Done(first)
+		if j := next(t, s); j.URL != "http://example.net/1" {
+			t.Errorf("expected http://example.net/1, got %s", j.URL)
+		}
+
+		if ip := s.State()[0].IP; ip != "10.0.0.1" {
+			t.Errorf("expected ip 10.0.0.1, got %s", ip)
+		}
+	})
+
+	t.Run("counts hosts that fail to resolve on their own", func(t *testing.T) {
+		c := newClock()
+		s := NewService(
+			WithClock(c.Now),
+			WithMaxConnectionsPerIP(1),
+			WithResolver(func(host string) (string, error) {
+				return "", errors.New("no such host")
+			}),
+		)
+
+		enqueue(t, s, "http://example.com/1", "http://example.net/1")
+
+		next(t, s)
+		next(t, s)
+	})
+
+	t.Run("forgets idle hosts", func(t *testing.T) {
+		c := newClock()
+		s := NewService(WithClock(c.Now), WithRateLimit(time.Second))
+
+		enqueue(t, s, "http://example.com/1")
+
+		s.Done(next(t, s))
+		expectEmpty(t, s)
+
+		if len(s.State()) != 1 {
+			t.Errorf("expected the host to be kept until its delay passes")
+		}
+
+		c.Advance(time.Second)
+		expectEmpty(t, s)
+
+		if len(s.State()) != 0 {
+			t.Errorf("expected the host to be forgotten, got %+v", s.State())
+		}
+	})
 }
 
-func TestWithRateLimit(t *testing.T) {
-	rateLimit := 100 * time.Millisecond
-	service := NewService()
+func TestObserve(t *testing.T) {
+	t.Run("uses the robots.txt crawl delay", func(t *testing.T) {
+		c := newClock()
+		s := NewService(WithClock(c.Now), WithRateLimit(time.Second))
 
-	// Apply rate limit option
-	WithRateLimit(rateLimit)(service)
+		enqueue(t, s, "http://example.com/1", "http://example.com/2")
 
-	// Add a URL
-	url := domain.URL("http://example.com")
-	throttled, err := service.CheckAndThrottle(url)
+		first := next(t, s)
+		s.Observe(first.URL, domain.CrawlOutcome{StatusCode: 200, CrawlDelay: 30 * time.Second})
+		s.Done(first)
 
-	if err != nil {
-		t.Errorf("expected no error, got %v", err)
-	}
+		c.Advance(29 * time.Second)
+		expectEmpty(t, s)
 
-	if !throttled {
-		t.Errorf("expected to throttle URL %s, but it was not throttled", url)
-	}
+		c.Advance(time.Second)
+		next(t, s)
+	})
 
-	// Ensure URL is throttled immediately
-	throttled, err = service.CheckAndThrottle(url)
+	t.Run("caps the crawl delay", func(t *testing.T) {
+		c := newClock()
+		s := NewService(WithClock(c.Now), WithMaxCrawlDelay(10*time.Second))
 
-	if err != nil {
-		t.Errorf("expected no error, got %v", err)
-	}
+		enqueue(t, s, "http://example.com/1")
 
-	if throttled {
-		t.Errorf("expected not to throttle URL %s immediately after, but it was throttled", url)
-	}
+		first := next(t, s)
+		s.Observe(first.URL, domain.CrawlOutcome{StatusCode: 200, CrawlDelay: time.Hour})
 
-	// Wait for rate limit duration
-	time.Sleep(rateLimit + 10*time.Millisecond)
+		if delay := s.State()[0].Delay; delay != 10*time.Second {
+			t.Errorf("expected a delay of 10s, got %s", delay)
+		}
+	})
 
-	// Ensure URL is throttled again after rate limit duration
-	throttled, err = service.CheckAndThrottle(url)
-	if err != nil {
-		t.Errorf("expected no error, got %v", err)
-	}
+	t.Run("backs off when rate limited", func(t *testing.T) {
+		c := newClock()
+		s := NewService(WithClock(c.Now), WithRateLimit(time.Second), WithMaxBackoff(time.Minute))
 
-	if !throttled {
-		t.Errorf("expected to throttle URL %s after rate limit duration, but it was not throttled", url)
-	}
+		enqueue(t, s, "http://example.com/1")
+
+		first := next(t, s)
+
+		s.Observe(first.URL, domain.CrawlOutcome{StatusCode: 429})
+		if backoff := s.State()[0].Backoff; backoff != 2*time.Second {
+			t.Errorf("expected a backoff of 2s, got %s", backoff)
+		}
+
+		s.Observe(first.URL, domain.CrawlOutcome{StatusCode: 503})
+		if backoff := s.State()[0].Backoff; backoff != 4*time.Second {
+			t.Errorf("expected a backoff of 4s, got %s", backoff)
+		}
+
+		s.Observe(first.URL, domain.CrawlOutcome{StatusCode: 429, RetryAfter: 30 * time.Second})
+		if backoff := s.State()[0].Backoff; backoff != 30*time.Second {
+			t.Errorf("expected a backoff of 30s, got %s", backoff)
+		}
+
+		s.Observe(first.URL, domain.CrawlOutcome{StatusCode: 429})
+		if backoff := s.State()[0].Backoff; backoff != time.Minute {
+			t.Errorf("expected the backoff capped at 1m, got %s", backoff)
+		}
+
+		if next := s.State()[0].NextCrawlAt; !next.Equal(c.Now().Add(time.Minute)) {
+			t.Errorf("expected the next crawl in 1m, got %s", next)
+		}
+	})
+
+	t.Run("recovers from backoff on success", func(t *testing.T) {
+		c := newClock()
+		s := NewService(WithClock(c.Now), WithRateLimit(time.Second))
+
+		enqueue(t, s, "http://example.com/1")
+
+		first := next(t, s)
+
+		s.Observe(first.URL, domain.CrawlOutcome{StatusCode: 429, RetryAfter: 4 * time.Second})
+		s.Observe(first.URL, domain.CrawlOutcome{StatusCode: 200})
+		if backoff := s.State()[0].Backoff; backoff != 2*time.Second {
+			t.Errorf("expected a backoff of 2s, got %s", backoff)
+		}
+
+		s.Observe(first.URL, domain.CrawlOutcome{StatusCode: 200})
+		s.Observe(first.URL, domain.CrawlOutcome{StatusCode: 200})
+		if backoff := s.State()[0].Backoff; backoff != 0 {
+			t.Errorf("expected no backoff, got %s", backoff)
+		}
+	})
+
+	t.Run("backs off slow hosts", func(t *testing.T) {
+		c := newClock()
+		s := NewService(WithClock(c.Now), WithSlowResponse(5*time.Second))
+
+		enqueue(t, s, "http://example.com/1")
+
+		first := next(t, s)
+		s.Observe(first.URL, domain.CrawlOutcome{StatusCode: 200, Duration: 8 * time.Second})
+
+		if backoff := s.State()[0].Backoff; backoff != 16*time.Second {
+			t.Errorf("expected a backoff of 16s, got %s", backoff)
+		}
+	})
 }
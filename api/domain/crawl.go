@@ -4,10 +4,14 @@ import (
 	"context"
 	"errors"
 	"time"
+
+	"github.com/gin-gonic/gin"
 )
 
 var ErrCrawlJobNotFound = errors.New("crawl job not found")
 var ErrCrawlQueueEmpty = errors.New("crawl queue is empty")
+var ErrCrawlJobStale = errors.New("crawl job was in progress for too long")
+var ErrCrawlDisallowed = errors.New("disallowed by robots.txt")
 
 type CrawlLogID string
 type CrawlStatus uint8
@@ -17,6 +21,8 @@ const (
 	CrawlStatusInProgress
 	CrawlStatusCompleted
 	CrawlStatusFailed
+	// CrawlStatusDead is given to jobs that failed too many times to retry.
+	CrawlStatusDead
 )
 
 func (cs CrawlStatus) String() string {
@@ -29,24 +35,44 @@ func (cs CrawlStatus) String() string {
 		return "completed"
 	case CrawlStatusFailed:
 		return "failed"
+	case CrawlStatusDead:
+		return "dead"
 	default:
 		return "unknown"
 	}
 }
 
+// CrawlJob is a page to crawl. Jobs with a higher Priority are crawled
+// first. Attempts counts the crawls started for it since it last completed.
+// A failed job is retried once NextAttemptAt has passed, and a completed one
+// is crawled again then if it is set.
 type CrawlJob struct {
-	PageID    PageID
-	URL       URL
-	ShardID   ShardID
-	Status    CrawlStatus
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	PageID        PageID
+	URL           URL
+	Host          Host
+	ShardID       ShardID
+	ScopeID       ScopeID
+	Status        CrawlStatus
+	Priority      float64
+	Attempts      int
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
 }
 
 type CrawlJobRepository interface {
 	Get(pageID PageID) (*CrawlJob, error)
 	Save(cj *CrawlJob) error
 	ListByStatus(limit int, status CrawlStatus) ([]*CrawlJob, error)
+	// ListDue lists pending jobs, failed jobs due a retry and completed jobs
+	// due a recrawl by now, highest priority first, taking at most perHost
+	// jobs for any one host.
+	ListDue(limit, perHost int, now time.Time) ([]*CrawlJob, error)
+	// CountByHost counts the jobs for pages on a host.
+	CountByHost(host Host) (int, error)
+	// ListStale lists jobs in progress that were last updated before the
+	// given time.
+	ListStale(limit int, updatedBefore time.Time) ([]*CrawlJob, error)
 }
 
 type CrawlLog struct {
@@ -70,23 +96,144 @@ type CrawlRateLimiter interface {
 	Limit(job *CrawlJob) bool
 }
 
+// CrawlPriorityService decides how soon a page is crawled relative to
+// others.
+type CrawlPriorityService interface {
+	Priority(page *Page) float64
+}
+
 type CrawlService interface {
 	CreateJob(page *Page) error
 	RunCrawlProcess(ctx context.Context) error
 }
 
+// CrawlFetchError is returned when a node reached a site but the site
+// answered with an error status.
+type CrawlFetchError struct {
+	StatusCode int
+	// RetryAfter is how long the site asked crawlers to wait before trying
+	// again, if it said.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *CrawlFetchError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CrawlFetchError) Unwrap() error {
+	return e.Err
+}
+
+// CrawlOutcome is how a site answered a crawl, used to decide how soon it may
+// be crawled again.
+type CrawlOutcome struct {
+	StatusCode int
+	Duration   time.Duration
+	// CrawlDelay is the delay between requests the site's robots.txt asks for.
+	CrawlDelay time.Duration
+	RetryAfter time.Duration
+}
+
+// HostCrawlState is a snapshot of how a host is being crawled.
+type HostCrawlState struct {
+	Host Host
+	// IP is the address connections to the host are counted against, when
+	// connections per IP are capped.
+	IP          string
+	Queued      int
+	Active      int
+	Delay       time.Duration
+	CrawlDelay  time.Duration
+	Backoff     time.Duration
+	NextCrawlAt time.Time
+}
+
+// CrawlThrottleService queues crawl jobs per host and hands them out no
+// faster than each host allows.
 type CrawlThrottleService interface {
-	CheckAndThrottle(url URL) (bool, error)
+	// Enqueue adds a job to its host's queue. Jobs already queued are ignored.
+	Enqueue(job *CrawlJob) error
+	// Next returns the next job whose host may be crawled now, or
+	// ErrCrawlQueueEmpty when there is none.
+	Next() (*CrawlJob, error)
+	// Observe adjusts the delay for the URL's host to how it answered.
+	Observe(url URL, outcome CrawlOutcome)
+	// Done releases the connection held by a job returned from Next.
+	Done(job *CrawlJob)
+	State() []HostCrawlState
+}
+
+type CrawlThrottleHandler interface {
+	Hosts(c *gin.Context)
+}
+
+// URLFilterDecision is whether a URL may be added to the crawl and the rule
+// or limit that decided it. Rule is empty when no rule matched and the
+// default applied.
+type URLFilterDecision struct {
+	URL     URL
+	Allowed bool
+	Rule    string
+	Reason  string
+}
+
+// URLFilterService decides which discovered URLs are worth crawling.
+type URLFilterService interface {
+	Evaluate(url URL) URLFilterDecision
+}
+
+type URLFilterHandler interface {
+	Evaluate(c *gin.Context)
 }
 
 const CrawlCompletedKey = "crawl.completed"
 
+// Anchor is a link found by a crawl, with the text of the anchor and its rel
+// attribute.
+type Anchor struct {
+	URL  URL
+	Text string
+	Rel  string
+}
+
 type CrawlCompleted struct {
 	PageID      PageID
 	ShardID     ShardID
 	URL         URL
 	ContentHash ContentHash
 	Links       []URL
+	// Anchors is empty when the node that crawled the page only reported
+	// link targets.
+	Anchors []Anchor
+	// FinalURL is where the page was fetched from after following redirects.
+	FinalURL URL
+	// Redirects are the URLs that redirected on the way to FinalURL, in
+	// order, starting with URL.
+	Redirects []URL
+	// Canonical is the URL the page names as its canonical version, if any.
+	// It is left empty when that URL is on another site, see util.Site.
+	Canonical URL
+	// Feeds are the RSS and Atom feeds the page links to.
+	Feeds []URL
+	// NoIndex is set when the page asks to be kept out of the index.
+	NoIndex bool
+}
+
+// Target returns the URL of the page that the crawled page stands in for and
+// why, preferring the canonical URL the page names over where it redirected
+// to. It returns an empty URL when the page was neither redirected nor names
+// a canonical URL.
+func (c CrawlCompleted) Target() (URL, PageAliasReason) {
+	if c.Canonical != "" {
+		return c.Canonical, PageAliasCanonical
+	}
+
+	if c.FinalURL != "" && c.FinalURL != c.URL {
+		return c.FinalURL, PageAliasRedirect
+	}
+
+	return "", ""
 }
 
 func (c CrawlCompleted) Key() EventKey {
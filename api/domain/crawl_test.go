@@ -0,0 +1,46 @@
+package domain_test
+
+import (
+	"crawlquery/api/domain"
+	"testing"
+)
+
+func TestCrawlCompletedTarget(t *testing.T) {
+	cases := []struct {
+		name       string
+		event      domain.CrawlCompleted
+		wantURL    domain.URL
+		wantReason domain.PageAliasReason
+	}{
+		{
+			name:  "stands for itself",
+			event: domain.CrawlCompleted{URL: "http://example.com/", FinalURL: "http://example.com/"},
+		},
+		{
+			name:       "redirected",
+			event:      domain.CrawlCompleted{URL: "http://example.com/", FinalURL: "https://example.com/"},
+			wantURL:    "https://example.com/",
+			wantReason: domain.PageAliasRedirect,
+		},
+		{
+			name: "canonical over redirect",
+			event: domain.CrawlCompleted{
+				URL:       "http://example.com/print",
+				FinalURL:  "https://example.com/print",
+				Canonical: "https://example.com/article",
+			},
+			wantURL:    "https://example.com/article",
+			wantReason: domain.PageAliasCanonical,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			url, reason := tc.event.Target()
+
+			if url != tc.wantURL || reason != tc.wantReason {
+				t.Errorf("Expected %q %q, got %q %q", tc.wantURL, tc.wantReason, url, reason)
+			}
+		})
+	}
+}
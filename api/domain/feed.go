@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrFeedNotFound = errors.New("feed not found")
+var ErrFeedDenied = errors.New("feed denied by the url filter")
+
+type FeedID string
+
+// Feed is an RSS or Atom feed found on a crawled page, polled for entries
+// that are new to the crawl. Interval is the time between polls, which
+// shortens while polls find new entries and lengthens while they don't.
+// ETag and LastModified are the validators of the last response, sent back
+// so that unchanged feeds aren't downloaded again.
+type Feed struct {
+	ID           FeedID
+	URL          URL
+	PageID       PageID
+	ETag         string
+	LastModified string
+	Interval     time.Duration
+	Failures     int
+	LastPolledAt time.Time
+	NextPollAt   time.Time
+	CreatedAt    time.Time
+}
+
+type FeedRepository interface {
+	Get(id FeedID) (*Feed, error)
+	Save(feed *Feed) error
+	// ListDue lists feeds due a poll by now, longest overdue first.
+	ListDue(limit int, now time.Time) ([]*Feed, error)
+}
+
+type FeedService interface {
+	// Register adds a feed found on a page to be polled, unless it is known.
+	Register(url URL, pageID PageID) (*Feed, error)
+	// Poll fetches a feed and adds its new entries to the crawl, returning
+	// how many pages it created.
+	Poll(feed *Feed) (int, error)
+	PollDue() error
+}
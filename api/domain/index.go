@@ -37,10 +37,13 @@ type IndexJob struct {
 	PageID      PageID      `json:"page_id"`
 	URL         URL         `json:"url"`
 	ContentHash ContentHash `json:"content_hash"`
-	ShardID     ShardID     `json:"shard_id"`
-	Status      IndexStatus `json:"status"`
-	CreatedAt   time.Time   `json:"created_at"`
-	UpdatedAt   time.Time   `json:"updated_at"`
+	// AnchorHash identifies the anchor texts the page was last indexed
+	// with, or is empty when it had none.
+	AnchorHash string      `json:"anchor_hash"`
+	ShardID    ShardID     `json:"shard_id"`
+	Status     IndexStatus `json:"status"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
 }
 
 type IndexService interface {
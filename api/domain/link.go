@@ -2,6 +2,7 @@ package domain
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -10,19 +11,74 @@ import (
 var ErrLinkAlreadyExists = errors.New("link already exists")
 
 type Link struct {
-	SrcID     PageID    `json:"src_id"`
-	DstID     PageID    `json:"dst_id"`
+	SrcID PageID `json:"src_id"`
+	DstID PageID `json:"dst_id"`
+	// Internal is set when both pages are on the same site, see util.Site.
+	Internal bool `json:"internal"`
+	// Text is the anchor text of the link and Rel its rel attribute.
+	Text      string    `json:"text"`
+	Rel       string    `json:"rel"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// Link rel values that ask search engines not to treat the link as an
+// endorsement of its target.
+const (
+	RelNofollow  = "nofollow"
+	RelSponsored = "sponsored"
+	RelUGC       = "ugc"
+)
+
+// HasRel reports whether rel is one of the link's space separated rel values.
+func (l *Link) HasRel(rel string) bool {
+	for _, r := range strings.Fields(l.Rel) {
+		if strings.EqualFold(r, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// Nofollow reports whether the link should pass no authority to its target,
+// which is the case for nofollow, sponsored and user generated links.
+func (l *Link) Nofollow() bool {
+	return l.HasRel(RelNofollow) || l.HasRel(RelSponsored) || l.HasRel(RelUGC)
+}
+
+// Host is the lower cased host name of a site, see util.Host.
+type Host string
+
+// HostLink aggregates the links from the pages of one site to the pages of
+// another. Hosts are registrable domains, see util.Site, so the subdomains
+// of a site share their links.
+type HostLink struct {
+	SrcHost   Host      `json:"src_host"`
+	DstHost   Host      `json:"dst_host"`
+	Count     int       `json:"count"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type HostLinkRepository interface {
+	Increment(srcHost, dstHost Host, updatedAt time.Time) error
+	UpdateAll(links []*HostLink) error
+	GetAll() ([]*HostLink, error)
+}
+
 type LinkRepository interface {
 	Create(*Link) error
+	SetInternal(srcID, dstID PageID, internal bool) error
 	GetAll() ([]*Link, error)
+	GetAllByDstID(dstID PageID) ([]*Link, error)
+	Count() (int, error)
 }
 
 type LinkService interface {
 	Create(srcID PageID, url URL) (*Link, error)
 	GetAll() ([]*Link, error)
+	Count() (int, error)
+	GetHostLinks() ([]*HostLink, error)
+	BackfillHostLinks() error
+	GetAnchorTexts(dstID PageID) ([]string, error)
 }
 
 type LinkHandler interface {
@@ -32,8 +88,11 @@ type LinkHandler interface {
 const LinkCreatedKey = "link.created"
 
 type LinkCreated struct {
-	Link   *Link
+	Link *Link
+	// DstURL is the canonical form of the link's target, which identifies
+	// it, and URL the target as it was linked, which is fetched.
 	DstURL URL
+	URL    URL
 }
 
 func (l LinkCreated) Key() EventKey {
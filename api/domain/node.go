@@ -43,7 +43,8 @@ type NodeService interface {
 	ListByShardID(shardID ShardID) ([]*Node, error)
 	Randomize(nodes []*Node) []*Node
 	SendCrawlJob(ctx context.Context, node *Node, crawlJob *CrawlJob) (*dto.CrawlResponse, error)
-	SendIndexJob(ctx context.Context, node *Node, indexJob *IndexJob) error
+	SendIndexJob(ctx context.Context, node *Node, indexJob *IndexJob, anchors ...string) error
+	SendQuery(ctx context.Context, node *Node, query string) (*dto.QueryResponse, error)
 	Auth(key string) (*Node, error)
 }
 
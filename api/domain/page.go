@@ -10,15 +10,20 @@ import (
 var ErrPageNotFound = errors.New("page not found")
 var ErrPageAlreadyExists = errors.New("page already exists")
 var ErrPageVersionNotFound = errors.New("page version not found")
+var ErrPageAliasNotFound = errors.New("page alias not found")
 
 type PageID string
 type URL string
 type ContentHash string
 
+// Page is a URL known to the crawl. Pages found from a crawl scope carry its
+// ID and how many links away from the scope's seeds they were found.
 type Page struct {
 	ID        PageID
 	URL       URL
 	ShardID   ShardID
+	ScopeID   ScopeID
+	Depth     int
 	CreatedAt time.Time
 }
 
@@ -37,14 +42,54 @@ type PageVersionRepository interface {
 	ListByPageID(pageID PageID) ([]*PageVersion, error)
 }
 
+// PageAliasReason says why a page was folded into another.
+type PageAliasReason string
+
+const (
+	// PageAliasCanonical is used when a page names another as its canonical
+	// version with a rel="canonical" link.
+	PageAliasCanonical PageAliasReason = "canonical"
+	// PageAliasRedirect is used when fetching a page redirects to another.
+	PageAliasRedirect PageAliasReason = "redirect"
+	// PageAliasLegacy is used for a page stored under the ID of its URL as
+	// it was written, before URLs were canonicalized.
+	PageAliasLegacy PageAliasReason = "legacy"
+)
+
+// PageAlias records that a page is a duplicate of another or redirects to it,
+// so that links to and from it count for the canonical page instead.
+type PageAlias struct {
+	PageID      PageID
+	CanonicalID PageID
+	Reason      PageAliasReason
+	CreatedAt   time.Time
+}
+
+type PageAliasRepository interface {
+	Get(pageID PageID) (*PageAlias, error)
+	GetAll() ([]*PageAlias, error)
+	// GetByCanonicalID returns the aliases folded straight into a page.
+	GetByCanonicalID(canonicalID PageID) ([]*PageAlias, error)
+	Save(alias *PageAlias) error
+}
+
 type PageRepository interface {
 	Get(id PageID) (*Page, error)
+	GetMany(ids []PageID) (map[PageID]*Page, error)
 	Create(p *Page) error
+	// SetScope moves a page into a scope at the given depth.
+	SetScope(id PageID, scopeID ScopeID, depth int) error
+	CountByScope(scopeID ScopeID) (int, error)
+	// ListAfter lists up to limit pages ordered by ID, starting after the
+	// page afterID.
+	ListAfter(afterID PageID, limit int) ([]*Page, error)
 }
 
 type PageService interface {
 	Get(id PageID) (*Page, error)
+	GetMany(ids []PageID) (map[PageID]*Page, error)
 	Create(url URL) (*Page, error)
+	CreateInScope(url URL, scopeID ScopeID, depth int) (*Page, error)
 }
 
 type PageHandler interface {
@@ -60,3 +105,13 @@ type PageCreated struct {
 func (p PageCreated) Key() EventKey {
 	return PageCreatedKey
 }
+
+const PageVersionCreatedKey = "page.version.created"
+
+type PageVersionCreated struct {
+	PageVersion *PageVersion
+}
+
+func (p PageVersionCreated) Key() EventKey {
+	return PageVersionCreatedKey
+}
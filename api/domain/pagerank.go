@@ -9,13 +9,40 @@ type PageRank struct {
 	PageRank float64
 }
 
+// PageRankConvergence reports how a PageRank run ended. Residual is the L1
+// distance between the last two iterations.
+type PageRankConvergence struct {
+	Iterations int
+	Residual   float64
+	Converged  bool
+}
+
 type PageRankService interface {
 	UpdatePageRanks() error
+	UpdatePageRanksIfChanged() (bool, error)
 	GetPageRank(pageID PageID) (float64, error)
+	GetPageRanks(pageIDs []PageID) (map[PageID]float64, error)
+	GetHostRank(host Host) (float64, error)
+	GetHostRanks(hosts []Host) (map[Host]float64, error)
+	MaxPageRank() (float64, error)
+	MaxHostRank() (float64, error)
+	LastConvergence() PageRankConvergence
 	UpdatePageRanksEvery(duration time.Duration)
 }
 
 type PageRankRepository interface {
 	Get(pageID PageID) (float64, error)
+	GetMany(pageIDs []PageID) (map[PageID]float64, error)
+	GetAll() (map[PageID]float64, error)
+	Max() (float64, error)
 	Update(pageID PageID, rank float64, createdAt time.Time) error
+	UpdateAll(ranks map[PageID]float64, createdAt time.Time) error
+}
+
+type HostRankRepository interface {
+	Get(host Host) (float64, error)
+	GetMany(hosts []Host) (map[Host]float64, error)
+	GetAll() (map[Host]float64, error)
+	Max() (float64, error)
+	UpdateAll(ranks map[Host]float64, createdAt time.Time) error
 }
@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"crawlquery/node/domain"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+var ErrInvalidQuery = errors.New("invalid query")
+
+// QueryResult is a query's rows merged across shards. FailedShards are the
+// shards no node answered for, whose rows are missing from the result.
+type QueryResult struct {
+	domain.QueryResult
+	FailedShards []ShardID
+}
+
+type QueryService interface {
+	Query(query string) (*QueryResult, error)
+}
+
+type QueryHandler interface {
+	Query(c *gin.Context)
+}
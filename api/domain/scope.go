@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var ErrScopeNotFound = errors.New("crawl scope not found")
+var ErrScopeInvalid = errors.New("crawl scope needs a name, a seed url and no negative limits")
+var ErrPageOutOfScope = errors.New("page is outside the crawl scope")
+var ErrScopeBudgetSpent = errors.New("crawl scope has no page budget left")
+
+type ScopeID string
+
+// Scope is a focused crawl that starts from its seed URLs and only follows
+// links to its domains, up to a number of links away from the seeds and a
+// number of pages in total. A max depth or page budget of zero is no limit.
+type Scope struct {
+	ID         ScopeID
+	Name       string
+	Seeds      []URL
+	Domains    []Host
+	MaxDepth   int
+	PageBudget int
+	CreatedAt  time.Time
+}
+
+// AllowsHost reports whether host is one of the scope's domains or a
+// subdomain of one.
+func (s *Scope) AllowsHost(host Host) bool {
+	for _, d := range s.Domains {
+		if host == d || strings.HasSuffix(string(host), "."+string(d)) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsDepth reports whether a page depth links away from the seeds is
+// within the scope.
+func (s *Scope) AllowsDepth(depth int) bool {
+	return s.MaxDepth == 0 || depth <= s.MaxDepth
+}
+
+// AllowsPages reports whether a scope that has pages pages may have another.
+func (s *Scope) AllowsPages(pages int) bool {
+	return s.PageBudget == 0 || pages < s.PageBudget
+}
+
+type ScopeRepository interface {
+	Get(id ScopeID) (*Scope, error)
+	List() ([]*Scope, error)
+	Save(scope *Scope) error
+}
+
+type ScopeService interface {
+	Create(scope *Scope) (*Scope, error)
+	Get(id ScopeID) (*Scope, error)
+	List() ([]*Scope, error)
+	// Pages counts the pages in a scope.
+	Pages(id ScopeID) (int, error)
+}
+
+type ScopeHandler interface {
+	Create(c *gin.Context)
+	Get(c *gin.Context)
+	List(c *gin.Context)
+}
@@ -0,0 +1,47 @@
+package domain_test
+
+import (
+	"crawlquery/api/domain"
+	"testing"
+)
+
+func TestScopeAllowsHost(t *testing.T) {
+	scope := &domain.Scope{Domains: []domain.Host{"example.com", "recipes.example.net"}}
+
+	cases := map[domain.Host]bool{
+		"example.com":         true,
+		"blog.example.com":    true,
+		"recipes.example.net": true,
+		"example.net":         false,
+		"badexample.com":      false,
+		"example.com.evil.io": false,
+	}
+
+	for host, want := range cases {
+		if got := scope.AllowsHost(host); got != want {
+			t.Errorf("AllowsHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestScopeLimits(t *testing.T) {
+	t.Run("limits depth and pages", func(t *testing.T) {
+		scope := &domain.Scope{MaxDepth: 2, PageBudget: 10}
+
+		if !scope.AllowsDepth(2) || scope.AllowsDepth(3) {
+			t.Errorf("Expected depths up to 2 to be allowed")
+		}
+
+		if !scope.AllowsPages(9) || scope.AllowsPages(10) {
+			t.Errorf("Expected up to 10 pages to be allowed")
+		}
+	})
+
+	t.Run("treats zero as no limit", func(t *testing.T) {
+		scope := &domain.Scope{}
+
+		if !scope.AllowsDepth(100) || !scope.AllowsPages(100000) {
+			t.Errorf("Expected no limits")
+		}
+	})
+}
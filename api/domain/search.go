@@ -8,9 +8,43 @@ import (
 )
 
 var ErrNoNodesAvailable = errors.New("no nodes available for search")
+var ErrInvalidSearchQuery = errors.New("invalid search query")
+
+// SearchSignal scores pages from data held by the API rather than the
+// shards, to be mixed into the blended score of search results.
+type SearchSignal interface {
+	Name() string
+	// Values returns a non negative value for each page. Pages left out
+	// count as 0.
+	Values(pageIDs []string) (map[string]float64, error)
+	// Max returns the largest value any page can have, which values are
+	// normalised against.
+	Max() (float64, error)
+}
+
+// MaxSearchLimit is the most results a single search can ask for.
+const MaxSearchLimit = 100
+
+// SearchRequest selects a window of the merged results for a query.
+type SearchRequest struct {
+	Query  string `json:"q"`
+	Offset int    `json:"o"`
+	Limit  int    `json:"l"`
+}
+
+// SearchResults is one window of the results merged from every shard.
+type SearchResults struct {
+	Results []domain.Result
+	// TotalHits estimates how many pages matched across every shard.
+	// Shards that didn't answer are assumed to match as many as the
+	// average shard that did.
+	TotalHits int
+	// Next requests the following window, or is nil on the last one.
+	Next *SearchRequest
+}
 
 type SearchService interface {
-	Search(term string) ([]domain.Result, error)
+	Search(term string, offset, limit int) (*SearchResults, error)
 }
 
 type SearchHandler interface {
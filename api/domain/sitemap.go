@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var ErrSitemapNotFound = errors.New("no sitemap found")
+var ErrSitemapSeedNotFound = errors.New("sitemap seed not found")
+
+// SitemapSeedResult counts what seeding a site from its sitemaps found.
+// Created pages are new to the crawl and Recrawled pages were known but
+// have changed since they were last crawled.
+type SitemapSeedResult struct {
+	Sitemaps  []URL
+	URLs      int
+	Created   int
+	Recrawled int
+	Skipped   int
+}
+
+type SitemapSeedID string
+type SitemapSeedStatus uint8
+
+const (
+	SitemapSeedStatusRunning SitemapSeedStatus = iota
+	SitemapSeedStatusCompleted
+	SitemapSeedStatusFailed
+)
+
+func (s SitemapSeedStatus) String() string {
+	switch s {
+	case SitemapSeedStatusRunning:
+		return "running"
+	case SitemapSeedStatusCompleted:
+		return "completed"
+	case SitemapSeedStatusFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// SitemapSeed is a seed of a site from its sitemaps running in the
+// background. Result is set once it has completed and Error once it has
+// failed.
+type SitemapSeed struct {
+	ID        SitemapSeedID
+	Site      URL
+	Status    SitemapSeedStatus
+	Result    *SitemapSeedResult
+	Error     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// SitemapService adds the pages a site lists in its sitemaps to the crawl.
+type SitemapService interface {
+	// Discover returns the sitemaps a site's robots.txt names, or its
+	// sitemap.xml when it names none.
+	Discover(site URL) ([]URL, error)
+	Seed(site URL) (*SitemapSeedResult, error)
+	// Start seeds a site in the background, returning the seed to follow it
+	// by. A site already being seeded returns the running seed.
+	Start(site URL) (*SitemapSeed, error)
+	GetSeed(id SitemapSeedID) (*SitemapSeed, error)
+}
+
+type SitemapHandler interface {
+	Seed(c *gin.Context)
+	GetSeed(c *gin.Context)
+}
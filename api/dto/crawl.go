@@ -1,6 +1,7 @@
 package dto
 
 import (
+	"crawlquery/api/domain"
 	"encoding/json"
 	"time"
 )
@@ -22,3 +23,177 @@ type CrawlJob struct {
 type CreateCrawlJobResponse struct {
 	CrawlJob CrawlJob `json:"crawl_job"`
 }
+
+// CrawlHost is how a host is being crawled. Durations are in seconds.
+type CrawlHost struct {
+	Host        string    `json:"host"`
+	IP          string    `json:"ip,omitempty"`
+	Queued      int       `json:"queued"`
+	Active      int       `json:"active"`
+	Delay       float64   `json:"delay"`
+	CrawlDelay  float64   `json:"crawl_delay"`
+	Backoff     float64   `json:"backoff"`
+	NextCrawlAt time.Time `json:"next_crawl_at"`
+}
+
+type ListCrawlHostsResponse struct {
+	Hosts []CrawlHost `json:"hosts"`
+}
+
+func NewListCrawlHostsResponse(states []domain.HostCrawlState) *ListCrawlHostsResponse {
+	res := &ListCrawlHostsResponse{
+		Hosts: []CrawlHost{},
+	}
+
+	for _, state := range states {
+		res.Hosts = append(res.Hosts, CrawlHost{
+			Host:        string(state.Host),
+			IP:          state.IP,
+			Queued:      state.Queued,
+			Active:      state.Active,
+			Delay:       state.Delay.Seconds(),
+			CrawlDelay:  state.CrawlDelay.Seconds(),
+			Backoff:     state.Backoff.Seconds(),
+			NextCrawlAt: state.NextCrawlAt,
+		})
+	}
+
+	return res
+}
+
+type EvaluateURLFilterRequest struct {
+	URLs []string `json:"urls" binding:"required"`
+}
+
+// URLFilterDecision is whether a URL would be crawled and why.
+type URLFilterDecision struct {
+	URL     string `json:"url"`
+	Allowed bool   `json:"allowed"`
+	Rule    string `json:"rule,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+type EvaluateURLFilterResponse struct {
+	Decisions []URLFilterDecision `json:"decisions"`
+}
+
+func NewEvaluateURLFilterResponse(decisions []domain.URLFilterDecision) *EvaluateURLFilterResponse {
+	res := &EvaluateURLFilterResponse{
+		Decisions: []URLFilterDecision{},
+	}
+
+	for _, decision := range decisions {
+		res.Decisions = append(res.Decisions, URLFilterDecision{
+			URL:     string(decision.URL),
+			Allowed: decision.Allowed,
+			Rule:    decision.Rule,
+			Reason:  decision.Reason,
+		})
+	}
+
+	return res
+}
+
+type CreateCrawlScopeRequest struct {
+	Name       string   `json:"name" binding:"required"`
+	Seeds      []string `json:"seeds" binding:"required,min=1"`
+	Domains    []string `json:"domains"`
+	MaxDepth   int      `json:"max_depth" binding:"min=0"`
+	PageBudget int      `json:"page_budget" binding:"min=0"`
+}
+
+// CrawlScope is a focused crawl and how many pages it has found.
+type CrawlScope struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Seeds      []string  `json:"seeds"`
+	Domains    []string  `json:"domains"`
+	MaxDepth   int       `json:"max_depth"`
+	PageBudget int       `json:"page_budget"`
+	Pages      int       `json:"pages"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func NewCrawlScope(scope *domain.Scope, pages int) CrawlScope {
+	res := CrawlScope{
+		ID:         string(scope.ID),
+		Name:       scope.Name,
+		Seeds:      []string{},
+		Domains:    []string{},
+		MaxDepth:   scope.MaxDepth,
+		PageBudget: scope.PageBudget,
+		Pages:      pages,
+		CreatedAt:  scope.CreatedAt,
+	}
+
+	for _, seed := range scope.Seeds {
+		res.Seeds = append(res.Seeds, string(seed))
+	}
+
+	for _, d := range scope.Domains {
+		res.Domains = append(res.Domains, string(d))
+	}
+
+	return res
+}
+
+type ListCrawlScopesResponse struct {
+	Scopes []CrawlScope `json:"scopes"`
+}
+
+type SeedSitemapsRequest struct {
+	URL string `json:"url" binding:"required,url"`
+}
+
+type SeedSitemapsResponse struct {
+	Sitemaps  []string `json:"sitemaps"`
+	URLs      int      `json:"urls"`
+	Created   int      `json:"created"`
+	Recrawled int      `json:"recrawled"`
+	Skipped   int      `json:"skipped"`
+}
+
+// SitemapSeedResponse is a seed of a site from its sitemaps. Result is set
+// once it has completed and Error once it has failed.
+type SitemapSeedResponse struct {
+	ID        string                `json:"id"`
+	Site      string                `json:"site"`
+	Status    string                `json:"status"`
+	Result    *SeedSitemapsResponse `json:"result,omitempty"`
+	Error     string                `json:"error,omitempty"`
+	CreatedAt time.Time             `json:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at"`
+}
+
+func NewSitemapSeedResponse(seed *domain.SitemapSeed) *SitemapSeedResponse {
+	res := &SitemapSeedResponse{
+		ID:        string(seed.ID),
+		Site:      string(seed.Site),
+		Status:    seed.Status.String(),
+		Error:     seed.Error,
+		CreatedAt: seed.CreatedAt,
+		UpdatedAt: seed.UpdatedAt,
+	}
+
+	if seed.Result != nil {
+		res.Result = NewSeedSitemapsResponse(seed.Result)
+	}
+
+	return res
+}
+
+func NewSeedSitemapsResponse(result *domain.SitemapSeedResult) *SeedSitemapsResponse {
+	res := &SeedSitemapsResponse{
+		Sitemaps:  []string{},
+		URLs:      result.URLs,
+		Created:   result.Created,
+		Recrawled: result.Recrawled,
+		Skipped:   result.Skipped,
+	}
+
+	for _, sitemap := range result.Sitemaps {
+		res.Sitemaps = append(res.Sitemaps, string(sitemap))
+	}
+
+	return res
+}
@@ -0,0 +1,48 @@
+package dto
+
+import (
+	"crawlquery/api/domain"
+)
+
+type QueryRequest struct {
+	Query string `json:"query" binding:"required"`
+}
+
+type QueryResponseColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// QueryResponse is the result of a query. Partial is set when some shards
+// failed, listed in FailedShards, and their rows are missing.
+type QueryResponse struct {
+	Columns      []QueryResponseColumn `json:"columns"`
+	Rows         [][]any               `json:"rows"`
+	Partial      bool                  `json:"partial"`
+	FailedShards []uint                `json:"failed_shards,omitempty"`
+}
+
+func NewQueryResponse(result *domain.QueryResult) *QueryResponse {
+	res := &QueryResponse{
+		Columns: []QueryResponseColumn{},
+		Rows:    [][]any{},
+	}
+
+	for _, col := range result.Columns {
+		res.Columns = append(res.Columns, QueryResponseColumn{
+			Name: col.Name,
+			Type: string(col.Type),
+		})
+	}
+
+	for _, row := range result.Rows {
+		res.Rows = append(res.Rows, row)
+	}
+
+	for _, shardID := range result.FailedShards {
+		res.FailedShards = append(res.FailedShards, uint(shardID))
+	}
+	res.Partial = len(res.FailedShards) > 0
+
+	return res
+}
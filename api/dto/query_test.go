@@ -0,0 +1,59 @@
+package dto_test
+
+import (
+	"crawlquery/api/domain"
+	"crawlquery/api/dto"
+	"reflect"
+	"testing"
+
+	nodeDomain "crawlquery/node/domain"
+)
+
+func TestNewQueryResponse(t *testing.T) {
+	t.Run("should create a new query response", func(t *testing.T) {
+		// given
+		result := &domain.QueryResult{
+			QueryResult: nodeDomain.QueryResult{
+				Columns: []nodeDomain.QueryColumn{
+					{Name: "id", Type: nodeDomain.QueryColumnTypeString},
+					{Name: "frequency", Type: nodeDomain.QueryColumnTypeInt},
+				},
+				Rows: []nodeDomain.QueryRow{
+					{"page1", 3},
+				},
+			},
+		}
+
+		// when
+		res := dto.NewQueryResponse(result)
+
+		// then
+		if len(res.Columns) != 2 {
+			t.Fatalf("Expected 2 columns, got %d", len(res.Columns))
+		}
+
+		if res.Columns[1].Name != "frequency" || res.Columns[1].Type != "int" {
+			t.Errorf("Expected int column frequency, got %v", res.Columns[1])
+		}
+
+		if len(res.Rows) != 1 {
+			t.Fatalf("Expected 1 row, got %d", len(res.Rows))
+		}
+
+		if res.Rows[0][0] != "page1" {
+			t.Errorf("Expected page1, got %v", res.Rows[0][0])
+		}
+
+		if res.Partial || res.FailedShards != nil {
+			t.Errorf("Expected a complete response, got %v and %v", res.Partial, res.FailedShards)
+		}
+	})
+
+	t.Run("marks responses missing shards as partial", func(t *testing.T) {
+		res := dto.NewQueryResponse(&domain.QueryResult{FailedShards: []domain.ShardID{2, 5}})
+
+		if !res.Partial || !reflect.DeepEqual(res.FailedShards, []uint{2, 5}) {
+			t.Errorf("Expected a partial response missing shards 2 and 5, got %v and %v", res.Partial, res.FailedShards)
+		}
+	})
+}
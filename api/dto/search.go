@@ -1,6 +1,69 @@
 package dto
 
-import "crawlquery/node/domain"
+import (
+	"crawlquery/api/domain"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	nodeDomain "crawlquery/node/domain"
+)
+
+var ErrInvalidSearchCursor = errors.New("invalid search cursor")
+
+// SearchRequest holds the query string parameters of a search. Page is one
+// based and, when set, takes precedence over Offset. A Cursor from a previous
+// response replaces all of the other parameters.
+type SearchRequest struct {
+	Query  string `form:"q"`
+	Page   int    `form:"page" binding:"omitempty,min=1"`
+	Offset int    `form:"offset" binding:"omitempty,min=0"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1"`
+	Cursor string `form:"cursor"`
+}
+
+// SearchWindow returns the query and window the request asks for.
+func (r *SearchRequest) SearchWindow() (*domain.SearchRequest, error) {
+	if r.Cursor != "" {
+		return DecodeSearchCursor(r.Cursor)
+	}
+
+	limit := r.Limit
+	if limit == 0 {
+		limit = nodeDomain.DefaultSearchLimit
+	}
+
+	offset := r.Offset
+	if r.Page > 0 {
+		offset = (r.Page - 1) * limit
+	}
+
+	return &domain.SearchRequest{
+		Query:  r.Query,
+		Offset: offset,
+		Limit:  limit,
+	}, nil
+}
+
+// EncodeSearchCursor encodes the request as an opaque, URL safe cursor.
+func EncodeSearchCursor(req *domain.SearchRequest) string {
+	data, _ := json.Marshal(req)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeSearchCursor(cursor string) (*domain.SearchRequest, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidSearchCursor
+	}
+
+	var req domain.SearchRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, ErrInvalidSearchCursor
+	}
+
+	return &req, nil
+}
 
 type SearchResponsePage struct {
 	ID          string `json:"id"`
@@ -16,10 +79,13 @@ type SearchResponseResult struct {
 }
 
 type SearchResponse struct {
-	Results []SearchResponseResult `json:"results"`
+	Results   []SearchResponseResult `json:"results"`
+	TotalHits int                    `json:"total_hits"`
+	// NextCursor fetches the next page of results and is empty on the last.
+	NextCursor string `json:"next_cursor"`
 }
 
-func NewSearchResponse(results []domain.Result) *SearchResponse {
+func NewSearchResponse(results []nodeDomain.Result) *SearchResponse {
 	res := &SearchResponse{}
 
 	for _, r := range results {
@@ -1,6 +1,7 @@
 package dto_test
 
 import (
+	apiDomain "crawlquery/api/domain"
 	"crawlquery/api/dto"
 	"crawlquery/node/domain"
 	"testing"
@@ -55,3 +56,55 @@ func TestNewSearchResponse(t *testing.T) {
 		}
 	})
 }
+
+func TestSearchWindow(t *testing.T) {
+	cases := []struct {
+		name     string
+		req      dto.SearchRequest
+		expected apiDomain.SearchRequest
+	}{
+		{
+			name:     "defaults",
+			req:      dto.SearchRequest{Query: "term"},
+			expected: apiDomain.SearchRequest{Query: "term", Offset: 0, Limit: domain.DefaultSearchLimit},
+		},
+		{
+			name:     "offset",
+			req:      dto.SearchRequest{Query: "term", Offset: 30, Limit: 15},
+			expected: apiDomain.SearchRequest{Query: "term", Offset: 30, Limit: 15},
+		},
+		{
+			name:     "page takes precedence over offset",
+			req:      dto.SearchRequest{Query: "term", Page: 3, Offset: 5, Limit: 20},
+			expected: apiDomain.SearchRequest{Query: "term", Offset: 40, Limit: 20},
+		},
+		{
+			name:     "cursor replaces the other parameters",
+			req:      dto.SearchRequest{Query: "other", Page: 2, Cursor: dto.EncodeSearchCursor(&apiDomain.SearchRequest{Query: "term", Offset: 20, Limit: 10})},
+			expected: apiDomain.SearchRequest{Query: "term", Offset: 20, Limit: 10},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.req.SearchWindow()
+			if err != nil {
+				t.Fatalf("Error getting window: %v", err)
+			}
+
+			if *got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, *got)
+			}
+		})
+	}
+
+	t.Run("rejects invalid cursors", func(t *testing.T) {
+		for _, cursor := range []string{"not a cursor", "bm90IGpzb24"} {
+			req := dto.SearchRequest{Cursor: cursor}
+
+			if _, err := req.SearchWindow(); err != dto.ErrInvalidSearchCursor {
+				t.Errorf("Expected ErrInvalidSearchCursor for %q, got %v", cursor, err)
+			}
+		}
+	})
+}
@@ -91,6 +91,7 @@ func TestSave(t *testing.T) {
 		}
 
 		job.Status = domain.IndexStatusInProgress
+		job.ContentHash = "hash2"
 		job.UpdatedAt = time.Now()
 
 		err = repo.Save(job)
@@ -141,11 +142,12 @@ func TestGet(t *testing.T) {
 		repo := mysql.NewRepository(db)
 
 		job := &domain.IndexJob{
-			PageID:    "page1",
-			ShardID:   0,
-			Status:    domain.IndexStatusPending,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+			PageID:     "page1",
+			AnchorHash: "anchorhash",
+			ShardID:    0,
+			Status:     domain.IndexStatusPending,
+			CreatedAt:  time.Now(),
+			UpdatedAt:  time.Now(),
 		}
 
 		err := repo.Save(job)
@@ -164,6 +166,10 @@ func TestGet(t *testing.T) {
 		if result.PageID != job.PageID {
 			t.Errorf("Expected job ID to be %s, got %s", job.PageID, result.PageID)
 		}
+
+		if result.AnchorHash != job.AnchorHash {
+			t.Errorf("Expected anchor hash to be %s, got %s", job.AnchorHash, result.AnchorHash)
+		}
 	})
 }
 
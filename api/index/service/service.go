@@ -5,6 +5,8 @@ import (
 	"crawlquery/api/domain"
 	"crawlquery/pkg/util"
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,6 +18,7 @@ type Service struct {
 	indexJobRepo domain.IndexJobRepository
 	indexLogRepo domain.IndexLogRepository
 	nodeService  domain.NodeService
+	linkService  domain.LinkService
 	logger       *zap.SugaredLogger
 	workers      int
 	maxQueueSize int
@@ -53,6 +56,14 @@ func WithNodeService(nodeService domain.NodeService) Option {
 	}
 }
 
+// WithLinkService sends the anchor text of links to a page along with it to
+// be indexed.
+func WithLinkService(linkService domain.LinkService) Option {
+	return func(s *Service) {
+		s.linkService = linkService
+	}
+}
+
 func WithLogger(logger *zap.SugaredLogger) Option {
 	return func(s *Service) {
 		s.logger = logger
@@ -92,6 +103,18 @@ func (s *Service) registerEventListeners() {
 func (s *Service) handleCrawlCompleted(event domain.Event) {
 	crawlCompleted := event.(*domain.CrawlCompleted)
 
+	if crawlCompleted.NoIndex {
+		s.logger.Infow("Not indexing page that asks not to be", "pageID", crawlCompleted.PageID)
+		return
+	}
+
+	// a duplicate or redirect is indexed through the page it stands in for,
+	// which is crawled on its own
+	if target, reason := crawlCompleted.Target(); target != "" && util.PageID(target) != crawlCompleted.PageID {
+		s.logger.Infow("Not indexing aliased page", "pageID", crawlCompleted.PageID, "target", target, "reason", reason)
+		return
+	}
+
 	err := s.CreateJob(
 		crawlCompleted.PageID,
 		crawlCompleted.URL,
@@ -120,9 +143,13 @@ func (s *Service) createlogEntry(pageID domain.PageID, status domain.IndexStatus
 	return nil
 }
 
+// CreateJob queues a page to be indexed. A page that was recrawled after its
+// last index job finished has that job reset to pending with the new content
+// hash, unless neither the content nor the anchor texts of links to it
+// changed.
 func (s *Service) CreateJob(pageID domain.PageID, url domain.URL, shardID domain.ShardID, contentHash domain.ContentHash) error {
-	if _, err := s.indexJobRepo.Get(pageID); err == nil {
-		return domain.ErrIndexJobAlreadyExists
+	if existing, err := s.indexJobRepo.Get(pageID); err == nil {
+		return s.resetJob(existing, url, shardID, contentHash)
 	}
 
 	job := &domain.IndexJob{
@@ -143,6 +170,30 @@ func (s *Service) CreateJob(pageID domain.PageID, url domain.URL, shardID domain
 	return s.createlogEntry(pageID, domain.IndexStatusPending)
 }
 
+func (s *Service) resetJob(job *domain.IndexJob, url domain.URL, shardID domain.ShardID, contentHash domain.ContentHash) error {
+	finished := job.Status == domain.IndexStatusCompleted || job.Status == domain.IndexStatusFailed
+	if !finished {
+		return domain.ErrIndexJobAlreadyExists
+	}
+
+	if job.Status == domain.IndexStatusCompleted && job.ContentHash == contentHash && job.AnchorHash == anchorHash(s.anchorTexts(job.PageID)) {
+		return domain.ErrIndexJobAlreadyExists
+	}
+
+	job.URL = url
+	job.ShardID = shardID
+	job.ContentHash = contentHash
+	job.Status = domain.IndexStatusPending
+	job.UpdatedAt = time.Now()
+
+	err := s.indexJobRepo.Save(job)
+	if err != nil {
+		return err
+	}
+
+	return s.createlogEntry(job.PageID, domain.IndexStatusPending)
+}
+
 func (s *Service) RunIndexProcess(ctx context.Context) error {
 	for {
 		select {
@@ -231,5 +282,36 @@ func (s *Service) attemptIndex(maxAttempts int, ctx context.Context, job *domain
 
 func (s *Service) indexPage(ctx context.Context, job *domain.IndexJob, node *domain.Node) error {
 	s.logger.Infof("Indexing page %s on node %s", job.PageID, node.ID)
-	return s.nodeService.SendIndexJob(ctx, node, job)
+
+	anchors := s.anchorTexts(job.PageID)
+	job.AnchorHash = anchorHash(anchors)
+
+	return s.nodeService.SendIndexJob(ctx, node, job, anchors...)
+}
+
+// anchorTexts returns the anchor texts indexed with a page, or none when
+// they can't be looked up.
+func (s *Service) anchorTexts(pageID domain.PageID) []string {
+	if s.linkService == nil {
+		return nil
+	}
+
+	anchors, err := s.linkService.GetAnchorTexts(pageID)
+	if err != nil {
+		s.logger.Errorw("Error getting anchor texts", "error", err, "pageID", pageID)
+	}
+
+	return anchors
+}
+
+// anchorHash identifies a set of anchor texts whatever their order.
+func anchorHash(anchors []string) string {
+	if len(anchors) == 0 {
+		return ""
+	}
+
+	sorted := slices.Clone(anchors)
+	slices.Sort(sorted)
+
+	return util.Sha256Hex32([]byte(strings.Join(sorted, "\n")))
 }
@@ -6,6 +6,8 @@ import (
 	indexJobRepo "crawlquery/api/index/job/repository/mem"
 	indexLogRepo "crawlquery/api/index/log/repository/mem"
 	indexService "crawlquery/api/index/service"
+	linkRepo "crawlquery/api/link/repository/mem"
+	linkService "crawlquery/api/link/service"
 
 	nodeService "crawlquery/api/node/service"
 	"crawlquery/api/testfactory"
@@ -210,6 +212,170 @@ func TestHandlesCrawlCompletedEvent(t *testing.T) {
 			t.Errorf("Expected log CreatedAt to be set")
 		}
 	})
+
+	t.Run("reindexes a page recrawled with new content", func(t *testing.T) {
+		sf := testfactory.NewServiceFactory()
+
+		indexJobRepo := indexJobRepo.NewRepository()
+		indexLogRepo := indexLogRepo.NewRepository()
+		indexService.NewService(
+			indexService.WithEventService(sf.EventService),
+			indexService.WithEventListeners(),
+			indexService.WithIndexLogRepo(indexLogRepo),
+			indexService.WithIndexJobRepo(indexJobRepo),
+			indexService.WithLogger(testutil.NewTestLogger()),
+		)
+
+		sf.EventService.Publish(&domain.CrawlCompleted{
+			PageID:      "page1",
+			URL:         "http://google.com",
+			ShardID:     1,
+			ContentHash: "hash1",
+		})
+
+		job, err := indexJobRepo.Get("page1")
+		if err != nil {
+			t.Fatalf("Error getting index job: %v", err)
+		}
+
+		job.Status = domain.IndexStatusCompleted
+		indexJobRepo.Save(job)
+
+		sf.EventService.Publish(&domain.CrawlCompleted{
+			PageID:      "page1",
+			URL:         "http://google.com",
+			ShardID:     1,
+			ContentHash: "hash2",
+		})
+
+		jobs, err := indexJobRepo.ListByStatus(10, domain.IndexStatusPending)
+
+		if err != nil {
+			t.Fatalf("Error listing index jobs: %v", err)
+		}
+
+		if len(jobs) != 1 {
+			t.Fatalf("Expected a second index job, got %v", len(jobs))
+		}
+
+		if jobs[0].ContentHash != "hash2" {
+			t.Errorf("Expected content hash to be hash2, got %s", jobs[0].ContentHash)
+		}
+
+		logs, err := indexLogRepo.ListByPageID("page1")
+
+		if err != nil {
+			t.Fatalf("Error listing index logs: %v", err)
+		}
+
+		if len(logs) != 2 {
+			t.Errorf("Expected 2 logs, got %v", len(logs))
+		}
+	})
+
+	t.Run("reindexes a page recrawled with new anchor texts", func(t *testing.T) {
+		sf := testfactory.NewServiceFactory()
+
+		linkRepo := linkRepo.NewRepository()
+		indexJobRepo := indexJobRepo.NewRepository()
+		indexService.NewService(
+			indexService.WithEventService(sf.EventService),
+			indexService.WithEventListeners(),
+			indexService.WithIndexLogRepo(indexLogRepo.NewRepository()),
+			indexService.WithIndexJobRepo(indexJobRepo),
+			indexService.WithLinkService(linkService.NewService(linkService.WithLinkRepo(linkRepo))),
+			indexService.WithLogger(testutil.NewTestLogger()),
+		)
+
+		crawl := func() {
+			sf.EventService.Publish(&domain.CrawlCompleted{
+				PageID:      "page1",
+				URL:         "http://google.com",
+				ShardID:     1,
+				ContentHash: "hash1",
+			})
+		}
+
+		complete := func() {
+			job, err := indexJobRepo.Get("page1")
+			if err != nil {
+				t.Fatalf("Error getting index job: %v", err)
+			}
+
+			job.Status = domain.IndexStatusCompleted
+			indexJobRepo.Save(job)
+		}
+
+		pending := func() int {
+			jobs, err := indexJobRepo.ListByStatus(10, domain.IndexStatusPending)
+			if err != nil {
+				t.Fatalf("Error listing index jobs: %v", err)
+			}
+			return len(jobs)
+		}
+
+		crawl()
+		complete()
+
+		// the same content and anchor texts are not indexed again
+		crawl()
+
+		if n := pending(); n != 0 {
+			t.Fatalf("Expected no pending index jobs, got %d", n)
+		}
+
+		linkRepo.Create(&domain.Link{SrcID: "page2", DstID: "page1", Text: "Search engine"})
+
+		crawl()
+
+		if n := pending(); n != 1 {
+			t.Fatalf("Expected the page to be indexed again, got %d pending jobs", n)
+		}
+	})
+
+	t.Run("skips noindex and duplicate pages", func(t *testing.T) {
+		sf := testfactory.NewServiceFactory()
+
+		indexJobRepo := indexJobRepo.NewRepository()
+		indexService.NewService(
+			indexService.WithEventService(sf.EventService),
+			indexService.WithEventListeners(),
+			indexService.WithIndexLogRepo(indexLogRepo.NewRepository()),
+			indexService.WithIndexJobRepo(indexJobRepo),
+			indexService.WithLogger(testutil.NewTestLogger()),
+		)
+
+		sf.EventService.Publish(&domain.CrawlCompleted{
+			PageID:      util.PageID("http://example.com/private"),
+			URL:         "http://example.com/private",
+			ContentHash: "hash1",
+			NoIndex:     true,
+		})
+
+		sf.EventService.Publish(&domain.CrawlCompleted{
+			PageID:      util.PageID("http://example.com/print"),
+			URL:         "http://example.com/print",
+			ContentHash: "hash2",
+			Canonical:   "http://example.com/article",
+		})
+
+		sf.EventService.Publish(&domain.CrawlCompleted{
+			PageID:      util.PageID("http://example.com/article"),
+			URL:         "http://example.com/article",
+			ContentHash: "hash3",
+			Canonical:   "http://example.com/article",
+		})
+
+		jobs, err := indexJobRepo.ListByStatus(10, domain.IndexStatusPending)
+
+		if err != nil {
+			t.Fatalf("Error listing index jobs: %v", err)
+		}
+
+		if len(jobs) != 1 || jobs[0].URL != "http://example.com/article" {
+			t.Errorf("Expected a single job for the canonical page, got %v", jobs)
+		}
+	})
 }
 
 func TestRunIndexProcess(t *testing.T) {
@@ -464,3 +630,75 @@ func TestRunIndexProcess(t *testing.T) {
 		}
 	})
 }
+
+func TestRunIndexProcessSendsAnchors(t *testing.T) {
+	defer gock.Off()
+
+	sf := testfactory.NewServiceFactory()
+
+	indexService := indexService.NewService(
+		indexService.WithEventService(sf.EventService),
+		indexService.WithIndexJobRepo(sf.IndexJobRepo),
+		indexService.WithNodeService(sf.NodeService),
+		indexService.WithLinkService(sf.LinkService),
+		indexService.WithIndexLogRepo(sf.IndexLogRepo),
+		indexService.WithLogger(testutil.NewTestLogger()),
+		indexService.WithWorkers(1),
+		indexService.WithMaxQueueSize(10),
+	)
+
+	sf.ShardRepo.Create(&domain.Shard{ID: 0})
+
+	sf.NodeRepo.Create(&domain.Node{
+		ID:        "node1",
+		ShardID:   0,
+		Hostname:  "anchors.cluster.com",
+		Port:      8080,
+		CreatedAt: time.Now(),
+	})
+
+	url := domain.URL("http://recipes.com/pasta")
+	job := &domain.IndexJob{
+		PageID:      util.PageID(url),
+		ShardID:     0,
+		Status:      domain.IndexStatusPending,
+		ContentHash: "hash1",
+		URL:         url,
+	}
+	sf.IndexJobRepo.Save(job)
+
+	sf.LinkRepo.Create(&domain.Link{SrcID: "blog", DstID: job.PageID, Text: "Pasta recipes"})
+	sf.LinkRepo.Create(&domain.Link{SrcID: "home", DstID: job.PageID, Text: "Pasta", Internal: true})
+
+	gock.New("http://anchors.cluster.com:8080").
+		Post("/index").
+		JSON(dto.IndexRequest{
+			PageID:      string(job.PageID),
+			URL:         string(job.URL),
+			ContentHash: string(job.ContentHash),
+			Anchors:     []string{"Pasta recipes"},
+		}).
+		Reply(200).
+		JSON(dto.IndexResponse{
+			Success: true,
+			Message: "indexed",
+		})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	indexService.RunIndexProcess(ctx)
+
+	updatedJob, err := sf.IndexJobRepo.Get(job.PageID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if updatedJob.Status != domain.IndexStatusCompleted {
+		t.Errorf("expected job to be completed, got %s", updatedJob.Status)
+	}
+
+	if !gock.IsDone() {
+		t.Errorf("expected the anchors to be sent")
+	}
+}
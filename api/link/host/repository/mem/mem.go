@@ -0,0 +1,72 @@
+package mem
+
+import (
+	"crawlquery/api/domain"
+	"sync"
+	"time"
+)
+
+type Repository struct {
+	links map[domain.Host]map[domain.Host]*domain.HostLink
+	lock  sync.RWMutex
+}
+
+func NewRepository() *Repository {
+	return &Repository{
+		links: make(map[domain.Host]map[domain.Host]*domain.HostLink),
+	}
+}
+
+func (r *Repository) Increment(srcHost, dstHost domain.Host, updatedAt time.Time) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.links[srcHost]; !ok {
+		r.links[srcHost] = make(map[domain.Host]*domain.HostLink)
+	}
+
+	link, ok := r.links[srcHost][dstHost]
+	if !ok {
+		link = &domain.HostLink{
+			SrcHost: srcHost,
+			DstHost: dstHost,
+		}
+		r.links[srcHost][dstHost] = link
+	}
+
+	link.Count++
+	link.UpdatedAt = updatedAt
+
+	return nil
+}
+
+func (r *Repository) UpdateAll(links []*domain.HostLink) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, link := range links {
+		if _, ok := r.links[link.SrcHost]; !ok {
+			r.links[link.SrcHost] = make(map[domain.Host]*domain.HostLink)
+		}
+
+		copied := *link
+		r.links[link.SrcHost][link.DstHost] = &copied
+	}
+
+	return nil
+}
+
+func (r *Repository) GetAll() ([]*domain.HostLink, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var links []*domain.HostLink
+	for _, dsts := range r.links {
+		for _, link := range dsts {
+			copied := *link
+			links = append(links, &copied)
+		}
+	}
+
+	return links, nil
+}
@@ -0,0 +1,71 @@
+package mem
+
+import (
+	"crawlquery/api/domain"
+	"testing"
+	"time"
+)
+
+func TestIncrement(t *testing.T) {
+	t.Run("counts links between hosts", func(t *testing.T) {
+		// Arrange
+		repo := NewRepository()
+
+		// Act
+		repo.Increment("a.com", "b.com", time.Now())
+		repo.Increment("a.com", "b.com", time.Now())
+		repo.Increment("b.com", "a.com", time.Now())
+
+		// Assert
+		links, err := repo.GetAll()
+		if err != nil {
+			t.Fatalf("Error getting host links: %v", err)
+		}
+
+		counts := map[domain.Host]int{}
+		for _, link := range links {
+			counts[link.SrcHost+">"+link.DstHost] = link.Count
+		}
+
+		if counts["a.com>b.com"] != 2 {
+			t.Errorf("Expected 2 links from a.com to b.com, got %d", counts["a.com>b.com"])
+		}
+
+		if counts["b.com>a.com"] != 1 {
+			t.Errorf("Expected 1 link from b.com to a.com, got %d", counts["b.com>a.com"])
+		}
+	})
+}
+
+func TestUpdateAll(t *testing.T) {
+	t.Run("sets the counts of host links", func(t *testing.T) {
+		// Arrange
+		repo := NewRepository()
+		repo.Increment("a.com", "b.com", time.Now())
+
+		// Act
+		err := repo.UpdateAll([]*domain.HostLink{
+			{SrcHost: "a.com", DstHost: "b.com", Count: 5},
+			{SrcHost: "b.com", DstHost: "c.com", Count: 2},
+		})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("Error updating host links: %v", err)
+		}
+
+		links, err := repo.GetAll()
+		if err != nil {
+			t.Fatalf("Error getting host links: %v", err)
+		}
+
+		counts := map[domain.Host]int{}
+		for _, link := range links {
+			counts[link.SrcHost+">"+link.DstHost] = link.Count
+		}
+
+		if len(counts) != 2 || counts["a.com>b.com"] != 5 || counts["b.com>c.com"] != 2 {
+			t.Errorf("Unexpected host link counts %v", counts)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package mysql
+
+import (
+	"crawlquery/api/domain"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// batchSize is the number of host links written per INSERT statement.
+const batchSize = 500
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func (r *Repository) Increment(srcHost, dstHost domain.Host, updatedAt time.Time) error {
+	_, err := r.db.Exec(
+		"INSERT INTO host_links (src_host, dst_host, count, updated_at) VALUES (?, ?, 1, ?) ON DUPLICATE KEY UPDATE count = count + 1, updated_at = VALUES(updated_at)",
+		srcHost,
+		dstHost,
+		updatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateAll sets the counts of the given host links in one transaction
+// using multi-row inserts of up to batchSize rows each.
+func (r *Repository) UpdateAll(links []*domain.HostLink) error {
+	if len(links) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < len(links); start += batchSize {
+		batch := links[start:min(start+batchSize, len(links))]
+
+		placeholders := make([]string, len(batch))
+		args := make([]any, 0, len(batch)*4)
+		for i, link := range batch {
+			placeholders[i] = "(?, ?, ?, ?)"
+			args = append(args, link.SrcHost, link.DstHost, link.Count, link.UpdatedAt)
+		}
+
+		query := "INSERT INTO host_links (src_host, dst_host, count, updated_at) VALUES " +
+			strings.Join(placeholders, ", ") +
+			" ON DUPLICATE KEY UPDATE count = VALUES(count), updated_at = VALUES(updated_at)"
+
+		if _, err := tx.Exec(query, args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) GetAll() ([]*domain.HostLink, error) {
+	rows, err := r.db.Query("SELECT src_host, dst_host, count, updated_at FROM host_links")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []*domain.HostLink
+	for rows.Next() {
+		var link domain.HostLink
+		err = rows.Scan(&link.SrcHost, &link.DstHost, &link.Count, &link.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		links = append(links, &link)
+	}
+
+	return links, rows.Err()
+}
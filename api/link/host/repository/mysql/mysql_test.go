@@ -0,0 +1,96 @@
+package mysql_test
+
+import (
+	"crawlquery/api/domain"
+	"crawlquery/api/migration"
+	"crawlquery/pkg/testutil"
+	"testing"
+	"time"
+
+	hostLinkRepo "crawlquery/api/link/host/repository/mysql"
+)
+
+func TestIncrement(t *testing.T) {
+	t.Run("counts links between hosts", func(t *testing.T) {
+		db := testutil.CreateTestMysqlDB()
+		defer db.Close()
+		migration.Up(db)
+
+		// Arrange
+		repo := hostLinkRepo.NewRepository(db)
+
+		defer db.Exec("DELETE FROM host_links WHERE src_host = ?", "incrementhost.com")
+
+		// Act
+		for i := 0; i < 2; i++ {
+			err := repo.Increment("incrementhost.com", "other.com", time.Now())
+			if err != nil {
+				t.Errorf("Error incrementing host link: %v", err)
+			}
+		}
+
+		// Assert
+		links, err := repo.GetAll()
+		if err != nil {
+			t.Fatalf("Error getting host links: %v", err)
+		}
+
+		found := false
+		for _, link := range links {
+			if link.SrcHost == "incrementhost.com" && link.DstHost == "other.com" {
+				found = true
+				if link.Count != 2 {
+					t.Errorf("Expected count 2, got %d", link.Count)
+				}
+			}
+		}
+
+		if !found {
+			t.Errorf("Expected host link to be stored")
+		}
+	})
+}
+
+func TestUpdateAll(t *testing.T) {
+	t.Run("sets the counts of host links", func(t *testing.T) {
+		db := testutil.CreateTestMysqlDB()
+		defer db.Close()
+		migration.Up(db)
+
+		// Arrange
+		repo := hostLinkRepo.NewRepository(db)
+
+		defer db.Exec("DELETE FROM host_links WHERE src_host = ?", "updateallhost.com")
+
+		if err := repo.Increment("updateallhost.com", "other.com", time.Now()); err != nil {
+			t.Fatalf("Error incrementing host link: %v", err)
+		}
+
+		// Act
+		err := repo.UpdateAll([]*domain.HostLink{
+			{SrcHost: "updateallhost.com", DstHost: "other.com", Count: 5, UpdatedAt: time.Now()},
+			{SrcHost: "updateallhost.com", DstHost: "third.com", Count: 2, UpdatedAt: time.Now()},
+		})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("Error updating host links: %v", err)
+		}
+
+		links, err := repo.GetAll()
+		if err != nil {
+			t.Fatalf("Error getting host links: %v", err)
+		}
+
+		counts := map[domain.Host]int{}
+		for _, link := range links {
+			if link.SrcHost == "updateallhost.com" {
+				counts[link.DstHost] = link.Count
+			}
+		}
+
+		if len(counts) != 2 || counts["other.com"] != 5 || counts["third.com"] != 2 {
+			t.Errorf("Unexpected host link counts %v", counts)
+		}
+	})
+}
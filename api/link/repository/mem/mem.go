@@ -26,10 +26,24 @@ func (r *Repository) Create(link *domain.Link) error {
 	return nil
 }
 
+func (r *Repository) SetInternal(srcID, dstID domain.PageID, internal bool) error {
+	for _, l := range r.links {
+		if l.SrcID == srcID && l.DstID == dstID {
+			l.Internal = internal
+		}
+	}
+
+	return nil
+}
+
 func (r *Repository) GetAll() ([]*domain.Link, error) {
 	return r.links, nil
 }
 
+func (r *Repository) Count() (int, error) {
+	return len(r.links), nil
+}
+
 func (r *Repository) GetAllBySrcID(srcID domain.PageID) ([]*domain.Link, error) {
 	var links []*domain.Link
 
@@ -41,3 +55,15 @@ func (r *Repository) GetAllBySrcID(srcID domain.PageID) ([]*domain.Link, error)
 
 	return links, nil
 }
+
+func (r *Repository) GetAllByDstID(dstID domain.PageID) ([]*domain.Link, error) {
+	var links []*domain.Link
+
+	for _, l := range r.links {
+		if l.DstID == dstID {
+			links = append(links, l)
+		}
+	}
+
+	return links, nil
+}
@@ -109,3 +109,70 @@ func TestGetAllBySrcID(t *testing.T) {
 		}
 	})
 }
+
+func TestCount(t *testing.T) {
+	t.Run("can count links", func(t *testing.T) {
+		// Arrange
+		repo := NewRepository()
+		repo.Create(&domain.Link{SrcID: "page1", DstID: "page2"})
+		repo.Create(&domain.Link{SrcID: "page2", DstID: "page3"})
+
+		// Act
+		count, err := repo.Count()
+
+		// Assert
+		if err != nil {
+			t.Errorf("Error counting links: %v", err)
+		}
+
+		if count != 2 {
+			t.Errorf("Expected 2 links, got %d", count)
+		}
+	})
+}
+
+func TestGetAllByDstID(t *testing.T) {
+	t.Run("can get all links by dstID", func(t *testing.T) {
+		// Arrange
+		repo := NewRepository()
+		repo.Create(&domain.Link{SrcID: "page1", DstID: "page3", Text: "three"})
+		repo.Create(&domain.Link{SrcID: "page2", DstID: "page3", Text: "page three"})
+		repo.Create(&domain.Link{SrcID: "page1", DstID: "page2"})
+
+		// Act
+		links, err := repo.GetAllByDstID("page3")
+
+		// Assert
+		if err != nil {
+			t.Errorf("Error getting links: %v", err)
+		}
+
+		if len(links) != 2 {
+			t.Errorf("Expected 2 links, got %d", len(links))
+		}
+	})
+}
+
+func TestSetInternal(t *testing.T) {
+	t.Run("updates the internal flag of a link", func(t *testing.T) {
+		// Arrange
+		repo := NewRepository()
+		repo.Create(&domain.Link{SrcID: "page1", DstID: "page2"})
+		repo.Create(&domain.Link{SrcID: "page1", DstID: "page3"})
+
+		// Act
+		err := repo.SetInternal("page1", "page2", true)
+
+		// Assert
+		if err != nil {
+			t.Errorf("Error setting internal: %v", err)
+		}
+
+		links, _ := repo.GetAllBySrcID("page1")
+		for _, link := range links {
+			if link.Internal != (link.DstID == "page2") {
+				t.Errorf("Unexpected internal %t for link to %s", link.Internal, link.DstID)
+			}
+		}
+	})
+}
@@ -16,7 +16,7 @@ func NewRepository(db *sql.DB) *Repository {
 }
 
 func (r *Repository) Create(link *domain.Link) error {
-	_, err := r.db.Exec("INSERT INTO links (src_id, dst_id, created_at) VALUES (?, ?, ?)", link.SrcID, link.DstID, link.CreatedAt)
+	_, err := r.db.Exec("INSERT INTO links (src_id, dst_id, internal, text, rel, created_at) VALUES (?, ?, ?, ?, ?, ?)", link.SrcID, link.DstID, link.Internal, link.Text, link.Rel, link.CreatedAt)
 	if err != nil {
 		return err
 	}
@@ -24,8 +24,13 @@ func (r *Repository) Create(link *domain.Link) error {
 	return nil
 }
 
+func (r *Repository) SetInternal(srcID, dstID domain.PageID, internal bool) error {
+	_, err := r.db.Exec("UPDATE links SET internal = ? WHERE src_id = ? AND dst_id = ?", internal, srcID, dstID)
+	return err
+}
+
 func (r *Repository) GetAll() ([]*domain.Link, error) {
-	rows, err := r.db.Query("SELECT src_id, dst_id, created_at FROM links")
+	rows, err := r.db.Query("SELECT src_id, dst_id, internal, text, rel, created_at FROM links")
 
 	if err != nil {
 		return nil, err
@@ -34,7 +39,7 @@ func (r *Repository) GetAll() ([]*domain.Link, error) {
 	var links []*domain.Link
 	for rows.Next() {
 		var link domain.Link
-		err = rows.Scan(&link.SrcID, &link.DstID, &link.CreatedAt)
+		err = rows.Scan(&link.SrcID, &link.DstID, &link.Internal, &link.Text, &link.Rel, &link.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -45,16 +50,47 @@ func (r *Repository) GetAll() ([]*domain.Link, error) {
 	return links, nil
 }
 
+func (r *Repository) Count() (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM links").Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
 func (r *Repository) GetAllBySrcID(srcID domain.PageID) ([]*domain.Link, error) {
-	rows, err := r.db.Query("SELECT src_id, dst_id, created_at FROM links WHERE src_id = ?", srcID)
+	rows, err := r.db.Query("SELECT src_id, dst_id, internal, text, rel, created_at FROM links WHERE src_id = ?", srcID)
+	if err != nil {
+		return nil, err
+	}
+
+	var links []*domain.Link
+	for rows.Next() {
+		var link domain.Link
+		err = rows.Scan(&link.SrcID, &link.DstID, &link.Internal, &link.Text, &link.Rel, &link.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		links = append(links, &link)
+	}
+
+	return links, nil
+}
+
+func (r *Repository) GetAllByDstID(dstID domain.PageID) ([]*domain.Link, error) {
+	rows, err := r.db.Query("SELECT src_id, dst_id, internal, text, rel, created_at FROM links WHERE dst_id = ?", dstID)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	var links []*domain.Link
 	for rows.Next() {
 		var link domain.Link
-		err = rows.Scan(&link.SrcID, &link.DstID, &link.CreatedAt)
+		err = rows.Scan(&link.SrcID, &link.DstID, &link.Internal, &link.Text, &link.Rel, &link.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -169,3 +169,131 @@ func TestGetAllBySrcID(t *testing.T) {
 		}
 	})
 }
+
+func TestCount(t *testing.T) {
+	t.Run("can count links", func(t *testing.T) {
+		db := testutil.CreateTestMysqlDB()
+		defer db.Close()
+		migration.Up(db)
+		// Arrange
+		repo := mysql.NewRepository(db)
+		link := &domain.Link{
+			SrcID:     util.PageID("https://countlinks.com"),
+			DstID:     util.PageID("https://countlinks.com/about"),
+			CreatedAt: time.Now(),
+		}
+
+		defer db.Exec("DELETE FROM links WHERE src_id = ?", link.SrcID)
+
+		before, err := repo.Count()
+		if err != nil {
+			t.Errorf("Error counting links: %v", err)
+		}
+
+		// Act
+		err = repo.Create(link)
+		if err != nil {
+			t.Errorf("Error adding link: %v", err)
+		}
+
+		after, err := repo.Count()
+
+		// Assert
+		if err != nil {
+			t.Errorf("Error counting links: %v", err)
+		}
+
+		if after != before+1 {
+			t.Errorf("Expected %d links, got %d", before+1, after)
+		}
+	})
+}
+
+func TestGetAllByDstID(t *testing.T) {
+	t.Run("can get all links by dstID with their anchors", func(t *testing.T) {
+		db := testutil.CreateTestMysqlDB()
+		defer db.Close()
+		migration.Up(db)
+		// Arrange
+		repo := mysql.NewRepository(db)
+		dstID := util.PageID("https://getlinksbydst.com/recipes")
+		link1 := &domain.Link{
+			SrcID:     util.PageID("https://getlinksbydst.com"),
+			DstID:     dstID,
+			Internal:  true,
+			Text:      "Recipes",
+			CreatedAt: time.Now(),
+		}
+		link2 := &domain.Link{
+			SrcID:     util.PageID("https://other.com"),
+			DstID:     dstID,
+			Text:      "Great recipes",
+			Rel:       "nofollow",
+			CreatedAt: time.Now(),
+		}
+
+		defer db.Exec("DELETE FROM links WHERE dst_id = ?", dstID)
+
+		// Act
+		for _, link := range []*domain.Link{link1, link2} {
+			if err := repo.Create(link); err != nil {
+				t.Errorf("Error adding link: %v", err)
+			}
+		}
+
+		links, err := repo.GetAllByDstID(dstID)
+
+		// Assert
+		if err != nil {
+			t.Errorf("Error getting links: %v", err)
+		}
+
+		if len(links) != 2 {
+			t.Fatalf("Expected 2 links, got %d", len(links))
+		}
+
+		for _, link := range links {
+			if link.SrcID == link2.SrcID && (link.Text != link2.Text || link.Rel != link2.Rel || link.Internal) {
+				t.Errorf("Expected link %+v, got %+v", link2, link)
+			}
+		}
+	})
+}
+
+func TestSetInternal(t *testing.T) {
+	t.Run("updates the internal flag of a link", func(t *testing.T) {
+		db := testutil.CreateTestMysqlDB()
+		defer db.Close()
+		migration.Up(db)
+		// Arrange
+		repo := mysql.NewRepository(db)
+		link := &domain.Link{
+			SrcID:     util.PageID("https://a.setinternal.com"),
+			DstID:     util.PageID("https://b.setinternal.com"),
+			CreatedAt: time.Now(),
+		}
+
+		defer db.Exec("DELETE FROM links WHERE src_id = ?", link.SrcID)
+
+		if err := repo.Create(link); err != nil {
+			t.Fatalf("Error adding link: %v", err)
+		}
+
+		// Act
+		err := repo.SetInternal(link.SrcID, link.DstID, true)
+
+		// Assert
+		if err != nil {
+			t.Errorf("Error setting internal: %v", err)
+		}
+
+		links, err := repo.GetAllBySrcID(link.SrcID)
+		if err != nil {
+			t.Fatalf("Error getting links: %v", err)
+		}
+
+		if len(links) != 1 || !links[0].Internal {
+			t.Errorf("Expected the link to be internal, got %v", links)
+		}
+	})
+}
@@ -2,10 +2,12 @@ package service
 
 import (
 	"crawlquery/api/domain"
+	"crawlquery/pkg/canonical"
 	"crawlquery/pkg/util"
-	"net/url"
+	"slices"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"go.uber.org/zap"
 )
@@ -13,6 +15,9 @@ import (
 type Service struct {
 	eventService domain.EventService
 	linkRepo     domain.LinkRepository
+	hostLinkRepo domain.HostLinkRepository
+	aliasRepo    domain.PageAliasRepository
+	pageRepo     domain.PageRepository
 	logger       *zap.SugaredLogger
 }
 
@@ -30,6 +35,30 @@ func WithLinkRepo(linkRepo domain.LinkRepository) Option {
 	}
 }
 
+// WithHostLinkRepo aggregates the links found by crawls into host to host
+// edge counts.
+func WithHostLinkRepo(hostLinkRepo domain.HostLinkRepository) Option {
+	return func(s *Service) {
+		s.hostLinkRepo = hostLinkRepo
+	}
+}
+
+// WithPageAliasRepo merges the links to and from pages that are aliases of
+// another page into that page.
+func WithPageAliasRepo(aliasRepo domain.PageAliasRepository) Option {
+	return func(s *Service) {
+		s.aliasRepo = aliasRepo
+	}
+}
+
+// WithPageRepo looks up the URLs of linked pages when backfilling the host
+// graph from stored links.
+func WithPageRepo(pageRepo domain.PageRepository) Option {
+	return func(s *Service) {
+		s.pageRepo = pageRepo
+	}
+}
+
 func WithLogger(logger *zap.SugaredLogger) Option {
 	return func(s *Service) {
 		s.logger = logger
@@ -59,32 +88,91 @@ func (s *Service) registerEventListeners() {
 	s.eventService.Subscribe(domain.CrawlCompletedKey, s.handleCrawlCompleted)
 }
 
-var trackingParams = []string{
-	"utm_source", "utm_medium", "utm_platform", "utm_campaign", "utm_term", "utm_content", "gclid", "fbclid",
-}
+const (
+	// maxAnchorTextLength and maxRelLength match the columns of the links
+	// table.
+	maxAnchorTextLength = 255
+	maxRelLength        = 64
 
-func normalizeURL(rawURL domain.URL) (domain.URL, error) {
-	parsedURL, err := url.Parse(string(rawURL))
-	if err != nil {
-		return "", err
+	// maxAnchorTexts is the most anchor texts sent to be indexed with a page.
+	maxAnchorTexts = 100
+)
+
+// truncate shortens s to at most n bytes without splitting a character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
 	}
 
-	queryParams := parsedURL.Query()
-	for _, param := range trackingParams {
-		queryParams.Del(param)
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
 	}
-	parsedURL.RawQuery = queryParams.Encode()
 
-	parsedURL.Host = strings.ToLower(parsedURL.Host)
-	parsedURL.Scheme = strings.ToLower(parsedURL.Scheme)
+	return s[:n]
+}
+
+// mergeAnchors combines anchors that point at the same URL once normalised,
+// in the order they were found. A page often links to the same place more
+// than once, such as through an image and then a text link, so the first
+// non empty text is kept along with every rel value used.
+func mergeAnchors(anchors []domain.Anchor) []domain.Anchor {
+	var merged []domain.Anchor
+	index := map[domain.URL]int{}
 
-	return domain.URL(parsedURL.String()), nil
+	for _, anchor := range anchors {
+		normalized, err := canonical.URL(anchor.URL)
+		if err != nil {
+			continue
+		}
+
+		i, ok := index[normalized]
+		if !ok {
+			index[normalized] = len(merged)
+			merged = append(merged, domain.Anchor{URL: normalized, Text: anchor.Text, Rel: anchor.Rel})
+			continue
+		}
+
+		if merged[i].Text == "" {
+			merged[i].Text = anchor.Text
+		}
+
+		for _, rel := range strings.Fields(anchor.Rel) {
+			if !slices.Contains(strings.Fields(merged[i].Rel), rel) {
+				merged[i].Rel = strings.TrimSpace(merged[i].Rel + " " + rel)
+			}
+		}
+	}
+
+	return merged
 }
 
 func (s *Service) handleCrawlCompleted(e domain.Event) {
 	crawlCompletedEvent := e.(*domain.CrawlCompleted)
-	for _, link := range crawlCompletedEvent.Links {
-		_, err := s.Create(crawlCompletedEvent.PageID, link)
+
+	// relative links and the site they are on are taken from where the page
+	// was fetched
+	srcSite := util.Site(crawlCompletedEvent.URL)
+	if crawlCompletedEvent.FinalURL != "" {
+		srcSite = util.Site(crawlCompletedEvent.FinalURL)
+	}
+
+	anchors := crawlCompletedEvent.Anchors
+	if len(anchors) == 0 {
+		for _, link := range crawlCompletedEvent.Links {
+			anchors = append(anchors, domain.Anchor{URL: link})
+		}
+	}
+
+	// the links found by following a redirect belong to the page it
+	// redirected to. A page naming a canonical version keeps its own links,
+	// so it cannot hand them to another page.
+	srcID := crawlCompletedEvent.PageID
+	if target, reason := crawlCompletedEvent.Target(); reason == domain.PageAliasRedirect {
+		srcID = util.PageID(target)
+	}
+
+	for _, anchor := range mergeAnchors(anchors) {
+		_, err := s.create(srcID, srcSite, anchor)
 		if err != nil {
 			if !strings.Contains(err.Error(), "Duplicate entry") {
 				s.logger.Errorw("Error creating link", "error", err)
@@ -94,13 +182,24 @@ func (s *Service) handleCrawlCompleted(e domain.Event) {
 }
 
 func (s *Service) Create(src domain.PageID, dst domain.URL) (*domain.Link, error) {
-	normalizedDst, err := normalizeURL(dst)
+	return s.create(src, "", domain.Anchor{URL: dst})
+}
+
+// create stores a link from the page src on srcSite. When the source site is
+// known the link is marked internal if it stays on the site, and new links
+// that pass authority are added to the host graph.
+func (s *Service) create(src domain.PageID, srcSite domain.Host, anchor domain.Anchor) (*domain.Link, error) {
+	normalizedDst, err := canonical.URL(anchor.URL)
 	if err != nil {
 		return nil, err
 	}
+	dstSite := util.Site(normalizedDst)
 	link := &domain.Link{
 		SrcID:     src,
 		DstID:     util.PageID(normalizedDst),
+		Internal:  srcSite != "" && srcSite == dstSite,
+		Text:      truncate(anchor.Text, maxAnchorTextLength),
+		Rel:       truncate(anchor.Rel, maxRelLength),
 		CreatedAt: time.Now(),
 	}
 
@@ -110,11 +209,270 @@ func (s *Service) Create(src domain.PageID, dst domain.URL) (*domain.Link, error
 		return nil, err
 	}
 
-	s.eventService.Publish(&domain.LinkCreated{Link: link, DstURL: normalizedDst})
+	if s.hostLinkRepo != nil && srcSite != "" && dstSite != "" && !link.Nofollow() {
+		err = s.hostLinkRepo.Increment(srcSite, dstSite, link.CreatedAt)
+		if err != nil {
+			s.logger.Errorw("Error updating host link", "error", err)
+		}
+	}
+
+	s.eventService.Publish(&domain.LinkCreated{Link: link, DstURL: normalizedDst, URL: anchor.URL})
 
 	return link, nil
 }
 
+// canonicalIDs maps each of the aliases accepted by include to the page it is
+// folded into, following chains of them.
+func canonicalIDs(aliases []*domain.PageAlias, include func(*domain.PageAlias) bool) map[domain.PageID]domain.PageID {
+	resolved := map[domain.PageID]domain.PageID{}
+
+	direct := map[domain.PageID]domain.PageID{}
+	for _, alias := range aliases {
+		if include(alias) {
+			direct[alias.PageID] = alias.CanonicalID
+		}
+	}
+
+	for pageID, canonicalID := range direct {
+		// a chain can be no longer than the number of aliases, which also
+		// stops at loops
+		for i := 0; i < len(direct); i++ {
+			next, ok := direct[canonicalID]
+			if !ok || next == pageID {
+				break
+			}
+			canonicalID = next
+		}
+		resolved[pageID] = canonicalID
+	}
+
+	return resolved
+}
+
+// GetAll returns every link with aliased pages replaced by the page they are
+// folded into. Links from a page naming a canonical version stay its own, as
+// only redirects and legacy IDs move a page's links. Links that then point back at their own
+// page are dropped, and links that became the same are merged, keeping one
+// that passes authority if there is one.
 func (s *Service) GetAll() ([]*domain.Link, error) {
-	return s.linkRepo.GetAll()
+	links, err := s.linkRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.aliasRepo == nil {
+		return links, nil
+	}
+
+	aliases, err := s.aliasRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(aliases) == 0 {
+		return links, nil
+	}
+
+	dstIDs := canonicalIDs(aliases, func(*domain.PageAlias) bool {
+		return true
+	})
+	srcIDs := canonicalIDs(aliases, func(alias *domain.PageAlias) bool {
+		return alias.Reason != domain.PageAliasCanonical
+	})
+
+	type edge struct {
+		src domain.PageID
+		dst domain.PageID
+	}
+
+	var merged []*domain.Link
+	index := map[edge]int{}
+
+	for _, link := range links {
+		resolved := *link
+		if canonicalID, ok := srcIDs[link.SrcID]; ok {
+			resolved.SrcID = canonicalID
+		}
+		if canonicalID, ok := dstIDs[link.DstID]; ok {
+			resolved.DstID = canonicalID
+		}
+
+		if resolved.SrcID == resolved.DstID {
+			continue
+		}
+
+		key := edge{src: resolved.SrcID, dst: resolved.DstID}
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(merged)
+			merged = append(merged, &resolved)
+			continue
+		}
+
+		if merged[i].Nofollow() && !resolved.Nofollow() {
+			merged[i] = &resolved
+		}
+	}
+
+	return merged, nil
+}
+
+func (s *Service) Count() (int, error) {
+	return s.linkRepo.Count()
+}
+
+// GetHostLinks returns the aggregated host graph, or nothing when host links
+// are not being recorded.
+func (s *Service) GetHostLinks() ([]*domain.HostLink, error) {
+	if s.hostLinkRepo == nil {
+		return nil, nil
+	}
+	return s.hostLinkRepo.GetAll()
+}
+
+// BackfillHostLinks rebuilds the host graph from the stored links when it is
+// empty, as it is after upgrading from a version that kept no host graph. It
+// also sets the internal flag of links stored before links were marked
+// internal. Links to pages the API doesn't know are
+// left out, as their URL is not stored.
+func (s *Service) BackfillHostLinks() error {
+	if s.hostLinkRepo == nil || s.pageRepo == nil {
+		return nil
+	}
+
+	existing, err := s.hostLinkRepo.GetAll()
+	if err != nil {
+		return err
+	}
+
+	if len(existing) > 0 {
+		return nil
+	}
+
+	links, err := s.linkRepo.GetAll()
+	if err != nil {
+		return err
+	}
+
+	var ids []domain.PageID
+	seen := map[domain.PageID]bool{}
+	for _, link := range links {
+		for _, id := range []domain.PageID{link.SrcID, link.DstID} {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	pages, err := s.pageRepo.GetMany(ids)
+	if err != nil {
+		return err
+	}
+
+	type edge struct {
+		src domain.Host
+		dst domain.Host
+	}
+
+	counts := map[edge]*domain.HostLink{}
+	var hostLinks []*domain.HostLink
+
+	for _, link := range links {
+		src, dst := pages[link.SrcID], pages[link.DstID]
+		if src == nil || dst == nil {
+			continue
+		}
+
+		srcSite, dstSite := util.Site(src.URL), util.Site(dst.URL)
+		if srcSite == "" || dstSite == "" {
+			continue
+		}
+
+		if internal := srcSite == dstSite; internal != link.Internal {
+			if err := s.linkRepo.SetInternal(link.SrcID, link.DstID, internal); err != nil {
+				return err
+			}
+		}
+
+		if link.Nofollow() {
+			continue
+		}
+
+		key := edge{src: srcSite, dst: dstSite}
+		hostLink, ok := counts[key]
+		if !ok {
+			hostLink = &domain.HostLink{SrcHost: srcSite, DstHost: dstSite}
+			counts[key] = hostLink
+			hostLinks = append(hostLinks, hostLink)
+		}
+
+		hostLink.Count++
+		if link.CreatedAt.After(hostLink.UpdatedAt) {
+			hostLink.UpdatedAt = link.CreatedAt
+		}
+	}
+
+	s.logger.Infow("Backfilled host links", "links", len(links), "host_links", len(hostLinks))
+
+	return s.hostLinkRepo.UpdateAll(hostLinks)
+}
+
+// aliasIDs returns the page with the pages folded into it, following chains
+// of aliases. Links to the aliases of a page describe it too.
+func (s *Service) aliasIDs(pageID domain.PageID) ([]domain.PageID, error) {
+	ids := []domain.PageID{pageID}
+	if s.aliasRepo == nil {
+		return ids, nil
+	}
+
+	seen := map[domain.PageID]bool{pageID: true}
+	for i := 0; i < len(ids); i++ {
+		aliases, err := s.aliasRepo.GetByCanonicalID(ids[i])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, alias := range aliases {
+			if !seen[alias.PageID] {
+				seen[alias.PageID] = true
+				ids = append(ids, alias.PageID)
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+// GetAnchorTexts returns the anchor text of links to the page from other
+// sites. Links within a site are mostly navigation, so they are left out.
+func (s *Service) GetAnchorTexts(dstID domain.PageID) ([]string, error) {
+	dstIDs, err := s.aliasIDs(dstID)
+	if err != nil {
+		return nil, err
+	}
+
+	var links []*domain.Link
+	for _, id := range dstIDs {
+		found, err := s.linkRepo.GetAllByDstID(id)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, found...)
+	}
+
+	var texts []string
+	for _, link := range links {
+		if link.Internal || link.Text == "" {
+			continue
+		}
+
+		texts = append(texts, link.Text)
+
+		if len(texts) == maxAnchorTexts {
+			break
+		}
+	}
+
+	return texts, nil
 }
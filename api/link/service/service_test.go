@@ -8,7 +8,9 @@ import (
 	"crawlquery/pkg/util"
 	"testing"
 
+	hostLinkRepo "crawlquery/api/link/host/repository/mem"
 	linkService "crawlquery/api/link/service"
+	aliasRepo "crawlquery/api/page/alias/repository/mem"
 )
 
 func TestCreate(t *testing.T) {
@@ -73,6 +75,41 @@ func TestCreate(t *testing.T) {
 			t.Errorf("Expected event to be published")
 		}
 	})
+
+	t.Run("links to the canonical url", func(t *testing.T) {
+		sf := testfactory.NewServiceFactory(
+			testfactory.WithShard(&domain.Shard{ID: 0}),
+		)
+
+		var dstURL domain.URL
+		sf.EventService.Subscribe(domain.LinkCreatedKey, func(e domain.Event) {
+			dstURL = e.(*domain.LinkCreated).DstURL
+		})
+
+		link, err := sf.LinkService.Create(util.PageID("https://example.com/"), "HTTPS://Example.com:443/about/?utm_source=x#team")
+
+		if err != nil {
+			t.Fatalf("Error adding link: %v", err)
+		}
+
+		if dstURL != "https://example.com/about" {
+			t.Errorf("Expected DstURL to be 'https://example.com/about', got '%s'", dstURL)
+		}
+
+		if link.DstID != util.PageID("https://example.com/about") {
+			t.Errorf("Expected DstID to be the ID of the canonical url, got %s", link.DstID)
+		}
+	})
+
+	t.Run("rejects links that cannot be crawled", func(t *testing.T) {
+		sf := testfactory.NewServiceFactory(
+			testfactory.WithShard(&domain.Shard{ID: 0}),
+		)
+
+		if _, err := sf.LinkService.Create(util.PageID("https://example.com/"), "mailto:someone@example.com"); err == nil {
+			t.Errorf("Expected an error")
+		}
+	})
 }
 
 func TestHandlesCrawlCompletedEvent(t *testing.T) {
@@ -114,3 +151,424 @@ func TestHandlesCrawlCompletedEvent(t *testing.T) {
 		}
 	})
 }
+
+func TestHostLinks(t *testing.T) {
+	t.Run("aggregates crawled links into the host graph", func(t *testing.T) {
+		sf := testfactory.NewServiceFactory(
+			testfactory.WithShard(&domain.Shard{ID: 0}),
+		)
+
+		hostLinkRepo := hostLinkRepo.NewRepository()
+
+		linkService := linkService.NewService(
+			linkService.WithLinkRepo(sf.LinkRepo),
+			linkService.WithHostLinkRepo(hostLinkRepo),
+			linkService.WithEventService(sf.EventService),
+			linkService.WithLogger(testutil.NewTestLogger()),
+			linkService.WithEventListeners(),
+		)
+
+		pageID := util.PageID("https://www.hostlinks.com")
+
+		event := &domain.CrawlCompleted{
+			PageID: pageID,
+			URL:    "https://www.hostlinks.com",
+			Links: []domain.URL{
+				"https://hostlinks.com/about",
+				"https://hostlinks.com/contact",
+				"https://other.com/",
+				"https://other.com/page",
+			},
+		}
+
+		// Act
+		sf.EventService.Publish(event)
+
+		// Assert
+		links, _ := sf.LinkRepo.GetAllBySrcID(pageID)
+
+		internal := 0
+		for _, link := range links {
+			if link.Internal {
+				internal++
+			}
+		}
+
+		if internal != 2 {
+			t.Errorf("Expected 2 internal links, got %d", internal)
+		}
+
+		hostLinks, err := linkService.GetHostLinks()
+		if err != nil {
+			t.Fatalf("Error getting host links: %v", err)
+		}
+
+		counts := map[domain.Host]int{}
+		for _, hostLink := range hostLinks {
+			if hostLink.SrcHost != "hostlinks.com" {
+				t.Errorf("Expected source host hostlinks.com, got %s", hostLink.SrcHost)
+			}
+			counts[hostLink.DstHost] = hostLink.Count
+		}
+
+		if counts["hostlinks.com"] != 2 || counts["other.com"] != 2 {
+			t.Errorf("Unexpected host link counts %v", counts)
+		}
+	})
+
+	t.Run("leaves nofollow links out of the host graph", func(t *testing.T) {
+		sf := testfactory.NewServiceFactory(
+			testfactory.WithShard(&domain.Shard{ID: 0}),
+		)
+
+		hostLinkRepo := hostLinkRepo.NewRepository()
+
+		linkService := linkService.NewService(
+			linkService.WithLinkRepo(sf.LinkRepo),
+			linkService.WithHostLinkRepo(hostLinkRepo),
+			linkService.WithEventService(sf.EventService),
+			linkService.WithLogger(testutil.NewTestLogger()),
+			linkService.WithEventListeners(),
+		)
+
+		sf.EventService.Publish(&domain.CrawlCompleted{
+			PageID: util.PageID("https://nofollow.com"),
+			URL:    "https://nofollow.com",
+			Anchors: []domain.Anchor{
+				{URL: "https://followed.com/"},
+				{URL: "https://ignored.com/", Rel: "nofollow"},
+			},
+		})
+
+		hostLinks, err := linkService.GetHostLinks()
+		if err != nil {
+			t.Fatalf("Error getting host links: %v", err)
+		}
+
+		if len(hostLinks) != 1 || hostLinks[0].DstHost != "followed.com" {
+			t.Errorf("Expected a single host link to followed.com, got %v", hostLinks)
+		}
+	})
+}
+
+func TestHostLinksBySite(t *testing.T) {
+	t.Run("treats links between subdomains as internal", func(t *testing.T) {
+		sf := testfactory.NewServiceFactory(
+			testfactory.WithShard(&domain.Shard{ID: 0}),
+		)
+
+		hostLinkRepo := hostLinkRepo.NewRepository()
+
+		linkService := linkService.NewService(
+			linkService.WithLinkRepo(sf.LinkRepo),
+			linkService.WithHostLinkRepo(hostLinkRepo),
+			linkService.WithEventService(sf.EventService),
+			linkService.WithLogger(testutil.NewTestLogger()),
+			linkService.WithEventListeners(),
+		)
+
+		pageID := util.PageID("https://blog.sites.co.uk/")
+
+		sf.EventService.Publish(&domain.CrawlCompleted{
+			PageID: pageID,
+			URL:    "https://blog.sites.co.uk/",
+			Links: []domain.URL{
+				"https://shop.sites.co.uk/",
+				"https://other.co.uk/",
+			},
+		})
+
+		links, _ := sf.LinkRepo.GetAllBySrcID(pageID)
+		for _, link := range links {
+			expected := link.DstID == util.PageID("https://shop.sites.co.uk/")
+			if link.Internal != expected {
+				t.Errorf("Expected internal %t for link to %s, got %t", expected, link.DstID, link.Internal)
+			}
+		}
+
+		hostLinks, err := linkService.GetHostLinks()
+		if err != nil {
+			t.Fatalf("Error getting host links: %v", err)
+		}
+
+		counts := map[domain.Host]int{}
+		for _, hostLink := range hostLinks {
+			if hostLink.SrcHost != "sites.co.uk" {
+				t.Errorf("Expected source site sites.co.uk, got %s", hostLink.SrcHost)
+			}
+			counts[hostLink.DstHost] = hostLink.Count
+		}
+
+		if counts["sites.co.uk"] != 1 || counts["other.co.uk"] != 1 {
+			t.Errorf("Unexpected host link counts %v", counts)
+		}
+	})
+
+	t.Run("backfills the host graph from stored links", func(t *testing.T) {
+		sf := testfactory.NewServiceFactory(
+			testfactory.WithShard(&domain.Shard{ID: 0}),
+		)
+
+		hostLinkRepo := hostLinkRepo.NewRepository()
+
+		linkService := linkService.NewService(
+			linkService.WithLinkRepo(sf.LinkRepo),
+			linkService.WithHostLinkRepo(hostLinkRepo),
+			linkService.WithPageRepo(sf.PageRepo),
+			linkService.WithLogger(testutil.NewTestLogger()),
+		)
+
+		urls := []domain.URL{
+			"https://a.backfill.com/",
+			"https://b.backfill.com/",
+			"https://other.com/",
+			"https://sponsor.com/",
+		}
+		for _, url := range urls {
+			sf.PageRepo.Create(&domain.Page{ID: util.PageID(url), URL: url})
+		}
+
+		// links stored before host links were aggregated by site
+		for _, link := range []*domain.Link{
+			{SrcID: util.PageID(urls[0]), DstID: util.PageID(urls[1])},
+			{SrcID: util.PageID(urls[0]), DstID: util.PageID(urls[2])},
+			{SrcID: util.PageID(urls[1]), DstID: util.PageID(urls[2])},
+			{SrcID: util.PageID(urls[1]), DstID: util.PageID(urls[3]), Rel: "sponsored"},
+			{SrcID: util.PageID(urls[1]), DstID: util.PageID("https://unknown.com/")},
+		} {
+			sf.LinkRepo.Create(link)
+		}
+
+		if err := linkService.BackfillHostLinks(); err != nil {
+			t.Fatalf("Error backfilling host links: %v", err)
+		}
+
+		hostLinks, err := linkService.GetHostLinks()
+		if err != nil {
+			t.Fatalf("Error getting host links: %v", err)
+		}
+
+		counts := map[domain.Host]int{}
+		for _, hostLink := range hostLinks {
+			counts[hostLink.SrcHost+">"+hostLink.DstHost] = hostLink.Count
+		}
+
+		expected := map[domain.Host]int{"backfill.com>backfill.com": 1, "backfill.com>other.com": 2}
+		if len(counts) != len(expected) || counts["backfill.com>backfill.com"] != 1 || counts["backfill.com>other.com"] != 2 {
+			t.Errorf("Expected host link counts %v, got %v", expected, counts)
+		}
+
+		links, _ := sf.LinkRepo.GetAllBySrcID(util.PageID(urls[0]))
+		for _, link := range links {
+			if expected := link.DstID == util.PageID(urls[1]); link.Internal != expected {
+				t.Errorf("Expected internal %t for link to %s, got %t", expected, link.DstID, link.Internal)
+			}
+		}
+
+		// a second backfill leaves the populated graph alone
+		sf.LinkRepo.Create(&domain.Link{SrcID: util.PageID(urls[2]), DstID: util.PageID(urls[0])})
+
+		if err := linkService.BackfillHostLinks(); err != nil {
+			t.Fatalf("Error backfilling host links: %v", err)
+		}
+
+		hostLinks, _ = linkService.GetHostLinks()
+		if len(hostLinks) != len(expected) {
+			t.Errorf("Expected %d host links after a second backfill, got %d", len(expected), len(hostLinks))
+		}
+	})
+}
+
+func TestAnchors(t *testing.T) {
+	t.Run("stores anchor text and rel from crawled links", func(t *testing.T) {
+		sf := testfactory.NewServiceFactory(
+			testfactory.WithShard(&domain.Shard{ID: 0}),
+		)
+
+		linkService := linkService.NewService(
+			linkService.WithLinkRepo(sf.LinkRepo),
+			linkService.WithEventService(sf.EventService),
+			linkService.WithLogger(testutil.NewTestLogger()),
+			linkService.WithEventListeners(),
+		)
+
+		srcID := util.PageID("https://anchors.com")
+		dst := domain.URL("https://recipes.com/pasta")
+
+		sf.EventService.Publish(&domain.CrawlCompleted{
+			PageID: srcID,
+			URL:    "https://anchors.com",
+			Links:  []domain.URL{dst, dst, "https://anchors.com/about"},
+			Anchors: []domain.Anchor{
+				{URL: dst, Text: "", Rel: "nofollow"},
+				{URL: dst, Text: "Pasta recipes", Rel: "ugc nofollow"},
+				{URL: "https://anchors.com/about", Text: "About"},
+			},
+		})
+
+		// Assert
+		links, _ := sf.LinkRepo.GetAllByDstID(util.PageID(dst))
+
+		if len(links) != 1 {
+			t.Fatalf("Expected 1 link, got %d", len(links))
+		}
+
+		if links[0].Text != "Pasta recipes" {
+			t.Errorf("Expected text 'Pasta recipes', got '%s'", links[0].Text)
+		}
+
+		if links[0].Rel != "nofollow ugc" {
+			t.Errorf("Expected rel 'nofollow ugc', got '%s'", links[0].Rel)
+		}
+
+		texts, err := linkService.GetAnchorTexts(util.PageID(dst))
+		if err != nil {
+			t.Fatalf("Error getting anchor texts: %v", err)
+		}
+
+		if len(texts) != 1 || texts[0] != "Pasta recipes" {
+			t.Errorf("Expected anchor texts [Pasta recipes], got %v", texts)
+		}
+
+		// internal links are left out
+		texts, _ = linkService.GetAnchorTexts(util.PageID("https://anchors.com/about"))
+
+		if len(texts) != 0 {
+			t.Errorf("Expected no anchor texts for an internal link, got %v", texts)
+		}
+	})
+}
+
+func TestPageAliases(t *testing.T) {
+	t.Run("merges the links to duplicate pages into their canonical page", func(t *testing.T) {
+		sf := testfactory.NewServiceFactory(
+			testfactory.WithShard(&domain.Shard{ID: 0}),
+		)
+
+		aliasRepo := aliasRepo.NewRepository()
+
+		linkService := linkService.NewService(
+			linkService.WithLinkRepo(sf.LinkRepo),
+			linkService.WithPageAliasRepo(aliasRepo),
+			linkService.WithEventService(sf.EventService),
+			linkService.WithLogger(testutil.NewTestLogger()),
+			linkService.WithEventListeners(),
+		)
+
+		printID := util.PageID("http://example.com/print")
+		articleID := util.PageID("http://example.com/article")
+		homeID := util.PageID("http://example.com/")
+
+		aliasRepo.Save(&domain.PageAlias{PageID: printID, CanonicalID: articleID, Reason: domain.PageAliasCanonical})
+
+		// the print version links home and back to the article
+		sf.EventService.Publish(&domain.CrawlCompleted{
+			PageID:    printID,
+			URL:       "http://example.com/print",
+			Canonical: "http://example.com/article",
+			Anchors: []domain.Anchor{
+				{URL: "http://example.com/"},
+				{URL: "http://example.com/article"},
+			},
+		})
+
+		// other pages link to both versions
+		sf.EventService.Publish(&domain.CrawlCompleted{
+			PageID: homeID,
+			URL:    "http://example.com/",
+			Anchors: []domain.Anchor{
+				{URL: "http://example.com/print", Text: "Printable pasta", Rel: "nofollow"},
+				{URL: "http://example.com/article", Text: "Pasta"},
+			},
+		})
+
+		sf.EventService.Publish(&domain.CrawlCompleted{
+			PageID: util.PageID("http://other.com/"),
+			URL:    "http://other.com/",
+			Anchors: []domain.Anchor{
+				{URL: "http://example.com/print", Text: "Printable pasta"},
+			},
+		})
+
+		links, err := linkService.GetAll()
+		if err != nil {
+			t.Fatalf("Error getting links: %v", err)
+		}
+
+		edges := map[domain.PageID]map[domain.PageID]*domain.Link{}
+		for _, link := range links {
+			if link.DstID == printID {
+				t.Errorf("Expected no links to the duplicate, got %v", link)
+			}
+			if edges[link.SrcID] == nil {
+				edges[link.SrcID] = map[domain.PageID]*domain.Link{}
+			}
+			if edges[link.SrcID][link.DstID] != nil {
+				t.Errorf("Expected merged links to be unique, got two from %s to %s", link.SrcID, link.DstID)
+			}
+			edges[link.SrcID][link.DstID] = link
+		}
+
+		// naming a canonical page doesn't hand it the duplicate's links
+		if edges[articleID][homeID] != nil || edges[printID][homeID] == nil {
+			t.Errorf("Expected the duplicate's links to stay its own")
+		}
+
+		if edges[printID][articleID] == nil {
+			t.Errorf("Expected the duplicate's link to the canonical page")
+		}
+
+		if link := edges[homeID][articleID]; link == nil || link.Nofollow() {
+			t.Errorf("Expected a followed link from home to the canonical page, got %v", link)
+		}
+
+		texts, err := linkService.GetAnchorTexts(articleID)
+		if err != nil {
+			t.Fatalf("Error getting anchor texts: %v", err)
+		}
+
+		if len(texts) != 1 || texts[0] != "Printable pasta" {
+			t.Errorf("Expected anchor text of links to the duplicate, got %v", texts)
+		}
+	})
+
+	t.Run("moves the links of redirected pages to their target", func(t *testing.T) {
+		sf := testfactory.NewServiceFactory(
+			testfactory.WithShard(&domain.Shard{ID: 0}),
+		)
+
+		aliasRepo := aliasRepo.NewRepository()
+
+		linkService := linkService.NewService(
+			linkService.WithLinkRepo(sf.LinkRepo),
+			linkService.WithPageAliasRepo(aliasRepo),
+			linkService.WithEventService(sf.EventService),
+			linkService.WithLogger(testutil.NewTestLogger()),
+			linkService.WithEventListeners(),
+		)
+
+		oldID := util.PageID("http://example.com/old")
+		newID := util.PageID("http://example.com/new")
+		homeID := util.PageID("http://example.com/")
+
+		aliasRepo.Save(&domain.PageAlias{PageID: oldID, CanonicalID: newID, Reason: domain.PageAliasRedirect})
+
+		sf.EventService.Publish(&domain.CrawlCompleted{
+			PageID:   oldID,
+			URL:      "http://example.com/old",
+			FinalURL: "http://example.com/new",
+			Anchors: []domain.Anchor{
+				{URL: "http://example.com/"},
+			},
+		})
+
+		links, err := linkService.GetAll()
+		if err != nil {
+			t.Fatalf("Error getting links: %v", err)
+		}
+
+		if len(links) != 1 || links[0].SrcID != newID || links[0].DstID != homeID {
+			t.Errorf("Expected one link from the redirect target home, got %v", links)
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package migration
 
 import (
+	"crawlquery/api/domain"
 	"database/sql"
 	"fmt"
 	"time"
@@ -118,6 +119,123 @@ var migrations = []Migration{
 			` + "`rank`" + ` FLOAT NOT NULL,
 			created_at TIMESTAMP NOT NULL)`,
 	},
+	{
+		Name: "add_internal_to_links_table",
+		SQL:  `ALTER TABLE links ADD COLUMN internal BOOLEAN NOT NULL DEFAULT FALSE`,
+	},
+	{
+		// hosts are sites, see util.Site, and the API backfills the table
+		// from the links table on start up while it is empty
+		Name: "create_host_links_table",
+		SQL: `CREATE TABLE host_links (
+			src_host VARCHAR(255) NOT NULL,
+			dst_host VARCHAR(255) NOT NULL,
+			count INT NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (src_host, dst_host))`,
+	},
+	{
+		Name: "create_host_ranks_table",
+		SQL: `CREATE TABLE host_ranks (
+			host VARCHAR(255) PRIMARY KEY,
+			` + "`rank`" + ` FLOAT NOT NULL,
+			created_at TIMESTAMP NOT NULL)`,
+	},
+	{
+		Name: "add_anchors_to_links_table",
+		SQL: `ALTER TABLE links
+			ADD COLUMN text VARCHAR(255) NOT NULL DEFAULT '',
+			ADD COLUMN rel VARCHAR(64) NOT NULL DEFAULT '',
+			ADD INDEX (dst_id)`,
+	},
+	{
+		Name: "create_page_aliases_table",
+		SQL: `CREATE TABLE page_aliases (
+			page_id VARCHAR(32) PRIMARY KEY,
+			canonical_id VARCHAR(32) NOT NULL,
+			reason VARCHAR(32) NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			INDEX (canonical_id))`,
+	},
+	{
+		Name: "add_retries_to_crawl_jobs",
+		SQL: `ALTER TABLE crawl_jobs
+			ADD COLUMN attempts INT UNSIGNED NOT NULL DEFAULT 0,
+			ADD COLUMN next_attempt_at TIMESTAMP NULL,
+			ADD INDEX (status, next_attempt_at),
+			ADD INDEX (status, updated_at)`,
+	},
+	{
+		Name: "add_priority_to_crawl_jobs",
+		SQL: `ALTER TABLE crawl_jobs
+			ADD COLUMN host VARCHAR(255) NOT NULL DEFAULT '',
+			ADD COLUMN priority DOUBLE NOT NULL DEFAULT 0,
+			ADD INDEX (host),
+			ADD INDEX (status, priority)`,
+	},
+	{
+		Name: "create_crawl_scopes_table",
+		SQL: `CREATE TABLE crawl_scopes (
+			id VARCHAR(36) PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			seeds TEXT NOT NULL,
+			domains TEXT NOT NULL,
+			max_depth INT NOT NULL DEFAULT 0,
+			page_budget INT NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL
+		)`,
+	},
+	{
+		Name: "add_scope_to_pages",
+		SQL: `ALTER TABLE pages
+			ADD COLUMN scope_id VARCHAR(36) NOT NULL DEFAULT '',
+			ADD COLUMN depth INT NOT NULL DEFAULT 0,
+			ADD INDEX (scope_id)`,
+	},
+	{
+		Name: "add_scope_to_crawl_jobs",
+		SQL: `ALTER TABLE crawl_jobs
+			ADD COLUMN scope_id VARCHAR(36) NOT NULL DEFAULT ''`,
+	},
+	{
+		Name: "create_feeds_table",
+		SQL: `CREATE TABLE feeds (
+			id VARCHAR(32) PRIMARY KEY,
+			url VARCHAR(2083) NOT NULL,
+			page_id VARCHAR(32) NOT NULL,
+			etag VARCHAR(255) NOT NULL DEFAULT '',
+			last_modified VARCHAR(255) NOT NULL DEFAULT '',
+			poll_interval BIGINT NOT NULL,
+			failures INT NOT NULL DEFAULT 0,
+			last_polled_at TIMESTAMP NULL,
+			next_poll_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			INDEX (next_poll_at)
+		)`,
+	},
+	{
+		// jobs completed before recrawls were scheduled are recrawled
+		// a day after they completed, as pages with too little history are
+		Name: "schedule_recrawl_of_completed_crawl_jobs",
+		SQL: fmt.Sprintf(`UPDATE crawl_jobs
+			SET next_attempt_at = updated_at + INTERVAL 1 DAY
+			WHERE status = %d AND next_attempt_at IS NULL`, domain.CrawlStatusCompleted),
+	},
+	{
+		Name: "add_anchor_hash_to_index_jobs",
+		SQL: `ALTER TABLE index_jobs
+			ADD COLUMN anchor_hash VARCHAR(32) NOT NULL DEFAULT ''`,
+	},
+	{
+		// the host of a job is its URL's host name without any "www.", see
+		// util.Host
+		Name: "backfill_host_of_crawl_jobs",
+		SQL: `UPDATE crawl_jobs
+			SET host = TRIM(LEADING 'www.' FROM LOWER(
+				SUBSTRING_INDEX(SUBSTRING_INDEX(SUBSTRING_INDEX(SUBSTRING_INDEX(SUBSTRING_INDEX(
+					SUBSTRING_INDEX(url, '://', -1), '/', 1), '?', 1), '#', 1), '@', -1), ':', 1)))
+			WHERE host = ''`,
+	},
 }
 
 var migrationTable = `CREATE TABLE IF NOT EXISTS migrations (
@@ -6,7 +6,11 @@ import (
 	"crawlquery/node/dto"
 	"crawlquery/pkg/client/node"
 	"crawlquery/pkg/util"
+	"errors"
+	"fmt"
 	"math/rand"
+	"net/http"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
@@ -245,17 +249,49 @@ func (s *Service) SendCrawlJob(ctx context.Context, n *domain.Node, job *domain.
 		node.WithContext(ctx),
 	)
 
-	return c.Crawl(string(job.PageID), string(job.URL))
+	res, err := c.Crawl(string(job.PageID), string(job.URL))
+
+	var crawlErr *node.CrawlError
+	if errors.As(err, &crawlErr) && crawlErr.Disallowed {
+		return nil, domain.ErrCrawlDisallowed
+	}
+
+	if errors.As(err, &crawlErr) && crawlErr.SiteStatusCode != 0 {
+		return nil, &domain.CrawlFetchError{
+			StatusCode: crawlErr.SiteStatusCode,
+			RetryAfter: crawlErr.RetryAfter,
+			Err:        err,
+		}
+	}
+
+	return res, err
 }
 
-func (s *Service) SendIndexJob(ctx context.Context, n *domain.Node, job *domain.IndexJob) error {
+func (s *Service) SendIndexJob(ctx context.Context, n *domain.Node, job *domain.IndexJob, anchors ...string) error {
 	c := node.NewClient(
 		node.WithHostname(n.Hostname),
 		node.WithPort(n.Port),
 		node.WithContext(ctx),
 	)
 
-	return c.Index(string(job.PageID), string(job.URL), string(job.ContentHash))
+	return c.Index(string(job.PageID), string(job.URL), string(job.ContentHash), anchors...)
+}
+
+func (s *Service) SendQuery(ctx context.Context, n *domain.Node, query string) (*dto.QueryResponse, error) {
+	c := node.NewClient(
+		node.WithHostname(n.Hostname),
+		node.WithPort(n.Port),
+		node.WithContext(ctx),
+	)
+
+	res, err := c.Query(query)
+
+	var queryErr *node.QueryError
+	if errors.As(err, &queryErr) && queryErr.StatusCode == http.StatusBadRequest {
+		return nil, fmt.Errorf("%w: %s", domain.ErrInvalidQuery, strings.TrimPrefix(queryErr.Message, domain.ErrInvalidQuery.Error()+": "))
+	}
+
+	return res, err
 }
 
 func (s *Service) Auth(key string) (*domain.Node, error) {
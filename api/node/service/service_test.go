@@ -916,6 +916,65 @@ func TestSendIndexJob(t *testing.T) {
 	})
 }
 
+func TestSendQuery(t *testing.T) {
+	t.Run("can send a query", func(t *testing.T) {
+		node := &domain.Node{
+			ID:       "1",
+			Hostname: "testnode",
+			Port:     8080,
+		}
+
+		defer gock.Off()
+
+		gock.New("http://testnode:8080").
+			Post("/query").
+			JSON(&dto.QueryRequest{
+				Query: "SELECT id FROM pages",
+			}).
+			Reply(200).
+			JSON(&dto.QueryResponse{
+				Columns: []dto.QueryColumn{{Name: "id", Type: "string"}},
+				Rows:    [][]any{{"page1"}},
+			})
+
+		nodeService := service.NewService(
+			service.WithLogger(testutil.NewTestLogger()),
+		)
+		res, err := nodeService.SendQuery(context.Background(), node, "SELECT id FROM pages")
+
+		if err != nil {
+			t.Fatalf("Error sending query: %v", err)
+		}
+
+		if len(res.Rows) != 1 || res.Rows[0][0] != "page1" {
+			t.Errorf("Expected one row with page1, got %v", res.Rows)
+		}
+	})
+
+	t.Run("handles error sending query", func(t *testing.T) {
+		node := &domain.Node{
+			ID:       "1",
+			Hostname: "testnode",
+			Port:     8080,
+		}
+
+		defer gock.Off()
+
+		gock.New("http://testnode:8080").
+			Post("/query").
+			Reply(500)
+
+		nodeService := service.NewService(
+			service.WithLogger(testutil.NewTestLogger()),
+		)
+		_, err := nodeService.SendQuery(context.Background(), node, "SELECT id FROM pages")
+
+		if err == nil {
+			t.Fatalf("Expected error sending query")
+		}
+	})
+}
+
 func TestRandomize(t *testing.T) {
 	t.Run("can randomize a list of nodes", func(t *testing.T) {
 		nodeRepo := nodeRepo.NewRepository()
@@ -0,0 +1,67 @@
+package mem
+
+import (
+	"crawlquery/api/domain"
+	"sync"
+)
+
+type Repository struct {
+	aliases map[domain.PageID]*domain.PageAlias
+	lock    sync.RWMutex
+}
+
+func NewRepository() *Repository {
+	return &Repository{
+		aliases: make(map[domain.PageID]*domain.PageAlias),
+	}
+}
+
+func (r *Repository) Get(pageID domain.PageID) (*domain.PageAlias, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	alias, ok := r.aliases[pageID]
+	if !ok {
+		return nil, domain.ErrPageAliasNotFound
+	}
+
+	copied := *alias
+	return &copied, nil
+}
+
+func (r *Repository) GetAll() ([]*domain.PageAlias, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var aliases []*domain.PageAlias
+	for _, alias := range r.aliases {
+		copied := *alias
+		aliases = append(aliases, &copied)
+	}
+
+	return aliases, nil
+}
+
+func (r *Repository) GetByCanonicalID(canonicalID domain.PageID) ([]*domain.PageAlias, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var aliases []*domain.PageAlias
+	for _, alias := range r.aliases {
+		if alias.CanonicalID == canonicalID {
+			copied := *alias
+			aliases = append(aliases, &copied)
+		}
+	}
+
+	return aliases, nil
+}
+
+func (r *Repository) Save(alias *domain.PageAlias) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	copied := *alias
+	r.aliases[alias.PageID] = &copied
+	return nil
+}
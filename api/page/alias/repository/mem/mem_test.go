@@ -0,0 +1,67 @@
+package mem
+
+import (
+	"crawlquery/api/domain"
+	"testing"
+	"time"
+)
+
+func TestSave(t *testing.T) {
+	t.Run("replaces the alias of a page", func(t *testing.T) {
+		repo := NewRepository()
+
+		repo.Save(&domain.PageAlias{PageID: "print", CanonicalID: "old", Reason: domain.PageAliasCanonical, CreatedAt: time.Now()})
+		repo.Save(&domain.PageAlias{PageID: "print", CanonicalID: "article", Reason: domain.PageAliasCanonical, CreatedAt: time.Now()})
+
+		alias, err := repo.Get("print")
+		if err != nil {
+			t.Fatalf("Error getting alias: %v", err)
+		}
+
+		if alias.CanonicalID != "article" {
+			t.Errorf("Expected canonical ID article, got %s", alias.CanonicalID)
+		}
+
+		aliases, err := repo.GetAll()
+		if err != nil {
+			t.Fatalf("Error getting aliases: %v", err)
+		}
+
+		if len(aliases) != 1 {
+			t.Errorf("Expected 1 alias, got %d", len(aliases))
+		}
+	})
+}
+
+func TestGet(t *testing.T) {
+	t.Run("returns an error for a page without an alias", func(t *testing.T) {
+		repo := NewRepository()
+
+		if _, err := repo.Get("article"); err != domain.ErrPageAliasNotFound {
+			t.Errorf("Expected ErrPageAliasNotFound, got %v", err)
+		}
+	})
+}
+
+func TestGetByCanonicalID(t *testing.T) {
+	repo := NewRepository()
+
+	repo.Save(&domain.PageAlias{PageID: "print", CanonicalID: "article", Reason: domain.PageAliasCanonical, CreatedAt: time.Now()})
+	repo.Save(&domain.PageAlias{PageID: "old", CanonicalID: "article", Reason: domain.PageAliasRedirect, CreatedAt: time.Now()})
+	repo.Save(&domain.PageAlias{PageID: "other", CanonicalID: "elsewhere", Reason: domain.PageAliasRedirect, CreatedAt: time.Now()})
+
+	aliases, err := repo.GetByCanonicalID("article")
+	if err != nil {
+		t.Fatalf("Error getting aliases: %v", err)
+	}
+
+	if len(aliases) != 2 {
+		t.Fatalf("Expected 2 aliases, got %d", len(aliases))
+	}
+
+	for _, alias := range aliases {
+		if alias.CanonicalID != "article" {
+			t.Errorf("Expected canonical ID article, got %s", alias.CanonicalID)
+		}
+	}
+}
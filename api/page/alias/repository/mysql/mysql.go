@@ -0,0 +1,81 @@
+package mysql
+
+import (
+	"crawlquery/api/domain"
+	"database/sql"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func (r *Repository) Get(pageID domain.PageID) (*domain.PageAlias, error) {
+	var alias domain.PageAlias
+
+	err := r.db.QueryRow("SELECT page_id, canonical_id, reason, created_at FROM page_aliases WHERE page_id = ?", pageID).Scan(&alias.PageID, &alias.CanonicalID, &alias.Reason, &alias.CreatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrPageAliasNotFound
+		}
+		return nil, err
+	}
+
+	return &alias, nil
+}
+
+func (r *Repository) GetAll() ([]*domain.PageAlias, error) {
+	rows, err := r.db.Query("SELECT page_id, canonical_id, reason, created_at FROM page_aliases")
+	if err != nil {
+		return nil, err
+	}
+
+	return scanAliases(rows)
+}
+
+func (r *Repository) GetByCanonicalID(canonicalID domain.PageID) ([]*domain.PageAlias, error) {
+	rows, err := r.db.Query("SELECT page_id, canonical_id, reason, created_at FROM page_aliases WHERE canonical_id = ?", canonicalID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanAliases(rows)
+}
+
+func scanAliases(rows *sql.Rows) ([]*domain.PageAlias, error) {
+	defer rows.Close()
+
+	var aliases []*domain.PageAlias
+	for rows.Next() {
+		var alias domain.PageAlias
+		err := rows.Scan(&alias.PageID, &alias.CanonicalID, &alias.Reason, &alias.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		aliases = append(aliases, &alias)
+	}
+
+	return aliases, rows.Err()
+}
+
+func (r *Repository) Save(alias *domain.PageAlias) error {
+	_, err := r.db.Exec(
+		"INSERT INTO page_aliases (page_id, canonical_id, reason, created_at) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE canonical_id = VALUES(canonical_id), reason = VALUES(reason), created_at = VALUES(created_at)",
+		alias.PageID,
+		alias.CanonicalID,
+		alias.Reason,
+		alias.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package mysql_test
+
+import (
+	"crawlquery/api/domain"
+	"crawlquery/api/migration"
+	"crawlquery/pkg/testutil"
+	"testing"
+	"time"
+
+	aliasRepo "crawlquery/api/page/alias/repository/mysql"
+)
+
+func TestSave(t *testing.T) {
+	t.Run("stores and replaces the alias of a page", func(t *testing.T) {
+		db := testutil.CreateTestMysqlDB()
+		defer db.Close()
+		migration.Up(db)
+
+		// Arrange
+		repo := aliasRepo.NewRepository(db)
+
+		defer db.Exec("DELETE FROM page_aliases WHERE page_id = ?", "savealiasprint")
+
+		// Act
+		for _, canonicalID := range []domain.PageID{"savealiasold", "savealiasarticle"} {
+			err := repo.Save(&domain.PageAlias{
+				PageID:      "savealiasprint",
+				CanonicalID: canonicalID,
+				Reason:      domain.PageAliasCanonical,
+				CreatedAt:   time.Now(),
+			})
+			if err != nil {
+				t.Errorf("Error saving alias: %v", err)
+			}
+		}
+
+		// Assert
+		alias, err := repo.Get("savealiasprint")
+		if err != nil {
+			t.Fatalf("Error getting alias: %v", err)
+		}
+
+		if alias.CanonicalID != "savealiasarticle" {
+			t.Errorf("Expected canonical ID savealiasarticle, got %s", alias.CanonicalID)
+		}
+
+		if alias.Reason != domain.PageAliasCanonical {
+			t.Errorf("Expected reason canonical, got %s", alias.Reason)
+		}
+	})
+}
+
+func TestGet(t *testing.T) {
+	t.Run("returns an error for a page without an alias", func(t *testing.T) {
+		db := testutil.CreateTestMysqlDB()
+		defer db.Close()
+		migration.Up(db)
+
+		repo := aliasRepo.NewRepository(db)
+
+		if _, err := repo.Get("getaliasmissing"); err != domain.ErrPageAliasNotFound {
+			t.Errorf("Expected ErrPageAliasNotFound, got %v", err)
+		}
+	})
+}
+
+func TestGetByCanonicalID(t *testing.T) {
+	t.Run("returns the aliases of a page", func(t *testing.T) {
+		db := testutil.CreateTestMysqlDB()
+		defer db.Close()
+		migration.Up(db)
+
+		repo := aliasRepo.NewRepository(db)
+
+		for _, pageID := range []domain.PageID{"bycanonicalprint", "bycanonicalold"} {
+			defer db.Exec("DELETE FROM page_aliases WHERE page_id = ?", pageID)
+
+			err := repo.Save(&domain.PageAlias{
+				PageID:      pageID,
+				CanonicalID: "bycanonicalarticle",
+				Reason:      domain.PageAliasCanonical,
+				CreatedAt:   time.Now(),
+			})
+			if err != nil {
+				t.Fatalf("Error saving alias: %v", err)
+			}
+		}
+
+		aliases, err := repo.GetByCanonicalID("bycanonicalarticle")
+		if err != nil {
+			t.Fatalf("Error getting aliases: %v", err)
+		}
+
+		if len(aliases) != 2 {
+			t.Errorf("Expected 2 aliases, got %d", len(aliases))
+		}
+	})
+}
@@ -70,7 +70,7 @@ func TestCreate(t *testing.T) {
 		ctx, _ := gin.CreateTestContext(w)
 
 		var req dto.CreatePageRequest
-		req.URL = "http://example.com"
+		req.URL = "http://example.com/"
 
 		encoded, err := json.Marshal(req)
 
@@ -87,7 +87,7 @@ func TestCreate(t *testing.T) {
 		}
 
 		// test
-		url := domain.URL("http://example.com")
+		url := domain.URL("http://example.com/")
 		pageID := util.PageID(url)
 
 		page, err := pageRepo.Get(pageID)
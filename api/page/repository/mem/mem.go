@@ -1,6 +1,9 @@
 package mem
 
-import "crawlquery/api/domain"
+import (
+	"crawlquery/api/domain"
+	"sort"
+)
 
 type Repository struct {
 	pages map[domain.PageID]*domain.Page
@@ -20,7 +23,56 @@ func (r *Repository) Get(id domain.PageID) (*domain.Page, error) {
 	return page, nil
 }
 
+func (r *Repository) GetMany(ids []domain.PageID) (map[domain.PageID]*domain.Page, error) {
+	pages := make(map[domain.PageID]*domain.Page, len(ids))
+	for _, id := range ids {
+		if page, ok := r.pages[id]; ok {
+			pages[id] = page
+		}
+	}
+	return pages, nil
+}
+
 func (r *Repository) Create(p *domain.Page) error {
 	r.pages[p.ID] = p
 	return nil
 }
+
+func (r *Repository) SetScope(id domain.PageID, scopeID domain.ScopeID, depth int) error {
+	page, ok := r.pages[id]
+	if !ok {
+		return domain.ErrPageNotFound
+	}
+	page.ScopeID = scopeID
+	page.Depth = depth
+	return nil
+}
+
+func (r *Repository) CountByScope(scopeID domain.ScopeID) (int, error) {
+	count := 0
+	for _, page := range r.pages {
+		if page.ScopeID == scopeID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *Repository) ListAfter(afterID domain.PageID, limit int) ([]*domain.Page, error) {
+	var pages []*domain.Page
+	for id, page := range r.pages {
+		if id > afterID {
+			pages = append(pages, page)
+		}
+	}
+
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].ID < pages[j].ID
+	})
+
+	if len(pages) > limit {
+		pages = pages[:limit]
+	}
+
+	return pages, nil
+}
@@ -75,3 +75,82 @@ func TestGet(t *testing.T) {
 		}
 	})
 }
+
+func TestCountByScope(t *testing.T) {
+	t.Run("counts the pages in a scope", func(t *testing.T) {
+		repo := NewRepository()
+
+		repo.Create(&domain.Page{ID: "a", ScopeID: "recipes"})
+		repo.Create(&domain.Page{ID: "b", ScopeID: "recipes", Depth: 1})
+		repo.Create(&domain.Page{ID: "c"})
+
+		count, err := repo.CountByScope("recipes")
+
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		if count != 2 {
+			t.Errorf("expected 2 pages, got %d", count)
+		}
+	})
+}
+
+func TestSetScope(t *testing.T) {
+	t.Run("moves a page into a scope", func(t *testing.T) {
+		repo := NewRepository()
+
+		repo.pages["a"] = &domain.Page{ID: "a", Depth: 3}
+
+		if err := repo.SetScope("a", "recipes", 0); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if repo.pages["a"].ScopeID != "recipes" || repo.pages["a"].Depth != 0 {
+			t.Errorf("expected scope recipes at depth 0, got %s at %d", repo.pages["a"].ScopeID, repo.pages["a"].Depth)
+		}
+
+		if err := repo.SetScope("b", "recipes", 0); err != domain.ErrPageNotFound {
+			t.Errorf("expected ErrPageNotFound, got %v", err)
+		}
+	})
+}
+
+func TestGetMany(t *testing.T) {
+	t.Run("returns the pages that exist", func(t *testing.T) {
+		repo := NewRepository()
+
+		repo.pages["123"] = &domain.Page{ID: "123"}
+		repo.pages["456"] = &domain.Page{ID: "456"}
+
+		res, err := repo.GetMany([]domain.PageID{"123", "456", "789"})
+
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		if len(res) != 2 || res["123"] == nil || res["456"] == nil {
+			t.Errorf("expected pages 123 and 456, got %v", res)
+		}
+	})
+}
+
+func TestListAfter(t *testing.T) {
+	t.Run("lists pages in order of ID", func(t *testing.T) {
+		repo := NewRepository()
+
+		for _, id := range []domain.PageID{"c", "a", "d", "b"} {
+			repo.pages[id] = &domain.Page{ID: id}
+		}
+
+		res, err := repo.ListAfter("a", 2)
+
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		if len(res) != 2 || res[0].ID != "b" || res[1].ID != "c" {
+			t.Errorf("expected pages b and c, got %v", res)
+		}
+	})
+}
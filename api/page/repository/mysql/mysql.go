@@ -3,8 +3,12 @@ package mysql
 import (
 	"crawlquery/api/domain"
 	"database/sql"
+	"strings"
 )
 
+// batchSize is the number of pages looked up per query by GetMany.
+const batchSize = 500
+
 type Repository struct {
 	db *sql.DB
 }
@@ -18,7 +22,7 @@ func NewRepository(db *sql.DB) *Repository {
 func (r *Repository) Get(id domain.PageID) (*domain.Page, error) {
 	var page domain.Page
 
-	err := r.db.QueryRow("SELECT id, url, shard_id, created_at FROM pages WHERE id = ?", id).Scan(&page.ID, &page.URL, &page.ShardID, &page.CreatedAt)
+	err := r.db.QueryRow("SELECT id, url, shard_id, scope_id, depth, created_at FROM pages WHERE id = ?", id).Scan(&page.ID, &page.URL, &page.ShardID, &page.ScopeID, &page.Depth, &page.CreatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -30,10 +34,80 @@ func (r *Repository) Get(id domain.PageID) (*domain.Page, error) {
 	return &page, nil
 }
 
+// GetMany returns the given pages by ID, leaving out those that don't
+// exist.
+func (r *Repository) GetMany(ids []domain.PageID) (map[domain.PageID]*domain.Page, error) {
+	pages := make(map[domain.PageID]*domain.Page, len(ids))
+
+	for start := 0; start < len(ids); start += batchSize {
+		batch := ids[start:min(start+batchSize, len(ids))]
+
+		args := make([]any, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+
+		rows, err := r.db.Query("SELECT id, url, shard_id, scope_id, depth, created_at FROM pages WHERE id IN (?"+strings.Repeat(", ?", len(batch)-1)+")", args...)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var page domain.Page
+			if err := rows.Scan(&page.ID, &page.URL, &page.ShardID, &page.ScopeID, &page.Depth, &page.CreatedAt); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			pages[page.ID] = &page
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return pages, nil
+}
+
 func (r *Repository) Create(p *domain.Page) error {
-	_, err := r.db.Exec("INSERT INTO pages (id, url, shard_id, created_at) VALUES (?, ?, ?, ?)", p.ID, p.URL, p.ShardID, p.CreatedAt)
+	_, err := r.db.Exec("INSERT INTO pages (id, url, shard_id, scope_id, depth, created_at) VALUES (?, ?, ?, ?, ?, ?)", p.ID, p.URL, p.ShardID, p.ScopeID, p.Depth, p.CreatedAt)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+func (r *Repository) SetScope(id domain.PageID, scopeID domain.ScopeID, depth int) error {
+	_, err := r.db.Exec("UPDATE pages SET scope_id = ?, depth = ? WHERE id = ?", scopeID, depth, id)
+	return err
+}
+
+func (r *Repository) CountByScope(scopeID domain.ScopeID) (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM pages WHERE scope_id = ?", scopeID).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *Repository) ListAfter(afterID domain.PageID, limit int) ([]*domain.Page, error) {
+	rows, err := r.db.Query("SELECT id, url, shard_id, scope_id, depth, created_at FROM pages WHERE id > ? ORDER BY id LIMIT ?", afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pages []*domain.Page
+	for rows.Next() {
+		var page domain.Page
+		if err := rows.Scan(&page.ID, &page.URL, &page.ShardID, &page.ScopeID, &page.Depth, &page.CreatedAt); err != nil {
+			return nil, err
+		}
+		pages = append(pages, &page)
+	}
+
+	return pages, rows.Err()
+}
@@ -123,3 +123,146 @@ func TestCreate(t *testing.T) {
 		}
 	})
 }
+
+func TestCountByScope(t *testing.T) {
+	t.Run("stores the scope of pages and counts them", func(t *testing.T) {
+		db := testutil.CreateTestMysqlDB()
+		defer db.Close()
+		err := migration.Up(db)
+
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		repo := mysql.NewRepository(db)
+
+		for _, page := range []*domain.Page{
+			{ID: "countscopea", URL: "http://example.com/a", ScopeID: "countscope", Depth: 0, CreatedAt: time.Now()},
+			{ID: "countscopeb", URL: "http://example.com/b", ScopeID: "countscope", Depth: 2, CreatedAt: time.Now()},
+		} {
+			if err := repo.Create(page); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer db.Exec("DELETE FROM pages WHERE id = ?", page.ID)
+		}
+
+		res, err := repo.Get("countscopeb")
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if res.ScopeID != "countscope" || res.Depth != 2 {
+			t.Errorf("expected scope countscope at depth 2, got %s at %d", res.ScopeID, res.Depth)
+		}
+
+		count, err := repo.CountByScope("countscope")
+
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		if count != 2 {
+			t.Errorf("expected 2 pages, got %d", count)
+		}
+	})
+}
+
+func TestSetScope(t *testing.T) {
+	t.Run("moves a page into a scope", func(t *testing.T) {
+		db := testutil.CreateTestMysqlDB()
+		defer db.Close()
+		err := migration.Up(db)
+
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		repo := mysql.NewRepository(db)
+
+		page := &domain.Page{ID: "setscope", URL: "http://example.com/setscope", Depth: 3, CreatedAt: time.Now()}
+		if err := repo.Create(page); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer db.Exec("DELETE FROM pages WHERE id = ?", page.ID)
+
+		if err := repo.SetScope(page.ID, "setscope", 0); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		res, err := repo.Get(page.ID)
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if res.ScopeID != "setscope" || res.Depth != 0 {
+			t.Errorf("expected scope setscope at depth 0, got %s at %d", res.ScopeID, res.Depth)
+		}
+	})
+}
+
+func TestGetMany(t *testing.T) {
+	t.Run("returns the pages that exist", func(t *testing.T) {
+		db := testutil.CreateTestMysqlDB()
+		defer db.Close()
+		err := migration.Up(db)
+
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		repo := mysql.NewRepository(db)
+
+		for _, page := range []*domain.Page{
+			{ID: "getmanya", URL: "http://example.com/a", CreatedAt: time.Now()},
+			{ID: "getmanyb", URL: "http://example.com/b", CreatedAt: time.Now()},
+		} {
+			if err := repo.Create(page); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer db.Exec("DELETE FROM pages WHERE id = ?", page.ID)
+		}
+
+		res, err := repo.GetMany([]domain.PageID{"getmanya", "getmanyb", "getmanymissing"})
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(res) != 2 || res["getmanyb"] == nil || res["getmanyb"].URL != "http://example.com/b" {
+			t.Errorf("expected pages getmanya and getmanyb, got %v", res)
+		}
+	})
+}
+
+func TestListAfter(t *testing.T) {
+	t.Run("lists pages in order of ID", func(t *testing.T) {
+		db := testutil.CreateTestMysqlDB()
+		defer db.Close()
+		err := migration.Up(db)
+
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		repo := mysql.NewRepository(db)
+
+		for _, id := range []domain.PageID{"listafterc", "listaftera", "listafterb"} {
+			if err := repo.Create(&domain.Page{ID: id, URL: "http://example.com/" + domain.URL(id), CreatedAt: time.Now()}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer db.Exec("DELETE FROM pages WHERE id = ?", id)
+		}
+
+		res, err := repo.ListAfter("listaftera", 2)
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(res) != 2 || res[0].ID != "listafterb" || res[1].ID != "listafterc" {
+			t.Errorf("expected pages listafterb and listafterc, got %v", res)
+		}
+	})
+}
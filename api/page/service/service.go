@@ -2,7 +2,9 @@ package service
 
 import (
 	"crawlquery/api/domain"
+	"crawlquery/pkg/canonical"
 	"crawlquery/pkg/util"
+	"strings"
 
 	"time"
 
@@ -11,9 +13,13 @@ import (
 
 type Service struct {
 	pageRepo     domain.PageRepository
+	aliasRepo    domain.PageAliasRepository
 	eventService domain.EventService
 	shardService domain.ShardService
 	crawlService domain.CrawlService
+	urlFilter    domain.URLFilterService
+	scopeRepo    domain.ScopeRepository
+	skippedRels  []string
 	logger       *zap.SugaredLogger
 }
 
@@ -31,12 +37,45 @@ func WithPageRepo(pageRepo domain.PageRepository) func(*Service) {
 	}
 }
 
+// WithPageAliasRepo records crawled pages that redirect to another page or
+// name it as their canonical version as aliases of it.
+func WithPageAliasRepo(aliasRepo domain.PageAliasRepository) func(*Service) {
+	return func(s *Service) {
+		s.aliasRepo = aliasRepo
+	}
+}
+
 func WithCrawlService(crawlService domain.CrawlService) func(*Service) {
 	return func(s *Service) {
 		s.crawlService = crawlService
 	}
 }
 
+// WithSkippedRels stops links carrying any of the rel values from adding
+// their target to the crawl, so that comment spam and paid links cannot steer
+// it. The target is still added if another link to it is found.
+func WithSkippedRels(rels ...string) func(*Service) {
+	return func(s *Service) {
+		s.skippedRels = rels
+	}
+}
+
+// WithURLFilterService keeps links to URLs the filter denies out of the
+// crawl. Pages created directly are not filtered.
+func WithURLFilterService(urlFilter domain.URLFilterService) func(*Service) {
+	return func(s *Service) {
+		s.urlFilter = urlFilter
+	}
+}
+
+// WithScopeRepo keeps the pages found from a page in a crawl scope within
+// the scope's boundaries.
+func WithScopeRepo(scopeRepo domain.ScopeRepository) func(*Service) {
+	return func(s *Service) {
+		s.scopeRepo = scopeRepo
+	}
+}
+
 func WithLogger(logger *zap.SugaredLogger) func(*Service) {
 	return func(s *Service) {
 		s.logger = logger
@@ -71,21 +110,163 @@ func (s *Service) registerEventListeners() {
 	}
 
 	s.eventService.Subscribe(domain.LinkCreatedKey, s.handleLinkCreated)
+	s.eventService.Subscribe(domain.CrawlCompletedKey, s.handleCrawlCompleted)
+}
+
+// handleCrawlCompleted folds a page that redirects to or names another as
+// its canonical version into it, adding that page to the crawl if it is new
+// and passes the URL filter.
+// Every URL the page redirected through on the way is folded in too.
+func (s *Service) handleCrawlCompleted(event domain.Event) {
+	crawlCompleted := event.(*domain.CrawlCompleted)
+
+	target, reason := crawlCompleted.Target()
+	if s.aliasRepo == nil || target == "" {
+		return
+	}
+
+	targetID := util.PageID(target)
+	if targetID == crawlCompleted.PageID {
+		return
+	}
+
+	// two pages pointing at each other would fold into nothing
+	if alias, err := s.aliasRepo.Get(targetID); err == nil && alias.CanonicalID == crawlCompleted.PageID {
+		s.logger.Warnw("Ignoring alias loop", "pageID", crawlCompleted.PageID, "targetID", targetID, "reason", reason)
+		return
+	}
+
+	err := s.aliasRepo.Save(&domain.PageAlias{
+		PageID:      crawlCompleted.PageID,
+		CanonicalID: targetID,
+		Reason:      reason,
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		s.logger.Errorw("Error saving page alias", "error", err, "pageID", crawlCompleted.PageID)
+		return
+	}
+
+	for _, redirect := range crawlCompleted.Redirects {
+		hopID := util.PageID(redirect)
+		if hopID == crawlCompleted.PageID || hopID == targetID {
+			continue
+		}
+
+		err := s.aliasRepo.Save(&domain.PageAlias{
+			PageID:      hopID,
+			CanonicalID: targetID,
+			Reason:      domain.PageAliasRedirect,
+			CreatedAt:   time.Now(),
+		})
+		if err != nil {
+			s.logger.Errorw("Error saving page alias", "error", err, "pageID", hopID)
+		}
+	}
+
+	if s.urlFilter != nil {
+		if decision := s.urlFilter.Evaluate(target); !decision.Allowed {
+			s.logger.Debugw("Filtered alias target", "url", target, "rule", decision.Rule, "reason", decision.Reason)
+			return
+		}
+	}
+
+	_, err = s.pageRepo.Get(targetID)
+	if err == domain.ErrPageNotFound {
+		// the target stands in for the page, so it is as far from the seeds
+		_, err = s.createFrom(crawlCompleted.PageID, target, 0)
+		if err != nil {
+			s.logCreateError(err, target)
+		}
+	}
 }
 
 func (s *Service) handleLinkCreated(event domain.Event) {
 	linkCreated := event.(*domain.LinkCreated)
 
+	for _, rel := range s.skippedRels {
+		if linkCreated.Link != nil && linkCreated.Link.HasRel(rel) {
+			return
+		}
+	}
+
+	if s.urlFilter != nil {
+		if decision := s.urlFilter.Evaluate(linkCreated.DstURL); !decision.Allowed {
+			s.logger.Debugw("Filtered link", "url", linkCreated.DstURL, "rule", decision.Rule, "reason", decision.Reason)
+			return
+		}
+	}
+
+	// a URL known to redirect elsewhere is crawled as its target
+	if s.aliasRepo != nil {
+		if _, err := s.aliasRepo.Get(util.PageID(linkCreated.DstURL)); err == nil {
+			return
+		}
+	}
+
+	url := linkCreated.URL
+	if url == "" {
+		url = linkCreated.DstURL
+	}
+
 	_, err := s.pageRepo.Get(util.PageID(linkCreated.DstURL))
 	if err == domain.ErrPageNotFound {
-		_, err = s.Create(linkCreated.DstURL)
+		if linkCreated.Link == nil {
+			_, err = s.Create(url)
+		} else {
+			_, err = s.createFrom(linkCreated.Link.SrcID, url, 1)
+		}
 		if err != nil {
-			s.logger.Errorw("Error creating page", "error", err, "url", linkCreated.DstURL)
+			s.logCreateError(err, linkCreated.DstURL)
 			return
 		}
 	}
 }
 
+// createFrom adds a page found from the page src to the crawl. A page found
+// from a page in a crawl scope joins the scope hops links further from its
+// seeds, unless that takes it outside the scope's domains, depth or page
+// budget. Pages found from unscoped pages are unscoped.
+func (s *Service) createFrom(src domain.PageID, url domain.URL, hops int) (*domain.Page, error) {
+	if s.scopeRepo == nil {
+		return s.Create(url)
+	}
+
+	srcPage, err := s.pageRepo.Get(src)
+	if err != nil || srcPage.ScopeID == "" {
+		return s.Create(url)
+	}
+
+	scope, err := s.scopeRepo.Get(srcPage.ScopeID)
+	if err != nil {
+		return nil, err
+	}
+
+	depth := srcPage.Depth + hops
+	if !scope.AllowsHost(util.Host(url)) || !scope.AllowsDepth(depth) {
+		return nil, domain.ErrPageOutOfScope
+	}
+
+	pages, err := s.pageRepo.CountByScope(scope.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !scope.AllowsPages(pages) {
+		return nil, domain.ErrScopeBudgetSpent
+	}
+
+	return s.CreateInScope(url, scope.ID, depth)
+}
+
+func (s *Service) logCreateError(err error, url domain.URL) {
+	if err == domain.ErrPageOutOfScope || err == domain.ErrScopeBudgetSpent {
+		s.logger.Debugw("Not creating page", "reason", err, "url", url)
+		return
+	}
+	s.logger.Errorw("Error creating page", "error", err, "url", url)
+}
+
 func (s *Service) Get(pageID domain.PageID) (*domain.Page, error) {
 	page, err := s.pageRepo.Get(pageID)
 	if err != nil {
@@ -95,9 +276,35 @@ func (s *Service) Get(pageID domain.PageID) (*domain.Page, error) {
 	return page, nil
 }
 
+// GetMany returns the given pages by ID in one lookup, leaving out those
+// that don't exist.
+func (s *Service) GetMany(pageIDs []domain.PageID) (map[domain.PageID]*domain.Page, error) {
+	pages, err := s.pageRepo.GetMany(pageIDs)
+	if err != nil {
+		s.logger.Errorw("Error getting pages", "error", err)
+		return nil, err
+	}
+	return pages, nil
+}
+
 func (s *Service) Create(url domain.URL) (*domain.Page, error) {
+	return s.CreateInScope(url, "", 0)
+}
 
-	pageID := util.PageID(url)
+// CreateInScope creates a page in a crawl scope, depth links away from the
+// scope's seeds. The page is identified by the canonical form of its URL,
+// but fetched from the URL as given, less any fragment, as servers can tell
+// apart URLs that canonicalize the same, such as "/dir/" and "/dir".
+func (s *Service) CreateInScope(url domain.URL, scopeID domain.ScopeID, depth int) (*domain.Page, error) {
+	canonicalURL, err := canonical.URL(url)
+	if err != nil {
+		s.logger.Errorw("Error canonicalizing url", "error", err, "url", url)
+		return nil, err
+	}
+	given, _, _ := strings.Cut(strings.TrimSpace(string(url)), "#")
+	url = domain.URL(given)
+
+	pageID := util.PageID(canonicalURL)
 
 	if _, err := s.pageRepo.Get(pageID); err == nil {
 		s.logger.Errorw("Page already exists", "pageID", pageID)
@@ -107,10 +314,12 @@ func (s *Service) Create(url domain.URL) (*domain.Page, error) {
 	page := &domain.Page{
 		ID:        pageID,
 		URL:       url,
+		ScopeID:   scopeID,
+		Depth:     depth,
 		CreatedAt: time.Now(),
 	}
 
-	shardID, err := s.shardService.GetURLShardID(url)
+	shardID, err := s.shardService.GetURLShardID(canonicalURL)
 
 	if err != nil {
 		s.logger.Errorw("Error getting shard ID", "error", err, "pageID", pageID)
@@ -130,3 +339,73 @@ func (s *Service) Create(url domain.URL) (*domain.Page, error) {
 
 	return page, nil
 }
+
+// legacyBatchSize is the number of pages read at a time by AliasLegacyPages.
+const legacyBatchSize = 1000
+
+// AliasLegacyPages folds each page stored under the ID of its URL as it was
+// written, before URLs were canonicalized, into the page of its canonical
+// URL, adding that page to the crawl if it is new. Links to and from either
+// then count for the one page. Pages that already have an alias are left as
+// they are.
+func (s *Service) AliasLegacyPages() error {
+	if s.aliasRepo == nil {
+		return nil
+	}
+
+	var after domain.PageID
+	aliased := 0
+
+	for {
+		pages, err := s.pageRepo.ListAfter(after, legacyBatchSize)
+		if err != nil {
+			return err
+		}
+
+		if len(pages) == 0 {
+			break
+		}
+
+		for _, page := range pages {
+			after = page.ID
+
+			canonicalURL, err := canonical.URL(page.URL)
+			if err != nil {
+				continue
+			}
+
+			canonicalID := util.PageID(canonicalURL)
+			if canonicalID == page.ID {
+				continue
+			}
+
+			if _, err := s.aliasRepo.Get(page.ID); err != domain.ErrPageAliasNotFound {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			err = s.aliasRepo.Save(&domain.PageAlias{
+				PageID:      page.ID,
+				CanonicalID: canonicalID,
+				Reason:      domain.PageAliasLegacy,
+				CreatedAt:   time.Now(),
+			})
+			if err != nil {
+				return err
+			}
+			aliased++
+
+			if _, err := s.CreateInScope(page.URL, page.ScopeID, page.Depth); err != nil && err != domain.ErrPageAlreadyExists {
+				s.logger.Errorw("Error creating canonical page", "error", err, "pageID", page.ID, "url", page.URL)
+			}
+		}
+	}
+
+	if aliased > 0 {
+		s.logger.Infow("Aliased legacy pages", "pages", aliased)
+	}
+
+	return nil
+}
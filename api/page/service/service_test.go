@@ -8,9 +8,13 @@ import (
 	"crawlquery/api/domain"
 	"crawlquery/api/testfactory"
 
+	urlFilterService "crawlquery/api/crawl/filter/service"
+	scopeRepo "crawlquery/api/crawl/scope/repository/mem"
+
 	shardRepo "crawlquery/api/shard/repository/mem"
 	shardService "crawlquery/api/shard/service"
 
+	aliasRepo "crawlquery/api/page/alias/repository/mem"
 	pageRepo "crawlquery/api/page/repository/mem"
 	pageService "crawlquery/api/page/service"
 )
@@ -85,7 +89,7 @@ func TestHandleLinkCreatedEvent(t *testing.T) {
 		)
 
 		linkCreated := &domain.LinkCreated{
-			DstURL: "http://example.com",
+			DstURL: "http://example.com/",
 		}
 
 		sf.EventService.Publish(linkCreated)
@@ -100,6 +104,331 @@ func TestHandleLinkCreatedEvent(t *testing.T) {
 			t.Fatalf("expected page to not exist, got %v", repoCheck)
 		}
 	})
+
+	t.Run("skips links with skipped rel values", func(t *testing.T) {
+		sf := testfactory.NewServiceFactory(
+			testfactory.WithShard(&domain.Shard{ID: 0}),
+		)
+
+		pageService.NewService(
+			pageService.WithPageRepo(sf.PageRepo),
+			pageService.WithShardService(sf.ShardService),
+			pageService.WithEventService(sf.EventService),
+			pageService.WithSkippedRels(domain.RelNofollow, domain.RelUGC),
+			pageService.WithLogger(testutil.NewTestLogger()),
+			pageService.WithEventListeners(),
+		)
+
+		sf.EventService.Publish(&domain.LinkCreated{
+			Link:   &domain.Link{Rel: "external UGC"},
+			DstURL: "http://spam.com/",
+		})
+
+		sf.EventService.Publish(&domain.LinkCreated{
+			Link:   &domain.Link{Rel: "sponsored"},
+			DstURL: "http://sponsor.com/",
+		})
+
+		if _, err := sf.PageRepo.Get(util.PageID("http://spam.com/")); err != domain.ErrPageNotFound {
+			t.Errorf("expected no page for a ugc link, got %v", err)
+		}
+
+		if _, err := sf.PageRepo.Get(util.PageID("http://sponsor.com/")); err != nil {
+			t.Errorf("expected a page for a link with a rel that is not skipped, got %v", err)
+		}
+	})
+}
+
+func TestHandleLinkCreatedEventFilter(t *testing.T) {
+	t.Run("skips links the url filter denies", func(t *testing.T) {
+		sf := testfactory.NewServiceFactory(
+			testfactory.WithShard(&domain.Shard{ID: 0}),
+		)
+
+		config, err := urlFilterService.ParseConfig([]byte(`{"rules": [{"name": "login", "action": "deny", "path_glob": "/login"}]}`))
+		if err != nil {
+			t.Fatalf("error parsing config: %v", err)
+		}
+
+		pageService.NewService(
+			pageService.WithPageRepo(sf.PageRepo),
+			pageService.WithShardService(sf.ShardService),
+			pageService.WithEventService(sf.EventService),
+			pageService.WithURLFilterService(urlFilterService.NewService(urlFilterService.WithConfig(config))),
+			pageService.WithLogger(testutil.NewTestLogger()),
+			pageService.WithEventListeners(),
+		)
+
+		sf.EventService.Publish(&domain.LinkCreated{DstURL: "http://example.com/login"})
+		sf.EventService.Publish(&domain.LinkCreated{DstURL: "http://example.com/about"})
+
+		if _, err := sf.PageRepo.Get(util.PageID("http://example.com/login")); err != domain.ErrPageNotFound {
+			t.Errorf("expected no page for a filtered link, got %v", err)
+		}
+
+		if _, err := sf.PageRepo.Get(util.PageID("http://example.com/about")); err != nil {
+			t.Errorf("expected a page for an allowed link, got %v", err)
+		}
+	})
+}
+
+func TestHandleLinkCreatedEventScope(t *testing.T) {
+	setup := func(scope *domain.Scope) *testfactory.ServiceFactory {
+		sf := testfactory.NewServiceFactory(
+			testfactory.WithShard(&domain.Shard{ID: 0}),
+		)
+
+		scopeRepo := scopeRepo.NewRepository()
+		scopeRepo.Save(scope)
+
+		pageService.NewService(
+			pageService.WithPageRepo(sf.PageRepo),
+			pageService.WithShardService(sf.ShardService),
+			pageService.WithEventService(sf.EventService),
+			pageService.WithScopeRepo(scopeRepo),
+			pageService.WithLogger(testutil.NewTestLogger()),
+			pageService.WithEventListeners(),
+		)
+
+		return sf
+	}
+
+	link := func(src domain.URL) *domain.Link {
+		return &domain.Link{SrcID: util.PageID(src)}
+	}
+
+	t.Run("adds pages linked from a scoped page to the scope", func(t *testing.T) {
+		sf := setup(&domain.Scope{ID: "recipes", Domains: []domain.Host{"example.com"}})
+		sf.PageRepo.Create(&domain.Page{ID: util.PageID("http://example.com/"), URL: "http://example.com/", ScopeID: "recipes", Depth: 1})
+
+		sf.EventService.Publish(&domain.LinkCreated{Link: link("http://example.com/"), DstURL: "http://blog.example.com/a"})
+
+		page, err := sf.PageRepo.Get(util.PageID("http://blog.example.com/a"))
+		if err != nil {
+			t.Fatalf("expected a page for the link, got %v", err)
+		}
+
+		if page.ScopeID != "recipes" || page.Depth != 2 {
+			t.Errorf("expected the page in scope recipes at depth 2, got %q at %d", page.ScopeID, page.Depth)
+		}
+	})
+
+	t.Run("skips links outside the scope", func(t *testing.T) {
+		sf := setup(&domain.Scope{ID: "recipes", Domains: []domain.Host{"example.com"}, MaxDepth: 1, PageBudget: 3})
+		sf.PageRepo.Create(&domain.Page{ID: util.PageID("http://example.com/"), URL: "http://example.com/", ScopeID: "recipes"})
+		sf.PageRepo.Create(&domain.Page{ID: util.PageID("http://example.com/deep"), URL: "http://example.com/deep", ScopeID: "recipes", Depth: 1})
+
+		sf.EventService.Publish(&domain.LinkCreated{Link: link("http://example.com/"), DstURL: "http://other.com/"})
+		sf.EventService.Publish(&domain.LinkCreated{Link: link("http://example.com/deep"), DstURL: "http://example.com/deeper"})
+
+		for _, url := range []domain.URL{"http://other.com/", "http://example.com/deeper"} {
+			if _, err := sf.PageRepo.Get(util.PageID(url)); err != domain.ErrPageNotFound {
+				t.Errorf("expected no page for %s, got %v", url, err)
+			}
+		}
+	})
+
+	t.Run("stops adding pages once the budget is spent", func(t *testing.T) {
+		sf := setup(&domain.Scope{ID: "recipes", Domains: []domain.Host{"example.com"}, PageBudget: 2})
+		sf.PageRepo.Create(&domain.Page{ID: util.PageID("http://example.com/"), URL: "http://example.com/", ScopeID: "recipes"})
+
+		sf.EventService.Publish(&domain.LinkCreated{Link: link("http://example.com/"), DstURL: "http://example.com/a"})
+		sf.EventService.Publish(&domain.LinkCreated{Link: link("http://example.com/"), DstURL: "http://example.com/b"})
+
+		if _, err := sf.PageRepo.Get(util.PageID("http://example.com/a")); err != nil {
+			t.Errorf("expected a page within the budget, got %v", err)
+		}
+
+		if _, err := sf.PageRepo.Get(util.PageID("http://example.com/b")); err != domain.ErrPageNotFound {
+			t.Errorf("expected no page past the budget, got %v", err)
+		}
+	})
+
+	t.Run("leaves pages linked from unscoped pages unscoped", func(t *testing.T) {
+		sf := setup(&domain.Scope{ID: "recipes", Domains: []domain.Host{"example.com"}})
+		sf.PageRepo.Create(&domain.Page{ID: util.PageID("http://other.com/"), URL: "http://other.com/"})
+
+		sf.EventService.Publish(&domain.LinkCreated{Link: link("http://other.com/"), DstURL: "http://elsewhere.com/"})
+
+		page, err := sf.PageRepo.Get(util.PageID("http://elsewhere.com/"))
+		if err != nil {
+			t.Fatalf("expected a page for the link, got %v", err)
+		}
+
+		if page.ScopeID != "" {
+			t.Errorf("expected no scope, got %q", page.ScopeID)
+		}
+	})
+}
+
+func TestHandleCrawlCompletedEvent(t *testing.T) {
+	t.Run("folds duplicate pages into their canonical page", func(t *testing.T) {
+		sf := testfactory.NewServiceFactory(
+			testfactory.WithShard(&domain.Shard{ID: 0}),
+		)
+
+		aliasRepo := aliasRepo.NewRepository()
+
+		pageService.NewService(
+			pageService.WithPageRepo(sf.PageRepo),
+			pageService.WithPageAliasRepo(aliasRepo),
+			pageService.WithShardService(sf.ShardService),
+			pageService.WithEventService(sf.EventService),
+			pageService.WithLogger(testutil.NewTestLogger()),
+			pageService.WithEventListeners(),
+		)
+
+		printID := util.PageID("http://example.com/print")
+		articleID := util.PageID("http://example.com/article")
+
+		sf.EventService.Publish(&domain.CrawlCompleted{
+			PageID:    printID,
+			URL:       "http://example.com/print",
+			Canonical: "http://example.com/article",
+		})
+
+		alias, err := aliasRepo.Get(printID)
+		if err != nil {
+			t.Fatalf("Expected an alias, got %v", err)
+		}
+
+		if alias.CanonicalID != articleID || alias.Reason != domain.PageAliasCanonical {
+			t.Errorf("Expected a canonical alias of %s, got %+v", articleID, alias)
+		}
+
+		if _, err := sf.PageRepo.Get(articleID); err != nil {
+			t.Errorf("Expected the canonical page to be created, got %v", err)
+		}
+
+		// the canonical page naming itself, or its duplicate, adds no alias
+		for _, canonical := range []domain.URL{"http://example.com/article", "http://example.com/print"} {
+			sf.EventService.Publish(&domain.CrawlCompleted{
+				PageID:    articleID,
+				URL:       "http://example.com/article",
+				Canonical: canonical,
+			})
+		}
+
+		if _, err := aliasRepo.Get(articleID); err != domain.ErrPageAliasNotFound {
+			t.Errorf("Expected no alias for the canonical page, got %v", err)
+		}
+	})
+
+	t.Run("records redirects as aliases of their target", func(t *testing.T) {
+		sf := testfactory.NewServiceFactory(
+			testfactory.WithShard(&domain.Shard{ID: 0}),
+		)
+
+		aliasRepo := aliasRepo.NewRepository()
+
+		pageService.NewService(
+			pageService.WithPageRepo(sf.PageRepo),
+			pageService.WithPageAliasRepo(aliasRepo),
+			pageService.WithShardService(sf.ShardService),
+			pageService.WithEventService(sf.EventService),
+			pageService.WithLogger(testutil.NewTestLogger()),
+			pageService.WithEventListeners(),
+		)
+
+		srcID := util.PageID("http://example.com/")
+		dstID := util.PageID("https://www.example.com/")
+
+		sf.EventService.Publish(&domain.CrawlCompleted{
+			PageID:   srcID,
+			URL:      "http://example.com/",
+			FinalURL: "https://www.example.com/",
+		})
+
+		alias, err := aliasRepo.Get(srcID)
+		if err != nil {
+			t.Fatalf("Expected an alias, got %v", err)
+		}
+
+		if alias.CanonicalID != dstID || alias.Reason != domain.PageAliasRedirect {
+			t.Errorf("Expected a redirect alias of %s, got %+v", dstID, alias)
+		}
+
+		if _, err := sf.PageRepo.Get(dstID); err != nil {
+			t.Errorf("Expected the redirect target to be created, got %v", err)
+		}
+	})
+
+	t.Run("records every hop of a redirect chain", func(t *testing.T) {
+		sf := testfactory.NewServiceFactory(
+			testfactory.WithShard(&domain.Shard{ID: 0}),
+		)
+
+		aliasRepo := aliasRepo.NewRepository()
+
+		pageService.NewService(
+			pageService.WithPageRepo(sf.PageRepo),
+			pageService.WithPageAliasRepo(aliasRepo),
+			pageService.WithShardService(sf.ShardService),
+			pageService.WithEventService(sf.EventService),
+			pageService.WithLogger(testutil.NewTestLogger()),
+			pageService.WithEventListeners(),
+		)
+
+		srcID := util.PageID("http://example.com/")
+		hopID := util.PageID("https://example.com/")
+		dstID := util.PageID("https://www.example.com/")
+
+		sf.EventService.Publish(&domain.CrawlCompleted{
+			PageID:    srcID,
+			URL:       "http://example.com/",
+			FinalURL:  "https://www.example.com/",
+			Redirects: []domain.URL{"http://example.com/", "https://example.com/"},
+		})
+
+		for _, pageID := range []domain.PageID{srcID, hopID} {
+			alias, err := aliasRepo.Get(pageID)
+			if err != nil {
+				t.Fatalf("Expected an alias for %s, got %v", pageID, err)
+			}
+
+			if alias.CanonicalID != dstID || alias.Reason != domain.PageAliasRedirect {
+				t.Errorf("Expected a redirect alias of %s for %s, got %+v", dstID, pageID, alias)
+			}
+		}
+
+		// links to a hop are not crawled again
+		sf.EventService.Publish(&domain.LinkCreated{DstURL: "https://example.com/"})
+
+		if _, err := sf.PageRepo.Get(hopID); err != domain.ErrPageNotFound {
+			t.Errorf("Expected no page for the redirect hop, got %v", err)
+		}
+	})
+
+	t.Run("doesn't crawl targets the url filter denies", func(t *testing.T) {
+		sf := testfactory.NewServiceFactory(
+			testfactory.WithShard(&domain.Shard{ID: 0}),
+		)
+
+		config, err := urlFilterService.ParseConfig([]byte(`{"rules": [{"name": "login", "action": "deny", "path_glob": "/login"}]}`))
+		if err != nil {
+			t.Fatalf("error parsing config: %v", err)
+		}
+
+		pageService.NewService(
+			pageService.WithPageRepo(sf.PageRepo),
+			pageService.WithPageAliasRepo(aliasRepo.NewRepository()),
+			pageService.WithShardService(sf.ShardService),
+			pageService.WithEventService(sf.EventService),
+			pageService.WithURLFilterService(urlFilterService.NewService(urlFilterService.WithConfig(config))),
+			pageService.WithLogger(testutil.NewTestLogger()),
+			pageService.WithEventListeners(),
+		)
+
+		sf.EventService.Publish(&domain.CrawlCompleted{
+			PageID:   util.PageID("http://example.com/account"),
+			URL:      "http://example.com/account",
+			FinalURL: "http://example.com/login",
+		})
+
+		if _, err := sf.PageRepo.Get(util.PageID("http://example.com/login")); err != domain.ErrPageNotFound {
+			t.Errorf("expected no page for a filtered redirect target, got %v", err)
+		}
+	})
 }
 
 func TestCreate(t *testing.T) {
@@ -117,7 +446,7 @@ func TestCreate(t *testing.T) {
 			pageEventPublished = true
 		})
 
-		url := domain.URL("http://example.com")
+		url := domain.URL("http://example.com/")
 		page, err := pageService.Create(url)
 
 		if err != nil {
@@ -159,13 +488,100 @@ func TestCreate(t *testing.T) {
 		)
 
 		pageRepo.Create(&domain.Page{
-			ID: util.PageID("http://google.com"),
+			ID: util.PageID("http://google.com/"),
 		})
 
-		_, err := pageService.Create("http://google.com")
+		_, err := pageService.Create("http://google.com/")
 
 		if err == nil {
 			t.Fatalf("expected error, got nil")
 		}
 	})
+	t.Run("creates the page under its canonical url", func(t *testing.T) {
+		sf := testfactory.NewServiceFactory(
+			testfactory.WithShard(&domain.Shard{ID: 0}),
+		)
+
+		page, err := sf.PageService.Create("HTTP://Example.com:80/a/?b=1&a=2&utm_source=x#top")
+
+		if err != nil {
+			t.Fatalf("error creating page: %v", err)
+		}
+
+		// the page is fetched as given, as /a/ might not be the same as /a
+		if page.URL != "HTTP://Example.com:80/a/?b=1&a=2&utm_source=x" {
+			t.Errorf("got page URL %s, want the url as given without its fragment", page.URL)
+		}
+
+		if page.ID != util.PageID("http://example.com/a?a=2&b=1") {
+			t.Errorf("expected page ID to be from the canonical url, got %s", page.ID)
+		}
+
+		_, err = sf.PageService.Create("http://example.com/a?b=1&a=2")
+
+		if err != domain.ErrPageAlreadyExists {
+			t.Errorf("expected the same page to already exist, got %v", err)
+		}
+	})
+
+	t.Run("returns error for urls that cannot be crawled", func(t *testing.T) {
+		sf := testfactory.NewServiceFactory(
+			testfactory.WithShard(&domain.Shard{ID: 0}),
+		)
+
+		if _, err := sf.PageService.Create("mailto:someone@example.com"); err == nil {
+			t.Errorf("expected error, got nil")
+		}
+	})
+}
+
+func TestAliasLegacyPages(t *testing.T) {
+	t.Run("folds pages stored under their url as written into their canonical page", func(t *testing.T) {
+		sf := testfactory.NewServiceFactory(
+			testfactory.WithShard(&domain.Shard{ID: 0}),
+		)
+
+		aliasRepo := aliasRepo.NewRepository()
+
+		pageService := pageService.NewService(
+			pageService.WithPageRepo(sf.PageRepo),
+			pageService.WithPageAliasRepo(aliasRepo),
+			pageService.WithShardService(sf.ShardService),
+			pageService.WithEventService(sf.EventService),
+			pageService.WithLogger(testutil.NewTestLogger()),
+		)
+
+		legacyURL := domain.URL("http://Example.com/dir/")
+		legacyID := util.PageID(legacyURL)
+		canonicalID := util.PageID("http://example.com/dir")
+
+		sf.PageRepo.Create(&domain.Page{ID: legacyID, URL: legacyURL})
+		sf.PageRepo.Create(&domain.Page{ID: util.PageID("http://example.com/"), URL: "http://example.com/"})
+
+		if err := pageService.AliasLegacyPages(); err != nil {
+			t.Fatalf("error aliasing legacy pages: %v", err)
+		}
+
+		alias, err := aliasRepo.Get(legacyID)
+		if err != nil {
+			t.Fatalf("expected an alias for the legacy page, got %v", err)
+		}
+
+		if alias.CanonicalID != canonicalID || alias.Reason != domain.PageAliasLegacy {
+			t.Errorf("expected a legacy alias of %s, got %+v", canonicalID, alias)
+		}
+
+		page, err := sf.PageRepo.Get(canonicalID)
+		if err != nil {
+			t.Fatalf("expected the canonical page to be created, got %v", err)
+		}
+
+		if page.URL != legacyURL {
+			t.Errorf("expected the canonical page to be fetched from %s, got %s", legacyURL, page.URL)
+		}
+
+		if aliases, _ := aliasRepo.GetAll(); len(aliases) != 1 {
+			t.Errorf("expected only the legacy page to be aliased, got %d aliases", len(aliases))
+		}
+	})
 }
@@ -83,6 +83,12 @@ func (s *Service) Create(pageID domain.PageID, contentHash domain.ContentHash) (
 		return nil, err
 	}
 
+	if s.eventService != nil {
+		s.eventService.Publish(&domain.PageVersionCreated{
+			PageVersion: pageVersion,
+		})
+	}
+
 	return pageVersion, nil
 }
 
@@ -139,6 +139,29 @@ func TestCreate(t *testing.T) {
 			t.Fatalf("Expected %v, got %v", now, got.CreatedAt)
 		}
 	})
+
+	t.Run("publishes a page version created event", func(t *testing.T) {
+		eventService := eventService.NewService()
+		pageVersionService := pageVersionService.NewService(
+			pageVersionService.WithVersionRepo(pageVersionRepo.NewRepository()),
+			pageVersionService.WithEventService(eventService),
+		)
+
+		var published *domain.PageVersion
+		eventService.Subscribe(domain.PageVersionCreatedKey, func(e domain.Event) {
+			published = e.(*domain.PageVersionCreated).PageVersion
+		})
+
+		got, err := pageVersionService.Create("page1", "hash")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if published != got {
+			t.Fatalf("Expected the created page version to be published, got %v", published)
+		}
+	})
 }
 
 func TestHandlesCrawlCompletedEvent(t *testing.T) {
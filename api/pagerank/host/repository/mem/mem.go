@@ -0,0 +1,80 @@
+package mem
+
+import (
+	"crawlquery/api/domain"
+	"errors"
+	"sync"
+	"time"
+)
+
+type Repository struct {
+	ranks map[domain.Host]float64
+	lock  sync.RWMutex
+}
+
+func NewRepository() *Repository {
+	return &Repository{
+		ranks: make(map[domain.Host]float64),
+	}
+}
+
+func (r *Repository) Get(host domain.Host) (float64, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	rank, ok := r.ranks[host]
+	if !ok {
+		return 0, errors.New("host rank not found")
+	}
+	return rank, nil
+}
+
+func (r *Repository) GetMany(hosts []domain.Host) (map[domain.Host]float64, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	ranks := make(map[domain.Host]float64, len(hosts))
+	for _, host := range hosts {
+		if rank, ok := r.ranks[host]; ok {
+			ranks[host] = rank
+		}
+	}
+
+	return ranks, nil
+}
+
+func (r *Repository) GetAll() (map[domain.Host]float64, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	ranks := make(map[domain.Host]float64, len(r.ranks))
+	for host, rank := range r.ranks {
+		ranks[host] = rank
+	}
+
+	return ranks, nil
+}
+
+func (r *Repository) UpdateAll(ranks map[domain.Host]float64, createdAt time.Time) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for host, rank := range ranks {
+		r.ranks[host] = rank
+	}
+
+	return nil
+}
+
+// Max returns the highest stored rank, or 0 when there are none.
+func (r *Repository) Max() (float64, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	highest := 0.0
+	for _, rank := range r.ranks {
+		highest = max(highest, rank)
+	}
+
+	return highest, nil
+}
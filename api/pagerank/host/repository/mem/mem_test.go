@@ -0,0 +1,68 @@
+package mem
+
+import (
+	"crawlquery/api/domain"
+	"testing"
+	"time"
+)
+
+func TestUpdateAll(t *testing.T) {
+	ranks := map[domain.Host]float64{
+		"a.com": 0.5,
+		"b.com": 0.3,
+	}
+
+	repo := NewRepository()
+
+	if err := repo.UpdateAll(ranks, time.Now()); err != nil {
+		t.Fatalf("Error updating ranks: %v", err)
+	}
+
+	for host, expected := range ranks {
+		rank, err := repo.Get(host)
+		if err != nil {
+			t.Fatalf("Error getting rank for %s: %v", host, err)
+		}
+		if rank != expected {
+			t.Errorf("Expected %f for %s, got %f", expected, host, rank)
+		}
+	}
+
+	if _, err := repo.Get("c.com"); err == nil {
+		t.Errorf("Expected an error for an unknown host")
+	}
+}
+
+func TestGetMany(t *testing.T) {
+	repo := NewRepository()
+
+	if err := repo.UpdateAll(map[domain.Host]float64{"a.com": 0.5, "b.com": 0.3}, time.Now()); err != nil {
+		t.Fatalf("Error updating ranks: %v", err)
+	}
+
+	ranks, err := repo.GetMany([]domain.Host{"a.com", "b.com", "missing.com"})
+	if err != nil {
+		t.Fatalf("Error getting ranks: %v", err)
+	}
+
+	if len(ranks) != 2 || ranks["a.com"] != 0.5 || ranks["b.com"] != 0.3 {
+		t.Errorf("Expected ranks for a.com and b.com, got %v", ranks)
+	}
+}
+
+func TestMax(t *testing.T) {
+	repo := NewRepository()
+
+	if err := repo.UpdateAll(map[domain.Host]float64{"a.com": 0.5, "b.com": 0.3}, time.Now()); err != nil {
+		t.Fatalf("Error updating ranks: %v", err)
+	}
+
+	highest, err := repo.Max()
+	if err != nil {
+		t.Fatalf("Error getting highest rank: %v", err)
+	}
+
+	if highest != 0.5 {
+		t.Errorf("Expected 0.5, got %f", highest)
+	}
+}
@@ -0,0 +1,155 @@
+package mysql
+
+import (
+	"crawlquery/api/domain"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// batchSize is the number of ranks written per INSERT statement.
+const batchSize = 500
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func (r *Repository) Get(host domain.Host) (float64, error) {
+	var rank float64
+	err := r.db.QueryRow("SELECT `rank` FROM host_ranks WHERE host = ?", host).Scan(&rank)
+	if err != nil {
+		return 0, err
+	}
+	return rank, nil
+}
+
+// GetMany returns the ranks of the given hosts, leaving out hosts that
+// have none. It looks them up batchSize at a time.
+func (r *Repository) GetMany(hosts []domain.Host) (map[domain.Host]float64, error) {
+	ranks := make(map[domain.Host]float64, len(hosts))
+
+	for start := 0; start < len(hosts); start += batchSize {
+		batch := hosts[start:min(start+batchSize, len(hosts))]
+
+		args := make([]any, len(batch))
+		for i, host := range batch {
+			args[i] = host
+		}
+
+		rows, err := r.db.Query("SELECT host, `rank` FROM host_ranks WHERE host IN (?"+strings.Repeat(", ?", len(batch)-1)+")", args...)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var host domain.Host
+			var rank float64
+			if err := rows.Scan(&host, &rank); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			ranks[host] = rank
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ranks, nil
+}
+
+// Max returns the highest stored rank, or 0 when there are none.
+func (r *Repository) Max() (float64, error) {
+	var rank float64
+	err := r.db.QueryRow("SELECT COALESCE(MAX(`rank`), 0) FROM host_ranks").Scan(&rank)
+	if err != nil {
+		return 0, err
+	}
+	return rank, nil
+}
+
+func (r *Repository) GetAll() (map[domain.Host]float64, error) {
+	rows, err := r.db.Query("SELECT host, `rank` FROM host_ranks")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ranks := make(map[domain.Host]float64)
+
+	for rows.Next() {
+		var host domain.Host
+		var rank float64
+		if err := rows.Scan(&host, &rank); err != nil {
+			return nil, err
+		}
+		ranks[host] = rank
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ranks, nil
+}
+
+// UpdateAll writes every rank in one transaction using multi-row inserts of
+// up to batchSize rows each.
+func (r *Repository) UpdateAll(ranks map[domain.Host]float64, createdAt time.Time) error {
+	if len(ranks) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	placeholders := make([]string, 0, batchSize)
+	args := make([]any, 0, batchSize*3)
+
+	flush := func() error {
+		if len(placeholders) == 0 {
+			return nil
+		}
+
+		query := "INSERT INTO host_ranks (host, `rank`, created_at) VALUES " +
+			strings.Join(placeholders, ", ") +
+			" ON DUPLICATE KEY UPDATE `rank` = VALUES(`rank`), created_at = VALUES(created_at)"
+
+		if _, err := tx.Exec(query, args...); err != nil {
+			return err
+		}
+
+		placeholders = placeholders[:0]
+		args = args[:0]
+		return nil
+	}
+
+	for host, rank := range ranks {
+		placeholders = append(placeholders, "(?, ?, ?)")
+		args = append(args, host, rank, createdAt)
+
+		if len(placeholders) == batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
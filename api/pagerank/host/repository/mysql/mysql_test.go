@@ -0,0 +1,80 @@
+package mysql_test
+
+import (
+	"crawlquery/api/domain"
+	"crawlquery/api/migration"
+	"crawlquery/pkg/testutil"
+	"testing"
+	"time"
+
+	hostRankRepo "crawlquery/api/pagerank/host/repository/mysql"
+)
+
+func TestUpdateAll(t *testing.T) {
+	db := testutil.CreateTestMysqlDB()
+	defer db.Close()
+
+	migration.Up(db)
+
+	repo := hostRankRepo.NewRepository(db)
+
+	ranks := map[domain.Host]float64{
+		"updateall1.com": 0.5,
+		"updateall2.com": 0.3,
+	}
+
+	for host := range ranks {
+		defer db.Exec("DELETE FROM host_ranks WHERE host = ?", host)
+	}
+
+	if err := repo.UpdateAll(ranks, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	for host, expected := range ranks {
+		rank, err := repo.Get(host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rank != expected {
+			t.Errorf("Expected %f for %s, got %f", expected, host, rank)
+		}
+	}
+}
+
+func TestGetMany(t *testing.T) {
+	db := testutil.CreateTestMysqlDB()
+	defer db.Close()
+
+	migration.Up(db)
+
+	repo := hostRankRepo.NewRepository(db)
+
+	ranks := map[domain.Host]float64{
+		"getmany1.com": 0.5,
+		"getmany2.com": 0.3,
+	}
+
+	for host := range ranks {
+		defer db.Exec("DELETE FROM host_ranks WHERE host = ?", host)
+	}
+
+	if err := repo.UpdateAll(ranks, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repo.GetMany([]domain.Host{"getmany1.com", "getmany2.com", "getmanymissing.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != len(ranks) {
+		t.Fatalf("Expected %d ranks, got %d", len(ranks), len(got))
+	}
+
+	for host, expected := range ranks {
+		if got[host] != expected {
+			t.Errorf("Expected %f for %s, got %f", expected, host, got[host])
+		}
+	}
+}
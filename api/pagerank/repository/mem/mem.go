@@ -3,11 +3,13 @@ package mem
 import (
 	"crawlquery/api/domain"
 	"errors"
+	"sync"
 	"time"
 )
 
 type Repository struct {
 	ranks map[domain.PageID]float64
+	lock  sync.RWMutex
 }
 
 func NewRepository() *Repository {
@@ -17,15 +19,71 @@ func NewRepository() *Repository {
 }
 
 func (r *Repository) Update(pageID domain.PageID, rank float64, createdAt time.Time) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
 	r.ranks[pageID] = rank
 
 	return nil
 }
 
+func (r *Repository) UpdateAll(ranks map[domain.PageID]float64, createdAt time.Time) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for pageID, rank := range ranks {
+		r.ranks[pageID] = rank
+	}
+
+	return nil
+}
+
 func (r *Repository) Get(pageID domain.PageID) (float64, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
 	rank, ok := r.ranks[pageID]
 	if !ok {
 		return 0, errors.New("page rank not found")
 	}
 	return rank, nil
 }
+
+func (r *Repository) GetMany(pageIDs []domain.PageID) (map[domain.PageID]float64, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	ranks := make(map[domain.PageID]float64, len(pageIDs))
+	for _, pageID := range pageIDs {
+		if rank, ok := r.ranks[pageID]; ok {
+			ranks[pageID] = rank
+		}
+	}
+
+	return ranks, nil
+}
+
+func (r *Repository) GetAll() (map[domain.PageID]float64, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	ranks := make(map[domain.PageID]float64, len(r.ranks))
+	for pageID, rank := range r.ranks {
+		ranks[pageID] = rank
+	}
+
+	return ranks, nil
+}
+
+// Max returns the highest stored rank, or 0 when there are none.
+func (r *Repository) Max() (float64, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	highest := 0.0
+	for _, rank := range r.ranks {
+		highest = max(highest, rank)
+	}
+
+	return highest, nil
+}
@@ -34,3 +34,66 @@ func TestRepo(t *testing.T) {
 		}
 	}
 }
+
+func TestUpdateAll(t *testing.T) {
+	ranks := map[domain.PageID]float64{
+		"test1": 0.5,
+		"test2": 0.3,
+		"test3": 0.7,
+	}
+
+	repo := NewRepository()
+	repo.Update("test1", 0.1, time.Now())
+
+	if err := repo.UpdateAll(ranks, time.Now()); err != nil {
+		t.Fatalf("Error updating ranks: %v", err)
+	}
+
+	all, err := repo.GetAll()
+	if err != nil {
+		t.Fatalf("Error getting ranks: %v", err)
+	}
+
+	if len(all) != len(ranks) {
+		t.Fatalf("Expected %d ranks, got %d", len(ranks), len(all))
+	}
+
+	for pageID, expected := range ranks {
+		if all[pageID] != expected {
+			t.Errorf("Expected %f for %s, got %f", expected, pageID, all[pageID])
+		}
+	}
+}
+
+func TestGetMany(t *testing.T) {
+	repo := NewRepository()
+	repo.Update("test1", 0.5, time.Now())
+	repo.Update("test2", 0.3, time.Now())
+
+	ranks, err := repo.GetMany([]domain.PageID{"test1", "test2", "missing"})
+	if err != nil {
+		t.Fatalf("Error getting ranks: %v", err)
+	}
+
+	if len(ranks) != 2 {
+		t.Fatalf("Expected 2 ranks, got %d", len(ranks))
+	}
+
+	if ranks["test1"] != 0.5 || ranks["test2"] != 0.3 {
+		t.Errorf("Expected ranks 0.5 and 0.3, got %v", ranks)
+	}
+}
+
+func TestMax(t *testing.T) {
+	repo := NewRepository()
+
+	if highest, _ := repo.Max(); highest != 0 {
+		t.Errorf("Expected 0 without ranks, got %f", highest)
+	}
+
+	repo.UpdateAll(map[domain.PageID]float64{"test1": 0.5, "test2": 0.7, "test3": 0.3}, time.Now())
+
+	if highest, _ := repo.Max(); highest != 0.7 {
+		t.Errorf("Expected 0.7, got %f", highest)
+	}
+}
@@ -3,9 +3,13 @@ package mysql
 import (
 	"crawlquery/api/domain"
 	"database/sql"
+	"strings"
 	"time"
 )
 
+// batchSize is the number of ranks written per INSERT statement.
+const batchSize = 500
+
 type Repository struct {
 	db *sql.DB
 }
@@ -25,10 +29,135 @@ func (r *Repository) Get(pageID domain.PageID) (float64, error) {
 	return rank, nil
 }
 
+// GetMany returns the ranks of the given pages, leaving out pages that
+// have none. It looks them up batchSize at a time.
+func (r *Repository) GetMany(pageIDs []domain.PageID) (map[domain.PageID]float64, error) {
+	ranks := make(map[domain.PageID]float64, len(pageIDs))
+
+	for start := 0; start < len(pageIDs); start += batchSize {
+		batch := pageIDs[start:min(start+batchSize, len(pageIDs))]
+
+		args := make([]any, len(batch))
+		for i, pageID := range batch {
+			args[i] = pageID
+		}
+
+		rows, err := r.db.Query("SELECT page_id, `rank` FROM page_ranks WHERE page_id IN (?"+strings.Repeat(", ?", len(batch)-1)+")", args...)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var pageID domain.PageID
+			var rank float64
+			if err := rows.Scan(&pageID, &rank); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			ranks[pageID] = rank
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ranks, nil
+}
+
+// Max returns the highest stored rank, or 0 when there are none.
+func (r *Repository) Max() (float64, error) {
+	var rank float64
+	err := r.db.QueryRow("SELECT COALESCE(MAX(`rank`), 0) FROM page_ranks").Scan(&rank)
+	if err != nil {
+		return 0, err
+	}
+	return rank, nil
+}
+
+func (r *Repository) GetAll() (map[domain.PageID]float64, error) {
+	rows, err := r.db.Query("SELECT page_id, `rank` FROM page_ranks")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ranks := make(map[domain.PageID]float64)
+
+	for rows.Next() {
+		var pageID domain.PageID
+		var rank float64
+		if err := rows.Scan(&pageID, &rank); err != nil {
+			return nil, err
+		}
+		ranks[pageID] = rank
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ranks, nil
+}
+
 func (r *Repository) Update(pageID domain.PageID, rank float64, createdAt time.Time) error {
-	_, err := r.db.Exec("INSERT INTO page_ranks (page_id, `rank`, created_at) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE `rank` = ?", pageID, rank, createdAt, pageID)
+	_, err := r.db.Exec("INSERT INTO page_ranks (page_id, `rank`, created_at) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE `rank` = VALUES(`rank`), created_at = VALUES(created_at)", pageID, rank, createdAt)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// UpdateAll writes every rank in one transaction using multi-row inserts of
+// up to batchSize rows each.
+func (r *Repository) UpdateAll(ranks map[domain.PageID]float64, createdAt time.Time) error {
+	if len(ranks) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	placeholders := make([]string, 0, batchSize)
+	args := make([]any, 0, batchSize*3)
+
+	flush := func() error {
+		if len(placeholders) == 0 {
+			return nil
+		}
+
+		query := "INSERT INTO page_ranks (page_id, `rank`, created_at) VALUES " +
+			strings.Join(placeholders, ", ") +
+			" ON DUPLICATE KEY UPDATE `rank` = VALUES(`rank`), created_at = VALUES(created_at)"
+
+		if _, err := tx.Exec(query, args...); err != nil {
+			return err
+		}
+
+		placeholders = placeholders[:0]
+		args = args[:0]
+		return nil
+	}
+
+	for pageID, rank := range ranks {
+		placeholders = append(placeholders, "(?, ?, ?)")
+		args = append(args, pageID, rank, createdAt)
+
+		if len(placeholders) == batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
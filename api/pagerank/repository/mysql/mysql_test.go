@@ -91,3 +91,103 @@ func TestUpdate(t *testing.T) {
 		}
 	}
 }
+
+func TestUpdateAll(t *testing.T) {
+	db := testutil.CreateTestMysqlDB()
+	defer db.Close()
+
+	migration.Up(db)
+
+	repo := pageRankRepo.NewRepository(db)
+
+	ranks := map[domain.PageID]float64{
+		"updateall1": 0.5,
+		"updateall2": 0.3,
+		"updateall3": 0.7,
+	}
+
+	for pageID := range ranks {
+		defer db.Exec("DELETE FROM page_ranks WHERE page_id = ?", pageID)
+	}
+
+	// an existing rank is overwritten
+	if err := repo.Update("updateall1", 0.1, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.UpdateAll(ranks, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := repo.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for pageID, expected := range ranks {
+		if all[pageID] != expected {
+			t.Errorf("Expected %f for %s, got %f", expected, pageID, all[pageID])
+		}
+	}
+}
+
+func TestGetMany(t *testing.T) {
+	db := testutil.CreateTestMysqlDB()
+	defer db.Close()
+
+	migration.Up(db)
+
+	repo := pageRankRepo.NewRepository(db)
+
+	ranks := map[domain.PageID]float64{
+		"getmany1": 0.5,
+		"getmany2": 0.3,
+	}
+
+	for pageID := range ranks {
+		defer db.Exec("DELETE FROM page_ranks WHERE page_id = ?", pageID)
+	}
+
+	if err := repo.UpdateAll(ranks, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repo.GetMany([]domain.PageID{"getmany1", "getmany2", "getmanymissing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != len(ranks) {
+		t.Fatalf("Expected %d ranks, got %d", len(ranks), len(got))
+	}
+
+	for pageID, expected := range ranks {
+		if got[pageID] != expected {
+			t.Errorf("Expected %f for %s, got %f", expected, pageID, got[pageID])
+		}
+	}
+}
+
+func TestMax(t *testing.T) {
+	db := testutil.CreateTestMysqlDB()
+	defer db.Close()
+
+	migration.Up(db)
+
+	repo := pageRankRepo.NewRepository(db)
+
+	defer db.Exec("DELETE FROM page_ranks WHERE page_id = ?", "maxhighest")
+
+	if err := repo.Update("maxhighest", 1000, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	highest, err := repo.Max()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if highest != 1000 {
+		t.Errorf("Expected 1000, got %f", highest)
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"crawlquery/api/domain"
+	"math"
+)
+
+// graph is a weighted directed graph of pages or hosts. Each node's outgoing
+// weights are shared out in proportion when its rank is passed on.
+type graph[K comparable] struct {
+	nodes map[K]bool
+	edges map[K]map[K]float64
+}
+
+func newGraph[K comparable]() *graph[K] {
+	return &graph[K]{
+		nodes: make(map[K]bool),
+		edges: make(map[K]map[K]float64),
+	}
+}
+
+// addEdge adds both nodes and adds weight to the edge between them. Edges
+// without weight are left out, so a node whose only outlinks have no weight
+// counts as having none.
+func (g *graph[K]) addEdge(src, dst K, weight float64) {
+	g.nodes[src] = true
+	g.nodes[dst] = true
+
+	if weight <= 0 {
+		return
+	}
+
+	if _, ok := g.edges[src]; !ok {
+		g.edges[src] = make(map[K]float64)
+	}
+	g.edges[src][dst] += weight
+}
+
+// teleportVector spreads the teleport probability uniformly over the seeds
+// in the graph, or over every node when there are none.
+func teleportVector[K comparable](nodes map[K]bool, seeds map[K]bool) map[K]float64 {
+	teleport := make(map[K]float64)
+
+	for id := range seeds {
+		if nodes[id] {
+			teleport[id] = 0
+		}
+	}
+
+	if len(teleport) == 0 {
+		for id := range nodes {
+			teleport[id] = 0
+		}
+	}
+
+	for id := range teleport {
+		teleport[id] = 1.0 / float64(len(teleport))
+	}
+
+	return teleport
+}
+
+// calculateRank iterates until the ranks converge and returns them with a
+// report of the run. Random jumps and the rank of nodes without outlinks go to
+// the teleport vector, so the ranks always sum to 1. Nodes with a rank in
+// start begin from it and the rest begin from a uniform share.
+func calculateRank[K comparable](g *graph[K], teleport map[K]float64, start map[K]float64) (map[K]float64, domain.PageRankConvergence) {
+	numNodes := len(g.nodes)
+	ranks := make(map[K]float64)
+	newRanks := make(map[K]float64)
+	convergence := domain.PageRankConvergence{}
+
+	if numNodes == 0 {
+		convergence.Converged = true
+		return ranks, convergence
+	}
+
+	var total float64
+	for id := range g.nodes {
+		if rank, ok := start[id]; ok && rank > 0 {
+			ranks[id] = rank
+		} else {
+			ranks[id] = 1.0 / float64(numNodes)
+		}
+		total += ranks[id]
+	}
+
+	for id := range ranks {
+		ranks[id] /= total
+	}
+
+	outWeights := make(map[K]float64, len(g.edges))
+	for src, dsts := range g.edges {
+		for _, weight := range dsts {
+			outWeights[src] += weight
+		}
+	}
+
+	for convergence.Iterations < maxIter {
+		convergence.Iterations++
+
+		var dangling float64
+		for id := range g.nodes {
+			if outWeights[id] == 0 {
+				dangling += ranks[id]
+			}
+		}
+
+		for id := range g.nodes {
+			newRanks[id] = ((1.0 - d) + d*dangling) * teleport[id]
+		}
+
+		for src, dsts := range g.edges {
+			share := d * ranks[src] / outWeights[src]
+
+			for dst, weight := range dsts {
+				newRanks[dst] += share * weight
+			}
+		}
+
+		var diff float64
+		for id := range g.nodes {
+			diff += math.Abs(newRanks[id] - ranks[id])
+			ranks[id] = newRanks[id]
+		}
+
+		convergence.Residual = diff
+
+		if diff < epsilon {
+			convergence.Converged = true
+			break
+		}
+	}
+
+	return ranks, convergence
+}
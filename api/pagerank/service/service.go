@@ -2,29 +2,165 @@ package service
 
 import (
 	"crawlquery/api/domain"
-	"math"
+	"crawlquery/pkg/canonical"
+	"crawlquery/pkg/util"
+	"errors"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
 )
 
 const (
-	d       = 0.85  // Damping factor
-	epsilon = 0.001 // Convergence threshold
-	maxIter = 100   // Maximum iterations
+	d       = 0.85 // Damping factor
+	epsilon = 1e-6 // Convergence threshold
+	maxIter = 100  // Maximum iterations
 )
 
 type Service struct {
-	linkService  domain.LinkService
-	pageRankRepo domain.PageRankRepository
-	logger       *zap.SugaredLogger
+	linkService        domain.LinkService
+	eventService       domain.EventService
+	pageRankRepo       domain.PageRankRepository
+	hostRankRepo       domain.HostRankRepository
+	logger             *zap.SugaredLogger
+	warmStart          bool
+	changeThreshold    float64
+	internalLinkWeight float64
+	seedPages          map[domain.PageID]bool
+	seedHosts          map[domain.Host]bool
+
+	// linkCount is the number of links seen by the last run, or -1 before
+	// the first run. changes counts the events that changed the link graph
+	// since then.
+	linkCount   int
+	changes     int
+	convergence domain.PageRankConvergence
+
+	// maxPageRank and maxHostRank cache the highest stored ranks until the
+	// next run replaces them, or are nil before they are first looked up.
+	maxPageRank *float64
+	maxHostRank *float64
+	lock        sync.Mutex
+}
+
+type Option func(*Service)
+
+// WithWarmStart seeds each run with the stored ranks instead of a uniform
+// distribution, so a run over a slightly changed graph converges in fewer
+// iterations.
+func WithWarmStart() Option {
+	return func(s *Service) {
+		s.warmStart = true
+	}
+}
+
+// WithEventService watches for links being created and pages being folded
+// into others, which is how UpdatePageRanksIfChanged knows the link graph
+// changed. Without it UpdatePageRanksIfChanged always reruns.
+func WithEventService(eventService domain.EventService) Option {
+	return func(s *Service) {
+		s.eventService = eventService
+	}
 }
 
-func NewService(linkService domain.LinkService, pageRankRepo domain.PageRankRepository, logger *zap.SugaredLogger) *Service {
-	return &Service{
+// WithChangeThreshold sets how many link graph changes, as a fraction of the
+// links seen by the last run, must happen before UpdatePageRanksIfChanged
+// reruns. The default of 0 reruns on any change.
+func WithChangeThreshold(fraction float64) Option {
+	return func(s *Service) {
+		s.changeThreshold = fraction
+	}
+}
+
+// WithSeedDomains personalises PageRank so that random jumps, and the rank of
+// pages without outlinks, land only on the pages of the given seed domains.
+// This biases authority toward sites reachable from trusted ones. Seeds are
+// matched by the page ID of their canonical URL.
+func WithSeedDomains(urls []domain.URL) Option {
+	return func(s *Service) {
+		s.seedPages = make(map[domain.PageID]bool, len(urls))
+		s.seedHosts = make(map[domain.Host]bool, len(urls))
+		for _, url := range urls {
+			if canonicalURL, err := canonical.URL(url); err == nil {
+				url = canonicalURL
+			}
+			s.seedPages[util.PageID(url)] = true
+			s.seedHosts[util.Site(url)] = true
+		}
+	}
+}
+
+// WithHostRank ranks hosts over the aggregated host graph after every page
+// rank run and stores the ranks in hostRankRepo.
+func WithHostRank(hostRankRepo domain.HostRankRepository) Option {
+	return func(s *Service) {
+		s.hostRankRepo = hostRankRepo
+	}
+}
+
+// WithInternalLinkWeight sets how much a link between pages of the same host
+// counts relative to a link between hosts. The default of 1 counts them the
+// same and 0 leaves internal links out.
+func WithInternalLinkWeight(weight float64) Option {
+	return func(s *Service) {
+		s.internalLinkWeight = weight
+	}
+}
+
+// ReadSeedDomains reads one seed URL per line, skipping blank lines.
+func ReadSeedDomains(path string) ([]domain.URL, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var urls []domain.URL
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			urls = append(urls, domain.URL(line))
+		}
+	}
+
+	return urls, nil
+}
+
+func NewService(linkService domain.LinkService, pageRankRepo domain.PageRankRepository, logger *zap.SugaredLogger, opts ...Option) *Service {
+	s := &Service{
 		linkService:  linkService,
 		pageRankRepo: pageRankRepo,
 		logger:       logger,
+		linkCount:    -1,
+
+		internalLinkWeight: 1,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.eventService != nil {
+		s.eventService.Subscribe(domain.LinkCreatedKey, s.handleGraphChanged)
+		s.eventService.Subscribe(domain.CrawlCompletedKey, s.handleCrawlCompleted)
+	}
+
+	return s
+}
+
+func (s *Service) handleGraphChanged(domain.Event) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.changes++
+}
+
+// handleCrawlCompleted counts crawls of pages that stand in for another, as
+// their links are folded into that page.
+func (s *Service) handleCrawlCompleted(e domain.Event) {
+	if target, _ := e.(*domain.CrawlCompleted).Target(); target != "" {
+		s.handleGraphChanged(e)
 	}
 }
 
@@ -38,108 +174,260 @@ func (s *Service) UpdatePageRanksEvery(interval time.Duration) {
 	defer ticker.Stop()
 
 	for range ticker.C {
-		err := s.UpdatePageRanks()
+		_, err := s.UpdatePageRanksIfChanged()
 		if err != nil {
 			s.logger.Errorw("Error updating page ranks", "error", err)
 		}
 	}
 }
 
+// UpdatePageRanksIfChanged reruns PageRank only when the link graph has
+// changed by more than the change threshold since the last run. It reports
+// whether a run happened.
+func (s *Service) UpdatePageRanksIfChanged() (bool, error) {
+	s.lock.Lock()
+	changed := s.changed()
+	s.lock.Unlock()
+
+	if !changed {
+		return false, nil
+	}
+
+	if err := s.UpdatePageRanks(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *Service) changed() bool {
+	if s.eventService == nil || s.linkCount < 0 {
+		return true
+	}
+
+	if s.changes == 0 {
+		return false
+	}
+
+	if s.linkCount == 0 {
+		return true
+	}
+
+	return float64(s.changes)/float64(s.linkCount) > s.changeThreshold
+}
+
 func (s *Service) UpdatePageRanks() error {
-	pages, links, err := s.fetchPagesAndLinks()
+	// changes made while the graph is being read are left for the next run
+	s.lock.Lock()
+	changes := s.changes
+	s.lock.Unlock()
+
+	pages, linkCount, err := s.fetchPageGraph()
+	if err != nil {
+		return err
+	}
+
+	var start map[domain.PageID]float64
+	if s.warmStart {
+		start, err = s.pageRankRepo.GetAll()
+		if err != nil {
+			s.logger.Errorw("Error getting stored page ranks", "error", err)
+			return err
+		}
+	}
+
+	teleport := teleportVector(pages.nodes, s.seedPages)
+
+	pageRanks, convergence := calculateRank(pages, teleport, start)
+
+	err = s.pageRankRepo.UpdateAll(pageRanks, time.Now())
 	if err != nil {
+		s.logger.Errorw("Error updating page ranks", "error", err)
 		return err
 	}
 
-	pageRanks := calculatePageRank(pages, links)
+	s.lock.Lock()
+	s.linkCount = linkCount
+	s.changes -= changes
+	s.convergence = convergence
+	s.maxPageRank = nil
+	s.lock.Unlock()
+
+	if !convergence.Converged {
+		s.logger.Warnw("Page ranks did not converge", "iterations", convergence.Iterations, "residual", convergence.Residual)
+	}
+
+	s.logger.Infow(
+		"Updated page ranks",
+		"pages", len(pageRanks),
+		"iterations", convergence.Iterations,
+		"residual", convergence.Residual,
+		"warm_start", start != nil,
+		"teleport_pages", len(teleport),
+	)
+
+	if s.hostRankRepo != nil {
+		return s.updateHostRanks()
+	}
+
+	return nil
+}
+
+// updateHostRanks ranks hosts over the host graph. Edges are weighted by the
+// number of page links behind them and links within a host are left out, so
+// a site cannot raise its own rank.
+func (s *Service) updateHostRanks() error {
+	hostLinks, err := s.linkService.GetHostLinks()
+	if err != nil {
+		s.logger.Errorw("Error getting host links", "error", err)
+		return err
+	}
 
-	for pageID, rank := range pageRanks {
+	hosts := newGraph[domain.Host]()
+	for _, hostLink := range hostLinks {
+		weight := float64(hostLink.Count)
+		if hostLink.SrcHost == hostLink.DstHost {
+			weight = 0
+		}
+		hosts.addEdge(hostLink.SrcHost, hostLink.DstHost, weight)
+	}
 
-		err := s.pageRankRepo.Update(pageID, rank, time.Now())
+	var start map[domain.Host]float64
+	if s.warmStart {
+		start, err = s.hostRankRepo.GetAll()
 		if err != nil {
-			s.logger.Errorw("Error updating page rank", "error", err)
+			s.logger.Errorw("Error getting stored host ranks", "error", err)
 			return err
 		}
 	}
 
+	hostRanks, convergence := calculateRank(hosts, teleportVector(hosts.nodes, s.seedHosts), start)
+
+	err = s.hostRankRepo.UpdateAll(hostRanks, time.Now())
+	if err != nil {
+		s.logger.Errorw("Error updating host ranks", "error", err)
+		return err
+	}
+
+	s.lock.Lock()
+	s.maxHostRank = nil
+	s.lock.Unlock()
+
+	s.logger.Infow(
+		"Updated host ranks",
+		"hosts", len(hostRanks),
+		"iterations", convergence.Iterations,
+		"residual", convergence.Residual,
+	)
+
 	return nil
 }
 
-func (s *Service) GetPageRank(pageID domain.PageID) (float64, error) {
+// LastConvergence reports how the most recent run ended.
+func (s *Service) LastConvergence() domain.PageRankConvergence {
+	s.lock.Lock()
+	defer s.lock.Unlock()
 
-	rank, err := s.pageRankRepo.Get(pageID)
-	if err != nil {
-		s.logger.Errorw("Error getting page rank", "error", err)
-		return 0, err
+	return s.convergence
+}
+
+// GetHostRank returns the rank of a host, when host ranks are kept.
+func (s *Service) GetHostRank(host domain.Host) (float64, error) {
+	if s.hostRankRepo == nil {
+		return 0, errors.New("host ranks are not enabled")
 	}
 
-	return rank, nil
+	return s.hostRankRepo.Get(host)
 }
 
-func (s *Service) fetchPagesAndLinks() (map[domain.PageID]*domain.PageRank, map[domain.PageID][]domain.PageID, error) {
-	pages := make(map[domain.PageID]*domain.PageRank)
-	links := make(map[domain.PageID][]domain.PageID)
+// GetHostRanks returns the ranks of the given hosts in one lookup, when host
+// ranks are kept. Hosts without a rank are left out.
+func (s *Service) GetHostRanks(hosts []domain.Host) (map[domain.Host]float64, error) {
+	if s.hostRankRepo == nil {
+		return nil, errors.New("host ranks are not enabled")
+	}
 
-	allLinks, err := s.linkService.GetAll()
-	if err != nil {
-		s.logger.Errorw("Error getting all links", "error", err)
-		return nil, nil, err
+	return s.hostRankRepo.GetMany(hosts)
+}
+
+// MaxPageRank returns the highest stored page rank, so ranks can be compared
+// on a scale that doesn't depend on which pages are being looked at.
+func (s *Service) MaxPageRank() (float64, error) {
+	return s.cachedMax(&s.maxPageRank, s.pageRankRepo.Max)
+}
+
+// MaxHostRank returns the highest stored host rank, when host ranks are
+// kept.
+func (s *Service) MaxHostRank() (float64, error) {
+	if s.hostRankRepo == nil {
+		return 0, errors.New("host ranks are not enabled")
 	}
 
-	for _, link := range allLinks {
-		if _, ok := pages[link.SrcID]; !ok {
-			pages[link.SrcID] = &domain.PageRank{
-				PageID:   link.SrcID,
-				PageRank: 1.0,
-			}
-		}
+	return s.cachedMax(&s.maxHostRank, s.hostRankRepo.Max)
+}
 
-		if _, ok := pages[link.DstID]; !ok {
-			pages[link.DstID] = &domain.PageRank{
-				PageID:   link.DstID,
-				PageRank: 1.0,
-			}
-		}
+func (s *Service) cachedMax(cached **float64, lookup func() (float64, error)) (float64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if *cached != nil {
+		return **cached, nil
+	}
 
-		links[link.SrcID] = append(links[link.SrcID], link.DstID)
+	highest, err := lookup()
+	if err != nil {
+		s.logger.Errorw("Error getting highest rank", "error", err)
+		return 0, err
 	}
 
-	return pages, links, nil
+	*cached = &highest
+	return highest, nil
 }
 
-func calculatePageRank(pages map[domain.PageID]*domain.PageRank, links map[domain.PageID][]domain.PageID) map[domain.PageID]float64 {
-	numPages := len(pages)
-	pageRanks := make(map[domain.PageID]float64)
-	newPageRanks := make(map[domain.PageID]float64)
+func (s *Service) GetPageRank(pageID domain.PageID) (float64, error) {
 
-	for id := range pages {
-		pageRanks[id] = 1.0 / float64(numPages)
+	rank, err := s.pageRankRepo.Get(pageID)
+	if err != nil {
+		s.logger.Errorw("Error getting page rank", "error", err)
+		return 0, err
 	}
 
-	for i := 0; i < maxIter; i++ {
-		for id := range pages {
-			newPageRanks[id] = (1.0 - d) / float64(numPages)
-		}
+	return rank, nil
+}
 
-		for src, dsts := range links {
-			srcRank := pageRanks[src]
-			lenDsts := float64(len(dsts))
+// GetPageRanks returns the ranks of the given pages in one lookup. Pages
+// without a rank are left out.
+func (s *Service) GetPageRanks(pageIDs []domain.PageID) (map[domain.PageID]float64, error) {
+	ranks, err := s.pageRankRepo.GetMany(pageIDs)
+	if err != nil {
+		s.logger.Errorw("Error getting page ranks", "error", err)
+		return nil, err
+	}
 
-			for _, dst := range dsts {
-				newPageRanks[dst] += d * (srcRank / lenDsts)
-			}
-		}
+	return ranks, nil
+}
 
-		var diff float64
-		for id := range pages {
-			diff += math.Abs(newPageRanks[id] - pageRanks[id])
-			pageRanks[id] = newPageRanks[id]
-		}
+// fetchPageGraph builds the page graph and returns it with the number of
+// links it was built from. Internal links carry the internal link weight and
+// nofollow links carry none, though their pages are still ranked.
+func (s *Service) fetchPageGraph() (*graph[domain.PageID], int, error) {
+	allLinks, err := s.linkService.GetAll()
+	if err != nil {
+		s.logger.Errorw("Error getting all links", "error", err)
+		return nil, 0, err
+	}
 
-		if diff < epsilon {
-			break
+	pages := newGraph[domain.PageID]()
+	for _, link := range allLinks {
+		weight := 1.0
+		if link.Internal {
+			weight = s.internalLinkWeight
+		}
+		if link.Nofollow() {
+			weight = 0
 		}
+		pages.addEdge(link.SrcID, link.DstID, weight)
 	}
 
-	return pageRanks
+	return pages, len(allLinks), nil
 }
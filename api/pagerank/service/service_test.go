@@ -2,12 +2,17 @@ package service_test
 
 import (
 	"crawlquery/api/domain"
+	eventService "crawlquery/api/event/service"
+	hostLinkRepo "crawlquery/api/link/host/repository/mem"
 	linksRepo "crawlquery/api/link/repository/mem"
 	linksService "crawlquery/api/link/service"
+	hostRankRepo "crawlquery/api/pagerank/host/repository/mem"
 	pageRankRepo "crawlquery/api/pagerank/repository/mem"
 	pageRankService "crawlquery/api/pagerank/service"
 	"crawlquery/pkg/testutil"
+	"crawlquery/pkg/util"
 	"math"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -45,11 +50,11 @@ func TestUpdatePageRanksEvery(t *testing.T) {
 
 	// Define expected ranks with a tolerance for comparison
 	expectedRanks := map[domain.PageID]float64{
-		"A": 0.26,
-		"B": 0.14,
-		"C": 0.27,
-		"D": 0.03,
-		"E": 0.04,
+		"A": 0.35,
+		"B": 0.19,
+		"C": 0.37,
+		"D": 0.04,
+		"E": 0.06,
 	}
 
 	tolerance := 0.01 // Adjust the tolerance as needed
@@ -100,11 +105,11 @@ func TestUpdatePageRanks(t *testing.T) {
 
 	// Define expected ranks with a tolerance for comparison
 	expectedRanks := map[domain.PageID]float64{
-		"A": 0.26,
-		"B": 0.14,
-		"C": 0.27,
-		"D": 0.03,
-		"E": 0.04,
+		"A": 0.35,
+		"B": 0.19,
+		"C": 0.37,
+		"D": 0.04,
+		"E": 0.06,
 	}
 
 	tolerance := 0.01 // Adjust the tolerance as needed
@@ -155,3 +160,458 @@ func TestGetPageRank(t *testing.T) {
 		}
 	}
 }
+
+func TestGetPageRanks(t *testing.T) {
+	linksService := linksService.NewService(
+		linksService.WithLinkRepo(linksRepo.NewRepository()),
+	)
+	pageRankRepo := pageRankRepo.NewRepository()
+	pageRankService := pageRankService.NewService(linksService, pageRankRepo, testutil.NewTestLogger())
+
+	pageRankRepo.Update("A", 0.26, time.Now())
+	pageRankRepo.Update("B", 0.14, time.Now())
+
+	ranks, err := pageRankService.GetPageRanks([]domain.PageID{"A", "B", "C"})
+	if err != nil {
+		t.Fatalf("Error getting page ranks: %v", err)
+	}
+
+	expected := map[domain.PageID]float64{"A": 0.26, "B": 0.14}
+	if !reflect.DeepEqual(ranks, expected) {
+		t.Errorf("Expected ranks %v, got %v", expected, ranks)
+	}
+}
+
+func TestUpdatePageRanksIfChanged(t *testing.T) {
+	setup := func(opts ...pageRankService.Option) (func(src, dst domain.PageID), *eventService.Service, *pageRankService.Service) {
+		linksRepo := linksRepo.NewRepository()
+		linksService := linksService.NewService(
+			linksService.WithLinkRepo(linksRepo),
+		)
+		eventService := eventService.NewService()
+		pageRankRepo := pageRankRepo.NewRepository()
+		opts = append(opts, pageRankService.WithEventService(eventService))
+		pageRankService := pageRankService.NewService(linksService, pageRankRepo, testutil.NewTestLogger(), opts...)
+
+		addLink := func(src, dst domain.PageID) {
+			link := &domain.Link{SrcID: src, DstID: dst}
+			if err := linksRepo.Create(link); err != nil {
+				t.Fatalf("Failed to create link: %v", err)
+			}
+			eventService.Publish(&domain.LinkCreated{Link: link})
+		}
+
+		addLink("A", "B")
+		addLink("A", "C")
+		addLink("B", "C")
+		addLink("C", "A")
+
+		return addLink, eventService, pageRankService
+	}
+
+	t.Run("runs on the first call and when links change", func(t *testing.T) {
+		addLink, _, pageRankService := setup()
+
+		ran, err := pageRankService.UpdatePageRanksIfChanged()
+		if err != nil {
+			t.Fatalf("Failed to update page ranks: %v", err)
+		}
+		if !ran {
+			t.Errorf("Expected the first call to run")
+		}
+
+		ran, err = pageRankService.UpdatePageRanksIfChanged()
+		if err != nil {
+			t.Fatalf("Failed to update page ranks: %v", err)
+		}
+		if ran {
+			t.Errorf("Expected no run when links are unchanged")
+		}
+
+		addLink("C", "D")
+
+		ran, err = pageRankService.UpdatePageRanksIfChanged()
+		if err != nil {
+			t.Fatalf("Failed to update page ranks: %v", err)
+		}
+		if !ran {
+			t.Errorf("Expected a run after a link was added")
+		}
+
+		if _, err := pageRankService.GetPageRank("D"); err != nil {
+			t.Errorf("Expected a rank for the new page: %v", err)
+		}
+	})
+
+	t.Run("runs when a page is folded into another", func(t *testing.T) {
+		_, eventService, pageRankService := setup()
+
+		if _, err := pageRankService.UpdatePageRanksIfChanged(); err != nil {
+			t.Fatalf("Failed to update page ranks: %v", err)
+		}
+
+		// the link count stays the same while C's links move to its
+		// canonical page
+		eventService.Publish(&domain.CrawlCompleted{
+			PageID:    "C",
+			URL:       "https://example.com/c?ref=1",
+			Canonical: "https://example.com/c",
+		})
+
+		ran, err := pageRankService.UpdatePageRanksIfChanged()
+		if err != nil {
+			t.Fatalf("Failed to update page ranks: %v", err)
+		}
+		if !ran {
+			t.Errorf("Expected a run after a page was folded into another")
+		}
+	})
+
+	t.Run("ignores crawls that leave the graph alone", func(t *testing.T) {
+		_, eventService, pageRankService := setup()
+
+		if _, err := pageRankService.UpdatePageRanksIfChanged(); err != nil {
+			t.Fatalf("Failed to update page ranks: %v", err)
+		}
+
+		eventService.Publish(&domain.CrawlCompleted{PageID: "C", URL: "https://example.com/c"})
+
+		ran, err := pageRankService.UpdatePageRanksIfChanged()
+		if err != nil {
+			t.Fatalf("Failed to update page ranks: %v", err)
+		}
+		if ran {
+			t.Errorf("Expected no run after a plain crawl")
+		}
+	})
+
+	t.Run("skips changes below the threshold", func(t *testing.T) {
+		addLink, _, pageRankService := setup(pageRankService.WithChangeThreshold(0.5))
+
+		if _, err := pageRankService.UpdatePageRanksIfChanged(); err != nil {
+			t.Fatalf("Failed to update page ranks: %v", err)
+		}
+
+		addLink("C", "D")
+
+		ran, err := pageRankService.UpdatePageRanksIfChanged()
+		if err != nil {
+			t.Fatalf("Failed to update page ranks: %v", err)
+		}
+		if ran {
+			t.Errorf("Expected no run for a change below the threshold")
+		}
+
+		addLink("D", "A")
+		addLink("D", "B")
+
+		ran, err = pageRankService.UpdatePageRanksIfChanged()
+		if err != nil {
+			t.Fatalf("Failed to update page ranks: %v", err)
+		}
+		if !ran {
+			t.Errorf("Expected a run for a change above the threshold")
+		}
+	})
+}
+
+func TestUpdatePageRanksWarmStart(t *testing.T) {
+	linksRepo := linksRepo.NewRepository()
+	linksService := linksService.NewService(
+		linksService.WithLinkRepo(linksRepo),
+	)
+	pageRankRepo := pageRankRepo.NewRepository()
+	pageRankService := pageRankService.NewService(linksService, pageRankRepo, testutil.NewTestLogger(), pageRankService.WithWarmStart())
+
+	links := []*domain.Link{
+		{SrcID: "A", DstID: "B"},
+		{SrcID: "A", DstID: "C"},
+		{SrcID: "B", DstID: "C"},
+		{SrcID: "C", DstID: "A"},
+		{SrcID: "D", DstID: "C"},
+		{SrcID: "D", DstID: "E"},
+	}
+
+	for _, link := range links {
+		if err := linksRepo.Create(link); err != nil {
+			t.Fatalf("Failed to create link: %v", err)
+		}
+	}
+
+	// the first run has nothing stored and the second starts from it
+	for i := 0; i < 2; i++ {
+		if err := pageRankService.UpdatePageRanks(); err != nil {
+			t.Fatalf("Failed to update page ranks: %v", err)
+		}
+	}
+
+	expectedRanks := map[domain.PageID]float64{
+		"A": 0.35,
+		"B": 0.19,
+		"C": 0.37,
+		"D": 0.04,
+		"E": 0.06,
+	}
+
+	tolerance := 0.01
+
+	for pageID, expected := range expectedRanks {
+		actual, err := pageRankService.GetPageRank(pageID)
+		if err != nil {
+			t.Fatalf("Failed to get PageRank for %s: %v", pageID, err)
+		}
+
+		if math.Abs(actual-expected) > tolerance {
+			t.Errorf("Unexpected PageRank for %s. Expected %f, got %f", pageID, expected, actual)
+		}
+	}
+}
+
+func TestUpdatePageRanksDanglingNodes(t *testing.T) {
+	linksRepo := linksRepo.NewRepository()
+	linksService := linksService.NewService(
+		linksService.WithLinkRepo(linksRepo),
+	)
+	pageRankRepo := pageRankRepo.NewRepository()
+	pageRankService := pageRankService.NewService(linksService, pageRankRepo, testutil.NewTestLogger())
+
+	// B and C have no outlinks
+	links := []*domain.Link{
+		{SrcID: "A", DstID: "B"},
+		{SrcID: "A", DstID: "C"},
+	}
+
+	for _, link := range links {
+		if err := linksRepo.Create(link); err != nil {
+			t.Fatalf("Failed to create link: %v", err)
+		}
+	}
+
+	if err := pageRankService.UpdatePageRanks(); err != nil {
+		t.Fatalf("Failed to update page ranks: %v", err)
+	}
+
+	ranks, err := pageRankRepo.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to get page ranks: %v", err)
+	}
+
+	var total float64
+	for _, rank := range ranks {
+		total += rank
+	}
+
+	if math.Abs(total-1) > 1e-4 {
+		t.Errorf("Expected ranks to sum to 1, got %f", total)
+	}
+
+	if ranks["B"] <= ranks["A"] {
+		t.Errorf("Expected B to outrank A, got %f and %f", ranks["B"], ranks["A"])
+	}
+
+	convergence := pageRankService.LastConvergence()
+
+	if !convergence.Converged {
+		t.Errorf("Expected the run to converge, got %+v", convergence)
+	}
+
+	if convergence.Iterations == 0 || convergence.Residual >= 1e-6 {
+		t.Errorf("Unexpected convergence report %+v", convergence)
+	}
+}
+
+func TestUpdatePageRanksSeedDomains(t *testing.T) {
+	trusted := domain.URL("http://trusted.com/")
+	other := domain.URL("http://other.com/")
+
+	trustedID := util.PageID(trusted)
+	otherID := util.PageID(other)
+
+	// both home pages link to one page of their own, which links back
+	links := []*domain.Link{
+		{SrcID: trustedID, DstID: "trusted-about"},
+		{SrcID: "trusted-about", DstID: trustedID},
+		{SrcID: otherID, DstID: "other-about"},
+		{SrcID: "other-about", DstID: otherID},
+	}
+
+	rank := func(opts ...pageRankService.Option) map[domain.PageID]float64 {
+		linksRepo := linksRepo.NewRepository()
+		linksService := linksService.NewService(
+			linksService.WithLinkRepo(linksRepo),
+		)
+		pageRankRepo := pageRankRepo.NewRepository()
+		pageRankService := pageRankService.NewService(linksService, pageRankRepo, testutil.NewTestLogger(), opts...)
+
+		for _, link := range links {
+			if err := linksRepo.Create(link); err != nil {
+				t.Fatalf("Failed to create link: %v", err)
+			}
+		}
+
+		if err := pageRankService.UpdatePageRanks(); err != nil {
+			t.Fatalf("Failed to update page ranks: %v", err)
+		}
+
+		ranks, err := pageRankRepo.GetAll()
+		if err != nil {
+			t.Fatalf("Failed to get page ranks: %v", err)
+		}
+
+		return ranks
+	}
+
+	uniform := rank()
+
+	if math.Abs(uniform[trustedID]-uniform[otherID]) > 1e-4 {
+		t.Errorf("Expected equal ranks without seeds, got %f and %f", uniform[trustedID], uniform[otherID])
+	}
+
+	personalised := rank(pageRankService.WithSeedDomains([]domain.URL{trusted}))
+
+	if personalised[trustedID] <= personalised[otherID] {
+		t.Errorf("Expected the seed domain to outrank the other, got %f and %f", personalised[trustedID], personalised[otherID])
+	}
+
+	if personalised["trusted-about"] <= personalised["other-about"] {
+		t.Errorf("Expected pages linked from the seed to outrank the rest, got %f and %f", personalised["trusted-about"], personalised["other-about"])
+	}
+}
+
+func TestUpdatePageRanksInternalLinks(t *testing.T) {
+	// A and B are pages of one site that link to each other, and both link
+	// out to C on another site
+	links := []*domain.Link{
+		{SrcID: "A", DstID: "B", Internal: true},
+		{SrcID: "B", DstID: "A", Internal: true},
+		{SrcID: "A", DstID: "C"},
+		{SrcID: "B", DstID: "C"},
+	}
+
+	rank := func(opts ...pageRankService.Option) map[domain.PageID]float64 {
+		linksRepo := linksRepo.NewRepository()
+		linksService := linksService.NewService(
+			linksService.WithLinkRepo(linksRepo),
+		)
+		pageRankRepo := pageRankRepo.NewRepository()
+		pageRankService := pageRankService.NewService(linksService, pageRankRepo, testutil.NewTestLogger(), opts...)
+
+		for _, link := range links {
+			if err := linksRepo.Create(link); err != nil {
+				t.Fatalf("Failed to create link: %v", err)
+			}
+		}
+
+		if err := pageRankService.UpdatePageRanks(); err != nil {
+			t.Fatalf("Failed to update page ranks: %v", err)
+		}
+
+		ranks, err := pageRankRepo.GetAll()
+		if err != nil {
+			t.Fatalf("Failed to get page ranks: %v", err)
+		}
+
+		return ranks
+	}
+
+	counted := rank()
+	excluded := rank(pageRankService.WithInternalLinkWeight(0))
+
+	if excluded["A"] >= counted["A"] {
+		t.Errorf("Expected excluding internal links to lower A, got %f and %f", excluded["A"], counted["A"])
+	}
+
+	if excluded["C"] <= counted["C"] {
+		t.Errorf("Expected excluding internal links to raise C, got %f and %f", excluded["C"], counted["C"])
+	}
+}
+
+func TestUpdatePageRanksHostRank(t *testing.T) {
+	hostLinkRepo := hostLinkRepo.NewRepository()
+	linksService := linksService.NewService(
+		linksService.WithLinkRepo(linksRepo.NewRepository()),
+		linksService.WithHostLinkRepo(hostLinkRepo),
+	)
+	hostRankRepo := hostRankRepo.NewRepository()
+	pageRankService := pageRankService.NewService(
+		linksService,
+		pageRankRepo.NewRepository(),
+		testutil.NewTestLogger(),
+		pageRankService.WithHostRank(hostRankRepo),
+	)
+
+	// spam.com links to itself heavily, the others link to authority.com
+	for i := 0; i < 50; i++ {
+		hostLinkRepo.Increment("spam.com", "spam.com", time.Now())
+	}
+	hostLinkRepo.Increment("blog.com", "authority.com", time.Now())
+	hostLinkRepo.Increment("news.com", "authority.com", time.Now())
+	hostLinkRepo.Increment("authority.com", "blog.com", time.Now())
+
+	if err := pageRankService.UpdatePageRanks(); err != nil {
+		t.Fatalf("Failed to update page ranks: %v", err)
+	}
+
+	authority, err := pageRankService.GetHostRank("authority.com")
+	if err != nil {
+		t.Fatalf("Failed to get host rank: %v", err)
+	}
+
+	spam, err := pageRankService.GetHostRank("spam.com")
+	if err != nil {
+		t.Fatalf("Failed to get host rank: %v", err)
+	}
+
+	if authority <= spam {
+		t.Errorf("Expected authority.com to outrank spam.com, got %f and %f", authority, spam)
+	}
+
+	ranks, _ := hostRankRepo.GetAll()
+
+	var total float64
+	for _, rank := range ranks {
+		total += rank
+	}
+
+	if math.Abs(total-1) > 1e-4 {
+		t.Errorf("Expected host ranks to sum to 1, got %f", total)
+	}
+}
+
+func TestUpdatePageRanksNofollow(t *testing.T) {
+	linksRepo := linksRepo.NewRepository()
+	linksService := linksService.NewService(
+		linksService.WithLinkRepo(linksRepo),
+	)
+	pageRankRepo := pageRankRepo.NewRepository()
+	pageRankService := pageRankService.NewService(linksService, pageRankRepo, testutil.NewTestLogger())
+
+	// A follows B but marks its link to C as nofollow
+	links := []*domain.Link{
+		{SrcID: "A", DstID: "B"},
+		{SrcID: "A", DstID: "C", Rel: "nofollow"},
+	}
+
+	for _, link := range links {
+		if err := linksRepo.Create(link); err != nil {
+			t.Fatalf("Failed to create link: %v", err)
+		}
+	}
+
+	if err := pageRankService.UpdatePageRanks(); err != nil {
+		t.Fatalf("Failed to update page ranks: %v", err)
+	}
+
+	ranks, err := pageRankRepo.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to get page ranks: %v", err)
+	}
+
+	if ranks["B"] <= ranks["C"] {
+		t.Errorf("Expected B to outrank C, got %f and %f", ranks["B"], ranks["C"])
+	}
+
+	if math.Abs(ranks["A"]-ranks["C"]) > 1e-6 {
+		t.Errorf("Expected C to get no rank from A, got %f and %f", ranks["C"], ranks["A"])
+	}
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"crawlquery/api/domain"
+	"crawlquery/api/dto"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	queryService domain.QueryService
+}
+
+func NewHandler(queryService domain.QueryService) *Handler {
+	return &Handler{
+		queryService: queryService,
+	}
+}
+
+func (h *Handler) Query(c *gin.Context) {
+	var req dto.QueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.NewErrorResponse(err))
+		return
+	}
+
+	result, err := h.queryService.Query(req.Query)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidQuery) {
+			c.JSON(http.StatusBadRequest, dto.NewErrorResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, dto.NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewQueryResponse(result))
+}
@@ -0,0 +1,115 @@
+package handler_test
+
+import (
+	"bytes"
+	"crawlquery/api/domain"
+	"crawlquery/api/dto"
+	"crawlquery/api/query/handler"
+	"crawlquery/pkg/testutil"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	nodeDto "crawlquery/node/dto"
+
+	nodeRepo "crawlquery/api/node/repository/mem"
+	nodeService "crawlquery/api/node/service"
+
+	queryService "crawlquery/api/query/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/h2non/gock"
+)
+
+func setupHandler() (*nodeRepo.Repository, *handler.Handler) {
+	nodeRepo := nodeRepo.NewRepository()
+	nodeService := nodeService.NewService(
+		nodeService.WithNodeRepo(nodeRepo),
+		nodeService.WithLogger(testutil.NewTestLogger()),
+		nodeService.WithRandSeed(time.Now().Unix()),
+	)
+	queryService := queryService.NewService(
+		queryService.WithNodeService(nodeService),
+		queryService.WithLogger(testutil.NewTestLogger()),
+	)
+
+	return nodeRepo, handler.NewHandler(queryService)
+}
+
+func TestQuery(t *testing.T) {
+	t.Run("should return rows", func(t *testing.T) {
+		nodeRepo, queryHandler := setupHandler()
+
+		nodeRepo.Create(&domain.Node{
+			ID:        "node1",
+			ShardID:   0,
+			Hostname:  "node1.cluster.com",
+			Port:      8080,
+			CreatedAt: time.Now(),
+		})
+
+		defer gock.Off()
+
+		gock.New("http://node1.cluster.com:8080").
+			Post("/query").
+			Reply(200).
+			JSON(&nodeDto.QueryResponse{
+				Columns: []nodeDto.QueryColumn{{Name: "title", Type: "string"}},
+				Rows:    [][]any{{"Example"}},
+			})
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+
+		reqBody, _ := json.Marshal(dto.QueryRequest{Query: "SELECT title FROM pages"})
+		ctx.Request, _ = http.NewRequest(http.MethodPost, "/query", bytes.NewReader(reqBody))
+
+		queryHandler.Query(ctx)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+
+		var res dto.QueryResponse
+		if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+			t.Fatalf("Error decoding response: %v", err)
+		}
+
+		if len(res.Rows) != 1 || res.Rows[0][0] != "Example" {
+			t.Errorf("Expected one row with Example, got %v", res.Rows)
+		}
+	})
+
+	t.Run("should return 400 for an invalid query", func(t *testing.T) {
+		_, queryHandler := setupHandler()
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+
+		reqBody, _ := json.Marshal(dto.QueryRequest{Query: "DROP TABLE pages"})
+		ctx.Request, _ = http.NewRequest(http.MethodPost, "/query", bytes.NewReader(reqBody))
+
+		queryHandler.Query(ctx)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("should return 400 for a missing query", func(t *testing.T) {
+		_, queryHandler := setupHandler()
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+
+		ctx.Request, _ = http.NewRequest(http.MethodPost, "/query", bytes.NewReader([]byte(`{}`)))
+
+		queryHandler.Query(ctx)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", w.Code)
+		}
+	})
+}
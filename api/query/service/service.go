@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"crawlquery/api/domain"
+	"crawlquery/node/dto"
+	"crawlquery/node/query/parser"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	nodeDomain "crawlquery/node/domain"
+
+	"go.uber.org/zap"
+)
+
+type Service struct {
+	nodeService domain.NodeService
+	timeout     time.Duration
+	logger      *zap.SugaredLogger
+}
+
+type Option func(*Service)
+
+func WithNodeService(nodeService domain.NodeService) Option {
+	return func(s *Service) {
+		s.nodeService = nodeService
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(s *Service) {
+		s.timeout = timeout
+	}
+}
+
+func WithLogger(logger *zap.SugaredLogger) Option {
+	return func(s *Service) {
+		s.logger = logger
+	}
+}
+
+func NewService(opts ...Option) *Service {
+	s := &Service{
+		timeout: 5 * time.Second,
+		logger:  zap.NewNop().Sugar(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+type shardResponse struct {
+	res *dto.QueryResponse
+	err error
+}
+
+// queryShard sends the query to up to 10 nodes of a shard and returns the
+// first successful response.
+func (s *Service) queryShard(nodes []*domain.Node, query string) (*dto.QueryResponse, error) {
+	if len(nodes) > 10 {
+		nodes = nodes[:10]
+	}
+
+	responses := make(chan shardResponse, len(nodes))
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	for _, node := range nodes {
+		go func(node *domain.Node) {
+			res, err := s.nodeService.SendQuery(ctx, node, query)
+			if err != nil {
+				s.logger.Errorf("Error querying node %s: %v", node.ID, err)
+			}
+			responses <- shardResponse{res: res, err: err}
+		}(node)
+	}
+
+	var lastErr error
+
+	for range nodes {
+		select {
+		case r := <-responses:
+			if r.err == nil {
+				return r.res, nil
+			}
+			lastErr = r.err
+		case <-ctx.Done():
+			return nil, fmt.Errorf("query timed out for shard %d", nodes[0].ShardID)
+		}
+	}
+
+	return nil, lastErr
+}
+
+// Query sends the query to the fastest node in each shard, merges the rows
+// and applies the global ORDER BY and LIMIT. Shards that fail are listed in
+// the result, which is then missing their rows.
+func (s *Service) Query(query string) (*domain.QueryResult, error) {
+	stmt, err := parser.Parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidQuery, err)
+	}
+
+	// rows can only be re-sorted on values that come back from the nodes
+	if !stmt.AllColumns() {
+		for _, orderBy := range stmt.OrderBy {
+			if !contains(stmt.Columns, orderBy.Column) {
+				return nil, fmt.Errorf("%w: ORDER BY column %s must be selected", domain.ErrInvalidQuery, orderBy.Column)
+			}
+		}
+	}
+
+	shardNodes, err := s.nodeService.RandomizedListGroupByShard()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(shardNodes) == 0 {
+		s.logger.Errorf("Query.Service.Query: No nodes found")
+		return nil, domain.ErrNoNodesAvailable
+	}
+
+	var responses []*dto.QueryResponse
+	var errs []error
+	var failed []domain.ShardID
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+
+	wg.Add(len(shardNodes))
+
+	for _, nodes := range shardNodes {
+		go func(nodes []*domain.Node) {
+			defer wg.Done()
+
+			res, err := s.queryShard(nodes, query)
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			if err != nil {
+				s.logger.Errorf("Error querying shard %d: %v", nodes[0].ShardID, err)
+				errs = append(errs, err)
+				failed = append(failed, nodes[0].ShardID)
+				return
+			}
+			responses = append(responses, res)
+		}(nodes)
+	}
+
+	wg.Wait()
+
+	if len(responses) == 0 {
+		// an invalid query is the caller's fault, whichever shard said so
+		for _, err := range errs {
+			if errors.Is(err, domain.ErrInvalidQuery) {
+				return nil, err
+			}
+		}
+		return nil, errs[0]
+	}
+
+	sort.Slice(failed, func(i, j int) bool {
+		return failed[i] < failed[j]
+	})
+
+	return &domain.QueryResult{
+		QueryResult:  *merge(stmt, responses),
+		FailedShards: failed,
+	}, nil
+}
+
+func contains(columns []string, column string) bool {
+	for _, c := range columns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+func merge(stmt *parser.Statement, responses []*dto.QueryResponse) *nodeDomain.QueryResult {
+	result := &nodeDomain.QueryResult{
+		Rows: []nodeDomain.QueryRow{},
+	}
+
+	for _, col := range responses[0].Columns {
+		result.Columns = append(result.Columns, nodeDomain.QueryColumn{
+			Name: col.Name,
+			Type: nodeDomain.QueryColumnType(col.Type),
+		})
+	}
+
+	for _, res := range responses {
+		for _, row := range res.Rows {
+			result.Rows = append(result.Rows, row)
+		}
+	}
+
+	type sortKey struct {
+		index   int
+		colType nodeDomain.QueryColumnType
+		desc    bool
+	}
+
+	var keys []sortKey
+	for _, orderBy := range stmt.OrderBy {
+		for i, col := range result.Columns {
+			if col.Name == orderBy.Column {
+				keys = append(keys, sortKey{index: i, colType: col.Type, desc: orderBy.Desc})
+				break
+			}
+		}
+	}
+
+	sort.SliceStable(result.Rows, func(i, j int) bool {
+		for _, k := range keys {
+			c := compare(k.colType, result.Rows[i][k.index], result.Rows[j][k.index])
+			if c == 0 {
+				continue
+			}
+			if k.desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+
+	if stmt.Limit >= 0 && len(result.Rows) > stmt.Limit {
+		result.Rows = result.Rows[:stmt.Limit]
+	}
+
+	return result
+}
+
+// compare orders two decoded JSON values of the given column type. Nulls
+// sort first, matching the ordering used by the nodes.
+func compare(colType nodeDomain.QueryColumnType, a, b any) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	switch colType {
+	case nodeDomain.QueryColumnTypeInt:
+		x, _ := a.(float64)
+		y, _ := b.(float64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	case nodeDomain.QueryColumnTypeTime:
+		x, errA := time.Parse(time.RFC3339Nano, fmt.Sprint(a))
+		y, errB := time.Parse(time.RFC3339Nano, fmt.Sprint(b))
+		if errA == nil && errB == nil {
+			return x.Compare(y)
+		}
+	}
+
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
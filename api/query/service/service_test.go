@@ -0,0 +1,207 @@
+package service_test
+
+import (
+	"crawlquery/api/domain"
+	nodeRepo "crawlquery/api/node/repository/mem"
+	nodeService "crawlquery/api/node/service"
+	queryService "crawlquery/api/query/service"
+	"crawlquery/node/dto"
+	"crawlquery/pkg/testutil"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	nodeDomain "crawlquery/node/domain"
+
+	"github.com/h2non/gock"
+)
+
+func setupServices() (*nodeRepo.Repository, *queryService.Service) {
+	nodeRepo := nodeRepo.NewRepository()
+	nodeService := nodeService.NewService(
+		nodeService.WithNodeRepo(nodeRepo),
+		nodeService.WithLogger(testutil.NewTestLogger()),
+		nodeService.WithRandSeed(time.Now().Unix()),
+	)
+	queryService := queryService.NewService(
+		queryService.WithNodeService(nodeService),
+		queryService.WithTimeout(time.Second),
+		queryService.WithLogger(testutil.NewTestLogger()),
+	)
+
+	return nodeRepo, queryService
+}
+
+func createNodes(nodeRepo *nodeRepo.Repository) {
+	nodeRepo.Create(&domain.Node{
+		ID:        "node1",
+		ShardID:   0,
+		Hostname:  "node1.cluster.com",
+		Port:      8080,
+		CreatedAt: time.Now(),
+	})
+
+	nodeRepo.Create(&domain.Node{
+		ID:        "node2",
+		ShardID:   1,
+		Hostname:  "node2.cluster.com",
+		Port:      8080,
+		CreatedAt: time.Now(),
+	})
+}
+
+func TestQuery(t *testing.T) {
+	t.Run("merges rows and applies order by and limit", func(t *testing.T) {
+		nodeRepo, queryService := setupServices()
+		createNodes(nodeRepo)
+
+		defer gock.Off()
+
+		query := "SELECT id, frequency FROM keywords WHERE keyword = 'example' ORDER BY frequency DESC LIMIT 3"
+		columns := []dto.QueryColumn{
+			{Name: "id", Type: "string"},
+			{Name: "frequency", Type: "int"},
+		}
+
+		gock.New("http://node1.cluster.com:8080").
+			Post("/query").
+			JSON(&dto.QueryRequest{Query: query}).
+			Reply(200).
+			JSON(&dto.QueryResponse{
+				Columns: columns,
+				Rows:    [][]any{{"page1", 9}, {"page2", 4}, {"page3", 1}},
+			})
+
+		gock.New("http://node2.cluster.com:8080").
+			Post("/query").
+			JSON(&dto.QueryRequest{Query: query}).
+			Reply(200).
+			JSON(&dto.QueryResponse{
+				Columns: columns,
+				Rows:    [][]any{{"page4", 5}, {"page5", 2}},
+			})
+
+		result, err := queryService.Query(query)
+		if err != nil {
+			t.Fatalf("Error querying: %v", err)
+		}
+
+		expectedColumns := []nodeDomain.QueryColumn{
+			{Name: "id", Type: nodeDomain.QueryColumnTypeString},
+			{Name: "frequency", Type: nodeDomain.QueryColumnTypeInt},
+		}
+
+		if !reflect.DeepEqual(result.Columns, expectedColumns) {
+			t.Errorf("Expected columns %v, got %v", expectedColumns, result.Columns)
+		}
+
+		expectedRows := []nodeDomain.QueryRow{
+			{"page1", float64(9)},
+			{"page4", float64(5)},
+			{"page2", float64(4)},
+		}
+
+		if !reflect.DeepEqual(result.Rows, expectedRows) {
+			t.Errorf("Expected rows %v, got %v", expectedRows, result.Rows)
+		}
+	})
+
+	t.Run("returns rows from the shards that answered", func(t *testing.T) {
+		nodeRepo, queryService := setupServices()
+		createNodes(nodeRepo)
+
+		defer gock.Off()
+
+		gock.New("http://node1.cluster.com:8080").
+			Post("/query").
+			Reply(200).
+			JSON(&dto.QueryResponse{
+				Columns: []dto.QueryColumn{{Name: "id", Type: "string"}},
+				Rows:    [][]any{{"page1"}},
+			})
+
+		gock.New("http://node2.cluster.com:8080").
+			Post("/query").
+			Reply(500)
+
+		result, err := queryService.Query("SELECT id FROM pages")
+		if err != nil {
+			t.Fatalf("Error querying: %v", err)
+		}
+
+		if len(result.Rows) != 1 {
+			t.Fatalf("Expected 1 row, got %d", len(result.Rows))
+		}
+
+		if !reflect.DeepEqual(result.FailedShards, []domain.ShardID{1}) {
+			t.Errorf("Expected shard 1 to be reported as failed, got %v", result.FailedShards)
+		}
+	})
+
+	t.Run("returns an error when every shard fails", func(t *testing.T) {
+		nodeRepo, queryService := setupServices()
+		createNodes(nodeRepo)
+
+		defer gock.Off()
+
+		gock.New("http://node1.cluster.com:8080").
+			Post("/query").
+			Reply(400).
+			JSON(&dto.ErrorResponse{Error: "invalid query: unknown table users"})
+
+		gock.New("http://node2.cluster.com:8080").
+			Post("/query").
+			Reply(400).
+			JSON(&dto.ErrorResponse{Error: "invalid query: unknown table users"})
+
+		_, err := queryService.Query("SELECT id FROM users")
+		if !errors.Is(err, domain.ErrInvalidQuery) {
+			t.Fatalf("Expected ErrInvalidQuery, got %v", err)
+		}
+
+		if err.Error() != "invalid query: unknown table users" {
+			t.Errorf("Expected the node's reason, got %v", err)
+		}
+	})
+
+	t.Run("works without a logger", func(t *testing.T) {
+		queryService := queryService.NewService(
+			queryService.WithNodeService(nodeService.NewService(
+				nodeService.WithNodeRepo(nodeRepo.NewRepository()),
+				nodeService.WithLogger(testutil.NewTestLogger()),
+			)),
+		)
+
+		_, err := queryService.Query("SELECT id FROM pages")
+		if err != domain.ErrNoNodesAvailable {
+			t.Errorf("Expected ErrNoNodesAvailable, got %v", err)
+		}
+	})
+
+	t.Run("rejects invalid queries without contacting nodes", func(t *testing.T) {
+		nodeRepo, queryService := setupServices()
+		createNodes(nodeRepo)
+
+		cases := []string{
+			"SELECT FROM pages",
+			"SELECT id FROM pages ORDER BY title",
+		}
+
+		for _, query := range cases {
+			_, err := queryService.Query(query)
+			if !errors.Is(err, domain.ErrInvalidQuery) {
+				t.Errorf("Expected ErrInvalidQuery for %q, got %v", query, err)
+			}
+		}
+	})
+
+	t.Run("returns an error when there are no nodes", func(t *testing.T) {
+		_, queryService := setupServices()
+
+		_, err := queryService.Query("SELECT id FROM pages")
+		if err != domain.ErrNoNodesAvailable {
+			t.Errorf("Expected ErrNoNodesAvailable, got %v", err)
+		}
+	})
+}
@@ -16,6 +16,11 @@ func NewRouter(
 	pageHandler domain.PageHandler,
 	nodeHandler domain.NodeHandler,
 	searchHandler domain.SearchHandler,
+	queryHandler domain.QueryHandler,
+	crawlThrottleHandler domain.CrawlThrottleHandler,
+	urlFilterHandler domain.URLFilterHandler,
+	scopeHandler domain.ScopeHandler,
+	sitemapHandler domain.SitemapHandler,
 ) *gin.Engine {
 	router := gin.Default()
 	router.Use(cors.New(cors.Config{
@@ -39,5 +44,17 @@ func NewRouter(
 
 	router.GET("/search", searchHandler.Search)
 
+	router.POST("/query", queryHandler.Query)
+
+	router.GET("/crawl/hosts", middleware.AuthMiddleware(as, crawlThrottleHandler.Hosts))
+	router.POST("/crawl/filters/evaluate", middleware.AuthMiddleware(as, urlFilterHandler.Evaluate))
+
+	router.POST("/crawl/scopes", middleware.AuthMiddleware(as, scopeHandler.Create))
+	router.GET("/crawl/scopes", middleware.AuthMiddleware(as, scopeHandler.List))
+	router.GET("/crawl/scopes/:scopeID", middleware.AuthMiddleware(as, scopeHandler.Get))
+
+	router.POST("/crawl/sitemaps", middleware.AuthMiddleware(as, sitemapHandler.Seed))
+	router.GET("/crawl/sitemaps/:seedID", middleware.AuthMiddleware(as, sitemapHandler.GetSeed))
+
 	return router
 }
@@ -79,6 +79,66 @@ func (m *MockSearchHandler) Search(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Search successful"})
 }
 
+type MockQueryHandler struct {
+	mock.Mock
+}
+
+func (m *MockQueryHandler) Query(c *gin.Context) {
+	m.Called(c)
+	c.JSON(http.StatusOK, gin.H{"message": "Query successful"})
+}
+
+type MockCrawlThrottleHandler struct {
+	mock.Mock
+}
+
+func (m *MockCrawlThrottleHandler) Hosts(c *gin.Context) {
+	m.Called(c)
+	c.JSON(http.StatusOK, gin.H{"message": "Hosts listed"})
+}
+
+type MockURLFilterHandler struct {
+	mock.Mock
+}
+
+func (m *MockURLFilterHandler) Evaluate(c *gin.Context) {
+	m.Called(c)
+	c.JSON(http.StatusOK, gin.H{"message": "URLs evaluated"})
+}
+
+type MockScopeHandler struct {
+	mock.Mock
+}
+
+func (m *MockScopeHandler) Create(c *gin.Context) {
+	m.Called(c)
+	c.JSON(http.StatusCreated, gin.H{"message": "Scope created"})
+}
+
+func (m *MockScopeHandler) Get(c *gin.Context) {
+	m.Called(c)
+	c.JSON(http.StatusOK, gin.H{"message": "Scope found"})
+}
+
+func (m *MockScopeHandler) List(c *gin.Context) {
+	m.Called(c)
+	c.JSON(http.StatusOK, gin.H{"message": "Scopes listed"})
+}
+
+type MockSitemapHandler struct {
+	mock.Mock
+}
+
+func (m *MockSitemapHandler) Seed(c *gin.Context) {
+	m.Called(c)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Sitemaps seeding"})
+}
+
+func (m *MockSitemapHandler) GetSeed(c *gin.Context) {
+	m.Called(c)
+	c.JSON(http.StatusOK, gin.H{"message": "Seed found"})
+}
+
 func setupRouterWithMocks() map[string]interface{} {
 	gin.SetMode(gin.TestMode)
 
@@ -100,6 +160,24 @@ func setupRouterWithMocks() map[string]interface{} {
 	mockSearchHandler := new(MockSearchHandler)
 	mockSearchHandler.On("Search", mock.Anything).Return()
 
+	mockQueryHandler := new(MockQueryHandler)
+	mockQueryHandler.On("Query", mock.Anything).Return()
+
+	mockCrawlThrottleHandler := new(MockCrawlThrottleHandler)
+	mockCrawlThrottleHandler.On("Hosts", mock.Anything).Return()
+
+	mockURLFilterHandler := new(MockURLFilterHandler)
+	mockURLFilterHandler.On("Evaluate", mock.Anything).Return()
+
+	mockScopeHandler := new(MockScopeHandler)
+	mockScopeHandler.On("Create", mock.Anything).Return()
+	mockScopeHandler.On("Get", mock.Anything).Return()
+	mockScopeHandler.On("List", mock.Anything).Return()
+
+	mockSitemapHandler := new(MockSitemapHandler)
+	mockSitemapHandler.On("Seed", mock.Anything).Return()
+	mockSitemapHandler.On("GetSeed", mock.Anything).Return()
+
 	accountService, accountRepo := factory.AccountServiceWithAccount(&domain.Account{})
 
 	// Setup the router with the mock handler
@@ -110,16 +188,26 @@ func setupRouterWithMocks() map[string]interface{} {
 		mockPageHandler,
 		mockNodeHandler,
 		mockSearchHandler,
+		mockQueryHandler,
+		mockCrawlThrottleHandler,
+		mockURLFilterHandler,
+		mockScopeHandler,
+		mockSitemapHandler,
 	)
 
 	return map[string]interface{}{
-		"testRouter":         testRouter,
-		"mockAccountHandler": mockAccountHandler,
-		"mockPageHandler":    mockPageHandler,
-		"mockNodeHandler":    mockNodeHandler,
-		"mockSearchHandler":  mockSearchHandler,
-		"accountService":     accountService,
-		"accountRepo":        accountRepo,
+		"testRouter":               testRouter,
+		"mockAccountHandler":       mockAccountHandler,
+		"mockPageHandler":          mockPageHandler,
+		"mockNodeHandler":          mockNodeHandler,
+		"mockSearchHandler":        mockSearchHandler,
+		"mockQueryHandler":         mockQueryHandler,
+		"mockCrawlThrottleHandler": mockCrawlThrottleHandler,
+		"mockURLFilterHandler":     mockURLFilterHandler,
+		"mockScopeHandler":         mockScopeHandler,
+		"mockSitemapHandler":       mockSitemapHandler,
+		"accountService":           accountService,
+		"accountRepo":              accountRepo,
 	}
 }
 
@@ -241,6 +329,26 @@ func TestSearchEndpoint(t *testing.T) {
 	mockSearchHandler.AssertExpectations(t)
 }
 
+func TestQueryEndpoint(t *testing.T) {
+	// Set the router to test mode
+	ifs := setupRouterWithMocks()
+
+	testRouter := ifs["testRouter"].(*gin.Engine)
+	mockQueryHandler := ifs["mockQueryHandler"].(*MockQueryHandler)
+
+	w := httptest.NewRecorder()
+
+	req, _ := http.NewRequest("POST", "/query", bytes.NewBufferString(`{"query":"SELECT * FROM pages"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	testRouter.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Query successful")
+
+	mockQueryHandler.AssertExpectations(t)
+}
+
 func TestNodeListByAccountIDEndpoint(t *testing.T) {
 	// Set the router to test mode
 	ifs := setupRouterWithMocks()
@@ -303,3 +411,194 @@ func TestNodeListByShardIDEndpoint(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "Nodes listed")
 }
+
+func TestCrawlHostsEndpoint(t *testing.T) {
+	// Set the router to test mode
+	ifs := setupRouterWithMocks()
+
+	testRouter := ifs["testRouter"].(*gin.Engine)
+	accountRepo := ifs["accountRepo"].(domain.AccountRepository)
+
+	account, err := accountRepo.GetByEmail("test@example.com")
+
+	if err != nil {
+		t.Fatalf("Error getting account: %v", err)
+	}
+
+	token, err := authutil.GenerateToken(account.ID)
+
+	if err != nil {
+		t.Fatalf("Error generating token: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+
+	req, _ := http.NewRequest("GET", "/crawl/hosts", nil)
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	testRouter.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Contains(t, w.Body.String(), "Hosts listed")
+
+	w = httptest.NewRecorder()
+
+	req, _ = http.NewRequest("GET", "/crawl/hosts", nil)
+
+	testRouter.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestEvaluateURLFilterEndpoint(t *testing.T) {
+	// Set the router to test mode
+	ifs := setupRouterWithMocks()
+
+	testRouter := ifs["testRouter"].(*gin.Engine)
+	accountRepo := ifs["accountRepo"].(domain.AccountRepository)
+	mockURLFilterHandler := ifs["mockURLFilterHandler"].(*MockURLFilterHandler)
+
+	account, err := accountRepo.GetByEmail("test@example.com")
+
+	if err != nil {
+		t.Fatalf("Error getting account: %v", err)
+	}
+
+	token, err := authutil.GenerateToken(account.ID)
+
+	if err != nil {
+		t.Fatalf("Error generating token: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+
+	req, _ := http.NewRequest("POST", "/crawl/filters/evaluate", bytes.NewBufferString(`{"urls":["http://example.com/"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	testRouter.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "URLs evaluated")
+
+	mockURLFilterHandler.AssertExpectations(t)
+
+	w = httptest.NewRecorder()
+
+	req, _ = http.NewRequest("POST", "/crawl/filters/evaluate", bytes.NewBufferString(`{"urls":["http://example.com/"]}`))
+
+	testRouter.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestCrawlScopeEndpoints(t *testing.T) {
+	// Set the router to test mode
+	ifs := setupRouterWithMocks()
+
+	testRouter := ifs["testRouter"].(*gin.Engine)
+	accountRepo := ifs["accountRepo"].(domain.AccountRepository)
+	mockScopeHandler := ifs["mockScopeHandler"].(*MockScopeHandler)
+
+	account, err := accountRepo.GetByEmail("test@example.com")
+
+	if err != nil {
+		t.Fatalf("Error getting account: %v", err)
+	}
+
+	token, err := authutil.GenerateToken(account.ID)
+
+	if err != nil {
+		t.Fatalf("Error generating token: %v", err)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{method: "POST", path: "/crawl/scopes", code: http.StatusCreated, body: "Scope created"},
+		{method: "GET", path: "/crawl/scopes", code: http.StatusOK, body: "Scopes listed"},
+		{method: "GET", path: "/crawl/scopes/recipes", code: http.StatusOK, body: "Scope found"},
+	}
+
+	for _, tc := range tests {
+		w := httptest.NewRecorder()
+
+		req, _ := http.NewRequest(tc.method, tc.path, bytes.NewBufferString(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		testRouter.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.code, w.Code)
+		assert.Contains(t, w.Body.String(), tc.body)
+
+		w = httptest.NewRecorder()
+
+		req, _ = http.NewRequest(tc.method, tc.path, bytes.NewBufferString(`{}`))
+
+		testRouter.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	mockScopeHandler.AssertExpectations(t)
+}
+
+func TestSeedSitemapsEndpoint(t *testing.T) {
+	// Set the router to test mode
+	ifs := setupRouterWithMocks()
+
+	testRouter := ifs["testRouter"].(*gin.Engine)
+	accountRepo := ifs["accountRepo"].(domain.AccountRepository)
+	mockSitemapHandler := ifs["mockSitemapHandler"].(*MockSitemapHandler)
+
+	account, err := accountRepo.GetByEmail("test@example.com")
+
+	if err != nil {
+		t.Fatalf("Error getting account: %v", err)
+	}
+
+	token, err := authutil.GenerateToken(account.ID)
+
+	if err != nil {
+		t.Fatalf("Error generating token: %v", err)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{method: "POST", path: "/crawl/sitemaps", code: http.StatusAccepted, body: "Sitemaps seeding"},
+		{method: "GET", path: "/crawl/sitemaps/seed1", code: http.StatusOK, body: "Seed found"},
+	}
+
+	for _, tc := range tests {
+		w := httptest.NewRecorder()
+
+		req, _ := http.NewRequest(tc.method, tc.path, bytes.NewBufferString(`{"url":"http://example.com/"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		testRouter.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.code, w.Code)
+		assert.Contains(t, w.Body.String(), tc.body)
+
+		w = httptest.NewRecorder()
+
+		req, _ = http.NewRequest(tc.method, tc.path, bytes.NewBufferString(`{"url":"http://example.com/"}`))
+
+		testRouter.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	mockSitemapHandler.AssertExpectations(t)
+}
@@ -2,6 +2,8 @@ package handler
 
 import (
 	"crawlquery/api/domain"
+	"crawlquery/api/dto"
+	"errors"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,14 +19,44 @@ func NewHandler(ss domain.SearchService) *SearchHandler {
 }
 
 func (sh *SearchHandler) Search(c *gin.Context) {
-	res, err := sh.searchService.Search(c.Query("q"))
+	var req dto.SearchRequest
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	window, err := req.SearchWindow()
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	res, err := sh.searchService.Search(window.Query, window.Offset, window.Limit)
+	if errors.Is(err, domain.ErrInvalidSearchQuery) {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(500, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
+	nextCursor := ""
+	if res.Next != nil {
+		nextCursor = dto.EncodeSearchCursor(res.Next)
+	}
+
 	c.JSON(200, gin.H{
-		"results": res,
+		"results":     res.Results,
+		"total_hits":  res.TotalHits,
+		"next_cursor": nextCursor,
 	})
 }
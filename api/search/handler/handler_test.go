@@ -131,4 +131,114 @@ func TestSearch(t *testing.T) {
 			t.Errorf("Expected 1 result, got %d", len(res.Results))
 		}
 	})
+	t.Run("returns 400 for an invalid query", func(t *testing.T) {
+		_, _, _, _, searchService := setupServices()
+
+		handler := handler.NewHandler(searchService)
+
+		responseWriter := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(responseWriter)
+		ctx.Request = httptest.NewRequest("GET", "/search?q=term+OR", nil)
+
+		handler.Search(ctx)
+
+		if ctx.Writer.Status() != http.StatusBadRequest {
+			t.Errorf("Expected status to be 400, got %d", ctx.Writer.Status())
+		}
+	})
+	t.Run("pages through results with the cursor", func(t *testing.T) {
+		nodeRepo, _, _, _, searchService := setupServices()
+
+		nodeRepo.Create(&domain.Node{
+			ID:        "node1",
+			ShardID:   0,
+			Hostname:  "node1.cluster.com",
+			Port:      8080,
+			CreatedAt: time.Now(),
+		})
+
+		defer gock.Off()
+
+		page := func(ids ...string) []nodeDomain.Result {
+			var results []nodeDomain.Result
+			for i, id := range ids {
+				results = append(results, nodeDomain.Result{PageID: id, Score: float64(10 - i), Page: nodeDomain.ResultPage{ID: id}})
+			}
+			return results
+		}
+
+		gock.New("http://node1.cluster.com:8080").
+			Get("/search").
+			MatchParam("q", "term").
+			MatchParam("limit", "1").
+			Reply(200).
+			JSON(nodeDto.NodeSearchResponse{Results: page("page1"), TotalHits: 2})
+
+		gock.New("http://node1.cluster.com:8080").
+			Get("/search").
+			MatchParam("q", "term").
+			MatchParam("limit", "2").
+			Reply(200).
+			JSON(nodeDto.NodeSearchResponse{Results: page("page1", "page2"), TotalHits: 2})
+
+		handler := handler.NewHandler(searchService)
+
+		search := func(target string) dto.SearchResponse {
+			responseWriter := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(responseWriter)
+			ctx.Request = httptest.NewRequest("GET", target, nil)
+
+			handler.Search(ctx)
+
+			if ctx.Writer.Status() != http.StatusOK {
+				t.Fatalf("Expected status to be 200, got %d", ctx.Writer.Status())
+			}
+
+			var res dto.SearchResponse
+			if err := json.NewDecoder(responseWriter.Body).Decode(&res); err != nil {
+				t.Fatalf("Error decoding response: %v", err)
+			}
+			return res
+		}
+
+		first := search("/search?q=term&limit=1")
+
+		if len(first.Results) != 1 || first.Results[0].PageID != "page1" {
+			t.Errorf("Expected page1, got %v", first.Results)
+		}
+
+		if first.TotalHits != 2 {
+			t.Errorf("Expected 2 total hits, got %d", first.TotalHits)
+		}
+
+		if first.NextCursor == "" {
+			t.Fatalf("Expected a next cursor")
+		}
+
+		second := search("/search?cursor=" + first.NextCursor)
+
+		if len(second.Results) != 1 || second.Results[0].PageID != "page2" {
+			t.Errorf("Expected page2, got %v", second.Results)
+		}
+
+		if second.NextCursor != "" {
+			t.Errorf("Expected no next cursor, got %s", second.NextCursor)
+		}
+	})
+
+	t.Run("returns 400 for an invalid cursor", func(t *testing.T) {
+		_, _, _, _, searchService := setupServices()
+
+		handler := handler.NewHandler(searchService)
+
+		responseWriter := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(responseWriter)
+		ctx.Request = httptest.NewRequest("GET", "/search?cursor=nope!", nil)
+
+		handler.Search(ctx)
+
+		if ctx.Writer.Status() != http.StatusBadRequest {
+			t.Errorf("Expected status to be 400, got %d", ctx.Writer.Status())
+		}
+	})
 }
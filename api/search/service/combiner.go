@@ -0,0 +1,140 @@
+package service
+
+import (
+	"crawlquery/api/domain"
+	"sort"
+
+	nodeDomain "crawlquery/node/domain"
+	"crawlquery/pkg/dto"
+)
+
+const (
+	// ComponentScore is the score a node gave a result.
+	ComponentScore = "score"
+	// ComponentPageRank is the PageRank of the result's page.
+	ComponentPageRank = "pagerank"
+)
+
+// Weights sets how much the built in components count towards the blended
+// score of a result.
+type Weights struct {
+	Score    float64
+	PageRank float64
+}
+
+var DefaultWeights = Weights{
+	Score:    1,
+	PageRank: 0.5,
+}
+
+type weightedSignal struct {
+	signal domain.SearchSignal
+	weight float64
+}
+
+// normalize divides value by max, so the best possible value is 1. It returns 0 when there is nothing to compare against.
+func normalize(value, max float64) float64 {
+	if max <= 0 || value <= 0 {
+		return 0
+	}
+	return value / max
+}
+
+func component(value, max, weight float64) nodeDomain.ScoreComponent {
+	normalized := normalize(value, max)
+	return nodeDomain.ScoreComponent{
+		Value:        value,
+		Normalized:   normalized,
+		Weight:       weight,
+		Contribution: normalized * weight,
+	}
+}
+
+// blend replaces the score of every result with a weighted sum of its
+// normalised components and re-sorts each shard's results by it.
+//
+// Nodes score independently of each other, so node scores are normalised
+// against the best result of the same shard. PageRank and the global signals
+// are normalised against the highest value any page has, not just the
+// candidates, so a result scores the same whichever window it is fetched in.
+func (s *Service) blend(responses []dto.NodeSearchResponse) error {
+	var pageIDs []string
+	seen := map[string]bool{}
+
+	for _, res := range responses {
+		for _, result := range res.Results {
+			if !seen[result.PageID] {
+				seen[result.PageID] = true
+				pageIDs = append(pageIDs, result.PageID)
+			}
+		}
+	}
+
+	ids := make([]domain.PageID, len(pageIDs))
+	for i, pageID := range pageIDs {
+		ids[i] = domain.PageID(pageID)
+	}
+
+	ranks, err := s.pageRankService.GetPageRanks(ids)
+	if err != nil {
+		s.logger.Errorf("Error getting pageranks: %v", err)
+	}
+
+	maxPageRank, err := s.pageRankService.MaxPageRank()
+	if err != nil {
+		s.logger.Errorf("Error getting highest pagerank: %v", err)
+	}
+
+	pageRanks := map[string]float64{}
+	for pageID, rank := range ranks {
+		pageRanks[string(pageID)] = rank
+	}
+
+	signalValues := make([]map[string]float64, len(s.signals))
+	signalMax := make([]float64, len(s.signals))
+
+	for i, ws := range s.signals {
+		values, err := ws.signal.Values(pageIDs)
+		if err != nil {
+			return err
+		}
+		signalValues[i] = values
+
+		signalMax[i], err = ws.signal.Max()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, res := range responses {
+		maxScore := 0.0
+		for _, result := range res.Results {
+			maxScore = max(maxScore, result.Score)
+		}
+
+		for i := range res.Results {
+			result := &res.Results[i]
+
+			result.PageRank = pageRanks[result.PageID]
+			result.Components = map[string]nodeDomain.ScoreComponent{
+				ComponentScore:    component(result.Score, maxScore, s.weights.Score),
+				ComponentPageRank: component(result.PageRank, maxPageRank, s.weights.PageRank),
+			}
+
+			for j, ws := range s.signals {
+				result.Components[ws.signal.Name()] = component(signalValues[j][result.PageID], signalMax[j], ws.weight)
+			}
+
+			result.Score = 0
+			for _, c := range result.Components {
+				result.Score += c.Contribution
+			}
+		}
+
+		sort.Slice(res.Results, func(i, j int) bool {
+			return ranksBefore(res.Results[i], res.Results[j])
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"container/heap"
+	nodeDomain "crawlquery/node/domain"
+	"crawlquery/pkg/dto"
+)
+
+// ranksBefore orders results the way the nodes do, by score and then by
+// page ID, so the merged order is stable across pages.
+func ranksBefore(a, b nodeDomain.Result) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	return a.PageID < b.PageID
+}
+
+// shardCursor points at the next unmerged result of a shard.
+type shardCursor struct {
+	results []nodeDomain.Result
+	pos     int
+}
+
+func (c *shardCursor) head() nodeDomain.Result {
+	return c.results[c.pos]
+}
+
+// cursorHeap keeps the shard with the highest ranked unmerged result on top.
+type cursorHeap []*shardCursor
+
+func (h cursorHeap) Len() int           { return len(h) }
+func (h cursorHeap) Less(i, j int) bool { return ranksBefore(h[i].head(), h[j].head()) }
+func (h cursorHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *cursorHeap) Push(x any) {
+	*h = append(*h, x.(*shardCursor))
+}
+
+func (h *cursorHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// merge k-way merges the ranked results of every shard and returns the
+// first depth unique pages.
+func merge(responses []dto.NodeSearchResponse, depth int) []nodeDomain.Result {
+	h := cursorHeap{}
+
+	for _, res := range responses {
+		if len(res.Results) > 0 {
+			h = append(h, &shardCursor{results: res.Results})
+		}
+	}
+
+	heap.Init(&h)
+
+	merged := []nodeDomain.Result{}
+	seen := map[string]bool{}
+
+	for len(h) > 0 && len(merged) < depth {
+		cursor := h[0]
+		result := cursor.head()
+
+		if !seen[result.PageID] {
+			seen[result.PageID] = true
+			merged = append(merged, result)
+		}
+
+		cursor.pos++
+		if cursor.pos == len(cursor.results) {
+			heap.Pop(&h)
+		} else {
+			heap.Fix(&h, 0)
+		}
+	}
+
+	return merged
+}
+
+// estimateTotalHits adds up the hits reported by the shards that answered
+// and scales the sum up for the shards that didn't.
+func estimateTotalHits(responses []dto.NodeSearchResponse, shards int) int {
+	if len(responses) == 0 {
+		return 0
+	}
+
+	total := 0
+	for _, res := range responses {
+		total += res.TotalHits
+	}
+
+	return total * shards / len(responses)
+}
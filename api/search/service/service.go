@@ -7,12 +7,12 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
-	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	nodeDomain "crawlquery/node/domain"
+	"crawlquery/node/search/parser"
 
 	"crawlquery/pkg/dto"
 
@@ -23,24 +23,72 @@ type Service struct {
 	nodeService     domain.NodeService
 	pageRankService domain.PageRankService
 	logger          *zap.SugaredLogger
+	weights         Weights
+	signals         []weightedSignal
 }
 
-func NewService(nodeService domain.NodeService, pageRankService domain.PageRankService, logger *zap.SugaredLogger) *Service {
-	return &Service{
+type Option func(*Service)
+
+// WithWeights sets the weights of the node score and PageRank in the
+// blended score.
+func WithWeights(weights Weights) Option {
+	return func(s *Service) {
+		s.weights = weights
+	}
+}
+
+// WithSignal mixes a global signal into the blended score.
+func WithSignal(signal domain.SearchSignal, weight float64) Option {
+	return func(s *Service) {
+		s.signals = append(s.signals, weightedSignal{signal: signal, weight: weight})
+	}
+}
+
+func NewService(nodeService domain.NodeService, pageRankService domain.PageRankService, logger *zap.SugaredLogger, opts ...Option) *Service {
+	s := &Service{
 		nodeService:     nodeService,
 		pageRankService: pageRankService,
 		logger:          logger,
+		weights:         DefaultWeights,
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
 // Search searches for the term and waits for the fastest node in each shard.
-func (s *Service) Search(term string) ([]nodeDomain.Result, error) {
+// The term is checked against the node query syntax up front and otherwise
+// forwarded as is, so the nodes evaluate its operators.
+//
+// Any shard may hold every result up to offset+limit, so each node is asked
+// for its top offset+limit. Those candidates are rescored with the blended
+// score, see Service.blend, and the ranked lists are merged before the
+// window is cut. A page whose PageRank would lift it into the window from
+// below a shard's top offset+limit is not seen.
+func (s *Service) Search(term string, offset, limit int) (*domain.SearchResults, error) {
 
 	// trim space either side of the term
 	term = strings.TrimSpace(term)
 	// remove duplicate spaces
 	term = strings.Join(strings.Fields(term), " ")
 
+	if _, err := parser.Parse(term); err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidSearchQuery, err)
+	}
+
+	if offset < 0 || limit < 1 || limit > domain.MaxSearchLimit {
+		return nil, fmt.Errorf("%w: offset must be at least 0 and limit between 1 and %d", domain.ErrInvalidSearchQuery, domain.MaxSearchLimit)
+	}
+
+	depth := offset + limit
+
+	if depth > nodeDomain.MaxSearchDepth {
+		return nil, fmt.Errorf("%w: results beyond %d are not available", domain.ErrInvalidSearchQuery, nodeDomain.MaxSearchDepth)
+	}
+
 	shardNodes, err := s.nodeService.RandomizedListGroupByShard()
 	if err != nil {
 		return nil, err
@@ -51,8 +99,8 @@ func (s *Service) Search(term string) ([]nodeDomain.Result, error) {
 		return nil, domain.ErrInternalError
 	}
 
-	var results []nodeDomain.Result
-	var resultsLock sync.Mutex
+	var responses []dto.NodeSearchResponse
+	var responsesLock sync.Mutex
 	var wg sync.WaitGroup
 
 	wg.Add(len(shardNodes))
@@ -65,14 +113,14 @@ func (s *Service) Search(term string) ([]nodeDomain.Result, error) {
 				nodes = nodes[:10]
 			}
 
-			// Initialize results channel with buffer size of the number of nodes
-			resultsChan := make(chan []nodeDomain.Result, len(nodes))
+			// Initialize responses channel with buffer size of the number of nodes
+			responsesChan := make(chan dto.NodeSearchResponse, len(nodes))
 			ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 			defer cancel()
 
 			for _, node := range nodes {
 				go func(node *domain.Node) {
-					endpoint := fmt.Sprintf("http://%s:%d/search?q=%s", node.Hostname, node.Port, url.QueryEscape(term))
+					endpoint := fmt.Sprintf("http://%s:%d/search?q=%s&limit=%d", node.Hostname, node.Port, url.QueryEscape(term), depth)
 					res, err := http.Get(endpoint) // Simplified for clarity, consider handling with context
 					if err != nil {
 						s.logger.Errorf("Error searching node %s: %v", node.ID, err)
@@ -80,21 +128,26 @@ func (s *Service) Search(term string) ([]nodeDomain.Result, error) {
 					}
 					defer res.Body.Close()
 
+					if res.StatusCode != http.StatusOK {
+						s.logger.Errorf("Error searching node %s: status %d", node.ID, res.StatusCode)
+						return
+					}
+
 					var response dto.NodeSearchResponse
 					if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
 						s.logger.Errorf("Error decoding response from node %s: %v", node.ID, err)
 						return
 					}
-					resultsChan <- response.Results
+					responsesChan <- response
 				}(node)
 			}
 
-			// Wait for the first result
+			// Wait for the first response
 			select {
-			case res := <-resultsChan:
-				resultsLock.Lock()
-				results = append(results, res...)
-				resultsLock.Unlock()
+			case res := <-responsesChan:
+				responsesLock.Lock()
+				responses = append(responses, res)
+				responsesLock.Unlock()
 			case <-ctx.Done():
 				s.logger.Errorf("Search timed out for shard %d", nodes[0].ShardID)
 			}
@@ -103,34 +156,38 @@ func (s *Service) Search(term string) ([]nodeDomain.Result, error) {
 
 	wg.Wait()
 
-	// filter out duplicate results
-	uniqueResults := make(map[string]nodeDomain.Result)
+	if err := s.blend(responses); err != nil {
+		return nil, err
+	}
 
-	for _, res := range results {
-		if _, ok := uniqueResults[res.PageID]; !ok {
-			rank, err := s.pageRankService.GetPageRank(domain.PageID(res.PageID))
+	merged := merge(responses, depth)
 
-			if err != nil {
-				s.logger.Errorf("No pagerank found for %s: %v", res.PageID, err)
-			}
-			res.PageRank = rank
-			uniqueResults[res.PageID] = res
-		}
+	if offset > len(merged) {
+		offset = len(merged)
 	}
 
-	results = make([]nodeDomain.Result, 0, len(uniqueResults))
+	results := merged[offset:]
 
-	for _, res := range uniqueResults {
-		results = append(results, res)
+	totalHits := estimateTotalHits(responses, len(shardNodes))
+
+	// shards can overlap while pages are being moved between them, so the
+	// estimate can't be lower than what was actually merged
+	if totalHits < len(merged) {
+		totalHits = len(merged)
 	}
 
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Score > results[j].Score
-	})
+	searchResults := &domain.SearchResults{
+		Results:   results,
+		TotalHits: totalHits,
+	}
 
-	if len(results) > 10 {
-		results = results[:10]
+	if next := depth; next < totalHits && next < nodeDomain.MaxSearchDepth {
+		searchResults.Next = &domain.SearchRequest{
+			Query:  term,
+			Offset: next,
+			Limit:  min(limit, nodeDomain.MaxSearchDepth-next),
+		}
 	}
 
-	return results, nil
+	return searchResults, nil
 }
@@ -14,6 +14,9 @@ import (
 
 	"crawlquery/pkg/dto"
 	"crawlquery/pkg/testutil"
+	"errors"
+	"reflect"
+	"regexp"
 	"testing"
 	"time"
 
@@ -39,6 +42,14 @@ func setupServices() (*nodeRepo.Repository, *nodeService.Service, *linkService.S
 	return nodeRepo, nodeService, linkService, linkRepo, pageRankRepo, pageRankService, searchService
 }
 
+func search(svc *searchService.Service, term string) ([]nodeDomain.Result, error) {
+	res, err := svc.Search(term, 0, nodeDomain.DefaultSearchLimit)
+	if err != nil {
+		return nil, err
+	}
+	return res.Results, nil
+}
+
 func TestSearch(t *testing.T) {
 	t.Run("returns results", func(t *testing.T) {
 		nodeRepo, _, _, _, _, _, searchService := setupServices()
@@ -97,7 +108,7 @@ func TestSearch(t *testing.T) {
 				},
 			})
 
-		results, err := searchService.Search("term")
+		results, err := search(searchService, "term")
 		if err != nil {
 			t.Fatalf("Error searching: %v", err)
 		}
@@ -168,7 +179,49 @@ func TestSearch(t *testing.T) {
 				},
 			})
 
-		results, err := searchService.Search("   term      hello   ")
+		results, err := search(searchService, "   term      hello   ")
+		if err != nil {
+			t.Fatalf("Error searching: %v", err)
+		}
+
+		if len(results) != 1 {
+			t.Errorf("Expected 1 result, got %v", len(results))
+		}
+	})
+	t.Run("forwards operators unchanged", func(t *testing.T) {
+		nodeRepo, _, _, _, _, _, searchService := setupServices()
+
+		nodeRepo.Create(&domain.Node{
+			ID:        "node1",
+			ShardID:   0,
+			Hostname:  "node1.cluster.com",
+			Port:      8080,
+			CreatedAt: time.Now(),
+		})
+
+		defer gock.Off()
+
+		query := `"bolognese sauce" -garlic (pasta OR pizza) site:example.com lang:it intitle:recipe inurl:dinner`
+
+		gock.New("http://node1.cluster.com:8080").
+			Get("/search").
+			MatchParam("q", regexp.QuoteMeta(query)).
+			Reply(200).
+			JSON(dto.NodeSearchResponse{
+				Results: []nodeDomain.Result{
+					{
+						PageID: "page1",
+						Score:  0.5,
+						Page: nodeDomain.ResultPage{
+							ID:    "page1",
+							URL:   "http://example.com",
+							Title: "Example",
+						},
+					},
+				},
+			})
+
+		results, err := search(searchService, query)
 		if err != nil {
 			t.Fatalf("Error searching: %v", err)
 		}
@@ -176,7 +229,31 @@ func TestSearch(t *testing.T) {
 		if len(results) != 1 {
 			t.Errorf("Expected 1 result, got %v", len(results))
 		}
+
+		if !gock.IsDone() {
+			t.Errorf("Expected the query to be forwarded unchanged")
+		}
 	})
+
+	t.Run("rejects invalid queries without contacting nodes", func(t *testing.T) {
+		nodeRepo, _, _, _, _, _, searchService := setupServices()
+
+		nodeRepo.Create(&domain.Node{
+			ID:        "node1",
+			ShardID:   0,
+			Hostname:  "node1.cluster.com",
+			Port:      8080,
+			CreatedAt: time.Now(),
+		})
+
+		for _, query := range []string{"", "term OR", "(term", "site:"} {
+			_, err := search(searchService, query)
+			if !errors.Is(err, domain.ErrInvalidSearchQuery) {
+				t.Errorf("Expected ErrInvalidSearchQuery for %q, got %v", query, err)
+			}
+		}
+	})
+
 	t.Run("removes duplicate results", func(t *testing.T) {
 		nodeRepo, _, _, _, _, _, searchService := setupServices()
 
@@ -234,7 +311,7 @@ func TestSearch(t *testing.T) {
 				},
 			})
 
-		results, err := searchService.Search("term")
+		results, err := search(searchService, "term")
 		if err != nil {
 			t.Fatalf("Error searching: %v", err)
 		}
@@ -301,7 +378,7 @@ func TestSearch(t *testing.T) {
 
 		pageRankRepo.Update("page1", 0.5, time.Now())
 
-		results, err := searchService.Search("term")
+		results, err := search(searchService, "term")
 		if err != nil {
 			t.Fatalf("Error searching: %v", err)
 		}
@@ -323,3 +400,382 @@ func TestSearch(t *testing.T) {
 		}
 	})
 }
+
+func TestSearchPagination(t *testing.T) {
+	createNodes := func(nodeRepo *nodeRepo.Repository) {
+		nodeRepo.Create(&domain.Node{
+			ID:        "node1",
+			ShardID:   0,
+			Hostname:  "node1.cluster.com",
+			Port:      8080,
+			CreatedAt: time.Now(),
+		})
+
+		nodeRepo.Create(&domain.Node{
+			ID:        "node2",
+			ShardID:   1,
+			Hostname:  "node2.cluster.com",
+			Port:      8080,
+			CreatedAt: time.Now(),
+		})
+	}
+
+	results := func(scores map[string]float64, ids ...string) []nodeDomain.Result {
+		var results []nodeDomain.Result
+		for _, id := range ids {
+			results = append(results, nodeDomain.Result{PageID: id, Score: scores[id], Page: nodeDomain.ResultPage{ID: id}})
+		}
+		return results
+	}
+
+	scores := map[string]float64{"a": 0.9, "b": 0.8, "c": 0.7, "d": 0.6, "e": 0.5, "f": 0.4, "g": 0.3}
+
+	t.Run("merges the shards and cuts the window", func(t *testing.T) {
+		nodeRepo, _, _, _, _, _, searchService := setupServices()
+		createNodes(nodeRepo)
+
+		defer gock.Off()
+
+		// every shard is asked for the top offset+limit
+		gock.New("http://node1.cluster.com:8080").
+			Get("/search").
+			MatchParam("q", "term").
+			MatchParam("limit", "4").
+			Reply(200).
+			JSON(dto.NodeSearchResponse{
+				Results:   results(scores, "a", "c", "e", "g"),
+				TotalHits: 10,
+			})
+
+		gock.New("http://node2.cluster.com:8080").
+			Get("/search").
+			MatchParam("q", "term").
+			MatchParam("limit", "4").
+			Reply(200).
+			JSON(dto.NodeSearchResponse{
+				Results:   results(scores, "b", "d", "f"),
+				TotalHits: 3,
+			})
+
+		res, err := searchService.Search("term", 2, 2)
+		if err != nil {
+			t.Fatalf("Error searching: %v", err)
+		}
+
+		if len(res.Results) != 2 || res.Results[0].PageID != "c" || res.Results[1].PageID != "d" {
+			t.Errorf("Expected c and d, got %v", res.Results)
+		}
+
+		if res.TotalHits != 13 {
+			t.Errorf("Expected 13 total hits, got %d", res.TotalHits)
+		}
+
+		expectedNext := &domain.SearchRequest{Query: "term", Offset: 4, Limit: 2}
+		if res.Next == nil || *res.Next != *expectedNext {
+			t.Errorf("Expected next %v, got %v", expectedNext, res.Next)
+		}
+	})
+
+	t.Run("has no next window on the last page", func(t *testing.T) {
+		nodeRepo, _, _, _, _, _, searchService := setupServices()
+		createNodes(nodeRepo)
+
+		defer gock.Off()
+
+		gock.New("http://node1.cluster.com:8080").
+			Get("/search").
+			MatchParam("q", "term").
+			Reply(200).
+			JSON(dto.NodeSearchResponse{
+				Results:   results(scores, "a", "c"),
+				TotalHits: 2,
+			})
+
+		gock.New("http://node2.cluster.com:8080").
+			Get("/search").
+			MatchParam("q", "term").
+			Reply(200).
+			JSON(dto.NodeSearchResponse{
+				Results:   results(scores, "b"),
+				TotalHits: 1,
+			})
+
+		res, err := searchService.Search("term", 2, 2)
+		if err != nil {
+			t.Fatalf("Error searching: %v", err)
+		}
+
+		if len(res.Results) != 1 || res.Results[0].PageID != "c" {
+			t.Errorf("Expected c only, got %v", res.Results)
+		}
+
+		if res.Next != nil {
+			t.Errorf("Expected no next window, got %v", res.Next)
+		}
+	})
+
+	t.Run("estimates hits for shards that did not answer", func(t *testing.T) {
+		nodeRepo, _, _, _, _, _, searchService := setupServices()
+		createNodes(nodeRepo)
+
+		defer gock.Off()
+
+		gock.New("http://node1.cluster.com:8080").
+			Get("/search").
+			MatchParam("q", "term").
+			Reply(200).
+			JSON(dto.NodeSearchResponse{
+				Results:   results(scores, "a"),
+				TotalHits: 10,
+			})
+
+		gock.New("http://node2.cluster.com:8080").
+			Get("/search").
+			MatchParam("q", "term").
+			Reply(500)
+
+		res, err := searchService.Search("term", 0, 10)
+		if err != nil {
+			t.Fatalf("Error searching: %v", err)
+		}
+
+		if res.TotalHits != 20 {
+			t.Errorf("Expected 20 total hits, got %d", res.TotalHits)
+		}
+	})
+
+	t.Run("rejects invalid windows", func(t *testing.T) {
+		nodeRepo, _, _, _, _, _, searchService := setupServices()
+		createNodes(nodeRepo)
+
+		windows := [][2]int{{-1, 10}, {0, 0}, {0, domain.MaxSearchLimit + 1}, {nodeDomain.MaxSearchDepth, 1}}
+
+		for _, window := range windows {
+			_, err := searchService.Search("term", window[0], window[1])
+			if !errors.Is(err, domain.ErrInvalidSearchQuery) {
+				t.Errorf("Expected ErrInvalidSearchQuery for offset %d limit %d, got %v", window[0], window[1], err)
+			}
+		}
+	})
+}
+
+type fixedSignal struct {
+	values map[string]float64
+}
+
+func (fixedSignal) Name() string {
+	return "fixed"
+}
+
+func (f fixedSignal) Values(pageIDs []string) (map[string]float64, error) {
+	return f.values, nil
+}
+
+func (f fixedSignal) Max() (float64, error) {
+	highest := 0.0
+	for _, value := range f.values {
+		highest = max(highest, value)
+	}
+	return highest, nil
+}
+
+func TestSearchBlend(t *testing.T) {
+	setup := func(opts ...searchService.Option) (*nodeRepo.Repository, *pageRankRepo.Repository, *searchService.Service) {
+		nodeRepo := nodeRepo.NewRepository()
+		nodeService := nodeService.NewService(
+			nodeService.WithNodeRepo(nodeRepo),
+			nodeService.WithLogger(testutil.NewTestLogger()),
+			nodeService.WithRandSeed(time.Now().Unix()),
+		)
+		linkService := linkService.NewService(
+			linkService.WithLinkRepo(linkRepo.NewRepository()),
+			linkService.WithLogger(testutil.NewTestLogger()),
+		)
+		pageRankRepo := pageRankRepo.NewRepository()
+		pageRankService := pageRankService.NewService(linkService, pageRankRepo, testutil.NewTestLogger())
+
+		nodeRepo.Create(&domain.Node{
+			ID:        "node1",
+			ShardID:   0,
+			Hostname:  "node1.cluster.com",
+			Port:      8080,
+			CreatedAt: time.Now(),
+		})
+
+		nodeRepo.Create(&domain.Node{
+			ID:        "node2",
+			ShardID:   1,
+			Hostname:  "node2.cluster.com",
+			Port:      8080,
+			CreatedAt: time.Now(),
+		})
+
+		return nodeRepo, pageRankRepo, searchService.NewService(nodeService, pageRankService, testutil.NewTestLogger(), opts...)
+	}
+
+	mockShards := func() {
+		// shard 0 scores on a much larger scale than shard 1
+		gock.New("http://node1.cluster.com:8080").
+			Get("/search").
+			MatchParam("q", "term").
+			Reply(200).
+			JSON(dto.NodeSearchResponse{
+				Results: []nodeDomain.Result{
+					{PageID: "page1", Score: 100},
+					{PageID: "page2", Score: 50},
+				},
+				TotalHits: 2,
+			})
+
+		gock.New("http://node2.cluster.com:8080").
+			Get("/search").
+			MatchParam("q", "term").
+			Reply(200).
+			JSON(dto.NodeSearchResponse{
+				Results: []nodeDomain.Result{
+					{PageID: "page3", Score: 2},
+					{PageID: "page4", Score: 1.5},
+				},
+				TotalHits: 2,
+			})
+	}
+
+	ids := func(results []nodeDomain.Result) []string {
+		var ids []string
+		for _, result := range results {
+			ids = append(ids, result.PageID)
+		}
+		return ids
+	}
+
+	t.Run("normalises scores per shard", func(t *testing.T) {
+		_, _, svc := setup()
+
+		defer gock.Off()
+		mockShards()
+
+		res, err := svc.Search("term", 0, 10)
+		if err != nil {
+			t.Fatalf("Error searching: %v", err)
+		}
+
+		// page3 and page1 both lead their shard, page4 is 0.75 of its
+		// shard's best and page2 only 0.5
+		expected := []string{"page1", "page3", "page4", "page2"}
+		if !reflect.DeepEqual(ids(res.Results), expected) {
+			t.Errorf("Expected %v, got %v", expected, ids(res.Results))
+		}
+	})
+
+	t.Run("page rank changes the order", func(t *testing.T) {
+		_, pageRankRepo, svc := setup()
+
+		defer gock.Off()
+		mockShards()
+
+		pageRankRepo.Update("page2", 0.2, time.Now())
+		pageRankRepo.Update("page3", 0.1, time.Now())
+
+		res, err := svc.Search("term", 0, 10)
+		if err != nil {
+			t.Fatalf("Error searching: %v", err)
+		}
+
+		// page3: 1 + 0.5*0.5, page2: 0.5 + 1*0.5, page1: 1
+		expected := []string{"page3", "page1", "page2", "page4"}
+		if !reflect.DeepEqual(ids(res.Results), expected) {
+			t.Errorf("Expected %v, got %v", expected, ids(res.Results))
+		}
+
+		for _, result := range res.Results {
+			if result.PageID != "page3" {
+				continue
+			}
+
+			if result.Score != 1.25 {
+				t.Errorf("Expected page3 score 1.25, got %f", result.Score)
+			}
+
+			if result.PageRank != 0.1 {
+				t.Errorf("Expected page3 page rank 0.1, got %f", result.PageRank)
+			}
+
+			expectedComponents := map[string]nodeDomain.ScoreComponent{
+				searchService.ComponentScore:    {Value: 2, Normalized: 1, Weight: 1, Contribution: 1},
+				searchService.ComponentPageRank: {Value: 0.1, Normalized: 0.5, Weight: 0.5, Contribution: 0.25},
+			}
+
+			if !reflect.DeepEqual(result.Components, expectedComponents) {
+				t.Errorf("Expected components %v, got %v", expectedComponents, result.Components)
+			}
+		}
+	})
+
+	t.Run("normalises page rank against every page", func(t *testing.T) {
+		_, pageRankRepo, svc := setup()
+
+		defer gock.Off()
+		mockShards()
+
+		pageRankRepo.Update("page3", 0.1, time.Now())
+		pageRankRepo.Update("page9", 0.2, time.Now())
+
+		res, err := svc.Search("term", 0, 10)
+		if err != nil {
+			t.Fatalf("Error searching: %v", err)
+		}
+
+		for _, result := range res.Results {
+			if result.PageID != "page3" {
+				continue
+			}
+
+			// page9 isn't a candidate but still sets the scale
+			if c := result.Components[searchService.ComponentPageRank]; c.Normalized != 0.5 {
+				t.Errorf("Expected page3 page rank normalised to 0.5, got %f", c.Normalized)
+			}
+		}
+	})
+
+	t.Run("weights are configurable", func(t *testing.T) {
+		_, pageRankRepo, svc := setup(searchService.WithWeights(searchService.Weights{Score: 0, PageRank: 1}))
+
+		defer gock.Off()
+		mockShards()
+
+		pageRankRepo.Update("page4", 0.3, time.Now())
+		pageRankRepo.Update("page2", 0.2, time.Now())
+
+		res, err := svc.Search("term", 0, 2)
+		if err != nil {
+			t.Fatalf("Error searching: %v", err)
+		}
+
+		expected := []string{"page4", "page2"}
+		if !reflect.DeepEqual(ids(res.Results), expected) {
+			t.Errorf("Expected %v, got %v", expected, ids(res.Results))
+		}
+	})
+
+	t.Run("mixes in global signals", func(t *testing.T) {
+		_, _, svc := setup(searchService.WithSignal(fixedSignal{values: map[string]float64{"page4": 4, "page2": 2}}, 2))
+
+		defer gock.Off()
+		mockShards()
+
+		res, err := svc.Search("term", 0, 10)
+		if err != nil {
+			t.Fatalf("Error searching: %v", err)
+		}
+
+		// page4: 0.75 + 2, page2: 0.5 + 1
+		expected := []string{"page4", "page2", "page1", "page3"}
+		if !reflect.DeepEqual(ids(res.Results), expected) {
+			t.Errorf("Expected %v, got %v", expected, ids(res.Results))
+		}
+
+		if c := res.Results[0].Components["fixed"]; c.Value != 4 || c.Contribution != 2 {
+			t.Errorf("Expected the fixed component to be reported, got %v", c)
+		}
+	})
+}
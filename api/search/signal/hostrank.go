@@ -0,0 +1,70 @@
+package signal
+
+import (
+	"crawlquery/api/domain"
+	"crawlquery/pkg/util"
+)
+
+// HostRank scores each page by the rank of the site it is on, so results
+// from authoritative sites are lifted over pages with similar PageRank on
+// unknown ones.
+type HostRank struct {
+	pageService     domain.PageService
+	pageRankService domain.PageRankService
+}
+
+func NewHostRank(pageService domain.PageService, pageRankService domain.PageRankService) *HostRank {
+	return &HostRank{
+		pageService:     pageService,
+		pageRankService: pageRankService,
+	}
+}
+
+func (h *HostRank) Name() string {
+	return "hostrank"
+}
+
+// Values leaves out pages that are not known to the API or whose site has
+// no rank yet, and every page when either lookup fails. Pages and ranks are
+// each looked up once for all of pageIDs.
+func (h *HostRank) Values(pageIDs []string) (map[string]float64, error) {
+	values := map[string]float64{}
+
+	ids := make([]domain.PageID, len(pageIDs))
+	for i, pageID := range pageIDs {
+		ids[i] = domain.PageID(pageID)
+	}
+
+	pages, err := h.pageService.GetMany(ids)
+	if err != nil {
+		return values, nil
+	}
+
+	var sites []domain.Host
+	seen := map[domain.Host]bool{}
+	for _, page := range pages {
+		site := util.Site(page.URL)
+		if !seen[site] {
+			seen[site] = true
+			sites = append(sites, site)
+		}
+	}
+
+	ranks, err := h.pageRankService.GetHostRanks(sites)
+	if err != nil {
+		return values, nil
+	}
+
+	for pageID, page := range pages {
+		if rank := ranks[util.Site(page.URL)]; rank > 0 {
+			values[string(pageID)] = rank
+		}
+	}
+
+	return values, nil
+}
+
+// Max is the highest rank of any site.
+func (h *HostRank) Max() (float64, error) {
+	return h.pageRankService.MaxHostRank()
+}
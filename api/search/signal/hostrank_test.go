@@ -0,0 +1,88 @@
+package signal_test
+
+import (
+	"crawlquery/api/domain"
+	"crawlquery/api/search/signal"
+	"crawlquery/pkg/testutil"
+	"crawlquery/pkg/util"
+	"testing"
+	"time"
+
+	hostLinkRepo "crawlquery/api/link/host/repository/mem"
+	linkRepo "crawlquery/api/link/repository/mem"
+	linkService "crawlquery/api/link/service"
+	pageRepo "crawlquery/api/page/repository/mem"
+	pageService "crawlquery/api/page/service"
+	hostRankRepo "crawlquery/api/pagerank/host/repository/mem"
+	pageRankRepo "crawlquery/api/pagerank/repository/mem"
+	pageRankService "crawlquery/api/pagerank/service"
+)
+
+func TestHostRank(t *testing.T) {
+	pageRepo := pageRepo.NewRepository()
+	pageService := pageService.NewService(
+		pageService.WithPageRepo(pageRepo),
+		pageService.WithLogger(testutil.NewTestLogger()),
+	)
+
+	hostLinkRepo := hostLinkRepo.NewRepository()
+	linkService := linkService.NewService(
+		linkService.WithLinkRepo(linkRepo.NewRepository()),
+		linkService.WithHostLinkRepo(hostLinkRepo),
+	)
+
+	pageRankService := pageRankService.NewService(
+		linkService,
+		pageRankRepo.NewRepository(),
+		testutil.NewTestLogger(),
+		pageRankService.WithHostRank(hostRankRepo.NewRepository()),
+	)
+
+	hostLinkRepo.Increment("blog.com", "authority.com", time.Now())
+	hostLinkRepo.Increment("news.com", "authority.com", time.Now())
+
+	if err := pageRankService.UpdatePageRanks(); err != nil {
+		t.Fatalf("Error updating page ranks: %v", err)
+	}
+
+	pages := map[string]domain.URL{
+		"authority": "https://www.authority.com/article",
+		"subdomain": "https://news.authority.com/story",
+		"blog":      "https://blog.com/post",
+		"unranked":  "https://unranked.com/",
+	}
+
+	for _, url := range pages {
+		pageRepo.Create(&domain.Page{ID: util.PageID(url), URL: url})
+	}
+
+	values, err := signal.NewHostRank(pageService, pageRankService).Values([]string{
+		string(util.PageID(pages["authority"])),
+		string(util.PageID(pages["subdomain"])),
+		string(util.PageID(pages["blog"])),
+		string(util.PageID(pages["unranked"])),
+		"unknown",
+	})
+	if err != nil {
+		t.Fatalf("Error getting values: %v", err)
+	}
+
+	authority := values[string(util.PageID(pages["authority"]))]
+	blog := values[string(util.PageID(pages["blog"]))]
+
+	if authority <= blog || blog <= 0 {
+		t.Errorf("Expected authority.com to outrank blog.com, got %f and %f", authority, blog)
+	}
+
+	if subdomain := values[string(util.PageID(pages["subdomain"]))]; subdomain != authority {
+		t.Errorf("Expected news.authority.com to share the rank of its site %f, got %f", authority, subdomain)
+	}
+
+	if _, ok := values[string(util.PageID(pages["unranked"]))]; ok {
+		t.Errorf("Expected no value for a page on an unranked host")
+	}
+
+	if _, ok := values["unknown"]; ok {
+		t.Errorf("Expected no value for an unknown page")
+	}
+}
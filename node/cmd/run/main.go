@@ -10,22 +10,30 @@ import (
 	htmlService "crawlquery/node/html/service"
 	htmlClient "crawlquery/pkg/client/html"
 
+	"crawlquery/pkg/canonical"
 	"crawlquery/pkg/client/api"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	pageRepo "crawlquery/node/page/repository/bolt"
 	pageService "crawlquery/node/page/service"
 
 	keywordOccurrenceRepo "crawlquery/node/keyword/occurrence/repository/bolt"
+	keywordInvertedRepo "crawlquery/node/keyword/occurrence/repository/inverted"
 	keywordService "crawlquery/node/keyword/service"
 
 	peerService "crawlquery/node/peer/service"
+	robotsService "crawlquery/node/robots/service"
 
 	indexHandler "crawlquery/node/index/handler"
 	indexService "crawlquery/node/index/service"
 
+	"crawlquery/node/signal"
+
 	searchHandler "crawlquery/node/search/handler"
+	searchScorer "crawlquery/node/search/scorer"
 	searchService "crawlquery/node/search/service"
 
 	queryHandler "crawlquery/node/query/handler"
@@ -64,14 +72,26 @@ func main() {
 	var htmlBackupURL string
 	var pageDBPath string
 	var keywordDBPath string
+	var keywordIndex string
+	var convertKeywordIndex bool
+	var scoringModel string
+	var trackingParams string
 
 	flag.StringVar(&htmlStoragePath, "html", "/tmp/htmlstorage", "path to the html storage")
 	flag.StringVar(&pageDBPath, "pdb", "/tmp/pagedb.bolt", "path to the pagedb")
 	flag.StringVar(&keywordDBPath, "kdb", "/tmp/keyworddb.bolt", "path to the keyworddb")
+	flag.StringVar(&keywordIndex, "kindex", "bolt", "keyword index layout (bolt or inverted)")
+	flag.BoolVar(&convertKeywordIndex, "kconvert", false, "rebuild the inverted keyword index from the bolt one before starting, if the bolt one changed since it was last converted")
+	flag.StringVar(&scoringModel, "scorer", "frequency", "search scoring model (frequency or bm25)")
 	flag.StringVar(&htmlBackupURL, "htmlbackup", "http://crawlquery-html1.dxs.network", "URL to the html backup service")
+	flag.StringVar(&trackingParams, "tracking", "", "comma separated query parameters dropped from urls, replacing the defaults")
 
 	flag.Parse()
 
+	if trackingParams != "" {
+		canonical.Default = canonical.New(canonical.WithTrackingParams(strings.Split(trackingParams, ",")...))
+	}
+
 	// Create repositories
 	htmlRepo, err := htmlRepo.NewRepository(htmlStoragePath)
 	if err != nil {
@@ -89,7 +109,23 @@ func main() {
 		sugar.Fatalf("Error opening bolt db: %v", err)
 	}
 
-	keywordRepo, err := keywordOccurrenceRepo.NewRepository(boltDB)
+	var keywordRepo domain.KeywordOccurrenceRepository
+
+	switch keywordIndex {
+	case "inverted":
+		if convertKeywordIndex {
+			sugar.Infow("Converting keyword index to the inverted layout")
+			if err := keywordInvertedRepo.ConvertLegacy(boltDB); err != nil {
+				sugar.Fatalf("Error converting keyword index: %v", err)
+			}
+		}
+		keywordRepo, err = keywordInvertedRepo.NewRepository(boltDB)
+	case "bolt":
+		keywordRepo, err = keywordOccurrenceRepo.NewRepository(boltDB)
+	default:
+		sugar.Fatalf("Unknown keyword index layout: %s", keywordIndex)
+	}
+
 	if err != nil {
 		sugar.Fatalf("Error creating keyword repository: %v", err)
 	}
@@ -133,11 +169,27 @@ func main() {
 	pageService := pageService.NewService(pageRepo, peerService)
 	keywordService := keywordService.NewService(keywordRepo)
 	indexService := indexService.NewService(pageService, htmlService, peerService, keywordService, sugar)
-	crawlService := crawlService.NewService(htmlService, pageService, indexService, api, sugar)
+	robotsService := robotsService.NewService(&http.Client{Timeout: 10 * time.Second}, time.Hour, sugar)
+	crawlService := crawlService.NewService(htmlService, pageService, indexService, robotsService, api, sugar)
 	dumpService := dumpService.NewService(pageService)
 	statService := statService.NewService(pageService, keywordService, dumpService)
-	searchService := searchService.NewService(pageService, keywordService)
-	queryService := queryService.NewService(pageService)
+
+	var scorer domain.Scorer
+
+	switch scoringModel {
+	case "frequency":
+		scorer = &searchScorer.Frequency{}
+	case "bm25":
+		scorer = searchScorer.NewBM25(keywordService)
+	default:
+		sugar.Fatalf("Unknown search scorer: %s", scoringModel)
+	}
+
+	searchService := searchService.NewService(pageService, keywordService, scorer, []domain.WeightedSignal{
+		{Signal: &signal.Domain{}, Weight: 1},
+		{Signal: &signal.Title{}, Weight: 1},
+	})
+	queryService := queryService.NewService(pageService, keywordService)
 	repairService := repairService.NewService(nil, pageService, keywordService, peerService, sugar)
 
 	// handlers
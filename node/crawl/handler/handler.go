@@ -3,6 +3,7 @@ package handler
 import (
 	"crawlquery/node/domain"
 	"crawlquery/node/dto"
+	"errors"
 	"net/url"
 
 	"github.com/gin-gonic/gin"
@@ -54,19 +55,44 @@ func (ch *CrawlHandler) Crawl(c *gin.Context) {
 		return
 	}
 
-	hash, links, err := ch.crawlService.Crawl(req.PageID, req.URL)
+	result, err := ch.crawlService.Crawl(req.PageID, req.URL)
 
 	if err != nil {
 		ch.logger.Errorw("Error crawling page", "error", err)
-		c.JSON(400, gin.H{
-			"error": err.Error(),
-		})
+
+		res := &dto.CrawlErrorResponse{Error: err.Error()}
+
+		var fetchErr *domain.CrawlFetchError
+		if errors.As(err, &fetchErr) {
+			res.StatusCode = fetchErr.StatusCode
+			res.RetryAfter = fetchErr.RetryAfter.Seconds()
+		}
+
+		res.Disallowed = errors.Is(err, domain.ErrCrawlDisallowed)
+
+		c.JSON(400, res)
 		return
 	}
 
+	res := &dto.CrawlResponse{
+		FinalURL:    result.URL,
+		Redirects:   result.Redirects,
+		ContentHash: result.ContentHash,
+		Canonical:   result.Canonical,
+		Feeds:       result.Feeds,
+		NoIndex:     result.NoIndex,
+		CrawlDelay:  result.CrawlDelay.Seconds(),
+	}
+
+	for _, link := range result.Links {
+		res.Links = append(res.Links, link.URL)
+		res.Anchors = append(res.Anchors, dto.CrawlLink{
+			URL:  link.URL,
+			Text: link.Text,
+			Rel:  link.Rel,
+		})
+	}
+
 	ch.logger.Infow("Page crawled", "pageID", req.PageID, "url", req.URL)
-	c.JSON(200, &dto.CrawlResponse{
-		ContentHash: hash,
-		Links:       links,
-	})
+	c.JSON(200, res)
 }
@@ -3,8 +3,10 @@ package handler_test
 import (
 	"bytes"
 	"encoding/json"
+	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	crawlHandler "crawlquery/node/crawl/handler"
 	crawlService "crawlquery/node/crawl/service"
@@ -13,9 +15,12 @@ import (
 	htmlRepo "crawlquery/node/html/repository/mem"
 	htmlService "crawlquery/node/html/service"
 	indexService "crawlquery/node/index/service"
+	keywordOccurrenceRepo "crawlquery/node/keyword/occurrence/repository/mem"
+	keywordService "crawlquery/node/keyword/service"
 	pageRepo "crawlquery/node/page/repository/mem"
 	pageService "crawlquery/node/page/service"
 	peerService "crawlquery/node/peer/service"
+	robotsService "crawlquery/node/robots/service"
 	"crawlquery/pkg/client/api"
 	"crawlquery/pkg/client/html"
 	"crawlquery/pkg/testutil"
@@ -34,10 +39,11 @@ func setupServices() (*crawlService.CrawlService, *htmlRepo.Repository, *pageRep
 	pageSvc := pageService.NewService(pageRepository, nil)
 
 	peerSvc := peerService.NewService(nil, nil, testutil.NewTestLogger())
-	indexSvc := indexService.NewService(pageSvc, htmlSvc, peerSvc, nil, testutil.NewTestLogger())
+	keywordSvc := keywordService.NewService(keywordOccurrenceRepo.NewRepository())
+	indexSvc := indexService.NewService(pageSvc, htmlSvc, peerSvc, keywordSvc, testutil.NewTestLogger())
 	apiClient := api.NewClient("http://localhost:8080", testutil.NewTestLogger())
 
-	crawlSvc := crawlService.NewService(htmlSvc, pageSvc, indexSvc, apiClient, testutil.NewTestLogger())
+	crawlSvc := crawlService.NewService(htmlSvc, pageSvc, indexSvc, robotsService.NewService(&http.Client{}, time.Hour, testutil.NewTestLogger()), apiClient, testutil.NewTestLogger())
 
 	return crawlSvc, htmlRepository, pageRepository
 }
@@ -47,7 +53,7 @@ func TestCrawl(t *testing.T) {
 		defer gock.Off()
 		crawlSvc, htmlRepo, _ := setupServices()
 
-		expectedData := `<html><head><title>Example</title></head><body><h1>Hello, World!</h1><p>Website description</p><a href="http://google.com"></body></html>`
+		expectedData := `<html><head><title>Example</title></head><body><h1>Hello, World!</h1><p>Website description</p><a href="http://google.com" rel="NoFollow">Search  engine</a></body></html>`
 		expectedPageHash := util.Sha256Hex32([]byte(expectedData))
 
 		gock.New("http://storage:8080").
@@ -102,6 +108,12 @@ func TestCrawl(t *testing.T) {
 			t.Fatalf("Expected link to be 'http://google.com', got '%s'", resp.Links[0])
 		}
 
+		expectedAnchor := dto.CrawlLink{URL: "http://google.com", Text: "Search engine", Rel: "nofollow"}
+
+		if len(resp.Anchors) != 1 || resp.Anchors[0] != expectedAnchor {
+			t.Fatalf("Expected anchors to be %v, got %v", []dto.CrawlLink{expectedAnchor}, resp.Anchors)
+		}
+
 		data, err := htmlRepo.Get(expectedPageHash)
 		if err != nil {
 			t.Fatalf("Error reading data: %v", err)
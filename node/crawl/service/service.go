@@ -1,53 +1,130 @@
 package service
 
 import (
+	"bytes"
+	apiDomain "crawlquery/api/domain"
 	"crawlquery/node/domain"
+	"crawlquery/node/parse"
+	"crawlquery/pkg/canonical"
 	"crawlquery/pkg/client/api"
 	"crawlquery/pkg/util"
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/PuerkitoBio/goquery"
 	"github.com/gocolly/colly/v2"
 	"github.com/gocolly/colly/v2/extensions"
 	"go.uber.org/zap"
 )
 
 type CrawlService struct {
-	htmlService  domain.HTMLService
-	pageService  domain.PageService
-	indexService domain.IndexService
-	api          *api.Client
-	logger       *zap.SugaredLogger
+	htmlService   domain.HTMLService
+	pageService   domain.PageService
+	indexService  domain.IndexService
+	robotsService domain.RobotsService
+	api           *api.Client
+	logger        *zap.SugaredLogger
 }
 
 func NewService(
 	htmlService domain.HTMLService,
 	pageService domain.PageService,
 	indexService domain.IndexService,
+	robotsService domain.RobotsService,
 	api *api.Client,
 	logger *zap.SugaredLogger,
 ) *CrawlService {
 	return &CrawlService{
-		htmlService:  htmlService,
-		pageService:  pageService,
-		indexService: indexService,
-		api:          api,
-		logger:       logger,
+		htmlService:   htmlService,
+		pageService:   pageService,
+		indexService:  indexService,
+		robotsService: robotsService,
+		api:           api,
+		logger:        logger,
 	}
 }
 
-func (cs *CrawlService) Crawl(pageID, url string) (contentHash string, links []string, failedErr error) {
+// maxRedirects is the most redirects followed before a crawl fails.
+const maxRedirects = 5
+
+// checkRedirect follows up to maxRedirects redirects, failing when one leads
+// back to a URL already requested.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > maxRedirects {
+		return domain.ErrCrawlTooManyRedirects
+	}
+
+	for _, previous := range via {
+		if previous.URL.String() == req.URL.String() {
+			return domain.ErrCrawlRedirectLoop
+		}
+	}
+
+	return nil
+}
+
+// retryAfter parses a Retry-After header given either in seconds or as an
+// HTTP date.
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+
+	return 0
+}
+
+// Crawl fetches and stores the page and reports the links on it, along with
+// the URL it ended up at, its canonical URL and whether it may be indexed.
+// Links on a page that asks not to be followed are reported as nofollow, and
+// a page that asks not to be indexed, redirects elsewhere or names another
+// page as canonical is taken out of the index here. Error statuses from the
+// site are returned as a *domain.CrawlFetchError.
+func (cs *CrawlService) Crawl(pageID, url string) (*domain.CrawlResult, error) {
+	result := &domain.CrawlResult{URL: url}
+
+	allowed, crawlDelay, err := cs.robotsService.Check(url)
+	if err != nil {
+		cs.logger.Errorw("Error checking robots.txt", "error", err, "pageID", pageID, "url", url)
+		return nil, err
+	}
+
+	if !allowed {
+		cs.logger.Infow("Page disallowed by robots.txt", "pageID", pageID, "url", url)
+		return nil, domain.ErrCrawlDisallowed
+	}
+
+	result.CrawlDelay = crawlDelay
+
+	var failedErr error
+	var robots parse.Robots
 
 	// Instantiate default collector
 	c := colly.NewCollector()
 
-	c.IgnoreRobotsTxt = false
+	// robots.txt has already been checked above
+	c.IgnoreRobotsTxt = true
 
 	extensions.RandomUserAgent(c)
 
 	c.SetRedirectHandler(func(req *http.Request, via []*http.Request) error {
-		// Returning an error prevents the redirect
-		return http.ErrUseLastResponse
+		if err := checkRedirect(req, via); err != nil {
+			return err
+		}
+		result.Redirects = append(result.Redirects, via[len(via)-1].URL.String())
+		return nil
 	})
 
 	c.OnResponse(func(r *colly.Response) {
@@ -61,19 +138,38 @@ func (cs *CrawlService) Crawl(pageID, url string) (contentHash string, links []s
 		if r.StatusCode != 200 {
 			cs.logger.Errorw("Error fetching page", "status", r.StatusCode, "pageID", pageID, "url", url)
 
-			failedErr = domain.ErrCrawlFailedToFetchHtml
+			failedErr = &domain.CrawlFetchError{StatusCode: r.StatusCode}
 			return
 		}
 
-		contentHash = util.Sha256Hex32(r.Body)
+		result.URL = r.Request.URL.String()
+		result.ContentHash = util.Sha256Hex32(r.Body)
 
-		err := cs.htmlService.Save(contentHash, r.Body)
+		err := cs.htmlService.Save(result.ContentHash, r.Body)
 		if err != nil {
 			cs.logger.Errorw("Error saving page", "error", err, "pageID", pageID, "url", url)
 			failedErr = domain.ErrCrawlFailedToStoreHtml
 			return
 		}
 
+		for _, value := range r.Headers.Values("X-Robots-Tag") {
+			robots = robots.Merge(parse.RobotsDirectives(value))
+		}
+
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(r.Body))
+		if err != nil {
+			cs.logger.Errorw("Error parsing page", "error", err, "pageID", pageID, "url", url)
+			return
+		}
+
+		robots = robots.Merge(parse.MetaRobots(doc))
+
+		if canonical, err := parse.Canonical(doc, result.URL); err == nil {
+			result.Canonical = canonical
+		}
+
+		result.Feeds = parse.Feeds(doc, result.URL)
+
 		cs.logger.Infow("Page crawled", "pageID", pageID, "url", url)
 	})
 
@@ -81,27 +177,89 @@ func (cs *CrawlService) Crawl(pageID, url string) (contentHash string, links []s
 		dst := e.Attr("href")
 
 		// if link is relative, make it absolute
-		absoluteDst, err := util.MakeAbsoluteIfRelative(url, dst)
+		absoluteDst, err := util.MakeAbsoluteIfRelative(result.URL, dst)
 
 		if err != nil {
 			cs.logger.Errorw("Error making link absolute", "error", err, "link", dst)
 			return
 		}
 
-		links = append(links, absoluteDst)
+		result.Links = append(result.Links, domain.CrawlLink{
+			URL:  absoluteDst,
+			Text: strings.Join(strings.Fields(e.Text), " "),
+			Rel:  strings.ToLower(strings.Join(strings.Fields(e.Attr("rel")), " ")),
+		})
 	})
 
 	c.OnError(func(r *colly.Response, e error) {
 		cs.logger.Errorw("Error crawling page", "error", e, "pageID", pageID)
+
+		if r != nil && r.StatusCode >= 300 {
+			failedErr = &domain.CrawlFetchError{
+				StatusCode: r.StatusCode,
+				RetryAfter: retryAfter(r.Headers.Get("Retry-After")),
+			}
+			return
+		}
+
 		failedErr = e
 	})
 
-	err := c.Visit(url)
+	err = c.Visit(url)
 
 	if err != nil {
 		cs.logger.Errorw("Error visiting page", "error", err, "pageID", pageID)
-		return "", nil, err
+
+		var fetchErr *domain.CrawlFetchError
+		if errors.As(failedErr, &fetchErr) {
+			return nil, fetchErr
+		}
+
+		return nil, err
+	}
+
+	if failedErr != nil {
+		return nil, failedErr
 	}
 
-	return
+	if robots.NoFollow {
+		for i := range result.Links {
+			result.Links[i].Rel = addRel(result.Links[i].Rel, "nofollow")
+		}
+	}
+
+	result.NoIndex = robots.NoIndex
+
+	redirected := !sameURL(result.URL, url)
+	duplicate := result.Canonical != "" && !sameURL(result.Canonical, url)
+
+	if result.NoIndex || redirected || duplicate {
+		cs.logger.Infow("Removing page from index", "pageID", pageID, "noindex", result.NoIndex, "url", result.URL, "canonical", result.Canonical)
+		if err := cs.indexService.Remove(pageID); err != nil {
+			cs.logger.Errorw("Error removing page from index", "error", err, "pageID", pageID)
+		}
+	}
+
+	return result, nil
+}
+
+// sameURL reports whether two URLs are the same page once canonicalized.
+// URLs that cannot be canonicalized are compared as they are.
+func sameURL(a, b string) bool {
+	canonicalA, errA := canonical.URL(apiDomain.URL(a))
+	canonicalB, errB := canonical.URL(apiDomain.URL(b))
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return canonicalA == canonicalB
+}
+
+// addRel adds value to a space separated rel attribute unless it is there.
+func addRel(rel, value string) string {
+	for _, r := range strings.Fields(rel) {
+		if r == value {
+			return rel
+		}
+	}
+	return strings.TrimSpace(rel + " " + value)
 }
@@ -1,23 +1,30 @@
 package service_test
 
 import (
-	"strings"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
 	"testing"
+	"time"
 
 	crawlService "crawlquery/node/crawl/service"
+	"crawlquery/node/domain"
 	htmlBackupService "crawlquery/node/html/backup/service"
 	htmlRepo "crawlquery/node/html/repository/mem"
 	htmlService "crawlquery/node/html/service"
 	indexService "crawlquery/node/index/service"
+	keywordOccurrenceRepo "crawlquery/node/keyword/occurrence/repository/mem"
+	keywordService "crawlquery/node/keyword/service"
 	pageRepo "crawlquery/node/page/repository/mem"
 	pageService "crawlquery/node/page/service"
 	peerService "crawlquery/node/peer/service"
+	robotsService "crawlquery/node/robots/service"
 	"crawlquery/pkg/client/api"
 	"crawlquery/pkg/client/html"
 	"crawlquery/pkg/testutil"
 	"crawlquery/pkg/util"
 
-	"github.com/gocolly/colly/v2"
 	"github.com/h2non/gock"
 )
 
@@ -30,10 +37,11 @@ func setupServices() (*crawlService.CrawlService, *htmlRepo.Repository, *pageRep
 	pageSvc := pageService.NewService(pageRepository, nil)
 
 	peerSvc := peerService.NewService(nil, nil, testutil.NewTestLogger())
-	indexSvc := indexService.NewService(pageSvc, htmlSvc, peerSvc, nil, testutil.NewTestLogger())
+	keywordSvc := keywordService.NewService(keywordOccurrenceRepo.NewRepository())
+	indexSvc := indexService.NewService(pageSvc, htmlSvc, peerSvc, keywordSvc, testutil.NewTestLogger())
 	apiClient := api.NewClient("http://localhost:8080", testutil.NewTestLogger())
 
-	crawlService := crawlService.NewService(htmlSvc, pageSvc, indexSvc, apiClient, testutil.NewTestLogger())
+	crawlService := crawlService.NewService(htmlSvc, pageSvc, indexSvc, robotsService.NewService(&http.Client{}, time.Hour, testutil.NewTestLogger()), apiClient, testutil.NewTestLogger())
 
 	return crawlService, htmlRepository, pageRepository
 }
@@ -60,7 +68,7 @@ func TestCrawl(t *testing.T) {
 			Reply(200).
 			BodyString(expectedData).Header.Set("Content-Type", "text/html")
 
-		_, _, err := service.Crawl("test1", "http://example.com:9292")
+		_, err := service.Crawl("test1", "http://example.com:9292")
 
 		if err != nil {
 			t.Errorf("Error crawling page: %v", err)
@@ -101,12 +109,14 @@ func TestCrawl(t *testing.T) {
 			BodyString(expectedData).
 			SetHeader("Content-Type", "text/html")
 
-		hash, links, err := service.Crawl("test1", "http://example.com")
+		result, err := service.Crawl("test1", "http://example.com")
 
 		if err != nil {
-			t.Errorf("Error crawling page: %v", err)
+			t.Fatalf("Error crawling page: %v", err)
 		}
 
+		hash, links := result.ContentHash, result.Links
+
 		expectedHash := util.Sha256Hex32([]byte(expectedData))
 
 		if hash != expectedHash {
@@ -117,8 +127,12 @@ func TestCrawl(t *testing.T) {
 			t.Fatalf("Expected 1 link, got %d", len(links))
 		}
 
-		if links[0] != "http://example.com/about" {
-			t.Fatalf("Expected link to be 'http://example.com/about', got '%s'", links[0])
+		if links[0].URL != "http://example.com/about" {
+			t.Fatalf("Expected link to be 'http://example.com/about', got '%s'", links[0].URL)
+		}
+
+		if links[0].Text != "About us" {
+			t.Fatalf("Expected anchor text to be 'About us', got '%s'", links[0].Text)
 		}
 
 		data, err := htmlRepo.Get(expectedHash)
@@ -156,12 +170,14 @@ func TestCrawl(t *testing.T) {
 			BodyString(expectedData).
 			SetHeader("Content-Type", "text/html")
 
-		hash, links, err := service.Crawl("test1", "http://example.com")
+		result, err := service.Crawl("test1", "http://example.com")
 
 		if err != nil {
-			t.Errorf("Error crawling page: %v", err)
+			t.Fatalf("Error crawling page: %v", err)
 		}
 
+		hash, links := result.ContentHash, result.Links
+
 		expectedHash := util.Sha256Hex32([]byte(expectedData))
 
 		if hash != expectedHash {
@@ -172,8 +188,12 @@ func TestCrawl(t *testing.T) {
 			t.Fatalf("Expected 1 link, got %d", len(links))
 		}
 
-		if links[0] != "http://example.com/about" {
-			t.Fatalf("Expected link to be 'http://example.com/about', got '%s'", links[0])
+		if links[0].URL != "http://example.com/about" {
+			t.Fatalf("Expected link to be 'http://example.com/about', got '%s'", links[0].URL)
+		}
+
+		if links[0].Text != "About us" {
+			t.Fatalf("Expected anchor text to be 'About us', got '%s'", links[0].Text)
 		}
 
 		data, err := htmlRepo.Get(expectedHash)
@@ -191,6 +211,99 @@ func TestCrawl(t *testing.T) {
 		}
 	})
 
+	t.Run("reads robots directives from headers and meta tags", func(t *testing.T) {
+		defer gock.Off()
+
+		gock.New("http://storage:8080").Post("/pages").Reply(201)
+
+		gock.New("http://example.com/robots.txt").
+			Reply(200).
+			BodyString("User-agent: *\nAllow: /")
+
+		service, _, _ := setupServices()
+
+		gock.New("http://example.com").
+			Get("/").
+			Reply(200).
+			BodyString(`<html><head><meta name="robots" content="nofollow"></head><body><a href="/about" rel="external">About us</a></body></html>`).
+			SetHeader("Content-Type", "text/html").
+			SetHeader("X-Robots-Tag", "noindex")
+
+		result, err := service.Crawl("test1", "http://example.com")
+
+		if err != nil {
+			t.Fatalf("Error crawling page: %v", err)
+		}
+
+		if !result.NoIndex {
+			t.Errorf("Expected page to be noindex")
+		}
+
+		if len(result.Links) != 1 || result.Links[0].Rel != "external nofollow" {
+			t.Errorf("Expected the link to be marked nofollow, got %v", result.Links)
+		}
+	})
+
+	t.Run("reports the canonical URL", func(t *testing.T) {
+		defer gock.Off()
+
+		gock.New("http://storage:8080").Post("/pages").Reply(201)
+
+		gock.New("http://example.com/robots.txt").
+			Reply(200).
+			BodyString("User-agent: *\nAllow: /")
+
+		service, _, _ := setupServices()
+
+		gock.New("http://example.com").
+			Get("/print").
+			Reply(200).
+			BodyString(`<html><head><link rel="canonical" href="/article"></head><body><p>Hello</p></body></html>`).
+			SetHeader("Content-Type", "text/html")
+
+		result, err := service.Crawl("test1", "http://example.com/print")
+
+		if err != nil {
+			t.Fatalf("Error crawling page: %v", err)
+		}
+
+		if result.Canonical != "http://example.com/article" {
+			t.Errorf("Expected canonical http://example.com/article, got %s", result.Canonical)
+		}
+
+		if result.NoIndex {
+			t.Errorf("Expected page to be indexable")
+		}
+	})
+
+	t.Run("reports the feeds the page links to", func(t *testing.T) {
+		defer gock.Off()
+
+		gock.New("http://storage:8080").Post("/pages").Reply(201)
+
+		gock.New("http://example.com/robots.txt").
+			Reply(200).
+			BodyString("User-agent: *\nAllow: /")
+
+		service, _, _ := setupServices()
+
+		gock.New("http://example.com").
+			Get("/blog").
+			Reply(200).
+			BodyString(`<html><head><link rel="alternate" type="application/rss+xml" href="/blog/feed"></head><body><p>Hello</p></body></html>`).
+			SetHeader("Content-Type", "text/html")
+
+		result, err := service.Crawl("test1", "http://example.com/blog")
+
+		if err != nil {
+			t.Fatalf("Error crawling page: %v", err)
+		}
+
+		if len(result.Feeds) != 1 || result.Feeds[0] != "http://example.com/blog/feed" {
+			t.Errorf("Expected the feed http://example.com/blog/feed, got %v", result.Feeds)
+		}
+	})
+
 	t.Run("handles 404", func(t *testing.T) {
 		defer gock.Off()
 
@@ -200,7 +313,7 @@ func TestCrawl(t *testing.T) {
 			Get("/").
 			Reply(404)
 
-		_, _, err := service.Crawl("test1", "http://example.com")
+		_, err := service.Crawl("test1", "http://example.com")
 
 		if err == nil {
 			t.Errorf("Expected error, got nil")
@@ -220,7 +333,7 @@ func TestCrawl(t *testing.T) {
 			BodyString(expectedData).
 			SetHeader("Content-Type", "application/atom+xml")
 
-		_, _, err := service.Crawl("test1", "http://example.com")
+		_, err := service.Crawl("test1", "http://example.com")
 
 		if err == nil {
 			t.Errorf("Expected error, got nil")
@@ -246,27 +359,172 @@ func TestCrawl(t *testing.T) {
 			BodyString("<html><head><title>Example</title></head><body><h1>Hello, World!</h1><p>Welcome to my example website.</p></body></html>").
 			SetHeader("Content-Type", "text/html")
 
-		_, _, err := service.Crawl("test1", "http://example.com")
+		_, err := service.Crawl("test1", "http://example.com")
 
-		if err != colly.ErrRobotsTxtBlocked {
-			t.Errorf("Expected error, got nil")
+		if err != domain.ErrCrawlDisallowed {
+			t.Errorf("Expected disallowed error, got %v", err)
 		}
 	})
 
-	t.Run("does not follow redirects", func(t *testing.T) {
+	t.Run("follows redirects", func(t *testing.T) {
 		defer gock.Off()
 
+		gock.New("http://storage:8080").Post("/pages").Reply(201)
+
 		service, _, _ := setupServices()
 
+		gock.New("http://exampleredirect.com/robots.txt").
+			Reply(200).
+			BodyString("User-agent: *\nAllow: /")
+
+		gock.New("https://www.exampleredirect.com/robots.txt").
+			Reply(200).
+			BodyString("User-agent: *\nAllow: /")
+
 		gock.New("http://exampleredirect.com").
 			Get("/").
 			Reply(301).
-			SetHeader("Location", "http://example.com/redirect")
+			SetHeader("Location", "https://exampleredirect.com/")
 
-		_, _, err := service.Crawl("test1", "http://exampleredirect.com")
+		gock.New("https://exampleredirect.com").
+			Get("/").
+			Reply(301).
+			SetHeader("Location", "https://www.exampleredirect.com/")
+
+		gock.New("https://www.exampleredirect.com").
+			Get("/").
+			Reply(200).
+			BodyString(`<html><body><a href="/about">About us</a></body></html>`).
+			SetHeader("Content-Type", "text/html")
+
+		result, err := service.Crawl("test1", "http://exampleredirect.com/")
+
+		if err != nil {
+			t.Fatalf("Error crawling page: %v", err)
+		}
+
+		if result.URL != "https://www.exampleredirect.com/" {
+			t.Errorf("Expected final URL https://www.exampleredirect.com/, got %s", result.URL)
+		}
+
+		expectedRedirects := []string{"http://exampleredirect.com/", "https://exampleredirect.com/"}
+		if !reflect.DeepEqual(result.Redirects, expectedRedirects) {
+			t.Errorf("Expected redirects %v, got %v", expectedRedirects, result.Redirects)
+		}
+
+		if len(result.Links) != 1 || result.Links[0].URL != "https://www.exampleredirect.com/about" {
+			t.Errorf("Expected links resolved against the final URL, got %v", result.Links)
+		}
+	})
+
+	t.Run("fails on a redirect loop", func(t *testing.T) {
+		defer gock.Off()
+
+		service, _, _ := setupServices()
+
+		gock.New("http://exampleredirect.com/robots.txt").
+			Reply(200).
+			BodyString("User-agent: *\nAllow: /")
+
+		gock.New("http://exampleredirect.com").
+			Get("/a").
+			Reply(302).
+			SetHeader("Location", "http://exampleredirect.com/b")
+
+		gock.New("http://exampleredirect.com").
+			Get("/b").
+			Reply(302).
+			SetHeader("Location", "http://exampleredirect.com/a")
+
+		_, err := service.Crawl("test1", "http://exampleredirect.com/a")
+
+		if !errors.Is(err, domain.ErrCrawlRedirectLoop) {
+			t.Errorf("Expected ErrCrawlRedirectLoop, got %v", err)
+		}
+	})
+
+	t.Run("fails after too many redirects", func(t *testing.T) {
+		defer gock.Off()
+
+		service, _, _ := setupServices()
+
+		gock.New("http://exampleredirect.com/robots.txt").
+			Reply(200).
+			BodyString("User-agent: *\nAllow: /")
+
+		for i := 0; i < 10; i++ {
+			gock.New("http://exampleredirect.com").
+				Get(fmt.Sprintf("/%d", i)).
+				Reply(302).
+				SetHeader("Location", fmt.Sprintf("http://exampleredirect.com/%d", i+1))
+		}
+
+		_, err := service.Crawl("test1", "http://exampleredirect.com/0")
+
+		if !errors.Is(err, domain.ErrCrawlTooManyRedirects) {
+			t.Errorf("Expected ErrCrawlTooManyRedirects, got %v", err)
+		}
+	})
+
+	t.Run("reports the robots.txt crawl delay", func(t *testing.T) {
+		defer gock.Off()
+
+		gock.New("http://storage:8080").Post("/pages").Reply(201)
+
+		gock.New("http://example.com/robots.txt").
+			Reply(200).
+			BodyString("User-agent: *\nCrawl-delay: 7\nAllow: /")
+
+		gock.New("http://example.com").
+			Get("/").
+			Reply(200).
+			BodyString("<html><body>Hello</body></html>").
+			SetHeader("Content-Type", "text/html")
+
+		service, _, _ := setupServices()
+
+		result, err := service.Crawl("test1", "http://example.com")
+
+		if err != nil {
+			t.Fatalf("Error crawling page: %v", err)
+		}
+
+		if result.CrawlDelay != 7*time.Second {
+			t.Errorf("Expected crawl delay of 7s, got %s", result.CrawlDelay)
+		}
+	})
+
+	t.Run("returns the status and retry after when rate limited", func(t *testing.T) {
+		defer gock.Off()
+
+		gock.New("http://example.com/robots.txt").
+			Reply(200).
+			BodyString("User-agent: *\nAllow: /")
+
+		gock.New("http://example.com").
+			Get("/").
+			Reply(429).
+			SetHeader("Retry-After", "120")
+
+		service, _, _ := setupServices()
+
+		_, err := service.Crawl("test1", "http://example.com")
+
+		var fetchErr *domain.CrawlFetchError
+		if !errors.As(err, &fetchErr) {
+			t.Fatalf("Expected CrawlFetchError, got %v", err)
+		}
+
+		if fetchErr.StatusCode != 429 {
+			t.Errorf("Expected status 429, got %d", fetchErr.StatusCode)
+		}
+
+		if fetchErr.RetryAfter != 2*time.Minute {
+			t.Errorf("Expected retry after of 2m, got %s", fetchErr.RetryAfter)
+		}
 
-		if !strings.Contains(err.Error(), "301") {
-			t.Errorf("Expected error to contain '301', got '%s'", err.Error())
+		if !errors.Is(err, domain.ErrCrawlFailedToFetchHtml) {
+			t.Errorf("Expected error to wrap ErrCrawlFailedToFetchHtml")
 		}
 	})
 }
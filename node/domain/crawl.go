@@ -2,17 +2,70 @@ package domain
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 var ErrCrawlFailedToStoreHtml = errors.New("failed to store html")
 var ErrCrawlFailedToFetchHtml = errors.New("failed to fetch html")
+var ErrCrawlTooManyRedirects = errors.New("too many redirects")
+var ErrCrawlRedirectLoop = errors.New("redirect loop")
+var ErrCrawlDisallowed = errors.New("disallowed by robots.txt")
+
+// CrawlFetchError is returned when a site answers a crawl with an error
+// status.
+type CrawlFetchError struct {
+	StatusCode int
+	// RetryAfter is how long the site asked crawlers to wait before trying
+	// again, if it said.
+	RetryAfter time.Duration
+}
+
+func (e *CrawlFetchError) Error() string {
+	return fmt.Sprintf("%v: status %d", ErrCrawlFailedToFetchHtml, e.StatusCode)
+}
+
+func (e *CrawlFetchError) Unwrap() error {
+	return ErrCrawlFailedToFetchHtml
+}
 
 type CrawlHandler interface {
 	Crawl(c *gin.Context)
 }
 
+// CrawlLink is a link found on a crawled page, with the text of the anchor
+// and its rel attribute as written.
+type CrawlLink struct {
+	URL  string
+	Text string
+	Rel  string
+}
+
+// CrawlResult is what a crawl found on a page.
+type CrawlResult struct {
+	// URL is where the page was fetched from after following redirects.
+	URL string
+	// Redirects are the URLs that redirected on the way to URL, in order,
+	// starting with the one requested.
+	Redirects   []string
+	ContentHash string
+	Links       []CrawlLink
+	// Canonical is the absolute URL of the page's rel="canonical" link, if it
+	// has one.
+	Canonical string
+	// Feeds are the absolute URLs of the RSS and Atom feeds the page links
+	// to.
+	Feeds []string
+	// NoIndex is set when the page's robots meta tags or X-Robots-Tag header
+	// ask for it to be kept out of the index.
+	NoIndex bool
+	// CrawlDelay is the delay between requests the site's robots.txt asks
+	// for, if it does.
+	CrawlDelay time.Duration
+}
+
 type CrawlService interface {
-	Crawl(pageID, url string) (string, []string, error)
+	Crawl(pageID, url string) (*CrawlResult, error)
 }
@@ -11,7 +11,12 @@ var ErrCrawlFailedToIndexPage = errors.New("failed to index page")
 type IndexService interface {
 	Hash() (string, error)
 	Index(pageID string, url string, contentHash string) error
+	// IndexWithAnchors indexes a page along with the text of links other
+	// pages make to it.
+	IndexWithAnchors(pageID string, url string, contentHash string, anchors []string) error
 	GetIndex(pageID string) (*Page, error)
+	// Remove takes a page out of the keyword index.
+	Remove(pageID string) error
 	ApplyPageUpdatedEvent(event *PageUpdatedEvent) error
 }
 
@@ -18,11 +18,21 @@ type KeywordMatch struct {
 	Occurrences []KeywordOccurrence `json:"occurrences"`
 }
 
+// KeywordStats describes the whole keyword index of a node.
+type KeywordStats struct {
+	// TotalPages is the number of pages with at least one keyword.
+	TotalPages int `json:"total_pages"`
+	// TotalTokens is the sum of keyword frequencies over every page.
+	TotalTokens int `json:"total_tokens"`
+}
+
 type KeywordService interface {
 	GetKeywordMatches(keywords []Keyword) ([]KeywordMatch, error)
 	GetForPageID(pageID string) (map[Keyword]KeywordOccurrence, error)
 	UpdateOccurrences(pageID string, keywordOccurrences map[Keyword]KeywordOccurrence) error
 	Count() (int, error)
+	PageTokenCount(pageID string) (int, error)
+	Stats() (*KeywordStats, error)
 }
 
 type KeywordOccurrenceRepository interface {
@@ -31,4 +41,6 @@ type KeywordOccurrenceRepository interface {
 	Add(keyword Keyword, occurrence KeywordOccurrence) error
 	RemoveForPageID(pageID string) error
 	Count() (int, error)
+	PageTokenCount(pageID string) (int, error)
+	Stats() (*KeywordStats, error)
 }
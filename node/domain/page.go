@@ -19,6 +19,10 @@ type Page struct {
 	Language      string     `json:"language"`
 	LastIndexedAt *time.Time `json:"last_indexed"`
 	UpdatedAt     time.Time  `json:"updated_at"`
+	// Removed is set when the page was taken out of the index, such as when
+	// it asks not to be indexed. The page is kept, and shared with peers, so
+	// it is not treated as new when crawled again, but it is never a result.
+	Removed bool `json:"removed,omitempty"`
 }
 
 type PageRepository interface {
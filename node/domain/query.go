@@ -1,9 +1,36 @@
 package domain
 
-import "github.com/gin-gonic/gin"
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+var ErrInvalidQuery = errors.New("invalid query")
+
+type QueryColumnType string
+
+const (
+	QueryColumnTypeString QueryColumnType = "string"
+	QueryColumnTypeInt    QueryColumnType = "int"
+	QueryColumnTypeTime   QueryColumnType = "time"
+)
+
+type QueryColumn struct {
+	Name string          `json:"name"`
+	Type QueryColumnType `json:"type"`
+}
+
+// QueryRow holds one value per column of the result, in column order.
+type QueryRow []any
+
+type QueryResult struct {
+	Columns []QueryColumn `json:"columns"`
+	Rows    []QueryRow    `json:"rows"`
+}
 
 type QueryService interface {
-	Query(query string) ([]Page, error)
+	Query(query string) (*QueryResult, error)
 }
 
 type QueryHandler interface {
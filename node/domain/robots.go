@@ -0,0 +1,10 @@
+package domain
+
+import "time"
+
+// RobotsService reads the robots.txt rules of the sites being crawled.
+type RobotsService interface {
+	// Check reports whether the URL may be crawled and how long the site
+	// asks crawlers to wait between requests, if it says.
+	Check(url string) (bool, time.Duration, error)
+}
@@ -1,6 +1,21 @@
 package domain
 
-import "github.com/gin-gonic/gin"
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+var ErrInvalidSearchQuery = errors.New("invalid search query")
+
+const (
+	// DefaultSearchLimit is the number of results returned when a search
+	// doesn't ask for a limit.
+	DefaultSearchLimit = 10
+	// MaxSearchDepth bounds offset plus limit, so deep pages can't make a
+	// node rank its whole index.
+	MaxSearchDepth = 1000
+)
 
 type Result struct {
 	PageID            string                       `json:"id"`
@@ -8,6 +23,19 @@ type Result struct {
 	Page              ResultPage                   `json:"page"`
 	KeywordOccurences map[string]KeywordOccurrence `json:"keyword_occurrences"`
 	PageRank          float64                      `json:"page_rank"`
+	Signals           map[string]SignalBreakdown   `json:"signals"`
+	// Components is set by the API when it blends results from every shard
+	// into one score, keyed by component name.
+	Components map[string]ScoreComponent `json:"components,omitempty"`
+}
+
+// ScoreComponent is one input to a blended score.
+type ScoreComponent struct {
+	// Value is the component before it was normalised.
+	Value        float64 `json:"value"`
+	Normalized   float64 `json:"normalized"`
+	Weight       float64 `json:"weight"`
+	Contribution float64 `json:"contribution"`
 }
 
 // Page represents a web page with metadata. Note this does not include the keywords.
@@ -19,8 +47,21 @@ type ResultPage struct {
 	Description string `json:"meta_description"`
 }
 
+// Scorer scores every page that appears in the keyword matches of a query.
+type Scorer interface {
+	Name() string
+	Score(matches []KeywordMatch) (map[string]float64, error)
+}
+
+// SearchResults is one window of the ranked results for a query.
+type SearchResults struct {
+	Results []Result `json:"results"`
+	// TotalHits is the number of pages that matched the query.
+	TotalHits int `json:"total_hits"`
+}
+
 type SearchService interface {
-	Search(query string) ([]Result, error)
+	Search(query string, offset, limit int) (*SearchResults, error)
 }
 
 type SearchHandler interface {
@@ -48,3 +48,10 @@ func (s SignalLevel) String() string {
 		return "Unknown"
 	}
 }
+
+// WeightedSignal pairs a signal with the multiplier applied to its level
+// before it is added to a search result's score.
+type WeightedSignal struct {
+	Signal Signal
+	Weight float64
+}
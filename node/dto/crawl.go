@@ -9,7 +9,39 @@ type CrawlRequest struct {
 	URL    string `json:"url"`
 }
 
+// CrawlLink is a link found on a crawled page.
+type CrawlLink struct {
+	URL  string `json:"url"`
+	Text string `json:"text"`
+	Rel  string `json:"rel,omitempty"`
+}
+
+// CrawlResponse lists the link targets in Links for callers that only need
+// the URLs, and every anchor with its text and rel attribute in Anchors.
+// FinalURL is where the page was fetched from after following redirects,
+// Redirects are the URLs that redirected on the way there, Canonical is the URL the page names as its canonical version, Feeds are the
+// RSS and Atom feeds it links to, and NoIndex is set when the page asks to be
+// kept out of the index. CrawlDelay is the delay in seconds the site's
+// robots.txt asks for between requests.
 type CrawlResponse struct {
-	ContentHash string   `json:"content_hash"`
-	Links       []string `json:"links"`
+	FinalURL    string      `json:"final_url,omitempty"`
+	Redirects   []string    `json:"redirects,omitempty"`
+	ContentHash string      `json:"content_hash"`
+	Links       []string    `json:"links"`
+	Anchors     []CrawlLink `json:"anchors,omitempty"`
+	Canonical   string      `json:"canonical,omitempty"`
+	Feeds       []string    `json:"feeds,omitempty"`
+	NoIndex     bool        `json:"noindex,omitempty"`
+	CrawlDelay  float64     `json:"crawl_delay,omitempty"`
+}
+
+// CrawlErrorResponse is returned when a crawl fails. StatusCode is the status
+// the site answered with, if it got that far, and RetryAfter the seconds it
+// asked crawlers to wait before trying again. Disallowed is set when the
+// site's robots.txt forbids crawling the page, so there is no point retrying.
+type CrawlErrorResponse struct {
+	Error      string  `json:"error"`
+	StatusCode int     `json:"status_code,omitempty"`
+	RetryAfter float64 `json:"retry_after,omitempty"`
+	Disallowed bool    `json:"disallowed,omitempty"`
 }
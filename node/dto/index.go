@@ -4,6 +4,8 @@ type IndexRequest struct {
 	PageID      string `json:"page_id" binding:"required"`
 	URL         string `json:"url" binding:"required"`
 	ContentHash string `json:"content_hash" binding:"required"`
+	// Anchors holds the text of links to the page from other pages.
+	Anchors []string `json:"anchors,omitempty"`
 }
 
 type IndexResponse struct {
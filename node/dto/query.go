@@ -1,15 +1,15 @@
 package dto
 
-type QueryResultPage struct {
-	ID       string `json:"id"`
-	Title    string `json:"title"`
-	HtmlLink string `json:"html_link"`
+type QueryRequest struct {
+	Query string `json:"query" binding:"required"`
 }
 
-type QueryRequest struct {
-	Query string `json:"query"`
+type QueryColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
 }
 
 type QueryResponse struct {
-	Pages []QueryResultPage `json:"pages"`
+	Columns []QueryColumn `json:"columns"`
+	Rows    [][]any       `json:"rows"`
 }
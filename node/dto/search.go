@@ -0,0 +1,25 @@
+package dto
+
+// SearchRequest holds the query string parameters of a search. Page is one
+// based and, when set, takes precedence over Offset.
+type SearchRequest struct {
+	Query  string `form:"q"`
+	Page   int    `form:"page" binding:"omitempty,min=1"`
+	Offset int    `form:"offset" binding:"omitempty,min=0"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1"`
+}
+
+// Window returns the offset and limit of the results the request asks for,
+// using defaultLimit when it doesn't set one.
+func (r *SearchRequest) Window(defaultLimit int) (int, int) {
+	limit := r.Limit
+	if limit == 0 {
+		limit = defaultLimit
+	}
+
+	if r.Page > 0 {
+		return (r.Page - 1) * limit, limit
+	}
+
+	return r.Offset, limit
+}
@@ -31,7 +31,7 @@ func (ih *IndexHandler) Index(c *gin.Context) {
 		return
 	}
 
-	if err := ih.service.Index(req.PageID, req.URL, req.ContentHash); err != nil {
+	if err := ih.service.IndexWithAnchors(req.PageID, req.URL, req.ContentHash, req.Anchors); err != nil {
 		ih.logger.Error(err)
 		c.JSON(422, &dto.ErrorResponse{
 			Error: err.Error(),
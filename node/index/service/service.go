@@ -36,16 +36,17 @@ func NewService(
 	}
 }
 
+// maxAnchors is the most anchors indexed for a page.
+const maxAnchors = 100
+
 func (s *Service) Index(pageID string, url string, contentHash string) error {
-	page, err := s.pageService.Get(pageID)
-	if err != nil {
-		page, err = s.pageService.Create(pageID, url, contentHash)
-		if err != nil {
-			s.logger.Errorw("Error creating page", "error", err, "pageID", pageID)
-			return err
-		}
-	}
+	return s.IndexWithAnchors(pageID, url, contentHash, nil)
+}
 
+// IndexWithAnchors indexes the page with the keywords of each anchor added
+// after its own, so that a page can be found by how other pages describe it.
+// A position is skipped between anchors so phrases do not run across them.
+func (s *Service) IndexWithAnchors(pageID string, url string, contentHash string, anchors []string) error {
 	html, err := s.htmlService.Get(contentHash)
 
 	if err != nil {
@@ -53,8 +54,6 @@ func (s *Service) Index(pageID string, url string, contentHash string) error {
 		return err
 	}
 
-	page.Hash = util.Sha256Hex32(html)
-
 	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(html))
 
 	if err != nil {
@@ -62,6 +61,22 @@ func (s *Service) Index(pageID string, url string, contentHash string) error {
 		return err
 	}
 
+	if parse.MetaRobots(doc).NoIndex {
+		s.logger.Infow("Page asks not to be indexed", "pageID", pageID)
+		return s.Remove(pageID)
+	}
+
+	page, err := s.pageService.Get(pageID)
+	if err != nil {
+		page, err = s.pageService.Create(pageID, url, contentHash)
+		if err != nil {
+			s.logger.Errorw("Error creating page", "error", err, "pageID", pageID)
+			return err
+		}
+	}
+
+	page.Hash = util.Sha256Hex32(html)
+
 	title, err := parse.Title(doc)
 	if err != nil {
 		s.logger.Errorw("Error parsing title", "error", err, "pageID", pageID)
@@ -93,6 +108,22 @@ func (s *Service) Index(pageID string, url string, contentHash string) error {
 		s.logger.Errorw("Error making keyword occurrences", "error", err, "pageID", pageID)
 	}
 
+	if len(anchors) > maxAnchors {
+		anchors = anchors[:maxAnchors]
+	}
+
+	anchorKeywords, err := parse.AnchorKeywords(anchors)
+
+	if err != nil {
+		s.logger.Errorw("Error parsing anchor keywords", "error", err, "pageID", pageID)
+	}
+
+	position := len(keywords) + 1
+	for _, anchor := range anchorKeywords {
+		keyword.AddKeywordOccurrences(occurrences, anchor, page.ID, position)
+		position += len(anchor) + 1
+	}
+
 	// Update keywords
 	err = s.keywordService.UpdateOccurrences(page.ID, occurrences)
 
@@ -104,6 +135,7 @@ func (s *Service) Index(pageID string, url string, contentHash string) error {
 	page.Title = title
 	page.Description = desc
 	page.Language = language
+	page.Removed = false
 
 	now := time.Now()
 	page.LastIndexedAt = &now
@@ -134,6 +166,40 @@ func (s *Service) GetIndex(pageID string) (*domain.Page, error) {
 	return page, nil
 }
 
+// Remove clears the keyword occurrences of a page and marks it removed, here
+// and on its peers, so it no longer appears in results. The page itself is
+// kept so that it is not treated as new when crawled again.
+func (s *Service) Remove(pageID string) error {
+	err := s.keywordService.UpdateOccurrences(pageID, map[domain.Keyword]domain.KeywordOccurrence{})
+
+	if err != nil {
+		s.logger.Errorw("Error removing keyword occurrences", "error", err, "pageID", pageID)
+		return err
+	}
+
+	page, err := s.pageService.Get(pageID)
+
+	if err != nil {
+		return nil
+	}
+
+	page.Removed = true
+
+	err = s.pageService.Update(page)
+
+	if err != nil {
+		s.logger.Errorw("Error marking page removed", "error", err, "pageID", pageID)
+		return err
+	}
+
+	go s.peerService.BroadcastPageUpdatedEvent(&domain.PageUpdatedEvent{
+		Page:               page,
+		KeywordOccurrences: map[domain.Keyword]domain.KeywordOccurrence{},
+	})
+
+	return nil
+}
+
 func (s *Service) ApplyPageUpdatedEvent(event *domain.PageUpdatedEvent) error {
 	// update the page
 	err := s.pageService.UpdateQuietly(event.Page)
@@ -263,6 +263,142 @@ func TestIndex(t *testing.T) {
 	})
 }
 
+func TestIndexNoIndex(t *testing.T) {
+	t.Run("removes pages with a noindex robots meta tag", func(t *testing.T) {
+		pageRepo, pageService, htmlRepo, htmlService, peerService, keywordService := setupTestRepos()
+
+		logger := testutil.NewTestLogger()
+		s := service.NewService(pageService, htmlService, peerService, keywordService, logger)
+
+		pageRepo.Save("page1", &domain.Page{
+			ID:  "page1",
+			URL: "http://example.com",
+		})
+
+		htmlRepo.Save("indexed", []byte(`<html><body><p>This is a test page.</p></body></html>`))
+		htmlRepo.Save("noindex", []byte(`
+		<html>
+			<head>
+				<meta name="ROBOTS" content="NOINDEX, follow">
+			</head>
+
+			<body>
+				<p>This is a test page.</p>
+			</body>
+		</html>
+	`))
+
+		if err := s.Index("page1", "http://example.com", "indexed"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if err := s.Index("page1", "http://example.com", "noindex"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		occurrences, err := keywordService.GetForPageID("page1")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(occurrences) != 0 {
+			t.Errorf("Expected no keyword occurrences, got %v", occurrences)
+		}
+
+		page, err := pageRepo.Get("page1")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if !page.Removed {
+			t.Errorf("Expected the page to be marked removed")
+		}
+
+		if err := s.Index("page1", "http://example.com", "indexed"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		page, _ = pageRepo.Get("page1")
+
+		if page.Removed {
+			t.Errorf("Expected the page to be indexed again once it allows it")
+		}
+	})
+
+	t.Run("does not create pages that ask not to be indexed", func(t *testing.T) {
+		pageRepo, pageService, htmlRepo, htmlService, peerService, keywordService := setupTestRepos()
+
+		logger := testutil.NewTestLogger()
+		s := service.NewService(pageService, htmlService, peerService, keywordService, logger)
+
+		htmlRepo.Save("noindex", []byte(`<html><head><meta name="robots" content="noindex"></head><body><p>Secret.</p></body></html>`))
+
+		if err := s.Index("page1", "http://example.com", "noindex"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if _, err := pageRepo.Get("page1"); err != domain.ErrPageNotFound {
+			t.Errorf("Expected no page to be created, got %v", err)
+		}
+	})
+}
+
+func TestIndexWithAnchors(t *testing.T) {
+	t.Run("indexes anchor text as keywords", func(t *testing.T) {
+		pageRepo, pageService, htmlRepo, htmlService, peerService, keywordService := setupTestRepos()
+
+		logger := testutil.NewTestLogger()
+		s := service.NewService(pageService, htmlService, peerService, keywordService, logger)
+
+		pageRepo.Save("page1", &domain.Page{
+			ID:  "page1",
+			URL: "http://example.com",
+		})
+
+		htmlRepo.Save("hash", []byte(`
+		<html>
+			<head>
+				<title>Test Page</title>
+			</head>
+
+			<body>
+				<h1>Test Page</h1>
+				<p>This is a test page.</p>
+			</body>
+		</html>
+	`))
+
+		err := s.IndexWithAnchors("page1", "http://example.com", "hash", []string{"Pasta", "pasta"})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		occurrences, err := keywordService.GetForPageID("page1")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		pasta, ok := occurrences["pasta"]
+
+		if !ok {
+			t.Fatalf("Expected anchor keyword to be indexed, got %v", occurrences)
+		}
+
+		if pasta.Frequency != 2 {
+			t.Errorf("Expected frequency 2, got %d", pasta.Frequency)
+		}
+
+		// anchors are kept apart by a skipped position
+		if len(pasta.Positions) != 2 || pasta.Positions[1]-pasta.Positions[0] != 2 {
+			t.Errorf("Expected anchors two positions apart, got %v", pasta.Positions)
+		}
+	})
+}
+
 func TestApplyPageUpdatedEvent(t *testing.T) {
 	t.Run("updates a page", func(t *testing.T) {
 		pageRepo, pageService, htmlRepo, htmlService, peerService, keywordService := setupTestRepos()
@@ -7,6 +7,14 @@ func MakeKeywordOccurrences(keywords []domain.Keyword, pageID string) (map[domai
 	// Update occurrences
 	keywordOccurrences := make(map[domain.Keyword]domain.KeywordOccurrence, 0)
 
+	AddKeywordOccurrences(keywordOccurrences, keywords, pageID, 0)
+
+	return keywordOccurrences, nil
+}
+
+// AddKeywordOccurrences records the keywords in occurrences at positions
+// counting up from start.
+func AddKeywordOccurrences(keywordOccurrences map[domain.Keyword]domain.KeywordOccurrence, keywords []domain.Keyword, pageID string, start int) {
 	for i, keyword := range keywords {
 
 		// Get the current occurrence or initialize a new one
@@ -15,17 +23,15 @@ func MakeKeywordOccurrences(keywords []domain.Keyword, pageID string) (map[domai
 			occurrence = domain.KeywordOccurrence{
 				PageID:    pageID,
 				Frequency: 1,
-				Positions: []int{i},
+				Positions: []int{start + i},
 			}
 		} else {
 			// Update the existing occurrence
 			occurrence.Frequency += 1
-			occurrence.Positions = append(occurrence.Positions, i)
+			occurrence.Positions = append(occurrence.Positions, start+i)
 		}
 
 		// Put the updated occurrence back into the map
 		keywordOccurrences[keyword] = occurrence
 	}
-
-	return keywordOccurrences, nil
 }
@@ -2,6 +2,7 @@ package bolt
 
 import (
 	"crawlquery/node/domain"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 
@@ -10,14 +11,41 @@ import (
 
 var occurrencesBucket = []byte("occurrences")
 
+// statsBucket holds the token count of every page, keyed by page id, and
+// the collection totals under the keys below, so scoring doesn't have to
+// scan every occurrence.
+var statsBucket = []byte("occurrence_stats")
+
+// invertedMetaBucket and convertedKey are where the inverted layout marks
+// that it has converted this layout's occurrences. Writing here clears the
+// mark so that converting again picks the writes up.
+var invertedMetaBucket = []byte("meta")
+var convertedKey = []byte("converted_occurrences")
+
+var totalTokensKey = []byte("\x00total_tokens")
+var totalPagesKey = []byte("\x00total_pages")
+
 type Repository struct {
 	db *bolt.DB
 }
 
 func NewRepository(db *bolt.DB) (*Repository, error) {
 	err := db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists(occurrencesBucket)
-		return err
+		bucket, err := tx.CreateBucketIfNotExists(occurrencesBucket)
+		if err != nil {
+			return err
+		}
+
+		if tx.Bucket(statsBucket) != nil {
+			return nil
+		}
+
+		stats, err := tx.CreateBucket(statsBucket)
+		if err != nil {
+			return err
+		}
+
+		return backfillStats(bucket, stats)
 	})
 	if err != nil {
 		return nil, err
@@ -26,6 +54,79 @@ func NewRepository(db *bolt.DB) (*Repository, error) {
 	return &Repository{db: db}, nil
 }
 
+// backfillStats computes the stats of a db written before they were kept.
+func backfillStats(bucket, stats *bolt.Bucket) error {
+	return bucket.ForEach(func(k, v []byte) error {
+		var occurrences []domain.KeywordOccurrence
+		if err := json.Unmarshal(v, &occurrences); err != nil {
+			return err
+		}
+
+		for _, occ := range occurrences {
+			if err := addTokens(stats, occ.PageID, occ.Frequency); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func getCounter(bucket *bolt.Bucket, key []byte) int {
+	data := bucket.Get(key)
+	if data == nil {
+		return 0
+	}
+	v, n := binary.Varint(data)
+	if n <= 0 {
+		return 0
+	}
+	return int(v)
+}
+
+func putCounter(bucket *bolt.Bucket, key []byte, v int) error {
+	return bucket.Put(key, binary.AppendVarint(nil, int64(v)))
+}
+
+// addTokens adds tokens to a page, counting it towards the total pages the
+// first time it gets any.
+func addTokens(stats *bolt.Bucket, pageID string, tokens int) error {
+	if tokens == 0 {
+		return nil
+	}
+
+	count := getCounter(stats, []byte(pageID))
+	if count == 0 {
+		if err := putCounter(stats, totalPagesKey, getCounter(stats, totalPagesKey)+1); err != nil {
+			return err
+		}
+	}
+
+	if err := putCounter(stats, []byte(pageID), count+tokens); err != nil {
+		return err
+	}
+
+	return putCounter(stats, totalTokensKey, getCounter(stats, totalTokensKey)+tokens)
+}
+
+// removeTokens drops a page and its tokens from the stats.
+func removeTokens(stats *bolt.Bucket, pageID string) error {
+	count := getCounter(stats, []byte(pageID))
+	if count == 0 {
+		return nil
+	}
+
+	if err := putCounter(stats, totalPagesKey, getCounter(stats, totalPagesKey)-1); err != nil {
+		return err
+	}
+
+	if err := putCounter(stats, totalTokensKey, getCounter(stats, totalTokensKey)-count); err != nil {
+		return err
+	}
+
+	return stats.Delete([]byte(pageID))
+}
+
 func (r *Repository) GetAll(keyword domain.Keyword) ([]domain.KeywordOccurrence, error) {
 	var occurrences []domain.KeywordOccurrence
 
@@ -107,10 +208,27 @@ func (r *Repository) Add(keyword domain.Keyword, occurrence domain.KeywordOccurr
 			return err
 		}
 
+		if err := addTokens(tx.Bucket(statsBucket), occurrence.PageID, occurrence.Frequency); err != nil {
+			return err
+		}
+
+		if err := clearConverted(tx); err != nil {
+			return err
+		}
+
 		return bucket.Put([]byte(keyword), encoded)
 	})
 }
 
+// clearConverted drops the inverted layout's conversion mark, if any.
+func clearConverted(tx *bolt.Tx) error {
+	meta := tx.Bucket(invertedMetaBucket)
+	if meta == nil {
+		return nil
+	}
+	return meta.Delete(convertedKey)
+}
+
 func (r *Repository) RemoveForPageID(pageID string) error {
 	return r.db.Update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket(occurrencesBucket)
@@ -118,6 +236,10 @@ func (r *Repository) RemoveForPageID(pageID string) error {
 			return errors.New("bucket not found")
 		}
 
+		if err := clearConverted(tx); err != nil {
+			return err
+		}
+
 		err := bucket.ForEach(func(k, v []byte) error {
 			var occurrences []domain.KeywordOccurrence
 			err := json.Unmarshal(v, &occurrences)
@@ -143,7 +265,11 @@ func (r *Repository) RemoveForPageID(pageID string) error {
 			return bucket.Delete(k)
 		})
 
-		return err
+		if err != nil {
+			return err
+		}
+
+		return removeTokens(tx.Bucket(statsBucket), pageID)
 	})
 }
 
@@ -168,3 +294,44 @@ func (r *Repository) Count() (int, error) {
 
 	return count, nil
 }
+
+func (r *Repository) PageTokenCount(pageID string) (int, error) {
+	var count int
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		stats := tx.Bucket(statsBucket)
+		if stats == nil {
+			return nil
+		}
+
+		count = getCounter(stats, []byte(pageID))
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *Repository) Stats() (*domain.KeywordStats, error) {
+	stats := &domain.KeywordStats{}
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(statsBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		stats.TotalPages = getCounter(bucket, totalPagesKey)
+		stats.TotalTokens = getCounter(bucket, totalTokensKey)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
@@ -1,15 +1,22 @@
 package bolt_test
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"reflect"
 	"testing"
 
 	"crawlquery/node/domain"
+	"crawlquery/node/keyword"
 	occRepo "crawlquery/node/keyword/occurrence/repository/bolt"
+	memRepo "crawlquery/node/keyword/occurrence/repository/mem"
+	keywordService "crawlquery/node/keyword/service"
+	"crawlquery/node/parse"
 
+	"github.com/PuerkitoBio/goquery"
 	"github.com/boltdb/bolt"
+	testdataloader "github.com/peteole/testdata-loader"
 )
 
 func setupTestDB(t *testing.T) *bolt.DB {
@@ -229,3 +236,186 @@ func TestCount(t *testing.T) {
 		t.Errorf("Expected count 5, got %d", count)
 	}
 }
+
+func TestStats(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	repo, err := occRepo.NewRepository(db)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	occurrences := map[domain.Keyword][]domain.KeywordOccurrence{
+		"example": {
+			{PageID: "page1", Frequency: 3, Positions: []int{1, 2, 3}},
+			{PageID: "page2", Frequency: 2, Positions: []int{4, 5}},
+		},
+		"other": {
+			{PageID: "page1", Frequency: 1, Positions: []int{4}},
+		},
+	}
+
+	for keyword, occs := range occurrences {
+		for _, occ := range occs {
+			if err := repo.Add(keyword, occ); err != nil {
+				t.Fatalf("Error adding occurrence: %v", err)
+			}
+		}
+	}
+
+	count, err := repo.PageTokenCount("page1")
+	if err != nil {
+		t.Fatalf("Error getting page token count: %v", err)
+	}
+
+	if count != 4 {
+		t.Errorf("Expected page token count 4, got %d", count)
+	}
+
+	stats, err := repo.Stats()
+	if err != nil {
+		t.Fatalf("Error getting stats: %v", err)
+	}
+
+	if stats.TotalPages != 2 {
+		t.Errorf("Expected 2 total pages, got %d", stats.TotalPages)
+	}
+
+	if stats.TotalTokens != 6 {
+		t.Errorf("Expected 6 total tokens, got %d", stats.TotalTokens)
+	}
+
+	if err := repo.RemoveForPageID("page1"); err != nil {
+		t.Fatalf("Error removing occurrences: %v", err)
+	}
+
+	count, err = repo.PageTokenCount("page1")
+	if err != nil {
+		t.Fatalf("Error getting page token count: %v", err)
+	}
+
+	if count != 0 {
+		t.Errorf("Expected page token count 0, got %d", count)
+	}
+
+	stats, err = repo.Stats()
+	if err != nil {
+		t.Fatalf("Error getting stats: %v", err)
+	}
+
+	if stats.TotalPages != 1 || stats.TotalTokens != 2 {
+		t.Errorf("Expected 1 page and 2 tokens, got %+v", stats)
+	}
+}
+
+func TestStatsMatchFullScanOnTestdataPages(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	// Every Add is its own transaction; skip the fsyncs.
+	db.NoSync = true
+
+	repo, err := occRepo.NewRepository(db)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	// The mem repository scans every occurrence, so it's the reference.
+	boltService := keywordService.NewService(repo)
+	memService := keywordService.NewService(memRepo.NewRepository())
+
+	pages := map[string]string{
+		"google":    "testdata/pages/google/search.html",
+		"bolognese": "testdata/pages/recipe/how-to-make-bolognese-sauce.html",
+		"bots":      "testdata/pages/stackoverflow/best-way-to-detect-bot-from-user-agent.html",
+	}
+
+	for pageID, path := range pages {
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(testdataloader.GetTestFile(path)))
+		if err != nil {
+			t.Fatalf("Error parsing %s: %v", path, err)
+		}
+
+		keywords, err := parse.Keywords(doc)
+		if err != nil {
+			t.Fatalf("Error parsing keywords for %s: %v", path, err)
+		}
+
+		occurrences, err := keyword.MakeKeywordOccurrences(keywords, pageID)
+		if err != nil {
+			t.Fatalf("Error making occurrences for %s: %v", path, err)
+		}
+
+		for _, svc := range []*keywordService.Service{boltService, memService} {
+			if err := svc.UpdateOccurrences(pageID, occurrences); err != nil {
+				t.Fatalf("Error updating occurrences for %s: %v", path, err)
+			}
+		}
+	}
+
+	// Reindexing and removing pages must keep the stats in step too.
+	if err := boltService.UpdateOccurrences("google", map[domain.Keyword]domain.KeywordOccurrence{
+		"google": {PageID: "google", Frequency: 2, Positions: []int{0, 1}},
+	}); err != nil {
+		t.Fatalf("Error reindexing page: %v", err)
+	}
+	if err := memService.UpdateOccurrences("google", map[domain.Keyword]domain.KeywordOccurrence{
+		"google": {PageID: "google", Frequency: 2, Positions: []int{0, 1}},
+	}); err != nil {
+		t.Fatalf("Error reindexing page: %v", err)
+	}
+
+	for _, svc := range []*keywordService.Service{boltService, memService} {
+		if err := svc.UpdateOccurrences("bots", map[domain.Keyword]domain.KeywordOccurrence{}); err != nil {
+			t.Fatalf("Error removing page: %v", err)
+		}
+	}
+
+	want, err := memService.Stats()
+	if err != nil {
+		t.Fatalf("Error getting reference stats: %v", err)
+	}
+
+	got, err := boltService.Stats()
+	if err != nil {
+		t.Fatalf("Error getting stats: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected stats %+v, got %+v", want, got)
+	}
+
+	for pageID := range pages {
+		want, _ := memService.PageTokenCount(pageID)
+		got, err := boltService.PageTokenCount(pageID)
+		if err != nil {
+			t.Fatalf("Error getting page token count: %v", err)
+		}
+
+		if got != want {
+			t.Errorf("Expected %d tokens for %s, got %d", want, pageID, got)
+		}
+	}
+
+	// Reopening a db written before stats were kept backfills them.
+	if err := db.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket([]byte("occurrence_stats"))
+	}); err != nil {
+		t.Fatalf("Error dropping stats: %v", err)
+	}
+
+	repo, err = occRepo.NewRepository(db)
+	if err != nil {
+		t.Fatalf("Failed to reopen repository: %v", err)
+	}
+
+	got, err = repo.Stats()
+	if err != nil {
+		t.Fatalf("Error getting stats: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected backfilled stats %+v, got %+v", want, got)
+	}
+}
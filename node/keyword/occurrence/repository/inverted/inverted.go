@@ -0,0 +1,405 @@
+package inverted
+
+import (
+	"crawlquery/node/domain"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+
+	"github.com/boltdb/bolt"
+)
+
+var postingsBucket = []byte("postings")
+var forwardBucket = []byte("forward")
+var lengthsBucket = []byte("lengths")
+var metaBucket = []byte("meta")
+
+var totalTokensKey = []byte("total_tokens")
+
+// convertedKey marks a db whose legacy occurrences have been converted. The
+// bolt layout deletes it under the same name when it writes.
+var convertedKey = []byte("converted_occurrences")
+
+// legacyOccurrencesBucket is where the bolt layout keeps a JSON list of
+// occurrences per keyword.
+var legacyOccurrencesBucket = []byte("occurrences")
+
+// Repository stores keyword occurrences as an inverted index. Each keyword
+// maps to a binary posting list and each page maps to the keywords it
+// appears under, so updating or removing a page only rewrites the keys
+// that page touches. Page token counts and the collection total are kept
+// up to date alongside for scoring.
+type Repository struct {
+	db *bolt.DB
+}
+
+var invertedBuckets = [][]byte{postingsBucket, forwardBucket, lengthsBucket, metaBucket}
+
+func NewRepository(db *bolt.DB) (*Repository, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range invertedBuckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Repository{db: db}, nil
+}
+
+// ConvertLegacy rebuilds the inverted buckets from the occurrences written
+// by the bolt layout, so nodes switching over keep their index. Anything
+// already in the inverted buckets is replaced. It does nothing when the
+// legacy bucket hasn't been written to since the last conversion; the bolt
+// layout clears the marker whenever it writes.
+func ConvertLegacy(db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		if meta := tx.Bucket(metaBucket); meta != nil && meta.Get(convertedKey) != nil {
+			return nil
+		}
+
+		for _, name := range invertedBuckets {
+			if tx.Bucket(name) != nil {
+				if err := tx.DeleteBucket(name); err != nil {
+					return err
+				}
+			}
+			if _, err := tx.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+
+		return convertLegacy(tx)
+	})
+}
+
+func convertLegacy(tx *bolt.Tx) error {
+	meta := tx.Bucket(metaBucket)
+
+	legacy := tx.Bucket(legacyOccurrencesBucket)
+	if legacy != nil {
+		postings, forward, err := buckets(tx)
+		if err != nil {
+			return err
+		}
+
+		err = legacy.ForEach(func(k, v []byte) error {
+			var occurrences []domain.KeywordOccurrence
+			if err := json.Unmarshal(v, &occurrences); err != nil {
+				return err
+			}
+
+			keyword := domain.Keyword(k)
+
+			pl, err := getPostingList(postings, keyword)
+			if err != nil {
+				return err
+			}
+
+			for _, occurrence := range occurrences {
+				delta := occurrence.Frequency
+				if i, ok := pl.find(occurrence.PageID); ok {
+					delta -= pl[i].Frequency
+				}
+				pl = pl.upsert(occurrence)
+
+				if err := addTokens(tx, occurrence.PageID, delta); err != nil {
+					return err
+				}
+
+				keywords, err := getForwardList(forward, occurrence.PageID)
+				if err != nil {
+					return err
+				}
+				if err := forward.Put([]byte(occurrence.PageID), keywords.add(keyword).encode()); err != nil {
+					return err
+				}
+			}
+
+			if len(pl) == 0 {
+				return nil
+			}
+
+			return postings.Put(k, pl.encode())
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return meta.Put(convertedKey, []byte{1})
+}
+
+func buckets(tx *bolt.Tx) (*bolt.Bucket, *bolt.Bucket, error) {
+	postings := tx.Bucket(postingsBucket)
+	forward := tx.Bucket(forwardBucket)
+	if postings == nil || forward == nil {
+		return nil, nil, errors.New("bucket not found")
+	}
+	return postings, forward, nil
+}
+
+func getCounter(bucket *bolt.Bucket, key []byte) int {
+	data := bucket.Get(key)
+	if data == nil {
+		return 0
+	}
+	v, n := binary.Varint(data)
+	if n <= 0 {
+		return 0
+	}
+	return int(v)
+}
+
+func putCounter(bucket *bolt.Bucket, key []byte, v int) error {
+	return bucket.Put(key, binary.AppendVarint(nil, int64(v)))
+}
+
+// addTokens adjusts the token count of a page and of the whole collection.
+func addTokens(tx *bolt.Tx, pageID string, delta int) error {
+	if delta == 0 {
+		return nil
+	}
+
+	lengths := tx.Bucket(lengthsBucket)
+	meta := tx.Bucket(metaBucket)
+	if lengths == nil || meta == nil {
+		return errors.New("bucket not found")
+	}
+
+	if err := putCounter(lengths, []byte(pageID), getCounter(lengths, []byte(pageID))+delta); err != nil {
+		return err
+	}
+
+	return putCounter(meta, totalTokensKey, getCounter(meta, totalTokensKey)+delta)
+}
+
+func getPostingList(bucket *bolt.Bucket, keyword domain.Keyword) (postingList, error) {
+	data := bucket.Get([]byte(keyword))
+	if data == nil {
+		return nil, nil
+	}
+	return decodePostingList(data)
+}
+
+func getForwardList(bucket *bolt.Bucket, pageID string) (forwardList, error) {
+	data := bucket.Get([]byte(pageID))
+	if data == nil {
+		return nil, nil
+	}
+	return decodeForwardList(data)
+}
+
+func (r *Repository) GetAll(keyword domain.Keyword) ([]domain.KeywordOccurrence, error) {
+	var occurrences []domain.KeywordOccurrence
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		postings, _, err := buckets(tx)
+		if err != nil {
+			return err
+		}
+
+		pl, err := getPostingList(postings, keyword)
+		if err != nil {
+			return err
+		}
+
+		if len(pl) == 0 {
+			return domain.ErrKeywordNotFound
+		}
+
+		occurrences = pl
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return occurrences, nil
+}
+
+func (r *Repository) GetForPageID(pageID string) (map[domain.Keyword]domain.KeywordOccurrence, error) {
+	occurrences := make(map[domain.Keyword]domain.KeywordOccurrence)
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		postings, forward, err := buckets(tx)
+		if err != nil {
+			return err
+		}
+
+		keywords, err := getForwardList(forward, pageID)
+		if err != nil {
+			return err
+		}
+
+		for _, keyword := range keywords {
+			pl, err := getPostingList(postings, keyword)
+			if err != nil {
+				return err
+			}
+
+			if i, ok := pl.find(pageID); ok {
+				occurrences[keyword] = pl[i]
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return occurrences, nil
+}
+
+func (r *Repository) Add(keyword domain.Keyword, occurrence domain.KeywordOccurrence) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		postings, forward, err := buckets(tx)
+		if err != nil {
+			return err
+		}
+
+		pl, err := getPostingList(postings, keyword)
+		if err != nil {
+			return err
+		}
+
+		delta := occurrence.Frequency
+		if i, ok := pl.find(occurrence.PageID); ok {
+			delta -= pl[i].Frequency
+		}
+
+		pl = pl.upsert(occurrence)
+
+		if err := addTokens(tx, occurrence.PageID, delta); err != nil {
+			return err
+		}
+
+		if err := postings.Put([]byte(keyword), pl.encode()); err != nil {
+			return err
+		}
+
+		keywords, err := getForwardList(forward, occurrence.PageID)
+		if err != nil {
+			return err
+		}
+
+		return forward.Put([]byte(occurrence.PageID), keywords.add(keyword).encode())
+	})
+}
+
+func (r *Repository) RemoveForPageID(pageID string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		postings, forward, err := buckets(tx)
+		if err != nil {
+			return err
+		}
+
+		keywords, err := getForwardList(forward, pageID)
+		if err != nil {
+			return err
+		}
+
+		for _, keyword := range keywords {
+			pl, err := getPostingList(postings, keyword)
+			if err != nil {
+				return err
+			}
+
+			pl = pl.remove(pageID)
+
+			if len(pl) == 0 {
+				err = postings.Delete([]byte(keyword))
+			} else {
+				err = postings.Put([]byte(keyword), pl.encode())
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		lengths := tx.Bucket(lengthsBucket)
+		if lengths == nil {
+			return errors.New("bucket not found")
+		}
+
+		if err := addTokens(tx, pageID, -getCounter(lengths, []byte(pageID))); err != nil {
+			return err
+		}
+
+		if err := lengths.Delete([]byte(pageID)); err != nil {
+			return err
+		}
+
+		return forward.Delete([]byte(pageID))
+	})
+}
+
+func (r *Repository) Count() (int, error) {
+	var count int
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		postings, _, err := buckets(tx)
+		if err != nil {
+			return err
+		}
+
+		count = postings.Stats().KeyN
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *Repository) PageTokenCount(pageID string) (int, error) {
+	var count int
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		lengths := tx.Bucket(lengthsBucket)
+		if lengths == nil {
+			return errors.New("bucket not found")
+		}
+
+		count = getCounter(lengths, []byte(pageID))
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *Repository) Stats() (*domain.KeywordStats, error) {
+	stats := &domain.KeywordStats{}
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		lengths := tx.Bucket(lengthsBucket)
+		meta := tx.Bucket(metaBucket)
+		if lengths == nil || meta == nil {
+			return errors.New("bucket not found")
+		}
+
+		stats.TotalPages = lengths.Stats().KeyN
+		stats.TotalTokens = getCounter(meta, totalTokensKey)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
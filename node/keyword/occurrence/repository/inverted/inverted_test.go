@@ -0,0 +1,529 @@
+package inverted_test
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+
+	"crawlquery/node/domain"
+	legacyRepo "crawlquery/node/keyword/occurrence/repository/bolt"
+	occRepo "crawlquery/node/keyword/occurrence/repository/inverted"
+
+	"github.com/boltdb/bolt"
+)
+
+func setupTestDB(t *testing.T) *bolt.DB {
+	db, err := bolt.Open("test.db", 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to open BoltDB: %v", err)
+	}
+	return db
+}
+
+func teardownTestDB(db *bolt.DB) {
+	db.Close()
+	os.Remove("test.db")
+}
+
+func TestGet(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	repo, err := occRepo.NewRepository(db)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	keyword := domain.Keyword("example")
+	occurrences := []domain.KeywordOccurrence{
+		{PageID: "page1", Frequency: 3, Positions: []int{1, 2, 3}},
+		{PageID: "page2", Frequency: 2, Positions: []int{4, 5}},
+	}
+
+	for _, occ := range occurrences {
+		err := repo.Add(keyword, occ)
+		if err != nil {
+			t.Fatalf("Error adding occurrence: %v", err)
+		}
+	}
+
+	gotOccurrences, err := repo.GetAll(keyword)
+	if err != nil {
+		t.Fatalf("Error getting occurrences: %v", err)
+	}
+
+	if !reflect.DeepEqual(gotOccurrences, occurrences) {
+		t.Errorf("Expected occurrences %v, got %v", occurrences, gotOccurrences)
+	}
+
+	_, err = repo.GetAll(domain.Keyword("nonexistent"))
+	if err != domain.ErrKeywordNotFound {
+		t.Errorf("Expected error %v, got %v", domain.ErrKeywordNotFound, err)
+	}
+}
+
+func TestGetForPageID(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	repo, err := occRepo.NewRepository(db)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	keyword := domain.Keyword("example")
+	keyword2 := domain.Keyword("example2")
+	occurrences := map[domain.Keyword]domain.KeywordOccurrence{
+		keyword:  {PageID: "page1", Frequency: 3, Positions: []int{1, 2, 3}},
+		keyword2: {PageID: "page2", Frequency: 2, Positions: []int{4, 5}},
+	}
+
+	for _, occ := range occurrences {
+		err := repo.Add(keyword, occ)
+		if err != nil {
+			t.Fatalf("Error adding occurrence: %v", err)
+		}
+	}
+
+	gotOccurrences, err := repo.GetForPageID("page1")
+	if err != nil {
+		t.Fatalf("Error getting occurrences: %v", err)
+	}
+
+	expectedOccurrences := map[domain.Keyword]domain.KeywordOccurrence{
+		keyword: {PageID: "page1", Frequency: 3, Positions: []int{1, 2, 3}},
+	}
+
+	if !reflect.DeepEqual(gotOccurrences, expectedOccurrences) {
+		t.Errorf("Expected occurrences %v, got %v", expectedOccurrences, gotOccurrences)
+	}
+
+	empty, err := repo.GetForPageID("nonexistent")
+
+	if err != nil {
+		t.Fatalf("Error getting occurrences: %v", err)
+	}
+
+	if len(empty) != 0 {
+		t.Errorf("Expected 0 occurrences, got %v", len(empty))
+	}
+
+}
+
+func TestAddOccurence(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	repo, err := occRepo.NewRepository(db)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	keyword := domain.Keyword("example")
+	occurrence := domain.KeywordOccurrence{PageID: "page1", Frequency: 1, Positions: []int{1}}
+
+	err = repo.Add(keyword, occurrence)
+	if err != nil {
+		t.Fatalf("Error adding occurrence: %v", err)
+	}
+
+	gotOccurrences, err := repo.GetAll(keyword)
+	if err != nil {
+		t.Fatalf("Error getting occurrences: %v", err)
+	}
+
+	if len(gotOccurrences) != 1 {
+		t.Fatalf("Expected 1 occurrence, got %d", len(gotOccurrences))
+	}
+
+	if !reflect.DeepEqual(gotOccurrences[0], occurrence) {
+		t.Errorf("Expected occurrence %v, got %v", occurrence, gotOccurrences[0])
+	}
+}
+
+func TestRemoveOccurencesForPageID(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	repo, err := occRepo.NewRepository(db)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	keyword := domain.Keyword("example")
+	occurrences := []domain.KeywordOccurrence{
+		{PageID: "page1", Frequency: 3, Positions: []int{1, 2, 3}},
+		{PageID: "page2", Frequency: 2, Positions: []int{4, 5}},
+	}
+
+	for _, occ := range occurrences {
+		err := repo.Add(keyword, occ)
+		if err != nil {
+			t.Fatalf("Error adding occurrence: %v", err)
+		}
+	}
+
+	err = repo.RemoveForPageID("page1")
+	if err != nil {
+		t.Fatalf("Error removing occurrences: %v", err)
+	}
+
+	gotOccurrences, err := repo.GetAll(keyword)
+	if err != nil {
+		t.Fatalf("Error getting occurrences: %v", err)
+	}
+
+	expectedOccurrences := []domain.KeywordOccurrence{
+		{PageID: "page2", Frequency: 2, Positions: []int{4, 5}},
+	}
+
+	if !reflect.DeepEqual(gotOccurrences, expectedOccurrences) {
+		t.Errorf("Expected occurrences %v, got %v", expectedOccurrences, gotOccurrences)
+	}
+
+	err = repo.RemoveForPageID("page2")
+	if err != nil {
+		t.Fatalf("Error removing occurrences: %v", err)
+	}
+
+	_, err = repo.GetAll(keyword)
+
+	if err != domain.ErrKeywordNotFound {
+		t.Errorf("Expected error %v, got %v", domain.ErrKeywordNotFound, err)
+	}
+}
+
+func TestCount(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	repo, err := occRepo.NewRepository(db)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	keywords := []domain.Keyword{"example1", "example2", "example3", "example4", "example5"}
+
+	for i, keyword := range keywords {
+		for j := 0; j < i+1; j++ {
+			occurrence := domain.KeywordOccurrence{
+				PageID:    fmt.Sprintf("page%d", i+1),
+				Frequency: 1,
+				Positions: []int{1},
+			}
+
+			err := repo.Add(keyword, occurrence)
+
+			if err != nil {
+				t.Fatalf("Error adding occurrence: %v", err)
+			}
+		}
+	}
+
+	count, err := repo.Count()
+	if err != nil {
+		t.Fatalf("Error counting occurrences: %v", err)
+	}
+
+	if count != 5 {
+		t.Errorf("Expected count 5, got %d", count)
+	}
+}
+
+func TestGetAllSortedByPageID(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	repo, err := occRepo.NewRepository(db)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	keyword := domain.Keyword("example")
+
+	for _, pageID := range []string{"page3", "page1", "page2"} {
+		err := repo.Add(keyword, domain.KeywordOccurrence{PageID: pageID, Frequency: 1, Positions: []int{1}})
+		if err != nil {
+			t.Fatalf("Error adding occurrence: %v", err)
+		}
+	}
+
+	gotOccurrences, err := repo.GetAll(keyword)
+	if err != nil {
+		t.Fatalf("Error getting occurrences: %v", err)
+	}
+
+	for i, pageID := range []string{"page1", "page2", "page3"} {
+		if gotOccurrences[i].PageID != pageID {
+			t.Errorf("Expected page ID %s at %d, got %s", pageID, i, gotOccurrences[i].PageID)
+		}
+	}
+}
+
+func TestAddReplacesOccurrenceForPage(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	repo, err := occRepo.NewRepository(db)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	keyword := domain.Keyword("example")
+
+	err = repo.Add(keyword, domain.KeywordOccurrence{PageID: "page1", Frequency: 1, Positions: []int{1}})
+	if err != nil {
+		t.Fatalf("Error adding occurrence: %v", err)
+	}
+
+	updated := domain.KeywordOccurrence{PageID: "page1", Frequency: 2, Positions: []int{7, 9}}
+
+	err = repo.Add(keyword, updated)
+	if err != nil {
+		t.Fatalf("Error adding occurrence: %v", err)
+	}
+
+	gotOccurrences, err := repo.GetAll(keyword)
+	if err != nil {
+		t.Fatalf("Error getting occurrences: %v", err)
+	}
+
+	if !reflect.DeepEqual(gotOccurrences, []domain.KeywordOccurrence{updated}) {
+		t.Errorf("Expected occurrences %v, got %v", []domain.KeywordOccurrence{updated}, gotOccurrences)
+	}
+}
+
+func TestRemoveForPageIDOnlyTouchesPageKeywords(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	repo, err := occRepo.NewRepository(db)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	occurrences := map[domain.Keyword]domain.KeywordOccurrence{
+		"shared": {PageID: "page1", Frequency: 1, Positions: []int{0}},
+		"only1":  {PageID: "page1", Frequency: 1, Positions: []int{1}},
+	}
+
+	for keyword, occ := range occurrences {
+		if err := repo.Add(keyword, occ); err != nil {
+			t.Fatalf("Error adding occurrence: %v", err)
+		}
+	}
+
+	if err := repo.Add("shared", domain.KeywordOccurrence{PageID: "page2", Frequency: 1, Positions: []int{3}}); err != nil {
+		t.Fatalf("Error adding occurrence: %v", err)
+	}
+
+	if err := repo.RemoveForPageID("page1"); err != nil {
+		t.Fatalf("Error removing occurrences: %v", err)
+	}
+
+	if _, err := repo.GetAll("only1"); err != domain.ErrKeywordNotFound {
+		t.Errorf("Expected error %v, got %v", domain.ErrKeywordNotFound, err)
+	}
+
+	page1, err := repo.GetForPageID("page1")
+	if err != nil {
+		t.Fatalf("Error getting occurrences: %v", err)
+	}
+
+	if len(page1) != 0 {
+		t.Errorf("Expected no occurrences for page1, got %v", page1)
+	}
+
+	page2, err := repo.GetForPageID("page2")
+	if err != nil {
+		t.Fatalf("Error getting occurrences: %v", err)
+	}
+
+	if len(page2) != 1 {
+		t.Errorf("Expected 1 occurrence for page2, got %v", page2)
+	}
+
+	count, err := repo.Count()
+	if err != nil {
+		t.Fatalf("Error counting keywords: %v", err)
+	}
+
+	if count != 1 {
+		t.Errorf("Expected count 1, got %d", count)
+	}
+}
+
+func TestStats(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	repo, err := occRepo.NewRepository(db)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	occurrences := map[domain.Keyword][]domain.KeywordOccurrence{
+		"example": {
+			{PageID: "page1", Frequency: 3, Positions: []int{1, 2, 3}},
+			{PageID: "page2", Frequency: 2, Positions: []int{4, 5}},
+		},
+		"other": {
+			{PageID: "page1", Frequency: 1, Positions: []int{4}},
+		},
+	}
+
+	for keyword, occs := range occurrences {
+		for _, occ := range occs {
+			if err := repo.Add(keyword, occ); err != nil {
+				t.Fatalf("Error adding occurrence: %v", err)
+			}
+		}
+	}
+
+	count, err := repo.PageTokenCount("page1")
+	if err != nil {
+		t.Fatalf("Error getting page token count: %v", err)
+	}
+
+	if count != 4 {
+		t.Errorf("Expected page token count 4, got %d", count)
+	}
+
+	stats, err := repo.Stats()
+	if err != nil {
+		t.Fatalf("Error getting stats: %v", err)
+	}
+
+	if stats.TotalPages != 2 {
+		t.Errorf("Expected 2 total pages, got %d", stats.TotalPages)
+	}
+
+	if stats.TotalTokens != 6 {
+		t.Errorf("Expected 6 total tokens, got %d", stats.TotalTokens)
+	}
+
+	if err := repo.RemoveForPageID("page1"); err != nil {
+		t.Fatalf("Error removing occurrences: %v", err)
+	}
+
+	count, err = repo.PageTokenCount("page1")
+	if err != nil {
+		t.Fatalf("Error getting page token count: %v", err)
+	}
+
+	if count != 0 {
+		t.Errorf("Expected page token count 0, got %d", count)
+	}
+
+	stats, err = repo.Stats()
+	if err != nil {
+		t.Fatalf("Error getting stats: %v", err)
+	}
+
+	if stats.TotalPages != 1 || stats.TotalTokens != 2 {
+		t.Errorf("Expected 1 page and 2 tokens, got %+v", stats)
+	}
+}
+
+func TestConvertLegacy(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	legacy, err := legacyRepo.NewRepository(db)
+	if err != nil {
+		t.Fatalf("Failed to create legacy repository: %v", err)
+	}
+
+	occurrences := map[domain.Keyword][]domain.KeywordOccurrence{
+		"example": {
+			{PageID: "page1", Frequency: 3, Positions: []int{1, 2, 3}},
+			{PageID: "page2", Frequency: 2, Positions: []int{4, 5}},
+		},
+		"other": {
+			{PageID: "page1", Frequency: 1, Positions: []int{4}},
+		},
+	}
+
+	for keyword, occs := range occurrences {
+		for _, occ := range occs {
+			if err := legacy.Add(keyword, occ); err != nil {
+				t.Fatalf("Error adding occurrence: %v", err)
+			}
+		}
+	}
+
+	repo, err := occRepo.NewRepository(db)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	// Opening the repository must not convert on its own.
+	if count, _ := repo.PageTokenCount("page1"); count != 0 {
+		t.Errorf("Expected nothing converted before ConvertLegacy, got %d tokens for page1", count)
+	}
+
+	if err := occRepo.ConvertLegacy(db); err != nil {
+		t.Fatalf("Error converting: %v", err)
+	}
+
+	for keyword, want := range occurrences {
+		got, err := repo.GetAll(keyword)
+		if err != nil {
+			t.Fatalf("Error getting occurrences for %s: %v", keyword, err)
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected occurrences %v for %s, got %v", want, keyword, got)
+		}
+	}
+
+	forPage, err := repo.GetForPageID("page1")
+	if err != nil {
+		t.Fatalf("Error getting occurrences for page: %v", err)
+	}
+
+	if len(forPage) != 2 {
+		t.Errorf("Expected 2 keywords for page1, got %d", len(forPage))
+	}
+
+	stats, err := repo.Stats()
+	if err != nil {
+		t.Fatalf("Error getting stats: %v", err)
+	}
+
+	if stats.TotalPages != 2 || stats.TotalTokens != 6 {
+		t.Errorf("Expected 2 pages and 6 tokens, got %d and %d", stats.TotalPages, stats.TotalTokens)
+	}
+
+	// Converting again without legacy writes in between changes nothing.
+	if err := repo.RemoveForPageID("page1"); err != nil {
+		t.Fatalf("Error removing occurrences: %v", err)
+	}
+
+	if err := occRepo.ConvertLegacy(db); err != nil {
+		t.Fatalf("Error converting: %v", err)
+	}
+
+	if count, _ := repo.PageTokenCount("page1"); count != 0 {
+		t.Errorf("Expected page token count 0 after converting again, got %d", count)
+	}
+
+	// A legacy write makes the next conversion rebuild from the legacy bucket.
+	if err := legacy.Add("new", domain.KeywordOccurrence{PageID: "page3", Frequency: 1, Positions: []int{0}}); err != nil {
+		t.Fatalf("Error adding occurrence: %v", err)
+	}
+
+	if err := occRepo.ConvertLegacy(db); err != nil {
+		t.Fatalf("Error converting: %v", err)
+	}
+
+	stats, err = repo.Stats()
+	if err != nil {
+		t.Fatalf("Error getting stats: %v", err)
+	}
+
+	if stats.TotalPages != 3 || stats.TotalTokens != 7 {
+		t.Errorf("Expected 3 pages and 7 tokens after rebuilding, got %d and %d", stats.TotalPages, stats.TotalTokens)
+	}
+}
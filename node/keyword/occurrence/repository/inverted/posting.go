@@ -0,0 +1,228 @@
+package inverted
+
+import (
+	"crawlquery/node/domain"
+	"encoding/binary"
+	"errors"
+	"sort"
+)
+
+var errCorruptPostingList = errors.New("corrupt posting list")
+
+// A posting list holds every occurrence of one keyword, sorted by page ID.
+//
+// It is encoded as a uvarint entry count followed by one entry per page:
+//
+//	uvarint  length of the prefix shared with the previous page ID
+//	uvarint  length of the remaining suffix
+//	bytes    suffix
+//	uvarint  frequency
+//	uvarint  position count
+//	varint   positions, each as the delta from the previous position
+//
+// Page IDs are content hashes of similar URLs, so front coding the sorted IDs
+// and delta coding the positions keeps lists small.
+type postingList []domain.KeywordOccurrence
+
+// find returns the index of pageID in the list and whether it is present.
+func (pl postingList) find(pageID string) (int, bool) {
+	i := sort.Search(len(pl), func(i int) bool {
+		return pl[i].PageID >= pageID
+	})
+	return i, i < len(pl) && pl[i].PageID == pageID
+}
+
+// upsert inserts the occurrence keeping the list sorted, replacing any
+// existing entry for the same page.
+func (pl postingList) upsert(occurrence domain.KeywordOccurrence) postingList {
+	i, ok := pl.find(occurrence.PageID)
+	if ok {
+		pl[i] = occurrence
+		return pl
+	}
+
+	pl = append(pl, domain.KeywordOccurrence{})
+	copy(pl[i+1:], pl[i:])
+	pl[i] = occurrence
+	return pl
+}
+
+func (pl postingList) remove(pageID string) postingList {
+	i, ok := pl.find(pageID)
+	if !ok {
+		return pl
+	}
+	return append(pl[:i], pl[i+1:]...)
+}
+
+func sharedPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+func (pl postingList) encode() []byte {
+	buf := binary.AppendUvarint(nil, uint64(len(pl)))
+
+	prev := ""
+	for _, occ := range pl {
+		shared := sharedPrefix(prev, occ.PageID)
+		suffix := occ.PageID[shared:]
+
+		buf = binary.AppendUvarint(buf, uint64(shared))
+		buf = binary.AppendUvarint(buf, uint64(len(suffix)))
+		buf = append(buf, suffix...)
+		buf = binary.AppendUvarint(buf, uint64(occ.Frequency))
+		buf = binary.AppendUvarint(buf, uint64(len(occ.Positions)))
+
+		last := 0
+		for _, pos := range occ.Positions {
+			buf = binary.AppendVarint(buf, int64(pos-last))
+			last = pos
+		}
+
+		prev = occ.PageID
+	}
+
+	return buf
+}
+
+type reader struct {
+	buf []byte
+	err error
+}
+
+func (r *reader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.err = errCorruptPostingList
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *reader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.err = errCorruptPostingList
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *reader) bytes(n uint64) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if uint64(len(r.buf)) < n {
+		r.err = errCorruptPostingList
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func decodePostingList(data []byte) (postingList, error) {
+	r := &reader{buf: data}
+
+	count := r.uvarint()
+	if r.err != nil || count > uint64(len(data)) {
+		return nil, errCorruptPostingList
+	}
+
+	pl := make(postingList, 0, count)
+
+	prev := ""
+	for i := uint64(0); i < count; i++ {
+		shared := r.uvarint()
+		suffixLen := r.uvarint()
+		suffix := r.bytes(suffixLen)
+		if r.err != nil || shared > uint64(len(prev)) {
+			return nil, errCorruptPostingList
+		}
+
+		occ := domain.KeywordOccurrence{
+			PageID:    prev[:shared] + string(suffix),
+			Frequency: int(r.uvarint()),
+		}
+
+		positions := r.uvarint()
+		if r.err != nil || positions > uint64(len(r.buf)) {
+			return nil, errCorruptPostingList
+		}
+
+		last := 0
+		for j := uint64(0); j < positions; j++ {
+			last += int(r.varint())
+			occ.Positions = append(occ.Positions, last)
+		}
+
+		if r.err != nil {
+			return nil, r.err
+		}
+
+		pl = append(pl, occ)
+		prev = occ.PageID
+	}
+
+	return pl, nil
+}
+
+// A forward list holds the keywords a page has postings under, sorted.
+// It is encoded as a uvarint count followed by length prefixed keywords.
+type forwardList []domain.Keyword
+
+func (fl forwardList) add(keyword domain.Keyword) forwardList {
+	i := sort.Search(len(fl), func(i int) bool {
+		return fl[i] >= keyword
+	})
+	if i < len(fl) && fl[i] == keyword {
+		return fl
+	}
+
+	fl = append(fl, "")
+	copy(fl[i+1:], fl[i:])
+	fl[i] = keyword
+	return fl
+}
+
+func (fl forwardList) encode() []byte {
+	buf := binary.AppendUvarint(nil, uint64(len(fl)))
+	for _, keyword := range fl {
+		buf = binary.AppendUvarint(buf, uint64(len(keyword)))
+		buf = append(buf, keyword...)
+	}
+	return buf
+}
+
+func decodeForwardList(data []byte) (forwardList, error) {
+	r := &reader{buf: data}
+
+	count := r.uvarint()
+	if r.err != nil || count > uint64(len(data)) {
+		return nil, errCorruptPostingList
+	}
+
+	fl := make(forwardList, 0, count)
+	for i := uint64(0); i < count; i++ {
+		n := r.uvarint()
+		keyword := r.bytes(n)
+		if r.err != nil {
+			return nil, r.err
+		}
+		fl = append(fl, domain.Keyword(keyword))
+	}
+
+	return fl, nil
+}
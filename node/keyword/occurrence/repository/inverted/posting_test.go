@@ -0,0 +1,82 @@
+package inverted
+
+import (
+	"crawlquery/node/domain"
+	"reflect"
+	"testing"
+)
+
+func TestPostingListEncoding(t *testing.T) {
+	cases := []struct {
+		name string
+		list postingList
+	}{
+		{
+			name: "empty",
+			list: postingList{},
+		},
+		{
+			name: "shared prefixes",
+			list: postingList{
+				{PageID: "abc123", Frequency: 3, Positions: []int{1, 5, 200}},
+				{PageID: "abc124", Frequency: 1, Positions: []int{0}},
+				{PageID: "abd", Frequency: 2, Positions: []int{10000, 10001}},
+				{PageID: "xyz", Frequency: 0},
+			},
+		},
+		{
+			name: "unsorted positions",
+			list: postingList{
+				{PageID: "page1", Frequency: 3, Positions: []int{9, 2, 4}},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			decoded, err := decodePostingList(tc.list.encode())
+			if err != nil {
+				t.Fatalf("Error decoding posting list: %v", err)
+			}
+
+			if !reflect.DeepEqual(decoded, tc.list) {
+				t.Errorf("Expected %v, got %v", tc.list, decoded)
+			}
+		})
+	}
+}
+
+func TestPostingListDecodeCorrupt(t *testing.T) {
+	encoded := postingList{
+		{PageID: "page1", Frequency: 2, Positions: []int{1, 2}},
+	}.encode()
+
+	for i := 0; i < len(encoded); i++ {
+		if _, err := decodePostingList(encoded[:i]); err == nil {
+			t.Errorf("Expected error decoding %d of %d bytes", i, len(encoded))
+		}
+	}
+}
+
+func TestForwardListEncoding(t *testing.T) {
+	fl := forwardList{}.add("b").add("a").add("c").add("a")
+
+	expected := forwardList{"a", "b", "c"}
+
+	if !reflect.DeepEqual(fl, expected) {
+		t.Fatalf("Expected %v, got %v", expected, fl)
+	}
+
+	decoded, err := decodeForwardList(fl.encode())
+	if err != nil {
+		t.Fatalf("Error decoding forward list: %v", err)
+	}
+
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("Expected %v, got %v", expected, decoded)
+	}
+
+	if decoded[0] != domain.Keyword("a") {
+		t.Errorf("Expected first keyword a, got %s", decoded[0])
+	}
+}
@@ -63,3 +63,33 @@ func (r *Repository) RemoveForPageID(pageID string) error {
 func (r *Repository) Count() (int, error) {
 	return len(r.occurrences), nil
 }
+
+func (r *Repository) PageTokenCount(pageID string) (int, error) {
+	count := 0
+
+	for _, occurrences := range r.occurrences {
+		for _, occurrence := range occurrences {
+			if occurrence.PageID == pageID {
+				count += occurrence.Frequency
+			}
+		}
+	}
+
+	return count, nil
+}
+
+func (r *Repository) Stats() (*domain.KeywordStats, error) {
+	stats := &domain.KeywordStats{}
+	pages := make(map[string]bool)
+
+	for _, occurrences := range r.occurrences {
+		for _, occurrence := range occurrences {
+			pages[occurrence.PageID] = true
+			stats.TotalTokens += occurrence.Frequency
+		}
+	}
+
+	stats.TotalPages = len(pages)
+
+	return stats, nil
+}
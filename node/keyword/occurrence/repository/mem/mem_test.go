@@ -197,3 +197,69 @@ func TestCount(t *testing.T) {
 		t.Errorf("Expected count 5, got %d", count)
 	}
 }
+
+func TestStats(t *testing.T) {
+	repo := mem.NewRepository()
+
+	occurrences := map[domain.Keyword][]domain.KeywordOccurrence{
+		"example": {
+			{PageID: "page1", Frequency: 3, Positions: []int{1, 2, 3}},
+			{PageID: "page2", Frequency: 2, Positions: []int{4, 5}},
+		},
+		"other": {
+			{PageID: "page1", Frequency: 1, Positions: []int{4}},
+		},
+	}
+
+	for keyword, occs := range occurrences {
+		for _, occ := range occs {
+			if err := repo.Add(keyword, occ); err != nil {
+				t.Fatalf("Error adding occurrence: %v", err)
+			}
+		}
+	}
+
+	count, err := repo.PageTokenCount("page1")
+	if err != nil {
+		t.Fatalf("Error getting page token count: %v", err)
+	}
+
+	if count != 4 {
+		t.Errorf("Expected page token count 4, got %d", count)
+	}
+
+	stats, err := repo.Stats()
+	if err != nil {
+		t.Fatalf("Error getting stats: %v", err)
+	}
+
+	if stats.TotalPages != 2 {
+		t.Errorf("Expected 2 total pages, got %d", stats.TotalPages)
+	}
+
+	if stats.TotalTokens != 6 {
+		t.Errorf("Expected 6 total tokens, got %d", stats.TotalTokens)
+	}
+
+	if err := repo.RemoveForPageID("page1"); err != nil {
+		t.Fatalf("Error removing occurrences: %v", err)
+	}
+
+	count, err = repo.PageTokenCount("page1")
+	if err != nil {
+		t.Fatalf("Error getting page token count: %v", err)
+	}
+
+	if count != 0 {
+		t.Errorf("Expected page token count 0, got %d", count)
+	}
+
+	stats, err = repo.Stats()
+	if err != nil {
+		t.Fatalf("Error getting stats: %v", err)
+	}
+
+	if stats.TotalPages != 1 || stats.TotalTokens != 2 {
+		t.Errorf("Expected 1 page and 2 tokens, got %+v", stats)
+	}
+}
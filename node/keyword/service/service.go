@@ -16,6 +16,14 @@ func (s *Service) Count() (int, error) {
 	return s.repo.Count()
 }
 
+func (s *Service) PageTokenCount(pageID string) (int, error) {
+	return s.repo.PageTokenCount(pageID)
+}
+
+func (s *Service) Stats() (*domain.KeywordStats, error) {
+	return s.repo.Stats()
+}
+
 func (s *Service) GetKeywordMatches(keywords []domain.Keyword) ([]domain.KeywordMatch, error) {
 	var matches []domain.KeywordMatch
 
@@ -0,0 +1,31 @@
+package parse
+
+import (
+	"crawlquery/node/domain"
+	"crawlquery/node/keyword"
+	"strings"
+)
+
+// AnchorKeywords parses the text of each anchor pointing at a page into its
+// own list of keywords, skipping anchors without any.
+func AnchorKeywords(anchors []string) ([][]domain.Keyword, error) {
+	var parsedAnchors [][]domain.Keyword
+
+	for _, anchor := range anchors {
+		clean := strings.ToLower(strings.Join(strings.Fields(anchor), " "))
+		if clean == "" {
+			continue
+		}
+
+		parsed, err := keyword.ParseText(clean)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(parsed) > 0 {
+			parsedAnchors = append(parsedAnchors, parsed)
+		}
+	}
+
+	return parsedAnchors, nil
+}
@@ -0,0 +1,31 @@
+package parse_test
+
+import (
+	"crawlquery/node/domain"
+	"crawlquery/node/parse"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestAnchorKeywords(t *testing.T) {
+	anchors, err := parse.AnchorKeywords([]string{"Best  Pasta Recipes", "", "   "})
+	if err != nil {
+		t.Fatalf("Error parsing anchors: %v", err)
+	}
+
+	if len(anchors) != 1 {
+		t.Fatalf("Expected 1 anchor, got %d", len(anchors))
+	}
+
+	keywords := anchors[0]
+	sort.Slice(keywords, func(i, j int) bool {
+		return keywords[i] < keywords[j]
+	})
+
+	expected := []domain.Keyword{"best", "pasta", "recipes"}
+
+	if !reflect.DeepEqual(keywords, expected) {
+		t.Errorf("Expected %v, got %v", expected, keywords)
+	}
+}
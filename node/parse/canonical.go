@@ -0,0 +1,31 @@
+package parse
+
+import (
+	"crawlquery/pkg/util"
+	"errors"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Canonical returns the absolute URL of the page's rel="canonical" link,
+// resolving a relative href against pageURL.
+func Canonical(doc *goquery.Document, pageURL string) (string, error) {
+	var href string
+
+	doc.Find("link[rel][href]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		for _, rel := range strings.Fields(s.AttrOr("rel", "")) {
+			if strings.EqualFold(rel, "canonical") {
+				href = strings.TrimSpace(s.AttrOr("href", ""))
+				return false
+			}
+		}
+		return true
+	})
+
+	if href == "" {
+		return "", errors.New("no canonical found")
+	}
+
+	return util.MakeAbsoluteIfRelative(pageURL, href)
+}
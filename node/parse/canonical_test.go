@@ -0,0 +1,70 @@
+package parse_test
+
+import (
+	"crawlquery/node/parse"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestCanonical(t *testing.T) {
+	cases := []struct {
+		name    string
+		html    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "absolute",
+			html: `<link rel="canonical" href="https://example.com/recipes/pasta">`,
+			want: "https://example.com/recipes/pasta",
+		},
+		{
+			name: "relative",
+			html: `<link rel="Canonical" href="/recipes/pasta">`,
+			want: "http://example.com/recipes/pasta",
+		},
+		{
+			name: "among other rel values",
+			html: `<link rel="stylesheet" href="/style.css"><link rel="canonical alternate" href="/pasta">`,
+			want: "http://example.com/pasta",
+		},
+		{
+			name:    "missing",
+			html:    `<link rel="stylesheet" href="/style.css">`,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported scheme",
+			html:    `<link rel="canonical" href="ftp://example.com/pasta">`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader("<html><head>" + tc.html + "</head></html>"))
+			if err != nil {
+				t.Fatalf("Error parsing html: %v", err)
+			}
+
+			got, err := parse.Canonical(doc, "http://example.com/recipes?page=2")
+
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %s", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("Expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package parse
+
+import (
+	"crawlquery/pkg/util"
+	"mime"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// feedTypes are the media types of the feeds a page can link to.
+var feedTypes = map[string]bool{
+	"application/rss+xml":  true,
+	"application/atom+xml": true,
+}
+
+// Feeds returns the absolute URLs of the RSS and Atom feeds the page links to
+// with <link rel="alternate">, resolving relative hrefs against pageURL.
+func Feeds(doc *goquery.Document, pageURL string) []string {
+	var feeds []string
+	seen := make(map[string]bool)
+
+	doc.Find("link[rel][type][href]").Each(func(_ int, s *goquery.Selection) {
+		if !hasRel(s.AttrOr("rel", ""), "alternate") {
+			return
+		}
+
+		mediaType, _, err := mime.ParseMediaType(s.AttrOr("type", ""))
+		if err != nil || !feedTypes[mediaType] {
+			return
+		}
+
+		feed, err := util.MakeAbsoluteIfRelative(pageURL, strings.TrimSpace(s.AttrOr("href", "")))
+		if err != nil || seen[feed] {
+			return
+		}
+
+		seen[feed] = true
+		feeds = append(feeds, feed)
+	})
+
+	return feeds
+}
+
+func hasRel(rels, rel string) bool {
+	for _, r := range strings.Fields(rels) {
+		if strings.EqualFold(r, rel) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package parse_test
+
+import (
+	"crawlquery/node/parse"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestFeeds(t *testing.T) {
+	cases := []struct {
+		name string
+		html string
+		want []string
+	}{
+		{
+			name: "rss and atom",
+			html: `<link rel="alternate" type="application/rss+xml" href="https://example.com/feed.rss">
+				<link rel="alternate" type="application/atom+xml" href="/feed.atom">`,
+			want: []string{"https://example.com/feed.rss", "http://example.com/feed.atom"},
+		},
+		{
+			name: "case and parameters",
+			html: `<link rel="Alternate" type="Application/RSS+XML; charset=utf-8" href="feed">`,
+			want: []string{"http://example.com/blog/feed"},
+		},
+		{
+			name: "duplicates",
+			html: `<link rel="alternate" type="application/rss+xml" href="/feed">
+				<link rel="alternate" type="application/rss+xml" href="http://example.com/feed">`,
+			want: []string{"http://example.com/feed"},
+		},
+		{
+			name: "other alternates and links",
+			html: `<link rel="alternate" hreflang="fr" href="/fr">
+				<link rel="alternate" type="text/html" href="/mobile">
+				<link rel="stylesheet" type="application/rss+xml" href="/style">
+				<link rel="alternate" type="application/rss+xml" href="ftp://example.com/feed">`,
+			want: nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader("<html><head>" + tc.html + "</head></html>"))
+			if err != nil {
+				t.Fatalf("Error parsing html: %v", err)
+			}
+
+			got := parse.Feeds(doc, "http://example.com/blog/post")
+
+			if strings.Join(got, " ") != strings.Join(tc.want, " ") {
+				t.Errorf("Expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package parse
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Robots holds the indexing directives a page gives to crawlers.
+type Robots struct {
+	NoIndex  bool
+	NoFollow bool
+}
+
+// Merge returns the directives of both, so that a directive given in either
+// the page or its response headers applies.
+func (r Robots) Merge(other Robots) Robots {
+	return Robots{
+		NoIndex:  r.NoIndex || other.NoIndex,
+		NoFollow: r.NoFollow || other.NoFollow,
+	}
+}
+
+// RobotsDirectives parses a comma separated list of directives as found in a
+// robots meta tag or an X-Robots-Tag header. Directives aimed at a named
+// crawler, such as "googlebot: noindex", apply to every crawler.
+func RobotsDirectives(value string) Robots {
+	var robots Robots
+
+	for _, directive := range strings.Split(value, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+
+		if i := strings.LastIndex(directive, ":"); i >= 0 {
+			directive = strings.TrimSpace(directive[i+1:])
+		}
+
+		switch directive {
+		case "noindex":
+			robots.NoIndex = true
+		case "nofollow":
+			robots.NoFollow = true
+		case "none":
+			robots.NoIndex = true
+			robots.NoFollow = true
+		}
+	}
+
+	return robots
+}
+
+// MetaRobots returns the directives of every robots meta tag on the page.
+func MetaRobots(doc *goquery.Document) Robots {
+	var robots Robots
+
+	doc.Find("meta[name]").Each(func(_ int, s *goquery.Selection) {
+		if strings.EqualFold(s.AttrOr("name", ""), "robots") {
+			robots = robots.Merge(RobotsDirectives(s.AttrOr("content", "")))
+		}
+	})
+
+	return robots
+}
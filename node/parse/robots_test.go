@@ -0,0 +1,55 @@
+package parse_test
+
+import (
+	"crawlquery/node/parse"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestRobotsDirectives(t *testing.T) {
+	cases := []struct {
+		value string
+		want  parse.Robots
+	}{
+		{value: "", want: parse.Robots{}},
+		{value: "index, follow", want: parse.Robots{}},
+		{value: "noindex", want: parse.Robots{NoIndex: true}},
+		{value: "NoIndex, NoFollow", want: parse.Robots{NoIndex: true, NoFollow: true}},
+		{value: "none", want: parse.Robots{NoIndex: true, NoFollow: true}},
+		{value: "googlebot: nofollow", want: parse.Robots{NoFollow: true}},
+		{value: "noarchive, nosnippet", want: parse.Robots{}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.value, func(t *testing.T) {
+			if got := parse.RobotsDirectives(tc.value); got != tc.want {
+				t.Errorf("Expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMetaRobots(t *testing.T) {
+	t.Run("merges every robots meta tag", func(t *testing.T) {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(`
+		<html>
+			<head>
+				<meta name="description" content="noindex">
+				<meta name="robots" content="noindex">
+				<meta name="Robots" content="nofollow">
+			</head>
+		</html>
+		`))
+		if err != nil {
+			t.Fatalf("Error parsing html: %v", err)
+		}
+
+		want := parse.Robots{NoIndex: true, NoFollow: true}
+
+		if got := parse.MetaRobots(doc); got != want {
+			t.Errorf("Expected %+v, got %+v", want, got)
+		}
+	})
+}
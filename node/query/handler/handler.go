@@ -3,6 +3,7 @@ package handler
 import (
 	"crawlquery/node/domain"
 	"crawlquery/node/dto"
+	"errors"
 
 	"github.com/gin-gonic/gin"
 )
@@ -25,20 +26,31 @@ func (h *Handler) Query(c *gin.Context) {
 		return
 	}
 
-	var response dto.QueryResponse
-
-	results, err := h.queryService.Query(req.Query)
+	result, err := h.queryService.Query(req.Query)
 	if err != nil {
+		if errors.Is(err, domain.ErrInvalidQuery) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
 
-	for _, result := range results {
-		response.Pages = append(response.Pages, dto.QueryResultPage{
-			ID:    result.ID,
-			Title: result.Title,
+	response := dto.QueryResponse{
+		Columns: make([]dto.QueryColumn, 0, len(result.Columns)),
+		Rows:    make([][]any, 0, len(result.Rows)),
+	}
+
+	for _, col := range result.Columns {
+		response.Columns = append(response.Columns, dto.QueryColumn{
+			Name: col.Name,
+			Type: string(col.Type),
 		})
 	}
 
+	for _, row := range result.Rows {
+		response.Rows = append(response.Rows, row)
+	}
+
 	c.JSON(200, response)
 }
@@ -2,29 +2,48 @@ package handler_test
 
 import (
 	"bytes"
+	"crawlquery/node/domain"
 	"crawlquery/node/dto"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	keywordRepo "crawlquery/node/keyword/occurrence/repository/mem"
+	keywordService "crawlquery/node/keyword/service"
+	pageRepo "crawlquery/node/page/repository/mem"
+	pageService "crawlquery/node/page/service"
 	queryHandler "crawlquery/node/query/handler"
 	queryService "crawlquery/node/query/service"
 
 	"github.com/gin-gonic/gin"
 )
 
+func setupHandler(t *testing.T) *queryHandler.Handler {
+	pr := pageRepo.NewRepository()
+
+	err := pr.Save("page1", &domain.Page{ID: "page1", Title: "Example Page"})
+	if err != nil {
+		t.Fatalf("Error saving page: %v", err)
+	}
+
+	queryService := queryService.NewService(
+		pageService.NewService(pr, nil),
+		keywordService.NewService(keywordRepo.NewRepository()),
+	)
+
+	return queryHandler.NewHandler(queryService)
+}
+
 func TestQuery(t *testing.T) {
 	t.Run("returns results", func(t *testing.T) {
-		queryService := queryService.NewService(nil)
-
-		queryHandler := queryHandler.NewHandler(queryService)
+		queryHandler := setupHandler(t)
 
 		w := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(w)
 
 		req := dto.QueryRequest{
-			Query: "SELECT title FROM pages WHERE title LIKE '%example%';",
+			Query: "SELECT id, title FROM pages WHERE title LIKE '%example%';",
 		}
 
 		reqBody, _ := json.Marshal(req)
@@ -45,17 +64,43 @@ func TestQuery(t *testing.T) {
 			t.Errorf("Error decoding response: %v", err)
 		}
 
-		if len(res.Pages) != 1 {
-			t.Errorf("Expected 1 result, got %d", len(res.Pages))
+		if len(res.Columns) != 2 {
+			t.Fatalf("Expected 2 columns, got %d", len(res.Columns))
 		}
 
-		if res.Pages[0].ID != "page1" {
-			t.Errorf("Expected page ID page1, got %s", res.Pages[0].ID)
+		if res.Columns[1].Name != "title" || res.Columns[1].Type != "string" {
+			t.Errorf("Expected string column title, got %v", res.Columns[1])
 		}
 
-		if res.Pages[0].Title != "Example Page" {
-			t.Errorf("Expected title Example Page, got %s", res.Pages[0].Title)
+		if len(res.Rows) != 1 {
+			t.Fatalf("Expected 1 result, got %d", len(res.Rows))
 		}
 
+		if res.Rows[0][0] != "page1" {
+			t.Errorf("Expected page ID page1, got %v", res.Rows[0][0])
+		}
+
+		if res.Rows[0][1] != "Example Page" {
+			t.Errorf("Expected title Example Page, got %v", res.Rows[0][1])
+		}
+	})
+
+	t.Run("returns 400 for an invalid query", func(t *testing.T) {
+		queryHandler := setupHandler(t)
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+
+		reqBody, _ := json.Marshal(dto.QueryRequest{
+			Query: "SELECT * FROM users",
+		})
+
+		ctx.Request, _ = http.NewRequest(http.MethodPost, "/query", bytes.NewReader(reqBody))
+
+		queryHandler.Query(ctx)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", w.Code)
+		}
 	})
 }
@@ -0,0 +1,94 @@
+package parser
+
+import "fmt"
+
+// Error is returned when a query cannot be parsed.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+// Statement is a parsed SELECT statement.
+type Statement struct {
+	// Columns is empty when the statement selects every column.
+	Columns []string
+	Table   string
+	Where   Expr
+	OrderBy []OrderBy
+	// Limit is -1 when the statement has no LIMIT clause.
+	Limit int
+}
+
+// AllColumns reports whether the statement is a SELECT *.
+func (s *Statement) AllColumns() bool {
+	return len(s.Columns) == 0
+}
+
+type OrderBy struct {
+	Column string
+	Desc   bool
+}
+
+// Expr is a node in a WHERE clause.
+type Expr interface {
+	expr()
+}
+
+type LogicalOp string
+
+const (
+	LogicalOpAnd LogicalOp = "AND"
+	LogicalOpOr  LogicalOp = "OR"
+)
+
+// LogicalExpr joins two expressions with AND or OR.
+type LogicalExpr struct {
+	Op    LogicalOp
+	Left  Expr
+	Right Expr
+}
+
+type CompareOp string
+
+const (
+	CompareOpEq  CompareOp = "="
+	CompareOpNeq CompareOp = "!="
+	CompareOpLt  CompareOp = "<"
+	CompareOpLte CompareOp = "<="
+	CompareOpGt  CompareOp = ">"
+	CompareOpGte CompareOp = ">="
+)
+
+// CompareExpr compares a column to a literal value.
+type CompareExpr struct {
+	Column string
+	Op     CompareOp
+	Value  Value
+}
+
+// LikeExpr matches a column against a pattern where % matches any
+// sequence of characters and _ matches exactly one.
+type LikeExpr struct {
+	Column  string
+	Pattern string
+	Not     bool
+}
+
+// InExpr checks whether a column is one of a list of values.
+type InExpr struct {
+	Column string
+	Values []Value
+	Not    bool
+}
+
+func (*LogicalExpr) expr() {}
+func (*CompareExpr) expr() {}
+func (*LikeExpr) expr()    {}
+func (*InExpr) expr()      {}
+
+// Value is a literal in a query. It holds a string, an int or a float64.
+type Value any
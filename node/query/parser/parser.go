@@ -0,0 +1,301 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse parses a query of the form
+//
+//	SELECT col, ... | * FROM table
+//	[WHERE expr] [ORDER BY col [ASC|DESC], ...] [LIMIT n] [;]
+//
+// where expr combines =, !=, <>, <, <=, >, >=, [NOT] LIKE and [NOT] IN
+// comparisons with AND, OR and parentheses.
+func Parse(query string) (*Statement, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	return p.parseStatement()
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return &Error{Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) expect(text string) error {
+	t := p.next()
+	if !t.is(text) {
+		return p.errorf(t, "expected %s, got %s", text, t)
+	}
+	return nil
+}
+
+func (p *parser) accept(text string) bool {
+	if p.peek().is(text) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseIdent() (string, error) {
+	t := p.next()
+	if t.kind != tokenIdent || isReserved(t.text) {
+		return "", p.errorf(t, "expected identifier, got %s", t)
+	}
+	return strings.ToLower(t.text), nil
+}
+
+func (p *parser) parseStatement() (*Statement, error) {
+	stmt := &Statement{Limit: -1}
+
+	if err := p.expect("SELECT"); err != nil {
+		return nil, err
+	}
+
+	if !p.accept("*") {
+		for {
+			col, err := p.parseIdent()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Columns = append(stmt.Columns, col)
+
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+
+	if err := p.expect("FROM"); err != nil {
+		return nil, err
+	}
+
+	table, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Table = table
+
+	if p.accept("WHERE") {
+		where, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Where = where
+	}
+
+	if p.accept("ORDER") {
+		if err := p.expect("BY"); err != nil {
+			return nil, err
+		}
+
+		for {
+			col, err := p.parseIdent()
+			if err != nil {
+				return nil, err
+			}
+
+			orderBy := OrderBy{Column: col}
+			if p.accept("DESC") {
+				orderBy.Desc = true
+			} else {
+				p.accept("ASC")
+			}
+			stmt.OrderBy = append(stmt.OrderBy, orderBy)
+
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+
+	if p.accept("LIMIT") {
+		t := p.next()
+		limit, err := strconv.Atoi(t.text)
+		if t.kind != tokenNumber || err != nil || limit < 0 {
+			return nil, p.errorf(t, "expected non-negative integer limit, got %s", t)
+		}
+		stmt.Limit = limit
+	}
+
+	p.accept(";")
+
+	if t := p.next(); t.kind != tokenEOF {
+		return nil, p.errorf(t, "unexpected %s", t)
+	}
+
+	return stmt, nil
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalExpr{Op: LogicalOpOr, Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("AND") {
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalExpr{Op: LogicalOpAnd, Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	if p.accept("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+
+	col, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+
+	not := p.accept("NOT")
+
+	switch {
+	case p.accept("LIKE"):
+		t := p.next()
+		if t.kind != tokenString {
+			return nil, p.errorf(t, "expected string pattern, got %s", t)
+		}
+		return &LikeExpr{Column: col, Pattern: t.text, Not: not}, nil
+
+	case p.accept("IN"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+
+		var values []Value
+		for {
+			v, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+
+			if !p.accept(",") {
+				break
+			}
+		}
+
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return &InExpr{Column: col, Values: values, Not: not}, nil
+	}
+
+	if not {
+		t := p.peek()
+		return nil, p.errorf(t, "expected LIKE or IN after NOT, got %s", t)
+	}
+
+	t := p.next()
+	var op CompareOp
+	switch {
+	case t.is("="):
+		op = CompareOpEq
+	case t.is("!="), t.is("<>"):
+		op = CompareOpNeq
+	case t.is("<"):
+		op = CompareOpLt
+	case t.is("<="):
+		op = CompareOpLte
+	case t.is(">"):
+		op = CompareOpGt
+	case t.is(">="):
+		op = CompareOpGte
+	default:
+		return nil, p.errorf(t, "expected comparison operator, got %s", t)
+	}
+
+	v, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	return &CompareExpr{Column: col, Op: op, Value: v}, nil
+}
+
+func (p *parser) parseValue() (Value, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokenString:
+		return t.text, nil
+	case tokenNumber:
+		if i, err := strconv.Atoi(t.text); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(t.text, 64); err == nil {
+			return f, nil
+		}
+	}
+
+	return nil, p.errorf(t, "expected string or number, got %s", t)
+}
+
+var reserved = []string{
+	"SELECT", "FROM", "WHERE", "AND", "OR", "NOT", "LIKE", "IN",
+	"ORDER", "BY", "ASC", "DESC", "LIMIT",
+}
+
+func isReserved(ident string) bool {
+	for _, r := range reserved {
+		if strings.EqualFold(ident, r) {
+			return true
+		}
+	}
+	return false
+}
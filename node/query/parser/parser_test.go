@@ -0,0 +1,147 @@
+package parser_test
+
+import (
+	"crawlquery/node/query/parser"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name  string
+		query string
+		want  *parser.Statement
+	}{
+		{
+			name:  "select all",
+			query: "SELECT * FROM pages",
+			want:  &parser.Statement{Table: "pages", Limit: -1},
+		},
+		{
+			name:  "projection is lower cased",
+			query: "select ID, Title from Pages;",
+			want:  &parser.Statement{Columns: []string{"id", "title"}, Table: "pages", Limit: -1},
+		},
+		{
+			name:  "like",
+			query: "SELECT title FROM pages WHERE title LIKE '%example%';",
+			want: &parser.Statement{
+				Columns: []string{"title"},
+				Table:   "pages",
+				Where:   &parser.LikeExpr{Column: "title", Pattern: "%example%"},
+				Limit:   -1,
+			},
+		},
+		{
+			name:  "not in",
+			query: "SELECT * FROM pages WHERE language NOT IN ('en', 'fr')",
+			want: &parser.Statement{
+				Table: "pages",
+				Where: &parser.InExpr{Column: "language", Values: []parser.Value{"en", "fr"}, Not: true},
+				Limit: -1,
+			},
+		},
+		{
+			name:  "and binds tighter than or",
+			query: "SELECT * FROM keywords WHERE keyword = 'a' OR keyword = 'b' AND frequency > 2",
+			want: &parser.Statement{
+				Table: "keywords",
+				Where: &parser.LogicalExpr{
+					Op:   parser.LogicalOpOr,
+					Left: &parser.CompareExpr{Column: "keyword", Op: parser.CompareOpEq, Value: "a"},
+					Right: &parser.LogicalExpr{
+						Op:    parser.LogicalOpAnd,
+						Left:  &parser.CompareExpr{Column: "keyword", Op: parser.CompareOpEq, Value: "b"},
+						Right: &parser.CompareExpr{Column: "frequency", Op: parser.CompareOpGt, Value: 2},
+					},
+				},
+				Limit: -1,
+			},
+		},
+		{
+			name:  "parentheses",
+			query: "SELECT * FROM keywords WHERE (keyword = 'a' OR keyword = 'b') AND frequency <> 2.5",
+			want: &parser.Statement{
+				Table: "keywords",
+				Where: &parser.LogicalExpr{
+					Op: parser.LogicalOpAnd,
+					Left: &parser.LogicalExpr{
+						Op:    parser.LogicalOpOr,
+						Left:  &parser.CompareExpr{Column: "keyword", Op: parser.CompareOpEq, Value: "a"},
+						Right: &parser.CompareExpr{Column: "keyword", Op: parser.CompareOpEq, Value: "b"},
+					},
+					Right: &parser.CompareExpr{Column: "frequency", Op: parser.CompareOpNeq, Value: 2.5},
+				},
+				Limit: -1,
+			},
+		},
+		{
+			name:  "order by and limit",
+			query: "SELECT url FROM pages ORDER BY title DESC, url ASC, id LIMIT 5",
+			want: &parser.Statement{
+				Columns: []string{"url"},
+				Table:   "pages",
+				OrderBy: []parser.OrderBy{
+					{Column: "title", Desc: true},
+					{Column: "url"},
+					{Column: "id"},
+				},
+				Limit: 5,
+			},
+		},
+		{
+			name:  "escaped quote",
+			query: "SELECT * FROM pages WHERE title = 'it''s'",
+			want: &parser.Statement{
+				Table: "pages",
+				Where: &parser.CompareExpr{Column: "title", Op: parser.CompareOpEq, Value: "it's"},
+				Limit: -1,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stmt, err := parser.Parse(tc.query)
+			if err != nil {
+				t.Fatalf("Error parsing query: %v", err)
+			}
+
+			if !reflect.DeepEqual(stmt, tc.want) {
+				t.Errorf("Expected %#v, got %#v", tc.want, stmt)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		query string
+	}{
+		{name: "empty", query: ""},
+		{name: "not a select", query: "DELETE FROM pages"},
+		{name: "missing from", query: "SELECT title pages"},
+		{name: "unterminated string", query: "SELECT * FROM pages WHERE title = 'abc"},
+		{name: "missing value", query: "SELECT * FROM pages WHERE title ="},
+		{name: "like needs a string", query: "SELECT * FROM pages WHERE title LIKE 1"},
+		{name: "dangling not", query: "SELECT * FROM pages WHERE title NOT = 'a'"},
+		{name: "unclosed paren", query: "SELECT * FROM pages WHERE (title = 'a'"},
+		{name: "negative limit", query: "SELECT * FROM pages LIMIT -1"},
+		{name: "trailing tokens", query: "SELECT * FROM pages; SELECT"},
+		{name: "reserved column", query: "SELECT from FROM pages"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parser.Parse(tc.query)
+			if err == nil {
+				t.Fatalf("Expected error parsing %q", tc.query)
+			}
+
+			if _, ok := err.(*parser.Error); !ok {
+				t.Errorf("Expected *parser.Error, got %T", err)
+			}
+		})
+	}
+}
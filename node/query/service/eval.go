@@ -0,0 +1,236 @@
+package service
+
+import (
+	"crawlquery/node/domain"
+	"crawlquery/node/query/parser"
+	"strings"
+	"time"
+)
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// coerce converts a literal into the representation used for values of the
+// given column type so the two can be compared.
+func coerce(colType domain.QueryColumnType, v parser.Value) (any, bool) {
+	switch colType {
+	case domain.QueryColumnTypeString:
+		s, ok := v.(string)
+		return s, ok
+	case domain.QueryColumnTypeInt:
+		switch n := v.(type) {
+		case int:
+			return float64(n), true
+		case float64:
+			return n, true
+		}
+	case domain.QueryColumnTypeTime:
+		s, ok := v.(string)
+		if !ok {
+			return nil, false
+		}
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func validate(expr parser.Expr, types map[string]domain.QueryColumnType) error {
+	if expr == nil {
+		return nil
+	}
+
+	checkValue := func(column string, v parser.Value) error {
+		colType, ok := types[column]
+		if !ok {
+			return invalidQuery("unknown column %s", column)
+		}
+		if _, ok := coerce(colType, v); !ok {
+			return invalidQuery("cannot compare %s column %s to %v", colType, column, v)
+		}
+		return nil
+	}
+
+	switch e := expr.(type) {
+	case *parser.LogicalExpr:
+		if err := validate(e.Left, types); err != nil {
+			return err
+		}
+		return validate(e.Right, types)
+	case *parser.CompareExpr:
+		return checkValue(e.Column, e.Value)
+	case *parser.LikeExpr:
+		colType, ok := types[e.Column]
+		if !ok {
+			return invalidQuery("unknown column %s", e.Column)
+		}
+		if colType != domain.QueryColumnTypeString {
+			return invalidQuery("LIKE requires a string column, %s is %s", e.Column, colType)
+		}
+	case *parser.InExpr:
+		for _, v := range e.Values {
+			if err := checkValue(e.Column, v); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// eval reports whether the record satisfies the expression. A nil
+// expression matches every record and a null value matches nothing.
+func eval(expr parser.Expr, rec record, types map[string]domain.QueryColumnType) (bool, error) {
+	if expr == nil {
+		return true, nil
+	}
+
+	switch e := expr.(type) {
+	case *parser.LogicalExpr:
+		left, err := eval(e.Left, rec, types)
+		if err != nil {
+			return false, err
+		}
+
+		if e.Op == parser.LogicalOpAnd && !left {
+			return false, nil
+		}
+		if e.Op == parser.LogicalOpOr && left {
+			return true, nil
+		}
+
+		return eval(e.Right, rec, types)
+
+	case *parser.CompareExpr:
+		value := rec[e.Column]
+		if value == nil {
+			return false, nil
+		}
+
+		literal, _ := coerce(types[e.Column], e.Value)
+		c := compare(value, literal)
+
+		switch e.Op {
+		case parser.CompareOpEq:
+			return c == 0, nil
+		case parser.CompareOpNeq:
+			return c != 0, nil
+		case parser.CompareOpLt:
+			return c < 0, nil
+		case parser.CompareOpLte:
+			return c <= 0, nil
+		case parser.CompareOpGt:
+			return c > 0, nil
+		case parser.CompareOpGte:
+			return c >= 0, nil
+		}
+
+	case *parser.LikeExpr:
+		s, ok := rec[e.Column].(string)
+		if !ok {
+			return false, nil
+		}
+		return like(s, e.Pattern) != e.Not, nil
+
+	case *parser.InExpr:
+		value := rec[e.Column]
+		if value == nil {
+			return false, nil
+		}
+
+		for _, v := range e.Values {
+			literal, _ := coerce(types[e.Column], v)
+			if compare(value, literal) == 0 {
+				return !e.Not, nil
+			}
+		}
+		return e.Not, nil
+	}
+
+	return false, invalidQuery("unsupported expression %T", expr)
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// compare orders two values of the same column. Nulls sort first.
+func compare(a, b any) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	if x, ok := toFloat(a); ok {
+		y, _ := toFloat(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+
+	switch x := a.(type) {
+	case string:
+		return strings.Compare(x, b.(string))
+	case time.Time:
+		return x.Compare(b.(time.Time))
+	}
+
+	return 0
+}
+
+// like matches s against a SQL LIKE pattern, ignoring case.
+func like(s, pattern string) bool {
+	str := []rune(strings.ToLower(s))
+	pat := []rune(strings.ToLower(pattern))
+
+	si, pi := 0, 0
+	starPi, starSi := -1, 0
+
+	for si < len(str) {
+		switch {
+		case pi < len(pat) && (pat[pi] == '_' || pat[pi] == str[si]):
+			si++
+			pi++
+		case pi < len(pat) && pat[pi] == '%':
+			starPi = pi
+			starSi = si
+			pi++
+		case starPi != -1:
+			// backtrack so the last % absorbs one more character
+			pi = starPi + 1
+			starSi++
+			si = starSi
+		default:
+			return false
+		}
+	}
+
+	for pi < len(pat) && pat[pi] == '%' {
+		pi++
+	}
+
+	return pi == len(pat)
+}
@@ -1,29 +1,308 @@
 package service
 
-import "crawlquery/node/domain"
+import (
+	"crawlquery/node/domain"
+	"crawlquery/node/query/parser"
+	"fmt"
+	"sort"
+)
 
 type Service struct {
-	pageService domain.PageService
+	pageService    domain.PageService
+	keywordService domain.KeywordService
 }
 
 func NewService(
 	pageService domain.PageService,
+	keywordService domain.KeywordService,
 ) *Service {
 	return &Service{
-		pageService: pageService,
+		pageService:    pageService,
+		keywordService: keywordService,
 	}
 }
 
-func (s *Service) Query(query string) ([]domain.Page, error) {
+// record is a full row of a table keyed by column name.
+type record map[string]any
 
-	if query == "SELECT title FROM pages WHERE title LIKE '%example%';" {
-		return []domain.Page{
-			{
-				ID:    "page1",
-				Title: "Example Page",
-			},
-		}, nil
+type table struct {
+	columns []domain.QueryColumn
+	// key is the set of columns rows are ordered by when the query has no ORDER BY.
+	key  []string
+	load func(s *Service, where parser.Expr) ([]record, error)
+}
+
+var tables = map[string]table{
+	"pages": {
+		columns: []domain.QueryColumn{
+			{Name: "id", Type: domain.QueryColumnTypeString},
+			{Name: "url", Type: domain.QueryColumnTypeString},
+			{Name: "title", Type: domain.QueryColumnTypeString},
+			{Name: "description", Type: domain.QueryColumnTypeString},
+			{Name: "language", Type: domain.QueryColumnTypeString},
+			{Name: "hash", Type: domain.QueryColumnTypeString},
+			{Name: "last_indexed_at", Type: domain.QueryColumnTypeTime},
+			{Name: "updated_at", Type: domain.QueryColumnTypeTime},
+		},
+		key:  []string{"id"},
+		load: (*Service).loadPages,
+	},
+	"keywords": {
+		columns: []domain.QueryColumn{
+			{Name: "keyword", Type: domain.QueryColumnTypeString},
+			{Name: "page_id", Type: domain.QueryColumnTypeString},
+			{Name: "frequency", Type: domain.QueryColumnTypeInt},
+		},
+		key:  []string{"keyword", "page_id"},
+		load: (*Service).loadKeywords,
+	},
+}
+
+func invalidQuery(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", domain.ErrInvalidQuery, fmt.Sprintf(format, args...))
+}
+
+func pageRecord(page *domain.Page) record {
+	var lastIndexedAt any
+	if page.LastIndexedAt != nil {
+		lastIndexedAt = *page.LastIndexedAt
+	}
+
+	return record{
+		"id":              page.ID,
+		"url":             page.URL,
+		"title":           page.Title,
+		"description":     page.Description,
+		"language":        page.Language,
+		"hash":            page.Hash,
+		"last_indexed_at": lastIndexedAt,
+		"updated_at":      page.UpdatedAt,
+	}
+}
+
+func keywordRecord(keyword domain.Keyword, occurrence domain.KeywordOccurrence) record {
+	return record{
+		"keyword":   string(keyword),
+		"page_id":   occurrence.PageID,
+		"frequency": occurrence.Frequency,
 	}
+}
+
+func (s *Service) loadPages(where parser.Expr) ([]record, error) {
+	var pages map[string]*domain.Page
+	var err error
+
+	if ids, ok := constrainedValues(where, "id"); ok {
+		pages, err = s.pageService.GetByIDs(ids)
+	} else {
+		pages, err = s.pageService.GetAll()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// removed pages are kept for repair but are no longer in the index
+	records := make([]record, 0, len(pages))
+	for _, page := range pages {
+		if page.Removed {
+			continue
+		}
+		records = append(records, pageRecord(page))
+	}
+
+	return records, nil
+}
+
+func (s *Service) loadKeywords(where parser.Expr) ([]record, error) {
+	var records []record
+
+	if keywords, ok := constrainedValues(where, "keyword"); ok {
+		var kws []domain.Keyword
+		for _, k := range keywords {
+			kws = append(kws, domain.Keyword(k))
+		}
+
+		matches, err := s.keywordService.GetKeywordMatches(kws)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, match := range matches {
+			for _, occurrence := range match.Occurrences {
+				records = append(records, keywordRecord(match.Keyword, occurrence))
+			}
+		}
+
+		return records, nil
+	}
+
+	pageIDs, ok := constrainedValues(where, "page_id")
+	if !ok {
+		pages, err := s.pageService.GetAll()
+		if err != nil {
+			return nil, err
+		}
+
+		for id, page := range pages {
+			if page.Removed {
+				continue
+			}
+			pageIDs = append(pageIDs, id)
+		}
+	}
+
+	for _, pageID := range pageIDs {
+		occurrences, err := s.keywordService.GetForPageID(pageID)
+		if err != nil {
+			return nil, err
+		}
+
+		for keyword, occurrence := range occurrences {
+			records = append(records, keywordRecord(keyword, occurrence))
+		}
+	}
+
+	return records, nil
+}
+
+// constrainedValues returns the string values that the expression requires
+// the column to equal. It returns false when the expression does not
+// restrict the column and so every row has to be scanned.
+func constrainedValues(expr parser.Expr, column string) ([]string, bool) {
+	switch e := expr.(type) {
+	case *parser.CompareExpr:
+		if v, ok := e.Value.(string); ok && e.Column == column && e.Op == parser.CompareOpEq {
+			return []string{v}, true
+		}
+	case *parser.InExpr:
+		if e.Column != column || e.Not {
+			return nil, false
+		}
+
+		var values []string
+		for _, value := range e.Values {
+			v, ok := value.(string)
+			if !ok {
+				return nil, false
+			}
+			values = append(values, v)
+		}
+		return values, true
+	case *parser.LogicalExpr:
+		left, leftOk := constrainedValues(e.Left, column)
+		right, rightOk := constrainedValues(e.Right, column)
+
+		if e.Op == parser.LogicalOpAnd {
+			if leftOk {
+				return left, true
+			}
+			return right, rightOk
+		}
+
+		if leftOk && rightOk {
+			return append(left, right...), true
+		}
+	}
+
+	return nil, false
+}
+
+func (s *Service) Query(query string) (*domain.QueryResult, error) {
+	stmt, err := parser.Parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidQuery, err)
+	}
+
+	t, ok := tables[stmt.Table]
+	if !ok {
+		return nil, invalidQuery("unknown table %s", stmt.Table)
+	}
+
+	types := map[string]domain.QueryColumnType{}
+	for _, col := range t.columns {
+		types[col.Name] = col.Type
+	}
+
+	columns := t.columns
+	if !stmt.AllColumns() {
+		columns = nil
+		for _, name := range stmt.Columns {
+			colType, ok := types[name]
+			if !ok {
+				return nil, invalidQuery("unknown column %s in table %s", name, stmt.Table)
+			}
+			columns = append(columns, domain.QueryColumn{Name: name, Type: colType})
+		}
+	}
+
+	for _, orderBy := range stmt.OrderBy {
+		if _, ok := types[orderBy.Column]; !ok {
+			return nil, invalidQuery("unknown column %s in table %s", orderBy.Column, stmt.Table)
+		}
+	}
+
+	if err := validate(stmt.Where, types); err != nil {
+		return nil, err
+	}
+
+	records, err := t.load(s, stmt.Where)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []record
+	for _, rec := range records {
+		ok, err := eval(stmt.Where, rec, types)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, rec)
+		}
+	}
+
+	sortRecords(matched, stmt.OrderBy, t.key)
+
+	if stmt.Limit >= 0 && len(matched) > stmt.Limit {
+		matched = matched[:stmt.Limit]
+	}
+
+	result := &domain.QueryResult{
+		Columns: columns,
+		Rows:    make([]domain.QueryRow, 0, len(matched)),
+	}
+
+	for _, rec := range matched {
+		row := make(domain.QueryRow, len(columns))
+		for i, col := range columns {
+			row[i] = rec[col.Name]
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	return result, nil
+}
+
+func sortRecords(records []record, orderBy []parser.OrderBy, key []string) {
+	sort.SliceStable(records, func(i, j int) bool {
+		for _, o := range orderBy {
+			c := compare(records[i][o.Column], records[j][o.Column])
+			if c == 0 {
+				continue
+			}
+			if o.Desc {
+				return c > 0
+			}
+			return c < 0
+		}
+
+		for _, k := range key {
+			if c := compare(records[i][k], records[j][k]); c != 0 {
+				return c < 0
+			}
+		}
 
-	return nil, nil
+		return false
+	})
 }
@@ -1,30 +1,181 @@
 package service_test
 
 import (
+	"crawlquery/node/domain"
+	keywordRepo "crawlquery/node/keyword/occurrence/repository/mem"
+	keywordService "crawlquery/node/keyword/service"
+	pageRepo "crawlquery/node/page/repository/mem"
+	pageService "crawlquery/node/page/service"
 	queryService "crawlquery/node/query/service"
+	"errors"
+	"reflect"
 	"testing"
+	"time"
 )
 
-func TestQuery(t *testing.T) {
-	t.Run("returns results", func(t *testing.T) {
-		queryService := queryService.NewService(nil)
+func setupService(t *testing.T) *queryService.Service {
+	pr := pageRepo.NewRepository()
+	kr := keywordRepo.NewRepository()
 
-		results, err := queryService.Query("SELECT title FROM pages WHERE title LIKE '%example%';")
+	indexedAt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
 
-		if err != nil {
-			t.Errorf("Error querying: %v", err)
-		}
+	pages := []*domain.Page{
+		{ID: "page1", URL: "http://example.com", Title: "Example Page", Language: "en", LastIndexedAt: &indexedAt},
+		{ID: "page2", URL: "http://example.com/about", Title: "About Us", Language: "en"},
+		{ID: "page3", URL: "http://example.fr", Title: "Bonjour", Language: "fr"},
+		{ID: "page4", URL: "http://example.com/gone", Title: "Gone Example", Language: "en", Removed: true},
+	}
 
-		if len(results) != 1 {
-			t.Errorf("Expected 1 result, got %d", len(results))
+	for _, page := range pages {
+		if err := pr.Save(page.ID, page); err != nil {
+			t.Fatalf("Error saving page: %v", err)
 		}
+	}
 
-		if results[0].ID != "page1" {
-			t.Errorf("Expected page ID page1, got %s", results[0].ID)
-		}
+	occurrences := []struct {
+		keyword    domain.Keyword
+		occurrence domain.KeywordOccurrence
+	}{
+		{"example", domain.KeywordOccurrence{PageID: "page1", Frequency: 3, Positions: []int{1, 4, 9}}},
+		{"example", domain.KeywordOccurrence{PageID: "page2", Frequency: 1, Positions: []int{2}}},
+		{"about", domain.KeywordOccurrence{PageID: "page2", Frequency: 2, Positions: []int{0, 5}}},
+		{"bonjour", domain.KeywordOccurrence{PageID: "page3", Frequency: 1, Positions: []int{0}}},
+	}
 
-		if results[0].Title != "Example Page" {
-			t.Errorf("Expected title Example Page, got %s", results[0].Title)
+	for _, o := range occurrences {
+		if err := kr.Add(o.keyword, o.occurrence); err != nil {
+			t.Fatalf("Error adding keyword occurrence: %v", err)
 		}
-	})
+	}
+
+	return queryService.NewService(
+		pageService.NewService(pr, nil),
+		keywordService.NewService(kr),
+	)
+}
+
+func TestQuery(t *testing.T) {
+	cases := []struct {
+		name    string
+		query   string
+		columns []domain.QueryColumn
+		rows    []domain.QueryRow
+	}{
+		{
+			name:    "like is case insensitive",
+			query:   "SELECT id, title FROM pages WHERE title LIKE '%example%';",
+			columns: []domain.QueryColumn{{Name: "id", Type: domain.QueryColumnTypeString}, {Name: "title", Type: domain.QueryColumnTypeString}},
+			rows:    []domain.QueryRow{{"page1", "Example Page"}},
+		},
+		{
+			name:    "equals and or",
+			query:   "SELECT id FROM pages WHERE language = 'fr' OR title = 'About Us'",
+			columns: []domain.QueryColumn{{Name: "id", Type: domain.QueryColumnTypeString}},
+			rows:    []domain.QueryRow{{"page2"}, {"page3"}},
+		},
+		{
+			name:    "in with and",
+			query:   "SELECT id FROM pages WHERE id IN ('page1', 'page3') AND language = 'en'",
+			columns: []domain.QueryColumn{{Name: "id", Type: domain.QueryColumnTypeString}},
+			rows:    []domain.QueryRow{{"page1"}},
+		},
+		{
+			name:    "not like",
+			query:   "SELECT id FROM pages WHERE url NOT LIKE '%.com%'",
+			columns: []domain.QueryColumn{{Name: "id", Type: domain.QueryColumnTypeString}},
+			rows:    []domain.QueryRow{{"page3"}},
+		},
+		{
+			name:    "time comparison skips nulls",
+			query:   "SELECT id FROM pages WHERE last_indexed_at > '2024-01-01'",
+			columns: []domain.QueryColumn{{Name: "id", Type: domain.QueryColumnTypeString}},
+			rows:    []domain.QueryRow{{"page1"}},
+		},
+		{
+			name:    "order by and limit",
+			query:   "SELECT title FROM pages ORDER BY title DESC LIMIT 2",
+			columns: []domain.QueryColumn{{Name: "title", Type: domain.QueryColumnTypeString}},
+			rows:    []domain.QueryRow{{"Example Page"}, {"Bonjour"}},
+		},
+		{
+			name:  "keywords by keyword",
+			query: "SELECT * FROM keywords WHERE keyword = 'example' ORDER BY frequency DESC",
+			columns: []domain.QueryColumn{
+				{Name: "keyword", Type: domain.QueryColumnTypeString},
+				{Name: "page_id", Type: domain.QueryColumnTypeString},
+				{Name: "frequency", Type: domain.QueryColumnTypeInt},
+			},
+			rows: []domain.QueryRow{{"example", "page1", 3}, {"example", "page2", 1}},
+		},
+		{
+			name:    "keywords by page",
+			query:   "SELECT keyword FROM keywords WHERE page_id = 'page2'",
+			columns: []domain.QueryColumn{{Name: "keyword", Type: domain.QueryColumnTypeString}},
+			rows:    []domain.QueryRow{{"about"}, {"example"}},
+		},
+		{
+			name:    "keywords full scan",
+			query:   "SELECT keyword, page_id FROM keywords WHERE frequency >= 2",
+			columns: []domain.QueryColumn{{Name: "keyword", Type: domain.QueryColumnTypeString}, {Name: "page_id", Type: domain.QueryColumnTypeString}},
+			rows:    []domain.QueryRow{{"about", "page2"}, {"example", "page1"}},
+		},
+		{
+			name:    "leaves out removed pages",
+			query:   "SELECT id FROM pages WHERE id = 'page4' OR title = 'Bonjour'",
+			columns: []domain.QueryColumn{{Name: "id", Type: domain.QueryColumnTypeString}},
+			rows:    []domain.QueryRow{{"page3"}},
+		},
+		{
+			name:    "no matches",
+			query:   "SELECT id FROM pages WHERE title = 'missing'",
+			columns: []domain.QueryColumn{{Name: "id", Type: domain.QueryColumnTypeString}},
+			rows:    []domain.QueryRow{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := setupService(t)
+
+			result, err := svc.Query(tc.query)
+			if err != nil {
+				t.Fatalf("Error querying: %v", err)
+			}
+
+			if !reflect.DeepEqual(result.Columns, tc.columns) {
+				t.Errorf("Expected columns %v, got %v", tc.columns, result.Columns)
+			}
+
+			if !reflect.DeepEqual(result.Rows, tc.rows) {
+				t.Errorf("Expected rows %v, got %v", tc.rows, result.Rows)
+			}
+		})
+	}
+}
+
+func TestQueryInvalid(t *testing.T) {
+	cases := []struct {
+		name  string
+		query string
+	}{
+		{name: "syntax error", query: "SELECT FROM pages"},
+		{name: "unknown table", query: "SELECT * FROM users"},
+		{name: "unknown column", query: "SELECT password FROM pages"},
+		{name: "unknown order by column", query: "SELECT * FROM pages ORDER BY rank"},
+		{name: "unknown where column", query: "SELECT * FROM pages WHERE rank = 1"},
+		{name: "type mismatch", query: "SELECT * FROM keywords WHERE frequency = 'high'"},
+		{name: "like on int", query: "SELECT * FROM keywords WHERE frequency LIKE '1%'"},
+		{name: "bad time", query: "SELECT * FROM pages WHERE updated_at > 'yesterday'"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := setupService(t)
+
+			_, err := svc.Query(tc.query)
+			if !errors.Is(err, domain.ErrInvalidQuery) {
+				t.Errorf("Expected ErrInvalidQuery, got %v", err)
+			}
+		})
+	}
 }
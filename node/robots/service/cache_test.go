@@ -0,0 +1,49 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"crawlquery/pkg/testutil"
+
+	"github.com/h2non/gock"
+)
+
+func TestCacheEviction(t *testing.T) {
+	t.Run("sweeps out expired sites when another is cached", func(t *testing.T) {
+		defer gock.Off()
+
+		for _, site := range []string{"http://first.com", "http://second.com"} {
+			gock.New(site).
+				Get("/robots.txt").
+				Reply(200).
+				BodyString("User-agent: *\nAllow: /")
+		}
+
+		service := NewService(&http.Client{}, time.Hour, testutil.NewTestLogger())
+
+		if _, _, err := service.Check("http://first.com/"); err != nil {
+			t.Fatalf("Error checking robots.txt: %v", err)
+		}
+
+		// first.com's robots.txt expired a while ago
+		service.cache["http://first.com"] = entry{
+			robots:    service.cache["http://first.com"].robots,
+			fetchedAt: time.Now().Add(-2 * time.Hour),
+		}
+		service.sweptAt = time.Now().Add(-2 * time.Hour)
+
+		if _, _, err := service.Check("http://second.com/"); err != nil {
+			t.Fatalf("Error checking robots.txt: %v", err)
+		}
+
+		if _, ok := service.cache["http://first.com"]; ok {
+			t.Errorf("Expected the expired robots.txt to be evicted")
+		}
+
+		if _, ok := service.cache["http://second.com"]; !ok {
+			t.Errorf("Expected the fresh robots.txt to stay cached")
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/temoto/robotstxt"
+	"go.uber.org/zap"
+)
+
+// UserAgent is the agent looked up in robots.txt groups. Sites that don't
+// name it get their "*" rules applied.
+const UserAgent = "crawlquery"
+
+type entry struct {
+	robots    *robotstxt.RobotsData
+	fetchedAt time.Time
+}
+
+// Service fetches robots.txt files and caches them per site for ttl.
+// Expired entries are swept out at most once per ttl, when another site is
+// cached.
+type Service struct {
+	client *http.Client
+	ttl    time.Duration
+	logger *zap.SugaredLogger
+
+	mu      sync.Mutex
+	cache   map[string]entry
+	sweptAt time.Time
+}
+
+func NewService(client *http.Client, ttl time.Duration, logger *zap.SugaredLogger) *Service {
+	return &Service{
+		client: client,
+		ttl:    ttl,
+		logger: logger,
+		cache:  make(map[string]entry),
+	}
+}
+
+// Check reports whether rawURL may be crawled and the crawl delay the site's
+// robots.txt asks for. A missing robots.txt allows everything and one that
+// fails with a server error disallows everything.
+func (s *Service) Check(rawURL string) (bool, time.Duration, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return false, 0, err
+	}
+
+	robots, err := s.robots(parsed)
+	if err != nil {
+		return false, 0, err
+	}
+
+	group := robots.FindGroup(UserAgent)
+
+	path := parsed.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if parsed.RawQuery != "" {
+		path += "?" + parsed.RawQuery
+	}
+
+	return robots.TestAgent(path, UserAgent), group.CrawlDelay, nil
+}
+
+func (s *Service) robots(u *url.URL) (*robotstxt.RobotsData, error) {
+	site := u.Scheme + "://" + u.Host
+
+	s.mu.Lock()
+	cached, ok := s.cache[site]
+	s.mu.Unlock()
+
+	if ok && time.Since(cached.fetchedAt) < s.ttl {
+		return cached.robots, nil
+	}
+
+	res, err := s.client.Get(site + "/robots.txt")
+	if err != nil {
+		s.logger.Errorw("Error fetching robots.txt", "error", err, "site", site)
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	robots, err := robotstxt.FromResponse(res)
+	if err != nil {
+		s.logger.Errorw("Error parsing robots.txt", "error", err, "site", site)
+		return nil, err
+	}
+
+	now := time.Now()
+
+	s.mu.Lock()
+	s.cache[site] = entry{robots: robots, fetchedAt: now}
+	if now.Sub(s.sweptAt) >= s.ttl {
+		s.evictExpired(now)
+	}
+	s.mu.Unlock()
+
+	return robots, nil
+}
+
+// evictExpired drops the cached robots.txt files older than ttl. The caller
+// must hold mu.
+func (s *Service) evictExpired(now time.Time) {
+	for site, cached := range s.cache {
+		if now.Sub(cached.fetchedAt) >= s.ttl {
+			delete(s.cache, site)
+		}
+	}
+	s.sweptAt = now
+}
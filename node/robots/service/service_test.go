@@ -0,0 +1,86 @@
+package service_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	robotsService "crawlquery/node/robots/service"
+	"crawlquery/pkg/testutil"
+
+	"github.com/h2non/gock"
+)
+
+func TestCheck(t *testing.T) {
+	t.Run("applies the rules and crawl delay", func(t *testing.T) {
+		defer gock.Off()
+
+		gock.New("http://example.com").
+			Get("/robots.txt").
+			Reply(200).
+			BodyString("User-agent: *\nCrawl-delay: 3\nDisallow: /private")
+
+		service := robotsService.NewService(&http.Client{}, time.Hour, testutil.NewTestLogger())
+
+		allowed, delay, err := service.Check("http://example.com/public")
+		if err != nil {
+			t.Fatalf("Error checking robots.txt: %v", err)
+		}
+
+		if !allowed {
+			t.Errorf("Expected /public to be allowed")
+		}
+
+		if delay != 3*time.Second {
+			t.Errorf("Expected crawl delay of 3s, got %s", delay)
+		}
+
+		// served from the cache, robots.txt is only mocked once
+		allowed, _, err = service.Check("http://example.com/private/page")
+		if err != nil {
+			t.Fatalf("Error checking robots.txt: %v", err)
+		}
+
+		if allowed {
+			t.Errorf("Expected /private/page to be disallowed")
+		}
+	})
+
+	t.Run("allows everything when robots.txt is missing", func(t *testing.T) {
+		defer gock.Off()
+
+		gock.New("http://example.com").
+			Get("/robots.txt").
+			Reply(404)
+
+		service := robotsService.NewService(&http.Client{}, time.Hour, testutil.NewTestLogger())
+
+		allowed, delay, err := service.Check("http://example.com/anything")
+		if err != nil {
+			t.Fatalf("Error checking robots.txt: %v", err)
+		}
+
+		if !allowed || delay != 0 {
+			t.Errorf("Expected allowed with no delay, got %v and %s", allowed, delay)
+		}
+	})
+
+	t.Run("disallows everything when robots.txt errors", func(t *testing.T) {
+		defer gock.Off()
+
+		gock.New("http://example.com").
+			Get("/robots.txt").
+			Reply(503)
+
+		service := robotsService.NewService(&http.Client{}, time.Hour, testutil.NewTestLogger())
+
+		allowed, _, err := service.Check("http://example.com/anything")
+		if err != nil {
+			t.Fatalf("Error checking robots.txt: %v", err)
+		}
+
+		if allowed {
+			t.Errorf("Expected disallowed")
+		}
+	})
+}
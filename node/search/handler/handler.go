@@ -2,6 +2,8 @@ package handler
 
 import (
 	"crawlquery/node/domain"
+	"crawlquery/node/dto"
+	"errors"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -20,15 +22,32 @@ func NewHandler(service domain.SearchService, logger *zap.SugaredLogger) *Search
 }
 
 func (sh *SearchHandler) Search(c *gin.Context) {
-	q := c.Query("q")
-	if q == "" {
+	var req dto.SearchRequest
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if req.Query == "" {
 		c.JSON(400, gin.H{
 			"error": "missing query",
 		})
 		return
 	}
 
-	res, err := sh.service.Search(q)
+	offset, limit := req.Window(domain.DefaultSearchLimit)
+
+	res, err := sh.service.Search(req.Query, offset, limit)
+
+	if errors.Is(err, domain.ErrInvalidSearchQuery) {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
 
 	if err != nil {
 		c.JSON(500, gin.H{
@@ -38,6 +57,7 @@ func (sh *SearchHandler) Search(c *gin.Context) {
 	}
 
 	c.JSON(200, gin.H{
-		"results": res,
+		"results":    res.Results,
+		"total_hits": res.TotalHits,
 	})
 }
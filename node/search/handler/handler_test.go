@@ -1,6 +1,7 @@
 package handler_test
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -14,7 +15,9 @@ import (
 	pageRepo "crawlquery/node/page/repository/mem"
 	pageService "crawlquery/node/page/service"
 	searchHandler "crawlquery/node/search/handler"
+	"crawlquery/node/search/scorer"
 	searchService "crawlquery/node/search/service"
+	"crawlquery/pkg/dto"
 	"crawlquery/pkg/testutil"
 )
 
@@ -54,7 +57,7 @@ func TestSearch(t *testing.T) {
 
 	t.Run("returns results", func(t *testing.T) {
 		pageRepo, keywordRepo, pageService, keywordService := setupTestRepos()
-		searchSvc := searchService.NewService(pageService, keywordService)
+		searchSvc := searchService.NewService(pageService, keywordService, &scorer.Frequency{}, nil)
 
 		page1 := domain.Page{
 			ID:    "page1",
@@ -107,4 +110,75 @@ func TestSearch(t *testing.T) {
 			t.Errorf("expected status BadRequest; got %v", w.Code)
 		}
 	})
+	t.Run("returns error if query is invalid", func(t *testing.T) {
+		_, _, pageService, keywordService := setupTestRepos()
+		searchSvc := searchService.NewService(pageService, keywordService, &scorer.Frequency{}, nil)
+		searchHandler := searchHandler.NewHandler(searchSvc, testutil.NewTestLogger())
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+
+		ctx.Request, _ = http.NewRequest(http.MethodGet, "/search?q=keyword+OR", nil)
+
+		searchHandler.Search(ctx)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status BadRequest; got %v", w.Code)
+		}
+	})
+	t.Run("returns the requested page", func(t *testing.T) {
+		pageRepo, keywordRepo, pageService, keywordService := setupTestRepos()
+		searchSvc := searchService.NewService(pageService, keywordService, &scorer.Frequency{}, nil)
+
+		for i, id := range []string{"page1", "page2", "page3"} {
+			savePage(t, pageRepo, keywordRepo, domain.Page{ID: id, URL: "http://example.com/" + id}, map[domain.Keyword]domain.KeywordOccurrence{
+				"keyword": {PageID: id, Frequency: 3 - i, Positions: []int{1}},
+			})
+		}
+
+		searchHandler := searchHandler.NewHandler(searchSvc, testutil.NewTestLogger())
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+
+		ctx.Request, _ = http.NewRequest(http.MethodGet, "/search?q=keyword&page=2&limit=1", nil)
+
+		searchHandler.Search(ctx)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status OK; got %v", w.Code)
+		}
+
+		var res dto.NodeSearchResponse
+		if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+			t.Fatalf("error decoding response: %v", err)
+		}
+
+		if len(res.Results) != 1 || res.Results[0].PageID != "page2" {
+			t.Errorf("expected page2 only; got %v", res.Results)
+		}
+
+		if res.TotalHits != 3 {
+			t.Errorf("expected 3 total hits; got %d", res.TotalHits)
+		}
+	})
+
+	t.Run("returns error if the window is invalid", func(t *testing.T) {
+		_, _, pageService, keywordService := setupTestRepos()
+		searchSvc := searchService.NewService(pageService, keywordService, &scorer.Frequency{}, nil)
+		searchHandler := searchHandler.NewHandler(searchSvc, testutil.NewTestLogger())
+
+		for _, params := range []string{"limit=-1", "offset=-1", "page=-1", "limit=abc", "offset=999&limit=2"} {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+
+			ctx.Request, _ = http.NewRequest(http.MethodGet, "/search?q=keyword&"+params, nil)
+
+			searchHandler.Search(ctx)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status BadRequest for %s; got %v", params, w.Code)
+			}
+		}
+	})
 }
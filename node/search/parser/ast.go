@@ -0,0 +1,78 @@
+package parser
+
+import "fmt"
+
+// Error is returned when a search query cannot be parsed.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+// Field is the name of a field a search can be scoped to.
+type Field string
+
+const (
+	// FieldSite matches pages on a host or its subdomains, optionally
+	// under a path, as in site:example.com/blog.
+	FieldSite Field = "site"
+	// FieldLang matches the page language, as in lang:fr.
+	FieldLang Field = "lang"
+	// FieldInTitle matches text in the page title.
+	FieldInTitle Field = "intitle"
+	// FieldInURL matches text in the page URL.
+	FieldInURL Field = "inurl"
+)
+
+var fields = map[string]Field{
+	string(FieldSite):    FieldSite,
+	string(FieldLang):    FieldLang,
+	string(FieldInTitle): FieldInTitle,
+	string(FieldInURL):   FieldInURL,
+}
+
+// Node is a node in a parsed search query.
+type Node interface {
+	node()
+}
+
+// Term is a single lower cased word.
+type Term struct {
+	Word string
+}
+
+// Phrase is a quoted run of lower cased words that must appear together.
+type Phrase struct {
+	Words []string
+}
+
+// FieldMatch scopes the search to pages whose field matches the value.
+type FieldMatch struct {
+	Field Field
+	Value string
+}
+
+// Not excludes pages that match its node.
+type Not struct {
+	Node Node
+}
+
+// And combines nodes written next to each other.
+type And struct {
+	Nodes []Node
+}
+
+// Or matches pages that match any of its nodes.
+type Or struct {
+	Nodes []Node
+}
+
+func (*Term) node()       {}
+func (*Phrase) node()     {}
+func (*FieldMatch) node() {}
+func (*Not) node()        {}
+func (*And) node()        {}
+func (*Or) node()         {}
@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind uint8
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenPhrase
+	tokenField
+	tokenMinus
+	tokenOr
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// field is set for field tokens, whose text is the value.
+	field Field
+	pos   int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenPhrase:
+		return `"` + t.text + `"`
+	case tokenField:
+		return string(t.field) + ":" + t.text
+	case tokenOr:
+		return "OR"
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+func isBreak(r rune) bool {
+	return unicode.IsSpace(r) || r == '(' || r == ')' || r == '"'
+}
+
+// readQuoted reads up to the closing quote from i, which is just past the
+// opening quote. An unterminated quote runs to the end of the input.
+func readQuoted(runes []rune, i int) (string, int) {
+	start := i
+	for i < len(runes) && runes[i] != '"' {
+		i++
+	}
+	text := string(runes[start:i])
+	if i < len(runes) {
+		i++
+	}
+	return text, i
+}
+
+// normalize lower cases text and collapses its whitespace.
+func normalize(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+
+		case r == '"':
+			start := i
+			var text string
+			text, i = readQuoted(runes, i+1)
+			tokens = append(tokens, token{kind: tokenPhrase, text: normalize(text), pos: start})
+
+		// a minus only negates when it starts a word, so e-mail stays a word
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != ')':
+			tokens = append(tokens, token{kind: tokenMinus, text: "-", pos: i})
+			i++
+
+		default:
+			start := i
+			for i < len(runes) && !isBreak(runes[i]) {
+				i++
+			}
+			word := string(runes[start:i])
+
+			if word == "OR" {
+				tokens = append(tokens, token{kind: tokenOr, text: word, pos: start})
+				continue
+			}
+
+			name, value, ok := strings.Cut(word, ":")
+			field, known := fields[strings.ToLower(name)]
+			if !ok || !known {
+				tokens = append(tokens, token{kind: tokenWord, text: strings.ToLower(word), pos: start})
+				continue
+			}
+
+			// the value may be quoted, as in intitle:"bolognese sauce"
+			if value == "" && i < len(runes) && runes[i] == '"' {
+				value, i = readQuoted(runes, i+1)
+			}
+
+			value = normalize(value)
+			if value == "" {
+				return nil, &Error{Pos: start, Msg: fmt.Sprintf("missing value for %s:", field)}
+			}
+
+			tokens = append(tokens, token{kind: tokenField, text: value, field: field, pos: start})
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(runes)})
+
+	return tokens, nil
+}
@@ -0,0 +1,156 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse parses a search query. Words written next to each other are
+// combined with AND and bind tighter than OR:
+//
+//	bolognese "tomato sauce" -garlic
+//	recipe (pasta OR pizza) site:example.com lang:it
+//	intitle:"weeknight dinners" inurl:recipes
+//
+// A leading - excludes a word, phrase, field or group. Only upper case OR is
+// an operator, so "or" on its own is searched for like any other word.
+func Parse(query string) (Node, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	if p.peek().kind == tokenEOF {
+		return nil, p.errorf(p.peek(), "empty query")
+	}
+
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.errorf(t, "unexpected %s", t)
+	}
+
+	return node, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return &Error{Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) parseOr() (Node, error) {
+	node, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []Node{node}
+
+	for p.peek().kind == tokenOr {
+		p.next()
+
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+
+	return &Or{Nodes: nodes}, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	var nodes []Node
+
+	for {
+		switch p.peek().kind {
+		case tokenEOF, tokenOr, tokenRParen:
+			if len(nodes) == 0 {
+				return nil, p.errorf(p.peek(), "expected a term, got %s", p.peek())
+			}
+			if len(nodes) == 1 {
+				return nodes[0], nil
+			}
+			return &And{Nodes: nodes}, nil
+		}
+
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	if p.peek().kind == tokenMinus {
+		p.next()
+
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Node: node}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokenWord:
+		return &Term{Word: t.text}, nil
+
+	case tokenPhrase:
+		words := strings.Fields(t.text)
+		if len(words) == 0 {
+			return nil, p.errorf(t, "empty phrase")
+		}
+		if len(words) == 1 {
+			return &Term{Word: words[0]}, nil
+		}
+		return &Phrase{Words: words}, nil
+
+	case tokenField:
+		return &FieldMatch{Field: t.field, Value: t.text}, nil
+
+	case tokenLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, p.errorf(closing, "expected ), got %s", closing)
+		}
+		return node, nil
+	}
+
+	return nil, p.errorf(t, "expected a term, got %s", t)
+}
@@ -0,0 +1,180 @@
+package parser_test
+
+import (
+	"crawlquery/node/search/parser"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name  string
+		query string
+		want  parser.Node
+	}{
+		{
+			name:  "single term is lower cased",
+			query: "Bolognese",
+			want:  &parser.Term{Word: "bolognese"},
+		},
+		{
+			name:  "terms are combined with and",
+			query: "bolognese sauce",
+			want: &parser.And{Nodes: []parser.Node{
+				&parser.Term{Word: "bolognese"},
+				&parser.Term{Word: "sauce"},
+			}},
+		},
+		{
+			name:  "phrase",
+			query: `"Bolognese   Sauce"`,
+			want:  &parser.Phrase{Words: []string{"bolognese", "sauce"}},
+		},
+		{
+			name:  "single word phrase is a term",
+			query: `"sauce"`,
+			want:  &parser.Term{Word: "sauce"},
+		},
+		{
+			name:  "unterminated phrase runs to the end",
+			query: `recipe "bolognese sauce`,
+			want: &parser.And{Nodes: []parser.Node{
+				&parser.Term{Word: "recipe"},
+				&parser.Phrase{Words: []string{"bolognese", "sauce"}},
+			}},
+		},
+		{
+			name:  "exclusion",
+			query: "sauce -garlic",
+			want: &parser.And{Nodes: []parser.Node{
+				&parser.Term{Word: "sauce"},
+				&parser.Not{Node: &parser.Term{Word: "garlic"}},
+			}},
+		},
+		{
+			name:  "hyphenated words are not excluded",
+			query: "e-mail - client",
+			want: &parser.And{Nodes: []parser.Node{
+				&parser.Term{Word: "e-mail"},
+				&parser.Term{Word: "-"},
+				&parser.Term{Word: "client"},
+			}},
+		},
+		{
+			name:  "and binds tighter than or",
+			query: "pasta sauce OR pizza",
+			want: &parser.Or{Nodes: []parser.Node{
+				&parser.And{Nodes: []parser.Node{
+					&parser.Term{Word: "pasta"},
+					&parser.Term{Word: "sauce"},
+				}},
+				&parser.Term{Word: "pizza"},
+			}},
+		},
+		{
+			name:  "lower case or is a term",
+			query: "pasta or pizza",
+			want: &parser.And{Nodes: []parser.Node{
+				&parser.Term{Word: "pasta"},
+				&parser.Term{Word: "or"},
+				&parser.Term{Word: "pizza"},
+			}},
+		},
+		{
+			name:  "parentheses",
+			query: "recipe (pasta OR pizza)",
+			want: &parser.And{Nodes: []parser.Node{
+				&parser.Term{Word: "recipe"},
+				&parser.Or{Nodes: []parser.Node{
+					&parser.Term{Word: "pasta"},
+					&parser.Term{Word: "pizza"},
+				}},
+			}},
+		},
+		{
+			name:  "excluded group",
+			query: "recipe -(garlic OR onion)",
+			want: &parser.And{Nodes: []parser.Node{
+				&parser.Term{Word: "recipe"},
+				&parser.Not{Node: &parser.Or{Nodes: []parser.Node{
+					&parser.Term{Word: "garlic"},
+					&parser.Term{Word: "onion"},
+				}}},
+			}},
+		},
+		{
+			name:  "fields",
+			query: "site:Example.com LANG:fr intitle:sauce inurl:recipes",
+			want: &parser.And{Nodes: []parser.Node{
+				&parser.FieldMatch{Field: parser.FieldSite, Value: "example.com"},
+				&parser.FieldMatch{Field: parser.FieldLang, Value: "fr"},
+				&parser.FieldMatch{Field: parser.FieldInTitle, Value: "sauce"},
+				&parser.FieldMatch{Field: parser.FieldInURL, Value: "recipes"},
+			}},
+		},
+		{
+			name:  "quoted field value",
+			query: `intitle:"Bolognese Sauce"`,
+			want:  &parser.FieldMatch{Field: parser.FieldInTitle, Value: "bolognese sauce"},
+		},
+		{
+			name:  "excluded field",
+			query: "sauce -site:example.com",
+			want: &parser.And{Nodes: []parser.Node{
+				&parser.Term{Word: "sauce"},
+				&parser.Not{Node: &parser.FieldMatch{Field: parser.FieldSite, Value: "example.com"}},
+			}},
+		},
+		{
+			name:  "unknown fields are terms",
+			query: "note:sauce",
+			want:  &parser.Term{Word: "note:sauce"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parser.Parse(tc.query)
+			if err != nil {
+				t.Fatalf("Error parsing %q: %v", tc.query, err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected %#v, got %#v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		query string
+		pos   int
+	}{
+		{name: "empty", query: "   ", pos: 3},
+		{name: "leading or", query: "OR sauce", pos: 0},
+		{name: "trailing or", query: "sauce OR", pos: 8},
+		{name: "missing field value", query: "sauce site:", pos: 6},
+		{name: "unclosed group", query: "(pasta OR pizza", pos: 15},
+		{name: "unopened group", query: "pasta)", pos: 5},
+		{name: "empty group", query: "recipe ()", pos: 8},
+		{name: "empty phrase", query: `recipe ""`, pos: 7},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parser.Parse(tc.query)
+
+			var parseErr *parser.Error
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Expected a parse error for %q, got %v", tc.query, err)
+			}
+
+			if parseErr.Pos != tc.pos {
+				t.Errorf("Expected error at %d, got %d (%v)", tc.pos, parseErr.Pos, err)
+			}
+		})
+	}
+}
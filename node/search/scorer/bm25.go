@@ -0,0 +1,86 @@
+package scorer
+
+import (
+	"crawlquery/node/domain"
+	"math"
+)
+
+const (
+	DefaultBM25K1 = 1.2
+	DefaultBM25B  = 0.75
+)
+
+// BM25 scores pages with Okapi BM25. Term frequencies are normalised by the
+// page's token count relative to the average page, and each keyword is
+// weighted by its inverse document frequency across the node's index.
+type BM25 struct {
+	keywordService domain.KeywordService
+	// K1 controls how quickly repeated terms saturate.
+	K1 float64
+	// B controls how strongly scores are normalised by page length.
+	B float64
+}
+
+func NewBM25(keywordService domain.KeywordService) *BM25 {
+	return &BM25{
+		keywordService: keywordService,
+		K1:             DefaultBM25K1,
+		B:              DefaultBM25B,
+	}
+}
+
+func (BM25) Name() string {
+	return "bm25"
+}
+
+func (s *BM25) idf(totalPages, pagesWithKeyword int) float64 {
+	n := float64(totalPages)
+	df := float64(pagesWithKeyword)
+	return math.Log(1 + (n-df+0.5)/(df+0.5))
+}
+
+func (s *BM25) Score(matches []domain.KeywordMatch) (map[string]float64, error) {
+	scores := map[string]float64{}
+
+	if len(matches) == 0 {
+		return scores, nil
+	}
+
+	stats, err := s.keywordService.Stats()
+	if err != nil {
+		return nil, err
+	}
+
+	avgLength := 0.0
+	if stats.TotalPages > 0 {
+		avgLength = float64(stats.TotalTokens) / float64(stats.TotalPages)
+	}
+
+	lengths := map[string]float64{}
+
+	for _, match := range matches {
+		idf := s.idf(stats.TotalPages, len(match.Occurrences))
+
+		for _, occurrence := range match.Occurrences {
+			length, ok := lengths[occurrence.PageID]
+			if !ok {
+				count, err := s.keywordService.PageTokenCount(occurrence.PageID)
+				if err != nil {
+					return nil, err
+				}
+				length = float64(count)
+				lengths[occurrence.PageID] = length
+			}
+
+			norm := 1.0
+			if avgLength > 0 {
+				norm = 1 - s.B + s.B*length/avgLength
+			}
+
+			tf := float64(occurrence.Frequency)
+			scores[occurrence.PageID] += idf * tf * (s.K1 + 1) / (tf + s.K1*norm)
+		}
+	}
+
+	return scores, nil
+}
@@ -0,0 +1,29 @@
+package scorer
+
+import "crawlquery/node/domain"
+
+// Frequency scores a page as the sum of its matched keyword frequencies
+// multiplied by the number of keywords it matched.
+type Frequency struct{}
+
+func (Frequency) Name() string {
+	return "frequency"
+}
+
+func (*Frequency) Score(matches []domain.KeywordMatch) (map[string]float64, error) {
+	scores := map[string]float64{}
+	matched := map[string]int{}
+
+	for _, match := range matches {
+		for _, occurrence := range match.Occurrences {
+			scores[occurrence.PageID] += float64(occurrence.Frequency)
+			matched[occurrence.PageID]++
+		}
+	}
+
+	for pageID := range scores {
+		scores[pageID] *= float64(matched[pageID])
+	}
+
+	return scores, nil
+}
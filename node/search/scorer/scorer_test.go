@@ -0,0 +1,122 @@
+package scorer_test
+
+import (
+	"testing"
+
+	"crawlquery/node/domain"
+	"crawlquery/node/search/scorer"
+
+	keywordOccurrenceRepo "crawlquery/node/keyword/occurrence/repository/mem"
+	keywordService "crawlquery/node/keyword/service"
+)
+
+func TestFrequency(t *testing.T) {
+	matches := []domain.KeywordMatch{
+		{
+			Keyword: "example",
+			Occurrences: []domain.KeywordOccurrence{
+				{PageID: "page1", Frequency: 3},
+				{PageID: "page2", Frequency: 1},
+			},
+		},
+		{
+			Keyword: "contact",
+			Occurrences: []domain.KeywordOccurrence{
+				{PageID: "page2", Frequency: 1},
+			},
+		},
+	}
+
+	scores, err := (&scorer.Frequency{}).Score(matches)
+	if err != nil {
+		t.Fatalf("Error scoring: %v", err)
+	}
+
+	if scores["page1"] != 3 {
+		t.Errorf("Expected page1 score 3, got %f", scores["page1"])
+	}
+
+	if scores["page2"] != 4 {
+		t.Errorf("Expected page2 score 4, got %f", scores["page2"])
+	}
+}
+
+func TestBM25(t *testing.T) {
+	repo := keywordOccurrenceRepo.NewRepository()
+	svc := keywordService.NewService(repo)
+
+	occurrences := map[domain.Keyword][]domain.KeywordOccurrence{
+		// a short page about go
+		"go": {
+			{PageID: "short", Frequency: 2},
+			{PageID: "long", Frequency: 4},
+			{PageID: "other", Frequency: 1},
+		},
+		"compiler": {
+			{PageID: "short", Frequency: 1},
+		},
+		// a long page that mentions go more often among many other words
+		"recipe": {
+			{PageID: "long", Frequency: 40},
+			{PageID: "other", Frequency: 5},
+		},
+		"cake": {
+			{PageID: "long", Frequency: 30},
+		},
+	}
+
+	for keyword, occs := range occurrences {
+		for _, occ := range occs {
+			if err := repo.Add(keyword, occ); err != nil {
+				t.Fatalf("Error adding occurrence: %v", err)
+			}
+		}
+	}
+
+	bm25 := scorer.NewBM25(svc)
+
+	matches, err := svc.GetKeywordMatches([]domain.Keyword{"go"})
+	if err != nil {
+		t.Fatalf("Error getting matches: %v", err)
+	}
+
+	scores, err := bm25.Score(matches)
+	if err != nil {
+		t.Fatalf("Error scoring: %v", err)
+	}
+
+	if scores["short"] <= scores["long"] {
+		t.Errorf("Expected the short page to outscore the long page, got %f <= %f", scores["short"], scores["long"])
+	}
+
+	t.Run("rare keywords weigh more", func(t *testing.T) {
+		matches, err := svc.GetKeywordMatches([]domain.Keyword{"go", "compiler"})
+		if err != nil {
+			t.Fatalf("Error getting matches: %v", err)
+		}
+
+		scores, err := bm25.Score(matches)
+		if err != nil {
+			t.Fatalf("Error scoring: %v", err)
+		}
+
+		both := scores["short"]
+		matches, _ = svc.GetKeywordMatches([]domain.Keyword{"go"})
+		without, _ := bm25.Score(matches)
+
+		if both-without["short"] <= without["short"]/2 {
+			t.Errorf("Expected a rare keyword to add more than half the common keyword score, got %f and %f", both-without["short"], without["short"])
+		}
+	})
+
+	t.Run("returns no scores without matches", func(t *testing.T) {
+		scores, err := bm25.Score(nil)
+		if err != nil {
+			t.Fatalf("Error scoring: %v", err)
+		}
+
+		if len(scores) != 0 {
+			t.Errorf("Expected no scores, got %v", scores)
+		}
+	})
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"crawlquery/node/domain"
+	"crawlquery/node/search/parser"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/pemistahl/lingua-go"
+)
+
+// queryTerms holds the words a parsed query looks up in the keyword index.
+type queryTerms struct {
+	// included holds the words searched for, in query order. They are
+	// matched, scored and ranked.
+	included []string
+	// excluded holds each excluded term or phrase. They are only looked up
+	// to filter pages out.
+	excluded [][]string
+	// fields is set when the query scopes the search by a field that is
+	// not excluded.
+	fields bool
+}
+
+func collectTerms(node parser.Node, negated bool, terms *queryTerms) {
+	switch n := node.(type) {
+	case *parser.Term:
+		if negated {
+			terms.excluded = append(terms.excluded, []string{n.Word})
+		} else {
+			terms.included = append(terms.included, n.Word)
+		}
+	case *parser.Phrase:
+		if negated {
+			terms.excluded = append(terms.excluded, n.Words)
+		} else {
+			terms.included = append(terms.included, n.Words...)
+		}
+	case *parser.FieldMatch:
+		if !negated {
+			terms.fields = true
+		}
+	case *parser.Not:
+		collectTerms(n.Node, !negated, terms)
+	case *parser.And:
+		for _, child := range n.Nodes {
+			collectTerms(child, negated, terms)
+		}
+	case *parser.Or:
+		for _, child := range n.Nodes {
+			collectTerms(child, negated, terms)
+		}
+	}
+}
+
+// matchesWithoutTerms reports whether a page could match the node without
+// containing any searched for word, in which case candidates can't be taken
+// from the keyword index alone.
+func matchesWithoutTerms(node parser.Node) bool {
+	switch n := node.(type) {
+	case *parser.FieldMatch, *parser.Not:
+		return true
+	case *parser.And:
+		for _, child := range n.Nodes {
+			if _, ok := child.(*parser.Term); ok || !matchesWithoutTerms(child) {
+				return false
+			}
+		}
+		return true
+	case *parser.Or:
+		for _, child := range n.Nodes {
+			if matchesWithoutTerms(child) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// document is a candidate page and the keyword occurrences looked up for it.
+type document struct {
+	page        *domain.Page
+	occurrences map[string]domain.KeywordOccurrence
+}
+
+// containsWord reports whether the word was found on the page, either as a
+// keyword of its own or as part of a longer keyword.
+func (d *document) containsWord(word string) bool {
+	for keyword := range d.occurrences {
+		if keyword == word || slices.Contains(strings.Fields(keyword), word) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches evaluates the node against the document. Of the words written next
+// to each other only one needs to be on the page, or none when a phrase
+// beside them matches, and the rest add to the score. This keeps plain
+// keyword queries as broad as before operators existed. Everything else in
+// an AND is required.
+func (d *document) matches(node parser.Node) bool {
+	switch n := node.(type) {
+	case *parser.Term:
+		return d.containsWord(n.Word)
+	case *parser.Phrase:
+		return matchPhrase(d.occurrences, n.Words)
+	case *parser.FieldMatch:
+		return d.matchesField(n)
+	case *parser.Not:
+		return !d.matches(n.Node)
+	case *parser.Or:
+		for _, child := range n.Nodes {
+			if d.matches(child) {
+				return true
+			}
+		}
+		return false
+	case *parser.And:
+		hasTerms, textMatched := false, false
+		for _, child := range n.Nodes {
+			if term, ok := child.(*parser.Term); ok {
+				hasTerms = true
+				textMatched = textMatched || d.containsWord(term.Word)
+				continue
+			}
+			if !d.matches(child) {
+				return false
+			}
+			if _, ok := child.(*parser.Phrase); ok {
+				textMatched = true
+			}
+		}
+		return !hasTerms || textMatched
+	}
+	return false
+}
+
+func (d *document) matchesField(field *parser.FieldMatch) bool {
+	switch field.Field {
+	case parser.FieldSite:
+		u, err := url.Parse(d.page.URL)
+		if err != nil {
+			return false
+		}
+
+		host, path, _ := strings.Cut(field.Value, "/")
+		pageHost := strings.ToLower(u.Hostname())
+
+		if pageHost != host && !strings.HasSuffix(pageHost, "."+host) {
+			return false
+		}
+
+		return path == "" || strings.HasPrefix(strings.ToLower(u.Path), "/"+path)
+
+	case parser.FieldLang:
+		language := strings.ToLower(d.page.Language)
+		if language == field.Value || strings.HasPrefix(language, field.Value+"-") {
+			return true
+		}
+
+		// The indexer stores the detected language by name, e.g. "French"
+		// for lang:fr.
+		detected := lingua.GetLanguageFromIsoCode639_1(lingua.GetIsoCode639_1FromValue(field.Value))
+		return detected != lingua.Unknown && strings.EqualFold(d.page.Language, detected.String())
+
+	case parser.FieldInTitle:
+		title := strings.Join(strings.Fields(strings.ToLower(d.page.Title)), " ")
+		return strings.Contains(title, field.Value)
+
+	case parser.FieldInURL:
+		return strings.Contains(strings.ToLower(d.page.URL), field.Value)
+	}
+
+	return false
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"crawlquery/node/domain"
+	"sort"
+	"strings"
+)
+
+// proximityWindow is the widest span of keyword positions, in keywords, over
+// which query terms still count as appearing close together.
+const proximityWindow = 10
+
+func containsPosition(positions []int, position int) bool {
+	for _, p := range positions {
+		if p == position {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPhrase reports whether the words appear as consecutive keywords on the
+// page. The keyword parser may keep several words together as one keyword, so
+// any split of the phrase into stored keywords at consecutive positions
+// matches, including the whole phrase stored as a single keyword.
+func matchPhrase(occurrences map[string]domain.KeywordOccurrence, words []string) bool {
+	var matchFrom func(start, position int) bool
+
+	matchFrom = func(start, position int) bool {
+		if start == len(words) {
+			return true
+		}
+
+		for end := start + 1; end <= len(words); end++ {
+			occurrence, ok := occurrences[strings.Join(words[start:end], " ")]
+			if !ok {
+				continue
+			}
+
+			if position < 0 {
+				for _, p := range occurrence.Positions {
+					if matchFrom(end, p+1) {
+						return true
+					}
+				}
+				continue
+			}
+
+			if containsPosition(occurrence.Positions, position) && matchFrom(end, position+1) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return matchFrom(0, -1)
+}
+
+// minSpan returns the smallest distance between the first and last keyword
+// position of a window holding every term, or false if a term is missing.
+func minSpan(occurrences map[string]domain.KeywordOccurrence, terms []string) (int, bool) {
+	type entry struct {
+		position int
+		term     int
+	}
+
+	var entries []entry
+
+	for i, term := range terms {
+		occurrence, ok := occurrences[term]
+		if !ok || len(occurrence.Positions) == 0 {
+			return 0, false
+		}
+		for _, p := range occurrence.Positions {
+			entries = append(entries, entry{position: p, term: i})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].position < entries[j].position
+	})
+
+	// slide a window over the sorted positions, shrinking it from the left
+	// while it still holds every term
+	counts := make([]int, len(terms))
+	covered := 0
+	best := -1
+	left := 0
+
+	for _, e := range entries {
+		if counts[e.term] == 0 {
+			covered++
+		}
+		counts[e.term]++
+
+		for covered == len(terms) {
+			span := e.position - entries[left].position
+			if best < 0 || span < best {
+				best = span
+			}
+
+			counts[entries[left].term]--
+			if counts[entries[left].term] == 0 {
+				covered--
+			}
+			left++
+		}
+	}
+
+	return best, best >= 0
+}
+
+// uniqueTerms drops repeated terms keeping the first occurrence of each.
+func uniqueTerms(terms []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			unique = append(unique, term)
+		}
+	}
+
+	return unique
+}
+
+// proximityLevel rewards pages where the query terms appear close together.
+// Terms in query order at consecutive positions get the full level. Otherwise
+// the level falls off with the span of the closest window holding every term,
+// so terms next to each other in a different order get half.
+func proximityLevel(occurrences map[string]domain.KeywordOccurrence, terms []string) domain.SignalLevel {
+	if len(terms) < 2 {
+		return domain.SignalLevelNone
+	}
+
+	if matchPhrase(occurrences, terms) {
+		return domain.SignalLevelHigh
+	}
+
+	terms = uniqueTerms(terms)
+	if len(terms) < 2 {
+		return domain.SignalLevelNone
+	}
+
+	span, ok := minSpan(occurrences, terms)
+	if !ok || span == 0 || span > proximityWindow {
+		return domain.SignalLevelNone
+	}
+
+	return domain.SignalLevelHigh * domain.SignalLevel(len(terms)-1) / domain.SignalLevel(span+1)
+}
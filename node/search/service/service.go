@@ -2,33 +2,55 @@ package service
 
 import (
 	"crawlquery/node/domain"
-	"sort"
+	"crawlquery/node/search/parser"
+	"fmt"
 	"strings"
 )
 
 type Service struct {
 	pageService    domain.PageService
 	keywordService domain.KeywordService
+	scorer         domain.Scorer
+	signals        []domain.WeightedSignal
 }
 
 func NewService(
 	pageService domain.PageService,
 	keywordService domain.KeywordService,
+	scorer domain.Scorer,
+	signals []domain.WeightedSignal,
 ) *Service {
 	return &Service{
 		pageService:    pageService,
 		keywordService: keywordService,
+		scorer:         scorer,
+		signals:        signals,
 	}
 }
 
-func sortResults(results []domain.Result) {
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Score > results[j].Score
-	})
+// applySignals adds the weighted level of every configured signal to the
+// result's score and records each signal's breakdown on the result.
+func (s *Service) applySignals(result *domain.Result, page *domain.Page, terms []string) {
+	for _, ws := range s.signals {
+		level, breakdown := ws.Signal.Level(page, terms)
+		result.Score += float64(level) * ws.Weight
+		result.Signals[ws.Signal.Name()] = breakdown
+	}
 }
 
-func (s *Service) getResultsForKeywords(keywords []domain.Keyword) ([]domain.Result, error) {
-	unsortedResults := map[string]domain.Result{}
+// excludedOccurrences looks up the excluded terms and phrases and returns
+// their occurrences by page.
+func (s *Service) excludedOccurrences(excluded [][]string) (map[string]map[string]domain.KeywordOccurrence, error) {
+	var keywords []domain.Keyword
+	for _, words := range excluded {
+		keywords = append(keywords, splitQueryIntoCombinations(strings.Join(words, " "))...)
+	}
+
+	occurrences := map[string]map[string]domain.KeywordOccurrence{}
+
+	if len(keywords) == 0 {
+		return occurrences, nil
+	}
 
 	matches, err := s.keywordService.GetKeywordMatches(keywords)
 	if err != nil {
@@ -37,61 +59,161 @@ func (s *Service) getResultsForKeywords(keywords []domain.Keyword) ([]domain.Res
 
 	for _, match := range matches {
 		for _, occurrence := range match.Occurrences {
-			page, err := s.pageService.Get(occurrence.PageID)
-			if err != nil {
-				return nil, err
+			if occurrences[occurrence.PageID] == nil {
+				occurrences[occurrence.PageID] = map[string]domain.KeywordOccurrence{}
 			}
+			occurrences[occurrence.PageID][string(match.Keyword)] = occurrence
+		}
+	}
+
+	return occurrences, nil
+}
+
+func (s *Service) getResults(query parser.Node, terms queryTerms) ([]domain.Result, error) {
+	unsortedResults := map[string]domain.Result{}
+	pages := map[string]*domain.Page{}
+
+	matches, err := s.keywordService.GetKeywordMatches(splitQueryIntoCombinations(strings.Join(terms.included, " ")))
+	if err != nil {
+		return nil, err
+	}
+
+	excluded, err := s.excludedOccurrences(terms.excluded)
+	if err != nil {
+		return nil, err
+	}
+
+	newResult := func(page *domain.Page) domain.Result {
+		return domain.Result{
+			PageID: page.ID,
+			Page: domain.ResultPage{
+				ID:          page.ID,
+				Hash:        page.Hash,
+				URL:         page.URL,
+				Title:       page.Title,
+				Description: page.Description,
+			},
+			Score:             0,
+			KeywordOccurences: map[string]domain.KeywordOccurrence{},
+			Signals:           map[string]domain.SignalBreakdown{},
+		}
+	}
+
+	// Field scoped queries can match pages without any of the words, so
+	// every page is a candidate
+	if matchesWithoutTerms(query) {
+		all, err := s.pageService.GetAll()
+		if err != nil {
+			return nil, err
+		}
+
+		for id, page := range all {
+			if page.Removed {
+				continue
+			}
+			unsortedResults[id] = newResult(page)
+			pages[id] = page
+		}
+	}
 
-			if _, ok := unsortedResults[page.ID]; !ok {
-				unsortedResults[page.ID] = domain.Result{
-					PageID: page.ID,
-					Page: domain.ResultPage{
-						ID:          page.ID,
-						Hash:        page.Hash,
-						URL:         page.URL,
-						Title:       page.Title,
-						Description: page.Description,
-					},
-					Score:             0,
-					KeywordOccurences: map[string]domain.KeywordOccurrence{},
+	for _, match := range matches {
+		for _, occurrence := range match.Occurrences {
+			if _, ok := unsortedResults[occurrence.PageID]; !ok {
+				page, err := s.pageService.Get(occurrence.PageID)
+				if err != nil {
+					return nil, err
 				}
+
+				unsortedResults[page.ID] = newResult(page)
+				pages[page.ID] = page
 			}
 
 			// Extract the result from the map, modify it, and put it back
-			result := unsortedResults[page.ID]
+			result := unsortedResults[occurrence.PageID]
 			result.KeywordOccurences[string(match.Keyword)] = occurrence
-			result.Score += float64(occurrence.Frequency)
-			unsortedResults[page.ID] = result
+			unsortedResults[occurrence.PageID] = result
 		}
 	}
 
-	// Multiply the score by the total number of keyword occurrences
-	for _, result := range unsortedResults {
-		result.Score *= float64(len(result.KeywordOccurences))
-		unsortedResults[result.Page.ID] = result
+	scores, err := s.scorer.Score(matches)
+	if err != nil {
+		return nil, err
 	}
 
 	results := []domain.Result{}
 
+	// Drop pages that don't satisfy the query, then score the keyword
+	// matches and add the proximity and weighted signal levels
 	for _, result := range unsortedResults {
+		if pages[result.PageID].Removed {
+			continue
+		}
+
+		doc := &document{
+			page:        pages[result.PageID],
+			occurrences: map[string]domain.KeywordOccurrence{},
+		}
+		for keyword, occurrence := range result.KeywordOccurences {
+			doc.occurrences[keyword] = occurrence
+		}
+		for keyword, occurrence := range excluded[result.PageID] {
+			doc.occurrences[keyword] = occurrence
+		}
+
+		if !doc.matches(query) {
+			continue
+		}
+
+		result.Score = scores[result.PageID]
+
+		proximity := proximityLevel(result.KeywordOccurences, terms.included)
+		result.Score += float64(proximity)
+		result.Signals["proximity"] = domain.SignalBreakdown{"proximity": proximity}
+
+		s.applySignals(&result, pages[result.PageID], terms.included)
 		results = append(results, result)
 	}
 
-	sortResults(results)
-
 	return results, nil
 }
 
-func (s *Service) Search(query string) ([]domain.Result, error) {
-	queryGroups := splitQueryIntoCombinations(query)
+// Search parses the query, finds the pages that satisfy it and returns the
+// limit highest ranked from offset on. See parser.Parse for the query syntax.
+func (s *Service) Search(query string, offset, limit int) (*domain.SearchResults, error) {
+	if offset < 0 || limit < 1 {
+		return nil, fmt.Errorf("%w: offset must be at least 0 and limit at least 1", domain.ErrInvalidSearchQuery)
+	}
+
+	if offset+limit > domain.MaxSearchDepth {
+		return nil, fmt.Errorf("%w: results beyond %d are not available", domain.ErrInvalidSearchQuery, domain.MaxSearchDepth)
+	}
+
+	node, err := parser.Parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidSearchQuery, err)
+	}
 
-	results, err := s.getResultsForKeywords(queryGroups)
+	var terms queryTerms
+	collectTerms(node, false, &terms)
+
+	if len(terms.included) == 0 && !terms.fields {
+		return nil, fmt.Errorf("%w: nothing to search for", domain.ErrInvalidSearchQuery)
+	}
 
+	results, err := s.getResults(node, terms)
 	if err != nil {
 		return nil, err
 	}
 
-	return results, nil
+	top := topResults(results, offset+limit)
+	if offset > len(top) {
+		offset = len(top)
+	}
+
+	return &domain.SearchResults{
+		Results:   top[offset:],
+		TotalHits: len(results),
+	}, nil
 }
 
 func splitQueryIntoCombinations(query string) []domain.Keyword {
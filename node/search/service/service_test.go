@@ -1,16 +1,25 @@
 package service_test
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"reflect"
+	"sort"
 	"testing"
 
 	"crawlquery/node/domain"
 	pageRepo "crawlquery/node/page/repository/mem"
 	pageService "crawlquery/node/page/service"
+	"crawlquery/node/parse"
+	"crawlquery/node/search/scorer"
 	"crawlquery/node/search/service"
 
 	keywordOccurrenceRepo "crawlquery/node/keyword/occurrence/repository/mem"
 	keywordService "crawlquery/node/keyword/service"
+
+	"github.com/PuerkitoBio/goquery"
+	testdataloader "github.com/peteole/testdata-loader"
 )
 
 func setupTestRepos() (*pageRepo.Repository, *keywordOccurrenceRepo.Repository, *pageService.Service, *keywordService.Service) {
@@ -37,6 +46,14 @@ func savePage(t *testing.T, pageRepo *pageRepo.Repository, keywordOccurrenceRepo
 	}
 }
 
+func search(svc *service.Service, query string) ([]domain.Result, error) {
+	res, err := svc.Search(query, 0, domain.DefaultSearchLimit)
+	if err != nil {
+		return nil, err
+	}
+	return res.Results, nil
+}
+
 func checkResult(t *testing.T, result, expected domain.Result) {
 	if result.PageID != expected.PageID {
 		t.Errorf("Expected PageID %s, got %s", expected.PageID, result.PageID)
@@ -60,7 +77,7 @@ func checkResult(t *testing.T, result, expected domain.Result) {
 
 func TestService_Search(t *testing.T) {
 	pageRepo, keywordRepo, pageService, keywordService := setupTestRepos()
-	svc := service.NewService(pageService, keywordService)
+	svc := service.NewService(pageService, keywordService, &scorer.Frequency{}, nil)
 
 	// Add pages and keyword occurrences
 	page1 := domain.Page{ID: "page1", URL: "http://example.com", Title: "Example"}
@@ -78,7 +95,7 @@ func TestService_Search(t *testing.T) {
 	savePage(t, pageRepo, keywordRepo, page2, keywordOccurrences2)
 
 	// Test Search
-	results, err := svc.Search("example contact")
+	results, err := search(svc, "example contact")
 	if err != nil {
 		t.Fatalf("Error searching: %v", err)
 	}
@@ -96,7 +113,9 @@ func TestService_Search(t *testing.T) {
 				URL:   "http://example.com/contact",
 				Title: "Contact",
 			},
-			Score: 4,
+			// frequency score of 4 plus a proximity boost of 7.5 for
+			// the terms appearing 3 keywords apart
+			Score: 11.5,
 			KeywordOccurences: map[string]domain.KeywordOccurrence{
 				"example": {PageID: "page2", Frequency: 1, Positions: []int{1}},
 				"contact": {PageID: "page2", Frequency: 1, Positions: []int{4}},
@@ -121,3 +140,401 @@ func TestService_Search(t *testing.T) {
 		checkResult(t, result, expectedResults[i])
 	}
 }
+
+type fixedSignal struct {
+	name  string
+	level domain.SignalLevel
+}
+
+func (f *fixedSignal) Name() string {
+	return f.name
+}
+
+func (f *fixedSignal) Level(page *domain.Page, terms []string) (domain.SignalLevel, domain.SignalBreakdown) {
+	if page.ID != "page1" {
+		return domain.SignalLevelNone, domain.SignalBreakdown{"fixed": domain.SignalLevelNone}
+	}
+	return f.level, domain.SignalBreakdown{"fixed": f.level}
+}
+
+func TestService_SearchSignals(t *testing.T) {
+	t.Run("adds weighted signal levels to the score", func(t *testing.T) {
+		pageRepo, keywordRepo, pageService, keywordService := setupTestRepos()
+		svc := service.NewService(pageService, keywordService, &scorer.Frequency{}, []domain.WeightedSignal{
+			{Signal: &fixedSignal{name: "fixed", level: domain.SignalLevelModerate}, Weight: 0.5},
+		})
+
+		savePage(t, pageRepo, keywordRepo, domain.Page{ID: "page1", URL: "http://example.com", Title: "Example"}, map[domain.Keyword]domain.KeywordOccurrence{
+			"example": {PageID: "page1", Frequency: 1, Positions: []int{1}},
+		})
+		savePage(t, pageRepo, keywordRepo, domain.Page{ID: "page2", URL: "http://example.com/contact", Title: "Contact"}, map[domain.Keyword]domain.KeywordOccurrence{
+			"example": {PageID: "page2", Frequency: 3, Positions: []int{1, 2, 3}},
+		})
+
+		results, err := search(svc, "example")
+		if err != nil {
+			t.Fatalf("Error searching: %v", err)
+		}
+
+		if len(results) != 2 {
+			t.Fatalf("Expected 2 results, got %d", len(results))
+		}
+
+		if results[0].PageID != "page1" {
+			t.Errorf("Expected page1 to rank first, got %s", results[0].PageID)
+		}
+
+		if results[0].Score != 6 {
+			t.Errorf("Expected score 6, got %f", results[0].Score)
+		}
+
+		if results[1].Score != 3 {
+			t.Errorf("Expected score 3, got %f", results[1].Score)
+		}
+	})
+
+	t.Run("returns the breakdown for each signal", func(t *testing.T) {
+		pageRepo, keywordRepo, pageService, keywordService := setupTestRepos()
+		svc := service.NewService(pageService, keywordService, &scorer.Frequency{}, []domain.WeightedSignal{
+			{Signal: &fixedSignal{name: "fixed", level: domain.SignalLevelLow}, Weight: 1},
+		})
+
+		savePage(t, pageRepo, keywordRepo, domain.Page{ID: "page1", URL: "http://example.com", Title: "Example"}, map[domain.Keyword]domain.KeywordOccurrence{
+			"example": {PageID: "page1", Frequency: 1, Positions: []int{1}},
+		})
+
+		results, err := search(svc, "example")
+		if err != nil {
+			t.Fatalf("Error searching: %v", err)
+		}
+
+		if len(results) != 1 {
+			t.Fatalf("Expected 1 result, got %d", len(results))
+		}
+
+		breakdown, ok := results[0].Signals["fixed"]
+		if !ok {
+			t.Fatalf("Expected breakdown for signal fixed, got %v", results[0].Signals)
+		}
+
+		if breakdown["fixed"] != domain.SignalLevelLow {
+			t.Errorf("Expected fixed level %f, got %f", domain.SignalLevelLow, breakdown["fixed"])
+		}
+	})
+}
+
+func TestService_SearchPhrase(t *testing.T) {
+	setup := func(t *testing.T) *service.Service {
+		pageRepo, keywordRepo, pageService, keywordService := setupTestRepos()
+
+		// the two words next to each other
+		savePage(t, pageRepo, keywordRepo, domain.Page{ID: "near", URL: "http://example.com/near", Title: "Recipe"}, map[domain.Keyword]domain.KeywordOccurrence{
+			"bolognese": {PageID: "near", Frequency: 1, Positions: []int{4}},
+			"sauce":     {PageID: "near", Frequency: 1, Positions: []int{5}},
+		})
+		// the two words far apart
+		savePage(t, pageRepo, keywordRepo, domain.Page{ID: "far", URL: "http://example.com/far", Title: "Recipe"}, map[domain.Keyword]domain.KeywordOccurrence{
+			"bolognese": {PageID: "far", Frequency: 1, Positions: []int{1}},
+			"sauce":     {PageID: "far", Frequency: 1, Positions: []int{40}},
+		})
+		// the phrase kept together as a single keyword
+		savePage(t, pageRepo, keywordRepo, domain.Page{ID: "keyword", URL: "http://example.com/keyword", Title: "Recipe"}, map[domain.Keyword]domain.KeywordOccurrence{
+			"bolognese sauce": {PageID: "keyword", Frequency: 1, Positions: []int{2}},
+		})
+		// the words in the wrong order
+		savePage(t, pageRepo, keywordRepo, domain.Page{ID: "reversed", URL: "http://example.com/reversed", Title: "Recipe"}, map[domain.Keyword]domain.KeywordOccurrence{
+			"sauce":     {PageID: "reversed", Frequency: 1, Positions: []int{7}},
+			"bolognese": {PageID: "reversed", Frequency: 1, Positions: []int{8}},
+		})
+
+		return service.NewService(pageService, keywordService, &scorer.Frequency{}, nil)
+	}
+
+	resultIDs := func(results []domain.Result) []string {
+		ids := []string{}
+		for _, result := range results {
+			ids = append(ids, result.PageID)
+		}
+		return ids
+	}
+
+	t.Run("quoted phrases only match consecutive keywords", func(t *testing.T) {
+		svc := setup(t)
+
+		results, err := search(svc, `"bolognese sauce"`)
+		if err != nil {
+			t.Fatalf("Error searching: %v", err)
+		}
+
+		ids := resultIDs(results)
+		sort.Strings(ids)
+
+		expected := []string{"keyword", "near"}
+		if !reflect.DeepEqual(ids, expected) {
+			t.Errorf("Expected results %v, got %v", expected, ids)
+		}
+	})
+
+	t.Run("close terms outrank distant terms", func(t *testing.T) {
+		svc := setup(t)
+
+		results, err := search(svc, "bolognese sauce")
+		if err != nil {
+			t.Fatalf("Error searching: %v", err)
+		}
+
+		ids := resultIDs(results)
+		expected := []string{"near", "keyword", "reversed", "far"}
+		if !reflect.DeepEqual(ids, expected) {
+			t.Errorf("Expected results %v, got %v", expected, ids)
+		}
+
+		for _, result := range results {
+			if _, ok := result.Signals["proximity"]; !ok {
+				t.Errorf("Expected a proximity breakdown for %s", result.PageID)
+			}
+		}
+	})
+
+	t.Run("mixes phrases with loose terms", func(t *testing.T) {
+		svc := setup(t)
+
+		results, err := search(svc, `recipe "sauce bolognese"`)
+		if err != nil {
+			t.Fatalf("Error searching: %v", err)
+		}
+
+		ids := resultIDs(results)
+		expected := []string{"reversed"}
+		if !reflect.DeepEqual(ids, expected) {
+			t.Errorf("Expected results %v, got %v", expected, ids)
+		}
+	})
+}
+
+func TestService_SearchOperators(t *testing.T) {
+	setup := func(t *testing.T) *service.Service {
+		pageRepo, keywordRepo, pageService, keywordService := setupTestRepos()
+
+		savePage(t, pageRepo, keywordRepo, domain.Page{ID: "pasta", URL: "https://recipes.example.com/pasta", Title: "Bolognese Sauce", Language: "en"}, map[domain.Keyword]domain.KeywordOccurrence{
+			"sauce":  {PageID: "pasta", Frequency: 2, Positions: []int{1, 5}},
+			"pasta":  {PageID: "pasta", Frequency: 1, Positions: []int{2}},
+			"garlic": {PageID: "pasta", Frequency: 1, Positions: []int{3}},
+		})
+		savePage(t, pageRepo, keywordRepo, domain.Page{ID: "pizza", URL: "https://example.org/pizza", Title: "Pizza Night", Language: "en-GB"}, map[domain.Keyword]domain.KeywordOccurrence{
+			"sauce": {PageID: "pizza", Frequency: 1, Positions: []int{1}},
+			"pizza": {PageID: "pizza", Frequency: 3, Positions: []int{2, 3, 4}},
+		})
+		savePage(t, pageRepo, keywordRepo, domain.Page{ID: "sauce", URL: "https://example.fr/sauce", Title: "Sauce Tomate", Language: "fr"}, map[domain.Keyword]domain.KeywordOccurrence{
+			"sauce":  {PageID: "sauce", Frequency: 1, Positions: []int{1}},
+			"tomate": {PageID: "sauce", Frequency: 1, Positions: []int{2}},
+		})
+
+		return service.NewService(pageService, keywordService, &scorer.Frequency{}, nil)
+	}
+
+	cases := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{name: "terms", query: "sauce", expected: []string{"pasta", "pizza", "sauce"}},
+		{name: "excluded term", query: "sauce -garlic", expected: []string{"pizza", "sauce"}},
+		{name: "excluded phrase", query: `sauce -"sauce tomate"`, expected: []string{"pasta", "pizza"}},
+		{name: "or", query: "pasta OR pizza", expected: []string{"pasta", "pizza"}},
+		{name: "grouped or", query: "sauce -(pasta OR pizza)", expected: []string{"sauce"}},
+		{name: "site", query: "sauce site:example.com", expected: []string{"pasta"}},
+		{name: "site with path", query: "sauce site:recipes.example.com/pasta", expected: []string{"pasta"}},
+		{name: "excluded site", query: "sauce -site:example.com", expected: []string{"pizza", "sauce"}},
+		{name: "site alone", query: "site:example.org", expected: []string{"pizza"}},
+		{name: "lang", query: "sauce lang:en", expected: []string{"pasta", "pizza"}},
+		{name: "lang alone", query: "lang:fr", expected: []string{"sauce"}},
+		{name: "intitle", query: `sauce intitle:"pizza night"`, expected: []string{"pizza"}},
+		{name: "inurl", query: "inurl:recipes", expected: []string{"pasta"}},
+		{name: "field or term", query: "garlic OR lang:fr", expected: []string{"pasta", "sauce"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := setup(t)
+
+			results, err := search(svc, tc.query)
+			if err != nil {
+				t.Fatalf("Error searching %q: %v", tc.query, err)
+			}
+
+			ids := []string{}
+			for _, result := range results {
+				ids = append(ids, result.PageID)
+			}
+			sort.Strings(ids)
+
+			if !reflect.DeepEqual(ids, tc.expected) {
+				t.Errorf("Expected results %v for %q, got %v", tc.expected, tc.query, ids)
+			}
+		})
+	}
+
+	t.Run("excluded terms are not scored", func(t *testing.T) {
+		svc := setup(t)
+
+		results, err := search(svc, "pizza -garlic")
+		if err != nil {
+			t.Fatalf("Error searching: %v", err)
+		}
+
+		if len(results) != 1 {
+			t.Fatalf("Expected 1 result, got %d", len(results))
+		}
+
+		if _, ok := results[0].KeywordOccurences["garlic"]; ok {
+			t.Errorf("Expected excluded keyword to be left out of the occurrences")
+		}
+
+		if results[0].Score != 3 {
+			t.Errorf("Expected score 3, got %f", results[0].Score)
+		}
+	})
+
+	t.Run("lang matches detected languages", func(t *testing.T) {
+		pageRepo, keywordRepo, pageService, keywordService := setupTestRepos()
+
+		for _, lang := range []string{"english", "french", "german"} {
+			doc, err := goquery.NewDocumentFromReader(bytes.NewReader(testdataloader.GetTestFile("testdata/pages/language/" + lang + ".html")))
+			if err != nil {
+				t.Fatalf("Error parsing %s page: %v", lang, err)
+			}
+
+			language, _ := parse.Language(doc)
+
+			savePage(t, pageRepo, keywordRepo, domain.Page{ID: lang, URL: "https://example.com/" + lang, Language: language}, map[domain.Keyword]domain.KeywordOccurrence{
+				"page": {PageID: lang, Frequency: 1, Positions: []int{1}},
+			})
+		}
+
+		svc := service.NewService(pageService, keywordService, &scorer.Frequency{}, nil)
+
+		for query, expected := range map[string][]string{
+			"lang:fr":       {"french"},
+			"page lang:de":  {"german"},
+			"page -lang:en": {"french", "german"},
+		} {
+			results, err := search(svc, query)
+			if err != nil {
+				t.Fatalf("Error searching %q: %v", query, err)
+			}
+
+			ids := []string{}
+			for _, result := range results {
+				ids = append(ids, result.PageID)
+			}
+			sort.Strings(ids)
+
+			if !reflect.DeepEqual(ids, expected) {
+				t.Errorf("Expected results %v for %q, got %v", expected, query, ids)
+			}
+		}
+	})
+
+	t.Run("leaves out removed pages", func(t *testing.T) {
+		pageRepo, keywordRepo, pageService, keywordService := setupTestRepos()
+
+		savePage(t, pageRepo, keywordRepo, domain.Page{ID: "kept", URL: "https://example.com/kept", Language: "en"}, map[domain.Keyword]domain.KeywordOccurrence{
+			"sauce": {PageID: "kept", Frequency: 1, Positions: []int{1}},
+		})
+		savePage(t, pageRepo, keywordRepo, domain.Page{ID: "removed", URL: "https://example.com/removed", Language: "en", Removed: true}, map[domain.Keyword]domain.KeywordOccurrence{
+			"sauce": {PageID: "removed", Frequency: 1, Positions: []int{1}},
+		})
+
+		svc := service.NewService(pageService, keywordService, &scorer.Frequency{}, nil)
+
+		for _, query := range []string{"sauce", "site:example.com", "lang:en"} {
+			results, err := search(svc, query)
+			if err != nil {
+				t.Fatalf("Error searching %q: %v", query, err)
+			}
+
+			if len(results) != 1 || results[0].PageID != "kept" {
+				t.Errorf("Expected only the kept page for %q, got %v", query, results)
+			}
+		}
+	})
+
+	t.Run("rejects invalid queries", func(t *testing.T) {
+		svc := setup(t)
+
+		for _, query := range []string{"", "sauce OR", "-garlic", "site:"} {
+			_, err := search(svc, query)
+			if !errors.Is(err, domain.ErrInvalidSearchQuery) {
+				t.Errorf("Expected ErrInvalidSearchQuery for %q, got %v", query, err)
+			}
+		}
+	})
+}
+
+func TestService_SearchPagination(t *testing.T) {
+	pageRepo, keywordRepo, pageService, keywordService := setupTestRepos()
+	svc := service.NewService(pageService, keywordService, &scorer.Frequency{}, nil)
+
+	// page01 has the highest frequency and page25 the lowest, with page26
+	// tied with page25 to check ties are broken by page ID
+	for i := 1; i <= 26; i++ {
+		id := fmt.Sprintf("page%02d", i)
+		frequency := 27 - i
+		if i == 26 {
+			frequency = 2
+		}
+		savePage(t, pageRepo, keywordRepo, domain.Page{ID: id, URL: "http://example.com/" + id}, map[domain.Keyword]domain.KeywordOccurrence{
+			"recipe": {PageID: id, Frequency: frequency, Positions: []int{1}},
+		})
+	}
+
+	ids := func(results []domain.Result) []string {
+		ids := []string{}
+		for _, result := range results {
+			ids = append(ids, result.PageID)
+		}
+		return ids
+	}
+
+	cases := []struct {
+		name     string
+		offset   int
+		limit    int
+		expected []string
+	}{
+		{name: "first page", offset: 0, limit: 3, expected: []string{"page01", "page02", "page03"}},
+		{name: "second page", offset: 3, limit: 3, expected: []string{"page04", "page05", "page06"}},
+		{name: "ties are broken by page id", offset: 23, limit: 3, expected: []string{"page24", "page25", "page26"}},
+		{name: "last partial page", offset: 24, limit: 5, expected: []string{"page25", "page26"}},
+		{name: "past the end", offset: 30, limit: 5, expected: []string{}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := svc.Search("recipe", tc.offset, tc.limit)
+			if err != nil {
+				t.Fatalf("Error searching: %v", err)
+			}
+
+			if !reflect.DeepEqual(ids(res.Results), tc.expected) {
+				t.Errorf("Expected results %v, got %v", tc.expected, ids(res.Results))
+			}
+
+			if res.TotalHits != 26 {
+				t.Errorf("Expected 26 total hits, got %d", res.TotalHits)
+			}
+		})
+	}
+
+	t.Run("rejects invalid windows", func(t *testing.T) {
+		windows := [][2]int{{-1, 10}, {0, 0}, {domain.MaxSearchDepth, 1}}
+
+		for _, window := range windows {
+			_, err := svc.Search("recipe", window[0], window[1])
+			if !errors.Is(err, domain.ErrInvalidSearchQuery) {
+				t.Errorf("Expected ErrInvalidSearchQuery for offset %d limit %d, got %v", window[0], window[1], err)
+			}
+		}
+	})
+}
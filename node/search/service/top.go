@@ -0,0 +1,63 @@
+package service
+
+import (
+	"container/heap"
+	"crawlquery/node/domain"
+	"sort"
+)
+
+// ranksBefore orders results by score, breaking ties by page ID so every
+// search for the same query pages through the results in the same order.
+func ranksBefore(a, b domain.Result) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	return a.PageID < b.PageID
+}
+
+// resultHeap keeps the lowest ranked result on top.
+type resultHeap []domain.Result
+
+func (h resultHeap) Len() int           { return len(h) }
+func (h resultHeap) Less(i, j int) bool { return ranksBefore(h[j], h[i]) }
+func (h resultHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *resultHeap) Push(x any) {
+	*h = append(*h, x.(domain.Result))
+}
+
+func (h *resultHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// topResults returns the k highest ranked results in rank order without
+// sorting the rest.
+func topResults(results []domain.Result, k int) []domain.Result {
+	if k <= 0 {
+		return []domain.Result{}
+	}
+
+	h := make(resultHeap, 0, k)
+
+	for _, result := range results {
+		if len(h) < k {
+			heap.Push(&h, result)
+			continue
+		}
+		if ranksBefore(result, h[0]) {
+			h[0] = result
+			heap.Fix(&h, 0)
+		}
+	}
+
+	top := []domain.Result(h)
+	sort.Slice(top, func(i, j int) bool {
+		return ranksBefore(top[i], top[j])
+	})
+
+	return top
+}
@@ -0,0 +1,330 @@
+// Package canonical rewrites URLs into a single canonical form, so that the
+// many ways of writing the same address hash to the same page ID.
+//
+// The canonical form only identifies a page, which is still fetched from its
+// URL as given.
+//
+// Pages and crawl jobs stored before URLs were canonicalized keep the IDs of
+// their URLs as they were written, and are not rewritten: their IDs are also
+// held by links, index jobs and the nodes' indexes. The API instead records
+// each of them as an alias of the page of its canonical URL on start up.
+package canonical
+
+import (
+	"crawlquery/api/domain"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var (
+	ErrNotAbsolute       = errors.New("url is not absolute")
+	ErrUnsupportedScheme = errors.New("unsupported url scheme")
+	ErrInvalidHost       = errors.New("invalid url host")
+)
+
+// DefaultTrackingParams are the query parameters dropped unless others are
+// given. A name ending in "*" matches every parameter starting with the rest
+// of it.
+var DefaultTrackingParams = []string{
+	"utm_*", "gclid", "dclid", "fbclid", "msclkid", "yclid", "igshid", "mc_cid", "mc_eid", "_ga", "_hsenc", "_hsmi",
+}
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+type Canonicalizer struct {
+	params   map[string]bool
+	prefixes []string
+}
+
+type Option func(*Canonicalizer)
+
+// WithTrackingParams sets the query parameters to drop, replacing the
+// defaults. Names are matched case insensitively and a name ending in "*"
+// matches every parameter starting with the rest of it.
+func WithTrackingParams(params ...string) Option {
+	return func(c *Canonicalizer) {
+		c.params = map[string]bool{}
+		c.prefixes = nil
+
+		for _, param := range params {
+			param = strings.ToLower(strings.TrimSpace(param))
+			if param == "" {
+				continue
+			}
+
+			if prefix, ok := strings.CutSuffix(param, "*"); ok {
+				c.prefixes = append(c.prefixes, prefix)
+				continue
+			}
+
+			c.params[param] = true
+		}
+	}
+}
+
+func New(opts ...Option) *Canonicalizer {
+	c := &Canonicalizer{}
+
+	WithTrackingParams(DefaultTrackingParams...)(c)
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Default is the canonicalizer used by URL. It is replaced at start up to
+// change the tracking parameters everywhere.
+var Default = New()
+
+// URL returns the canonical form of an absolute http or https URL using the
+// Default canonicalizer.
+func URL(rawURL domain.URL) (domain.URL, error) {
+	return Default.URL(rawURL)
+}
+
+// URL returns the canonical form of an absolute http or https URL. The
+// scheme and host are lower cased, the host is converted to punycode and
+// loses a default port, and the fragment is dropped. Dot segments, empty
+// segments and any trailing slash are removed from the path, leaving "/" for
+// the root. Tracking parameters are dropped from the query and the rest are
+// sorted by name. Percent-encoding is made consistent throughout by
+// decoding characters that never need it and upper casing the rest.
+func (c *Canonicalizer) URL(rawURL domain.URL) (domain.URL, error) {
+	parsed, err := url.Parse(strings.TrimSpace(string(rawURL)))
+	if err != nil {
+		return "", err
+	}
+
+	scheme := strings.ToLower(parsed.Scheme)
+	if scheme == "" {
+		return "", ErrNotAbsolute
+	}
+
+	if _, ok := defaultPorts[scheme]; !ok {
+		return "", ErrUnsupportedScheme
+	}
+
+	if parsed.Host == "" {
+		return "", ErrNotAbsolute
+	}
+
+	host, err := canonicalHost(scheme, parsed)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+
+	b.WriteString(scheme)
+	b.WriteString("://")
+
+	if parsed.User != nil {
+		b.WriteString(parsed.User.String())
+		b.WriteString("@")
+	}
+
+	b.WriteString(host)
+	b.WriteString(canonicalPath(parsed.EscapedPath()))
+
+	if query := c.canonicalQuery(parsed.RawQuery); query != "" {
+		b.WriteString("?")
+		b.WriteString(query)
+	}
+
+	return domain.URL(b.String()), nil
+}
+
+func canonicalHost(scheme string, parsed *url.URL) (string, error) {
+	hostname := strings.TrimSuffix(parsed.Hostname(), ".")
+	if hostname == "" {
+		return "", ErrInvalidHost
+	}
+
+	// IPv6 addresses keep their brackets and skip IDNA
+	if strings.Contains(hostname, ":") {
+		hostname = "[" + strings.ToLower(hostname) + "]"
+	} else {
+		ascii, err := idna.Lookup.ToASCII(hostname)
+		if err != nil {
+			// underscores and the like are rejected by IDNA but still
+			// resolve, so plain ASCII hosts are only lower cased
+			if !isASCII(hostname) {
+				return "", ErrInvalidHost
+			}
+			ascii = hostname
+		}
+		hostname = strings.ToLower(ascii)
+	}
+
+	if port := parsed.Port(); port != "" {
+		port = strings.TrimLeft(port, "0")
+		if port != defaultPorts[scheme] {
+			if port == "" {
+				port = "0"
+			}
+			hostname += ":" + port
+		}
+	}
+
+	return hostname, nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// canonicalPath removes dot segments, empty segments and a trailing slash
+// from an escaped path.
+func canonicalPath(escaped string) string {
+	var segments []string
+
+	for _, segment := range strings.Split(normalizeEscapes(escaped), "/") {
+		switch segment {
+		case "", ".":
+		case "..":
+			if len(segments) > 0 {
+				segments = segments[:len(segments)-1]
+			}
+		default:
+			segments = append(segments, segment)
+		}
+	}
+
+	return "/" + strings.Join(segments, "/")
+}
+
+// canonicalQuery drops empty and tracking parameters from a raw query and
+// sorts the rest by name, keeping repeated parameters in their order.
+func (c *Canonicalizer) canonicalQuery(rawQuery string) string {
+	type param struct {
+		name string
+		pair string
+	}
+
+	var params []param
+
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+
+		pair = normalizeEscapes(pair)
+
+		name, _, _ := strings.Cut(pair, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+
+		if c.tracking(name) {
+			continue
+		}
+
+		params = append(params, param{name: name, pair: pair})
+	}
+
+	sort.SliceStable(params, func(i, j int) bool {
+		return params[i].name < params[j].name
+	})
+
+	pairs := make([]string, len(params))
+	for i, p := range params {
+		pairs[i] = p.pair
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+func (c *Canonicalizer) tracking(name string) bool {
+	name = strings.ToLower(name)
+
+	if c.params[name] {
+		return true
+	}
+
+	for _, prefix := range c.prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+const upperhex = "0123456789ABCDEF"
+
+// normalizeEscapes decodes escaped unreserved characters, upper cases the
+// remaining escapes and escapes any byte that may not appear unescaped.
+func normalizeEscapes(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		if c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			decoded := unhex(s[i+1])<<4 | unhex(s[i+2])
+			if isUnreserved(decoded) {
+				b.WriteByte(decoded)
+			} else {
+				b.WriteByte('%')
+				b.WriteByte(upperhex[decoded>>4])
+				b.WriteByte(upperhex[decoded&15])
+			}
+			i += 2
+			continue
+		}
+
+		if c == '%' || mustEscape(c) {
+			b.WriteByte('%')
+			b.WriteByte(upperhex[c>>4])
+			b.WriteByte(upperhex[c&15])
+			continue
+		}
+
+		b.WriteByte(c)
+	}
+
+	return b.String()
+}
+
+// isUnreserved reports whether c is an unreserved character of RFC 3986,
+// which means the same escaped or not.
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func mustEscape(c byte) bool {
+	if c <= ' ' || c >= 0x7f {
+		return true
+	}
+	return strings.IndexByte("\"<>\\^`{|}", c) >= 0
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 )
 
 type Client struct {
@@ -67,6 +68,33 @@ func (c *Client) SendRequest(method, path string, body []byte) (*http.Response,
 	return http.DefaultClient.Do(req)
 }
 
+// CrawlError is returned by Crawl when the node fails to crawl a page.
+// SiteStatusCode is the status the crawled site answered with, if any, and
+// RetryAfter how long it asked crawlers to wait. Disallowed is set when the
+// site's robots.txt forbids crawling the page.
+type CrawlError struct {
+	StatusCode     int
+	Message        string
+	SiteStatusCode int
+	RetryAfter     time.Duration
+	Disallowed     bool
+}
+
+func (e *CrawlError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d (%s)", e.StatusCode, e.Message)
+}
+
+// QueryError is returned by Query when the node rejects or fails a query.
+// A StatusCode of 400 means the query itself is invalid.
+type QueryError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d (%s)", e.StatusCode, e.Message)
+}
+
 func (c *Client) Crawl(pageID, url string) (*dto.CrawlResponse, error) {
 	req := dto.CrawlRequest{
 		PageID: pageID,
@@ -90,7 +118,7 @@ func (c *Client) Crawl(pageID, url string) (*dto.CrawlResponse, error) {
 
 	if res.StatusCode != http.StatusOK {
 
-		var errRes dto.ErrorResponse
+		var errRes dto.CrawlErrorResponse
 
 		err = json.NewDecoder(res.Body).Decode(&errRes)
 
@@ -98,9 +126,13 @@ func (c *Client) Crawl(pageID, url string) (*dto.CrawlResponse, error) {
 			return nil, fmt.Errorf("unexpected status code: %d", res.StatusCode)
 		}
 
-		defer res.Body.Close()
-
-		return nil, fmt.Errorf("unexpected status code: %d (%s)", res.StatusCode, errRes.Error)
+		return nil, &CrawlError{
+			StatusCode:     res.StatusCode,
+			Message:        errRes.Error,
+			SiteStatusCode: errRes.StatusCode,
+			RetryAfter:     time.Duration(errRes.RetryAfter * float64(time.Second)),
+			Disallowed:     errRes.Disallowed,
+		}
 	}
 
 	var crawlRes dto.CrawlResponse
@@ -113,12 +145,15 @@ func (c *Client) Crawl(pageID, url string) (*dto.CrawlResponse, error) {
 	return &crawlRes, nil
 }
 
-func (c *Client) Index(pageID string, url string, contentHash string) error {
+// Index asks the node to index a page, along with the text of any anchors
+// pointing at it from other pages.
+func (c *Client) Index(pageID string, url string, contentHash string, anchors ...string) error {
 
 	req := dto.IndexRequest{
 		PageID:      pageID,
 		URL:         url,
 		ContentHash: contentHash,
+		Anchors:     anchors,
 	}
 
 	jsonBody, err := json.Marshal(req)
@@ -244,3 +279,41 @@ func (c *Client) GetPageDumps(pageIDs []string) ([]dto.PageDump, error) {
 
 	return pageDumpResponse.PageDumps, nil
 }
+
+func (c *Client) Query(query string) (*dto.QueryResponse, error) {
+	req := dto.QueryRequest{
+		Query: query,
+	}
+
+	jsonBody, err := json.Marshal(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.SendRequest("POST", "/query", jsonBody)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+
+		var errRes dto.ErrorResponse
+		if err := json.NewDecoder(res.Body).Decode(&errRes); err == nil {
+			return nil, &QueryError{StatusCode: res.StatusCode, Message: errRes.Error}
+		}
+
+		return nil, fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+
+	var queryRes dto.QueryResponse
+
+	if err := json.NewDecoder(res.Body).Decode(&queryRes); err != nil {
+		return nil, err
+	}
+
+	return &queryRes, nil
+}
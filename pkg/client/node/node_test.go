@@ -3,6 +3,7 @@ package node_test
 import (
 	"crawlquery/node/dto"
 	"crawlquery/pkg/client/node"
+	"errors"
 	"time"
 
 	"testing"
@@ -38,7 +39,7 @@ func TestCrawl(t *testing.T) {
 		}
 
 		if res.ContentHash != expectedRes.ContentHash {
-			t.Fatalf("Expected %s, got %s", expectedRes.ContentHash, res)
+			t.Fatalf("Expected %s, got %s", expectedRes.ContentHash, res.ContentHash)
 		}
 
 		if res.Links[0] != expectedRes.Links[0] {
@@ -49,6 +50,39 @@ func TestCrawl(t *testing.T) {
 			t.Fatalf("Expected all mocks to be called")
 		}
 	})
+
+	t.Run("returns the site status on failure", func(t *testing.T) {
+		defer gock.Off()
+
+		gock.New("http://node.com").
+			Post("/crawl").
+			Reply(400).
+			JSON(&dto.CrawlErrorResponse{
+				Error:      "failed to fetch html: status 429",
+				StatusCode: 429,
+				RetryAfter: 30,
+			})
+
+		client := node.NewClient(
+			node.WithHostname("node.com"),
+			node.WithPort(80),
+		)
+
+		_, err := client.Crawl("page1", "http://example.com")
+
+		var crawlErr *node.CrawlError
+		if !errors.As(err, &crawlErr) {
+			t.Fatalf("Expected CrawlError, got %v", err)
+		}
+
+		if crawlErr.SiteStatusCode != 429 {
+			t.Errorf("Expected site status 429, got %d", crawlErr.SiteStatusCode)
+		}
+
+		if crawlErr.RetryAfter != 30*time.Second {
+			t.Errorf("Expected retry after of 30s, got %s", crawlErr.RetryAfter)
+		}
+	})
 }
 
 func TestIndex(t *testing.T) {
@@ -86,6 +120,38 @@ func TestIndex(t *testing.T) {
 	})
 }
 
+func TestIndexWithAnchors(t *testing.T) {
+	t.Run("sends anchors", func(t *testing.T) {
+		defer gock.Off()
+
+		gock.New("http://node.com").
+			Post("/index").
+			JSON(&dto.IndexRequest{
+				PageID:      "page1",
+				URL:         "http://example.com",
+				ContentHash: "hash",
+				Anchors:     []string{"example site"},
+			}).
+			Reply(200).
+			JSON(&dto.IndexResponse{Success: true})
+
+		node := node.NewClient(
+			node.WithHostname("node.com"),
+			node.WithPort(80),
+		)
+
+		err := node.Index("page1", "http://example.com", "hash", "example site")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if !gock.IsDone() {
+			t.Fatalf("Expected all mocks to be called")
+		}
+	})
+}
+
 func TestGetIndexMetas(t *testing.T) {
 	t.Run("returns results", func(t *testing.T) {
 		defer gock.Off()
@@ -220,3 +286,75 @@ func TestGetPageDumps(t *testing.T) {
 		}
 	})
 }
+
+func TestQuery(t *testing.T) {
+	t.Run("returns results", func(t *testing.T) {
+		defer gock.Off()
+
+		expectedRes := &dto.QueryResponse{
+			Columns: []dto.QueryColumn{
+				{Name: "title", Type: "string"},
+			},
+			Rows: [][]any{
+				{"Example"},
+			},
+		}
+
+		gock.New("http://node.com").
+			Post("/query").
+			JSON(&dto.QueryRequest{
+				Query: "SELECT title FROM pages",
+			}).
+			Reply(200).
+			JSON(expectedRes)
+
+		node := node.NewClient(
+			node.WithHostname("node.com"),
+			node.WithPort(80),
+		)
+
+		res, err := node.Query("SELECT title FROM pages")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if res.Columns[0].Name != "title" {
+			t.Fatalf("Expected column title, got %s", res.Columns[0].Name)
+		}
+
+		if res.Rows[0][0] != "Example" {
+			t.Fatalf("Expected Example, got %v", res.Rows[0][0])
+		}
+
+		if !gock.IsDone() {
+			t.Fatalf("Expected all mocks to be called")
+		}
+	})
+
+	t.Run("returns the node error", func(t *testing.T) {
+		defer gock.Off()
+
+		gock.New("http://node.com").
+			Post("/query").
+			Reply(400).
+			JSON(&dto.ErrorResponse{
+				Error: "invalid query: unknown table users",
+			})
+
+		node := node.NewClient(
+			node.WithHostname("node.com"),
+			node.WithPort(80),
+		)
+
+		_, err := node.Query("SELECT * FROM users")
+
+		if err == nil {
+			t.Fatalf("Expected error, got nil")
+		}
+
+		if err.Error() != "unexpected status code: 400 (invalid query: unknown table users)" {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+}
@@ -3,7 +3,8 @@ package dto
 import "crawlquery/node/domain"
 
 type NodeSearchResponse struct {
-	Results []domain.Result `json:"results"`
+	Results   []domain.Result `json:"results"`
+	TotalHits int             `json:"total_hits"`
 }
 
 type SearchResponse NodeSearchResponse
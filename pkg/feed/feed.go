@@ -0,0 +1,168 @@
+// Package feed parses RSS 2.0, RSS 1.0 and Atom feeds into the entries a
+// crawler needs: where each one links to and when it was published.
+package feed
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+var ErrNotFeed = errors.New("not an rss or atom feed")
+
+// MaxSize is the largest feed read.
+const MaxSize = 10 * 1024 * 1024
+
+// Entry is an item of a feed. Link may be relative to the feed's URL and
+// Published is zero when the feed doesn't say when the entry was published
+// or updated.
+type Entry struct {
+	ID        string
+	Link      string
+	Published time.Time
+}
+
+type xmlLink struct {
+	Rel   string `xml:"rel,attr"`
+	Href  string `xml:"href,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type xmlEntry struct {
+	Links     []xmlLink `xml:"link"`
+	GUID      xmlGUID   `xml:"guid"`
+	ID        string    `xml:"id"`
+	PubDate   string    `xml:"pubDate"`
+	Date      string    `xml:"date"`
+	Published string    `xml:"published"`
+	Updated   string    `xml:"updated"`
+}
+
+type xmlFeed struct {
+	XMLName xml.Name
+	Channel struct {
+		Items []xmlEntry `xml:"item"`
+	} `xml:"channel"`
+	// RSS 1.0 lists its items beside the channel.
+	Items   []xmlEntry `xml:"item"`
+	Entries []xmlEntry `xml:"entry"`
+}
+
+// Parse reads an RSS or Atom feed in any encoding it declares. Entries without
+// a link are left out.
+func Parse(r io.Reader) ([]Entry, error) {
+	decoder := xml.NewDecoder(io.LimitReader(r, MaxSize))
+	decoder.CharsetReader = charset.NewReaderLabel
+	decoder.Strict = false
+
+	var doc xmlFeed
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	var items []xmlEntry
+
+	switch doc.XMLName.Local {
+	case "rss":
+		items = doc.Channel.Items
+	case "RDF":
+		items = doc.Items
+	case "feed":
+		items = doc.Entries
+	default:
+		return nil, ErrNotFeed
+	}
+
+	var entries []Entry
+
+	for _, item := range items {
+		if entry, ok := item.entry(); ok {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+func (e xmlEntry) entry() (Entry, bool) {
+	entry := Entry{
+		ID:   strings.TrimSpace(e.ID),
+		Link: e.link(),
+	}
+
+	if entry.ID == "" {
+		entry.ID = strings.TrimSpace(e.GUID.Value)
+	}
+
+	if entry.ID == "" {
+		entry.ID = entry.Link
+	}
+
+	for _, value := range []string{e.Published, e.PubDate, e.Date, e.Updated} {
+		if published := ParseTime(value); !published.IsZero() {
+			entry.Published = published
+			break
+		}
+	}
+
+	return entry, entry.Link != ""
+}
+
+// link is the entry's alternate link in Atom, its link in RSS or its guid
+// when the guid is a permalink.
+func (e xmlEntry) link() string {
+	for _, link := range e.Links {
+		if link.Href != "" && (link.Rel == "" || link.Rel == "alternate") {
+			return strings.TrimSpace(link.Href)
+		}
+		if link.Href == "" && strings.TrimSpace(link.Value) != "" {
+			return strings.TrimSpace(link.Value)
+		}
+	}
+
+	guid := strings.TrimSpace(e.GUID.Value)
+	if !strings.EqualFold(e.GUID.IsPermaLink, "false") && (strings.HasPrefix(guid, "http://") || strings.HasPrefix(guid, "https://")) {
+		return guid
+	}
+
+	return ""
+}
+
+// timeLayouts are the date formats found in feeds: RFC 822 and its common
+// variations in RSS 2.0 and RFC 3339 in Atom and RSS 1.0.
+var timeLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+}
+
+// ParseTime reads a feed date, returning the zero time for dates it can't
+// read.
+func ParseTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,110 @@
+package feed_test
+
+import (
+	"crawlquery/pkg/feed"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	published := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		feed string
+		want []feed.Entry
+	}{
+		{
+			name: "rss 2.0",
+			feed: `<?xml version="1.0"?>
+			<rss version="2.0"><channel>
+				<title>News</title>
+				<link>http://example.com/</link>
+				<item>
+					<title>First</title>
+					<link> http://example.com/first </link>
+					<guid isPermaLink="false">first-1</guid>
+					<pubDate>Wed, 01 May 2024 10:30:00 +0100</pubDate>
+				</item>
+				<item>
+					<guid>http://example.com/second</guid>
+					<pubDate>Wed, 1 May 2024 09:30:00 GMT</pubDate>
+				</item>
+				<item>
+					<title>No link</title>
+					<guid isPermaLink="false">http://example.com/not-a-link</guid>
+				</item>
+			</channel></rss>`,
+			want: []feed.Entry{
+				{ID: "first-1", Link: "http://example.com/first", Published: published},
+				{ID: "http://example.com/second", Link: "http://example.com/second", Published: published},
+			},
+		},
+		{
+			name: "rss 1.0",
+			feed: `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+				<channel><link>http://example.com/</link></channel>
+				<item><link>http://example.com/first</link><dc:date>2024-05-01T09:30:00Z</dc:date></item>
+			</rdf:RDF>`,
+			want: []feed.Entry{
+				{ID: "http://example.com/first", Link: "http://example.com/first", Published: published},
+			},
+		},
+		{
+			name: "atom",
+			feed: `<feed xmlns="http://www.w3.org/2005/Atom">
+				<link rel="self" href="http://example.com/feed.atom"/>
+				<entry>
+					<id>tag:example.com,2024:first</id>
+					<link rel="self" href="http://example.com/first.atom"/>
+					<link rel="alternate" href="/first"/>
+					<updated>2024-05-02T00:00:00Z</updated>
+					<published>2024-05-01T09:30:00Z</published>
+				</entry>
+				<entry>
+					<id>tag:example.com,2024:second</id>
+					<link href="http://example.com/second"/>
+				</entry>
+			</feed>`,
+			want: []feed.Entry{
+				{ID: "tag:example.com,2024:first", Link: "/first", Published: published},
+				{ID: "tag:example.com,2024:second", Link: "http://example.com/second"},
+			},
+		},
+		{
+			name: "other encodings",
+			feed: "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><rss><channel><item><link>http://example.com/caf\xe9</link></item></channel></rss>",
+			want: []feed.Entry{
+				{ID: "http://example.com/café", Link: "http://example.com/café"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := feed.Parse(strings.NewReader(tc.feed))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %d entries, got %d: %+v", len(tc.want), len(got), got)
+			}
+
+			for i := range got {
+				if got[i].ID != tc.want[i].ID || got[i].Link != tc.want[i].Link || !got[i].Published.Equal(tc.want[i].Published) {
+					t.Errorf("expected entry %+v, got %+v", tc.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseRejectsOtherDocuments(t *testing.T) {
+	for _, doc := range []string{`<html><body>Not found</body></html>`, `<urlset></urlset>`, ``} {
+		if _, err := feed.Parse(strings.NewReader(doc)); err == nil {
+			t.Errorf("expected an error for %q", doc)
+		}
+	}
+}
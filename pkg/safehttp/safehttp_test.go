@@ -0,0 +1,50 @@
+package safehttp_test
+
+import (
+	"crawlquery/pkg/safehttp"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestPublic(t *testing.T) {
+	cases := map[string]bool{
+		"93.184.216.34":    true,
+		"2606:2800:220::1": true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"10.0.0.1":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"0.0.0.0":          false,
+		"224.0.0.1":        false,
+		"::ffff:127.0.0.1": false,
+	}
+
+	for addr, public := range cases {
+		if got := safehttp.Public(netip.MustParseAddr(addr)); got != public {
+			t.Errorf("expected Public(%s) to be %v, got %v", addr, public, got)
+		}
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	t.Run("refuses to connect to loopback addresses", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("expected no request to reach the server")
+		}))
+		defer srv.Close()
+
+		_, err := safehttp.NewClient(time.Second).Get(srv.URL)
+
+		if !errors.Is(err, safehttp.ErrNonPublicAddress) {
+			t.Errorf("expected ErrNonPublicAddress, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,135 @@
+// Package sitemap parses sitemap.xml files and sitemap index files as
+// described at https://www.sitemaps.org/protocol.html.
+package sitemap
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrNotSitemap = errors.New("not a sitemap or sitemap index")
+
+// DefaultPriority is the priority of URLs that don't give one.
+const DefaultPriority = 0.5
+
+// MaxSize is the largest uncompressed sitemap read, which is the limit set
+// by the protocol.
+const MaxSize = 50 * 1024 * 1024
+
+// URL is a page listed in a sitemap with the hints the site gives about it.
+// LastMod is zero when the site doesn't say when the page last changed.
+type URL struct {
+	Loc        string
+	LastMod    time.Time
+	ChangeFreq string
+	Priority   float64
+}
+
+// Sitemap is a parsed sitemap. A sitemap index lists other sitemaps in
+// Sitemaps and a sitemap lists pages in URLs.
+type Sitemap struct {
+	URLs     []URL
+	Sitemaps []URL
+}
+
+type xmlEntry struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod"`
+	ChangeFreq string `xml:"changefreq"`
+	Priority   string `xml:"priority"`
+}
+
+type xmlSitemap struct {
+	XMLName  xml.Name
+	URLs     []xmlEntry `xml:"url"`
+	Sitemaps []xmlEntry `xml:"sitemap"`
+}
+
+// Parse reads a sitemap or sitemap index, which may be gzipped. Entries
+// without a location are left out and unreadable lastmod or priority values
+// are ignored.
+func Parse(r io.Reader) (*Sitemap, error) {
+	br := bufio.NewReader(r)
+
+	if magic, err := br.Peek(2); err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+
+	var doc xmlSitemap
+	if err := xml.NewDecoder(io.LimitReader(r, MaxSize)).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	if doc.XMLName.Local != "urlset" && doc.XMLName.Local != "sitemapindex" {
+		return nil, ErrNotSitemap
+	}
+
+	sitemap := &Sitemap{}
+
+	for _, entry := range doc.URLs {
+		if url, ok := entry.url(); ok {
+			sitemap.URLs = append(sitemap.URLs, url)
+		}
+	}
+
+	for _, entry := range doc.Sitemaps {
+		if url, ok := entry.url(); ok {
+			sitemap.Sitemaps = append(sitemap.Sitemaps, url)
+		}
+	}
+
+	return sitemap, nil
+}
+
+func (e xmlEntry) url() (URL, bool) {
+	url := URL{
+		Loc:        strings.TrimSpace(e.Loc),
+		LastMod:    ParseLastMod(e.LastMod),
+		ChangeFreq: strings.ToLower(strings.TrimSpace(e.ChangeFreq)),
+		Priority:   DefaultPriority,
+	}
+
+	if priority, err := strconv.ParseFloat(strings.TrimSpace(e.Priority), 64); err == nil && priority >= 0 && priority <= 1 {
+		url.Priority = priority
+	}
+
+	return url, url.Loc != ""
+}
+
+// lastModLayouts are the W3C datetime formats allowed for lastmod, most
+// precise first.
+var lastModLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// ParseLastMod reads a W3C datetime, returning the zero time for values it
+// can't read.
+func ParseLastMod(value string) time.Time {
+	value = strings.TrimSpace(value)
+
+	for _, layout := range lastModLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
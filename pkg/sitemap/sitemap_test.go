@@ -0,0 +1,106 @@
+package sitemap_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crawlquery/pkg/sitemap"
+	"strings"
+	"testing"
+	"time"
+)
+
+const urlset = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url>
+		<loc> http://example.com/ </loc>
+		<lastmod>2024-05-01T10:30:00+01:00</lastmod>
+		<changefreq>Daily</changefreq>
+		<priority>0.8</priority>
+	</url>
+	<url>
+		<loc>http://example.com/about?a=1&amp;b=2</loc>
+		<lastmod>2024-04</lastmod>
+	</url>
+	<url>
+		<loc>http://example.com/bad</loc>
+		<lastmod>yesterday</lastmod>
+		<priority>2</priority>
+	</url>
+	<url>
+		<lastmod>2024-05-01</lastmod>
+	</url>
+</urlset>`
+
+func TestParse(t *testing.T) {
+	t.Run("parses urls and their hints", func(t *testing.T) {
+		got, err := sitemap.Parse(strings.NewReader(urlset))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(got.URLs) != 3 || len(got.Sitemaps) != 0 {
+			t.Fatalf("expected 3 urls and no sitemaps, got %d and %d", len(got.URLs), len(got.Sitemaps))
+		}
+
+		first := got.URLs[0]
+		if first.Loc != "http://example.com/" || first.ChangeFreq != "daily" || first.Priority != 0.8 {
+			t.Errorf("unexpected url: %+v", first)
+		}
+
+		if want := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC); !first.LastMod.Equal(want) {
+			t.Errorf("expected lastmod %v, got %v", want, first.LastMod)
+		}
+
+		second := got.URLs[1]
+		if second.Loc != "http://example.com/about?a=1&b=2" || second.Priority != sitemap.DefaultPriority {
+			t.Errorf("unexpected url: %+v", second)
+		}
+
+		if want := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC); !second.LastMod.Equal(want) {
+			t.Errorf("expected lastmod %v, got %v", want, second.LastMod)
+		}
+
+		third := got.URLs[2]
+		if !third.LastMod.IsZero() || third.Priority != sitemap.DefaultPriority {
+			t.Errorf("expected bad hints to be ignored, got %+v", third)
+		}
+	})
+
+	t.Run("parses sitemap indexes", func(t *testing.T) {
+		got, err := sitemap.Parse(strings.NewReader(`<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+			<sitemap><loc>http://example.com/posts.xml.gz</loc><lastmod>2024-05-01</lastmod></sitemap>
+			<sitemap><loc>http://example.com/pages.xml</loc></sitemap>
+		</sitemapindex>`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(got.Sitemaps) != 2 || got.Sitemaps[0].Loc != "http://example.com/posts.xml.gz" || got.Sitemaps[0].LastMod.IsZero() {
+			t.Errorf("unexpected sitemaps: %+v", got.Sitemaps)
+		}
+	})
+
+	t.Run("parses gzipped sitemaps", func(t *testing.T) {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte(urlset))
+		gz.Close()
+
+		got, err := sitemap.Parse(&buf)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(got.URLs) != 3 {
+			t.Errorf("expected 3 urls, got %d", len(got.URLs))
+		}
+	})
+
+	t.Run("rejects other documents", func(t *testing.T) {
+		for _, doc := range []string{"<html><body>Not found</body></html>", "User-agent: *", ""} {
+			if _, err := sitemap.Parse(strings.NewReader(doc)); err == nil {
+				t.Errorf("expected an error for %q", doc)
+			}
+		}
+	})
+}
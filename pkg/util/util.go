@@ -5,10 +5,13 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"net"
 	"net/url"
 	"regexp"
+	"strings"
 
 	"github.com/google/uuid"
+	"golang.org/x/net/publicsuffix"
 )
 
 func MakeAbsoluteIfRelative(base, link string) (string, error) {
@@ -77,7 +80,9 @@ func Sha256Hex32(b []byte) string {
 	return hex.EncodeToString(hash.Sum(nil))[:32]
 }
 
-// PageID generates a unique 32-character hash for a given URL.
+// PageID generates a unique 32-character hash for a given URL. The URL is
+// hashed as given: callers canonicalize it with the canonical package first,
+// so that every way of writing an address gets the same ID.
 func PageID(url domain.URL) domain.PageID {
 	// Create a new SHA-256 hash.
 	hash := sha256.New()
@@ -90,3 +95,47 @@ func PageID(url domain.URL) domain.PageID {
 	// Return the first 32 characters of the hexadecimal string.
 	return domain.PageID(hashString[:32])
 }
+
+// Host returns the lower cased host name of a URL without its port or a
+// leading "www.", so that links between pages of the same site share a
+// host. It returns an empty host if the URL cannot be parsed.
+func Host(rawURL domain.URL) domain.Host {
+	parsed, err := url.Parse(string(rawURL))
+	if err != nil {
+		return ""
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	host = strings.TrimPrefix(host, "www.")
+
+	return domain.Host(host)
+}
+
+// Site returns the registrable domain of a URL, such as "example.co.uk" for
+// "https://blog.example.co.uk/", so that subdomains of one site are
+// aggregated together. Hosts without a registrable domain, such as IP
+// addresses and "localhost", are returned as they are by Host.
+func Site(rawURL domain.URL) domain.Host {
+	host := Host(rawURL)
+	if host == "" || net.ParseIP(string(host)) != nil {
+		return host
+	}
+
+	site, err := publicsuffix.EffectiveTLDPlusOne(string(host))
+	if err != nil {
+		return host
+	}
+
+	return domain.Host(site)
+}
+
+// PathDepth counts the segments of a URL path, ignoring empty ones.
+func PathDepth(path string) int {
+	depth := 0
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			depth++
+		}
+	}
+	return depth
+}
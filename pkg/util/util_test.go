@@ -158,3 +158,57 @@ func TestPageID(t *testing.T) {
 		}
 	}
 }
+
+func TestHost(t *testing.T) {
+	tests := []struct {
+		url      domain.URL
+		expected domain.Host
+	}{
+		{"https://example.com/page", "example.com"},
+		{"https://www.Example.com:8080/page", "example.com"},
+		{"http://blog.example.com", "blog.example.com"},
+		{"not a url%", ""},
+	}
+
+	for _, test := range tests {
+		if host := util.Host(test.url); host != test.expected {
+			t.Errorf("Expected host %q for %s, got %q", test.expected, test.url, host)
+		}
+	}
+}
+
+func TestSite(t *testing.T) {
+	tests := []struct {
+		url      domain.URL
+		expected domain.Host
+	}{
+		{"https://example.com/page", "example.com"},
+		{"http://blog.example.com", "example.com"},
+		{"https://www.shop.Example.co.uk:8080/", "example.co.uk"},
+		{"http://127.0.0.1:8080/", "127.0.0.1"},
+		{"http://localhost/", "localhost"},
+		{"not a url%", ""},
+	}
+
+	for _, test := range tests {
+		if site := util.Site(test.url); site != test.expected {
+			t.Errorf("Expected site %q for %s, got %q", test.expected, test.url, site)
+		}
+	}
+}
+
+func TestPathDepth(t *testing.T) {
+	tests := map[string]int{
+		"":              0,
+		"/":             0,
+		"/a":            1,
+		"/a/b/":         2,
+		"//a//b/c.html": 3,
+	}
+
+	for path, expected := range tests {
+		if depth := util.PathDepth(path); depth != expected {
+			t.Errorf("Expected depth %d for %q, got %d", expected, path, depth)
+		}
+	}
+}
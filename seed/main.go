@@ -2,8 +2,10 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"strings"
@@ -12,8 +14,12 @@ import (
 func main() {
 
 	var seedFile string
+	var sitemaps bool
+	var token string
 
 	flag.StringVar(&seedFile, "file", "global/domains.txt", "File containing domains to seed")
+	flag.BoolVar(&sitemaps, "sitemaps", false, "Seed each domain from its robots.txt and sitemaps instead of adding it as a page")
+	flag.StringVar(&token, "token", "", "API token, needed to seed from sitemaps")
 
 	flag.Parse()
 
@@ -26,6 +32,11 @@ func main() {
 	splitByLine := strings.Split(string(file), "\n")
 
 	for _, domain := range splitByLine {
+		if sitemaps {
+			seedSitemaps(strings.TrimSpace(domain), token)
+			continue
+		}
+
 		res, err := http.Post(
 			"http://localhost:8080/pages",
 			"application/json",
@@ -43,3 +54,38 @@ func main() {
 	}
 
 }
+
+func seedSitemaps(domain, token string) {
+	if domain == "" {
+		return
+	}
+
+	body, err := json.Marshal(map[string]string{"url": domain})
+	if err != nil {
+		return
+	}
+
+	req, err := http.NewRequest("POST", "http://localhost:8080/crawl/sitemaps", bytes.NewBuffer(body))
+	if err != nil {
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Printf("Failed to seed %s from its sitemaps: %v\n", domain, err)
+		return
+	}
+	defer res.Body.Close()
+
+	result, _ := io.ReadAll(res.Body)
+
+	if res.StatusCode != http.StatusAccepted {
+		fmt.Printf("Failed to seed %s from its sitemaps got unexpected status code: %d %s\n", domain, res.StatusCode, result)
+		return
+	}
+
+	fmt.Printf("Seeding %s from its sitemaps: %s\n", domain, result)
+}
@@ -5,9 +5,21 @@ import type { Result } from '@/types'
 export const useResultStore = defineStore('result', () => {
   const results = ref<Result[]>([])
 
+  const totalHits = ref(0)
+  const nextCursor = ref('')
+
   const setResults = (newResults: Result[]) => {
     results.value = newResults
   }
 
-  return { results, setResults }
+  const appendResults = (newResults: Result[]) => {
+    results.value = results.value.concat(newResults)
+  }
+
+  const setPaging = (newTotalHits: number, newNextCursor: string) => {
+    totalHits.value = newTotalHits
+    nextCursor.value = newNextCursor
+  }
+
+  return { results, totalHits, nextCursor, setResults, appendResults, setPaging }
 })
@@ -20,4 +20,8 @@ export interface Node {
     port: number;
     shard_id: number;
     created_at: string;
-}
\ No newline at end of file
+}
+export interface QueryColumn {
+    name: string;
+    type: string;
+}